@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
+	_ "time/tzdata" // 内置时区数据，精简镜像中也能按汇总配置的时区计算
 
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/database"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/handlers"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/scheduler"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/web"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
 
@@ -58,6 +61,11 @@ func main() {
 		log.Fatalf("Failed to initialize admin account: %v", err)
 	}
 
+	// 初始化默认 GitLab 实例
+	if err := h.EnsureDefaultGitLabInstance(); err != nil {
+		log.Fatalf("Failed to initialize default GitLab instance: %v", err)
+	}
+
 	// 注册路由
 	setupRoutes(router, h)
 
@@ -66,6 +74,14 @@ func main() {
 		web.ServeIndexHTML(c)
 	})
 
+	// 启动后台定时任务
+	jobScheduler := scheduler.New(db)
+	h.RegisterScheduledJobs(jobScheduler)
+	if err := jobScheduler.Start(context.Background()); err != nil {
+		log.Fatalf("Failed to start scheduler: %v", err)
+	}
+	defer jobScheduler.Stop()
+
 	// 启动服务器
 	logger.GetLogger().Infof("Starting server on %s:%d", cfg.Host, cfg.Port)
 	if err := router.Run(fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)); err != nil {
@@ -96,6 +112,7 @@ func setupRoutes(router *gin.Engine, h *handlers.Handler) {
 
 		// GitLab Webhook接收（无需认证，使用 webhook 自身的验证）
 		api.POST("/webhook/gitlab", h.HandleGitLabWebhook)
+		api.POST("/webhook/gitlab/:instance", h.HandleGitLabWebhook)
 
 		// 需要认证的路由
 		protected := api.Group("")
@@ -108,6 +125,8 @@ func setupRoutes(router *gin.Engine, h *handlers.Handler) {
 				authProtected.PUT("/profile", h.UpdateProfile)
 				authProtected.POST("/avatar", h.UploadAvatar)
 				authProtected.POST("/change-password", h.ChangePassword)
+				authProtected.GET("/notification-preferences", h.GetMyNotificationPreference)
+				authProtected.PUT("/notification-preferences", h.UpdateMyNotificationPreference)
 			}
 
 			// 账户管理（仅管理员）
@@ -129,8 +148,20 @@ func setupRoutes(router *gin.Engine, h *handlers.Handler) {
 			{
 				users.GET("", h.GetUsers)
 				users.POST("", h.CreateUser)
+				users.GET("/export", h.ExportUsers)
+				users.POST("/import", h.ImportUsers)
 				users.PUT("/:id", h.UpdateUser).Use(h.GetOwnershipChecker().CheckUserOwnership())
 				users.DELETE("/:id", h.DeleteUser).Use(h.GetOwnershipChecker().CheckUserOwnership())
+				users.POST("/:id/confirm-gitlab-mapping", h.GetOwnershipChecker().CheckUserOwnership(), h.ConfirmUserGitLabMapping)
+				users.GET("/:id/identities", h.GetOwnershipChecker().CheckUserOwnership(), h.GetUserIdentities)
+				users.PUT("/:id/identities/:channel", h.GetOwnershipChecker().CheckUserOwnership(), h.SetUserIdentity)
+				users.DELETE("/:id/identities/:channel", h.GetOwnershipChecker().CheckUserOwnership(), h.DeleteUserIdentity)
+				users.GET("/:id/aliases", h.GetOwnershipChecker().CheckUserOwnership(), h.GetUserAliases)
+				users.POST("/:id/aliases", h.GetOwnershipChecker().CheckUserOwnership(), h.CreateUserAlias)
+				users.DELETE("/:id/aliases/:alias_id", h.GetOwnershipChecker().CheckUserOwnership(), h.DeleteUserAlias)
+				users.POST("/:id/merge", h.GetOwnershipChecker().CheckUserOwnership(), h.MergeUsers)
+				users.GET("/:id/notification-preferences", h.GetOwnershipChecker().CheckUserOwnership(), h.GetUserNotificationPreference)
+				users.PUT("/:id/notification-preferences", h.GetOwnershipChecker().CheckUserOwnership(), h.UpdateUserNotificationPreference)
 			}
 
 			// 项目管理API
@@ -142,6 +173,8 @@ func setupRoutes(router *gin.Engine, h *handlers.Handler) {
 				projects.POST("/parse-url", h.ParseProjectURL)
 				projects.POST("/scan-group", h.ScanGroupProjects)
 				projects.POST("/batch-create", h.BatchCreateProjects)
+				projects.GET("/webhook-status", h.GetWebhookStatusOverview)
+				projects.POST("/webhook-status/refresh", h.RefreshWebhookStatus)
 				// 参数路由放在最后
 				projects.PUT("/:id", h.GetOwnershipChecker().CheckProjectOwnership(), h.UpdateProject)
 				projects.DELETE("/:id", h.GetOwnershipChecker().CheckProjectOwnership(), h.DeleteProject)
@@ -150,7 +183,15 @@ func setupRoutes(router *gin.Engine, h *handlers.Handler) {
 				projects.POST("/:id/sync-gitlab-webhook", h.SyncGitLabWebhook).Use(h.GetOwnershipChecker().CheckProjectOwnership())
 				projects.DELETE("/:id/sync-gitlab-webhook", h.DeleteGitLabWebhook).Use(h.GetOwnershipChecker().CheckProjectOwnership())
 				projects.GET("/:id/gitlab-webhook-status", h.GetGitLabWebhookStatus).Use(h.GetOwnershipChecker().CheckProjectOwnership())
+				projects.POST("/:id/repair-gitlab-webhook", h.GetOwnershipChecker().CheckProjectOwnership(), h.RepairGitLabWebhook)
 				projects.POST("/batch-check-webhook-status", h.BatchCheckWebhookStatus)
+				projects.GET("/:id/reminder-settings", h.GetOwnershipChecker().CheckProjectOwnership(), h.GetProjectReminderSetting)
+				projects.PUT("/:id/reminder-settings", h.GetOwnershipChecker().CheckProjectOwnership(), h.UpdateProjectReminderSetting)
+				projects.GET("/:id/escalation-policies", h.GetOwnershipChecker().CheckProjectOwnership(), h.GetEscalationPolicies)
+				projects.POST("/:id/escalation-policies", h.GetOwnershipChecker().CheckProjectOwnership(), h.CreateEscalationPolicy)
+				projects.PUT("/:id/escalation-policies/:policy_id", h.GetOwnershipChecker().CheckProjectOwnership(), h.UpdateEscalationPolicy)
+				projects.DELETE("/:id/escalation-policies/:policy_id", h.GetOwnershipChecker().CheckProjectOwnership(), h.DeleteEscalationPolicy)
+				projects.GET("/:id/escalation-history", h.GetOwnershipChecker().CheckProjectOwnership(), h.GetEscalationHistory)
 			}
 
 			// GitLab相关API
@@ -166,9 +207,12 @@ func setupRoutes(router *gin.Engine, h *handlers.Handler) {
 			{
 				webhooks.GET("", h.GetWebhooks)
 				webhooks.POST("", h.CreateWebhook)
+				webhooks.GET("/quota-usage", h.GetWebhookQuotaUsage)
 				webhooks.PUT("/:id", h.UpdateWebhook).Use(h.GetOwnershipChecker().CheckWebhookOwnership())
 				webhooks.DELETE("/:id", h.DeleteWebhook).Use(h.GetOwnershipChecker().CheckWebhookOwnership())
 				webhooks.POST("/:id/test", h.SendTestMessage).Use(h.GetOwnershipChecker().CheckWebhookOwnership())
+				webhooks.POST("/:id/digest", h.GetOwnershipChecker().CheckWebhookOwnership(), h.SendWebhookDigest)
+				webhooks.GET("/:id/quota-usage", h.GetOwnershipChecker().CheckWebhookOwnership(), h.GetWebhookQuotaUsageByID)
 			}
 
 			// 项目-Webhook关联API
@@ -186,6 +230,48 @@ func setupRoutes(router *gin.Engine, h *handlers.Handler) {
 				resourceManager.POST("/batch-assign/:id", h.BatchAssignResources)
 			}
 
+			// GitLab 实例管理API（仅管理员）
+			gitlabInstances := protected.Group("/gitlab-instances")
+			gitlabInstances.Use(h.GetAuthMiddleware().RequireAdmin())
+			{
+				gitlabInstances.GET("", h.GetGitLabInstances)
+				gitlabInstances.POST("", h.CreateGitLabInstance)
+				gitlabInstances.PUT("/:id", h.UpdateGitLabInstance)
+				gitlabInstances.DELETE("/:id", h.DeleteGitLabInstance)
+			}
+
+			// GitLab 组级 Webhook 管理API（仅管理员）
+			gitlabGroups := protected.Group("/gitlab-groups")
+			gitlabGroups.Use(h.GetAuthMiddleware().RequireAdmin())
+			{
+				gitlabGroups.GET("", h.GetGitLabGroups)
+				gitlabGroups.POST("", h.CreateGitLabGroup)
+				gitlabGroups.PUT("/:id", h.UpdateGitLabGroup)
+				gitlabGroups.DELETE("/:id", h.DeleteGitLabGroup)
+				gitlabGroups.POST("/:id/sync-hook", h.SyncGitLabGroupHook)
+				gitlabGroups.POST("/:id/sync", h.SyncGitLabGroup)
+				gitlabGroups.GET("/:id/sync-runs", h.GetGitLabGroupSyncRuns)
+			}
+
+			// 用户目录同步API（仅管理员）
+			userSync := protected.Group("/user-sync")
+			userSync.Use(h.GetAuthMiddleware().RequireAdmin())
+			{
+				userSync.GET("/sources", h.GetUserSyncSources)
+				userSync.POST("/sources", h.CreateUserSyncSource)
+				userSync.DELETE("/sources/:id", h.DeleteUserSyncSource)
+				userSync.POST("/sync", h.SyncUserDirectory)
+				userSync.GET("/runs", h.GetUserSyncRuns)
+				userSync.GET("/report", h.GetUserSyncReport)
+			}
+
+			// 系统告警API（管理员查看全部，其他用户只能查看自己资源的告警）
+			protected.GET("/ops-alerts", h.GetOpsAlerts)
+
+			// 合并请求跟踪API
+			protected.GET("/merge-requests", h.GetMergeRequests)
+			protected.GET("/merge-requests/:id", h.GetMergeRequest)
+
 			// 统计API
 			protected.GET("/stats", h.GetStats)
 			protected.GET("/notifications", h.GetNotifications)
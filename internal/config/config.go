@@ -9,28 +9,142 @@ import (
 )
 
 type Config struct {
-	Host             string             `mapstructure:"host"`
-	Port             int                `mapstructure:"port"`
-	Environment      string             `mapstructure:"environment"`
-	LogLevel         string             `mapstructure:"log_level"`
-	DatabasePath     string             `mapstructure:"database_path"`
-	GitLabURL        string             `mapstructure:"gitlab_url" json:"-"`
-	PublicWebhookURL string             `mapstructure:"public_webhook_url"`
-	JWTSecret        string             `mapstructure:"jwt_secret" json:"-"`
-	JWTDuration      time.Duration      `mapstructure:"jwt_duration"`
-	EncryptionKey    string             `mapstructure:"encryption_key" json:"-"`
-	Notification     NotificationConfig `mapstructure:"notification"`
+	Host             string        `mapstructure:"host"`
+	Port             int           `mapstructure:"port"`
+	Environment      string        `mapstructure:"environment"`
+	LogLevel         string        `mapstructure:"log_level"`
+	DatabasePath     string        `mapstructure:"database_path"`
+	GitLabURL        string        `mapstructure:"gitlab_url" json:"-"`
+	PublicWebhookURL string        `mapstructure:"public_webhook_url"`
+	JWTSecret        string        `mapstructure:"jwt_secret" json:"-"`
+	JWTDuration      time.Duration `mapstructure:"jwt_duration"`
+	EncryptionKey    string        `mapstructure:"encryption_key" json:"-"`
+	// GitLabServiceToken 后台任务访问 GitLab API 使用的令牌（可选），未配置时使用项目创建者的令牌
+	GitLabServiceToken string              `mapstructure:"gitlab_service_token" json:"-"`
+	GitLabAPI          GitLabAPIConfig     `mapstructure:"gitlab_api"`
+	Notification       NotificationConfig  `mapstructure:"notification"`
+	Reminder           ReminderConfig      `mapstructure:"reminder"`
+	GroupSync          GroupSyncConfig     `mapstructure:"group_sync"`
+	HookReconcile      HookReconcileConfig `mapstructure:"hook_reconcile"`
+	WebhookStatus      WebhookStatusConfig `mapstructure:"webhook_status"`
+	// MergeRequestEnrichment 合并请求通知补充 GitLab API 数据
+	MergeRequestEnrichment MergeRequestEnrichmentConfig `mapstructure:"mr_enrichment"`
+	// UserLookup 通过 GitLab 用户接口解析邮箱被隐藏的指派人
+	UserLookup UserLookupConfig `mapstructure:"user_lookup"`
+	// UserSync 从 GitLab 成员定时同步用户目录
+	UserSync UserSyncConfig `mapstructure:"user_sync"`
+}
+
+// GitLabAPIConfig GitLab API 请求超时与重试配置
+type GitLabAPIConfig struct {
+	Timeout time.Duration `mapstructure:"timeout"`
+	// MaxRetries 429 与 5xx（仅幂等请求）的最大重试次数
+	MaxRetries int `mapstructure:"max_retries"`
+	// MaxRetryWait 单次重试的最长等待时间，Retry-After 或 RateLimit-Reset 超过该值时不再重试
+	MaxRetryWait time.Duration `mapstructure:"max_retry_wait"`
+}
+
+// MergeRequestEnrichmentConfig 合并请求通知补充信息配置
+type MergeRequestEnrichmentConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Timeout 每条通知等待 GitLab API 的总时长，超时后按已取到的信息发送
+	Timeout  time.Duration `mapstructure:"timeout"`
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	// DiffStats 统计新增与删除行数，需要拉取完整 diff
+	DiffStats bool `mapstructure:"diff_stats"`
+}
+
+// UserLookupConfig GitLab 用户查询配置
+type UserLookupConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// AdminToken 默认实例的管理员令牌，用于按用户名查询用户邮箱；其他实例使用实例的默认令牌
+	AdminToken string        `mapstructure:"admin_token" json:"-"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+	// MissTTL 未匹配到本地用户时的缓存时长，期间同一 GitLab 用户不再重复查询
+	MissTTL time.Duration `mapstructure:"miss_ttl"`
+}
+
+// UserSyncConfig 用户目录定时同步配置
+type UserSyncConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+type ReminderConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+}
+
+// GroupSyncConfig 关注的 GitLab 组定时同步配置
+type GroupSyncConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// HookReconcileConfig 项目 Hook 偏差检查配置
+type HookReconcileConfig struct {
+	// EnableSSLVerification 创建与修复 Hook 时期望的 SSL 证书校验开关
+	EnableSSLVerification bool `mapstructure:"enable_ssl_verification"`
+	// Enabled 定时检查已同步项目的 Hook 是否被改动
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	// AutoRepair 定时检查发现偏差时自动修复，否则只记录并发送系统告警
+	AutoRepair bool `mapstructure:"auto_repair"`
+}
+
+// WebhookStatusConfig 后台刷新项目 hook 状态的配置
+type WebhookStatusConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	// Concurrency 同时请求 GitLab API 的项目数
+	Concurrency int `mapstructure:"concurrency"`
 }
 
 type NotificationConfig struct {
-	DingTalk DingTalkConfig `mapstructure:"dingtalk"`
+	// AdminWebhookID 接收系统告警（配额、熔断、投递失败等）的 Webhook，0 表示只记录日志
+	AdminWebhookID uint `mapstructure:"admin_webhook_id"`
+	// WebhookTimeout 合并请求回调中查询 GitLab（解析指派人、补充合并请求信息）的总时长，需小于 GitLab 的回调超时（10s）
+	WebhookTimeout time.Duration        `mapstructure:"webhook_timeout"`
+	OpsAlerts      OpsAlertConfig       `mapstructure:"ops_alerts"`
+	DingTalk       DingTalkConfig       `mapstructure:"dingtalk"`
+	WeCom          WeComConfig          `mapstructure:"wecom"`
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+}
+
+// OpsAlertConfig 系统告警配置
+type OpsAlertConfig struct {
+	// MinSeverity 低于该级别的告警只记录不通知：info、warning、critical
+	MinSeverity string `mapstructure:"min_severity"`
+	// DedupWindow 相同告警在窗口内只通知一次
+	DedupWindow time.Duration `mapstructure:"dedup_window"`
+}
+
+// CircuitBreakerConfig 渠道熔断配置：连续失败后暂停发送，冷却后试探恢复
+type CircuitBreakerConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+	Cooldown         time.Duration `mapstructure:"cooldown"`
+	// AlertAfter 熔断持续超过该时长时通知 Webhook 所属账户
+	AlertAfter time.Duration `mapstructure:"alert_after"`
+	// AutoDisable 通知的同时停用 Webhook
+	AutoDisable bool `mapstructure:"auto_disable"`
 }
 
 type DingTalkConfig struct {
+	// RateLimitPerMinute 每个机器人的默认速率，Webhook 可单独覆盖
+	RateLimitPerMinute int `mapstructure:"rate_limit_per_minute"`
+	// RateLimitMaxWait 超出速率时等待令牌的最长时间，超时后消息进入投递队列
+	RateLimitMaxWait time.Duration `mapstructure:"rate_limit_max_wait"`
+	MonthlyQuota     int           `mapstructure:"monthly_quota"`
+	// QuotaWarningThresholds 月度配额使用百分比达到这些阈值时发送预警
+	QuotaWarningThresholds []int         `mapstructure:"quota_warning_thresholds"`
+	RequestTimeout         time.Duration `mapstructure:"request_timeout"`
+	RetryAttempts          int           `mapstructure:"retry_attempts"`
+}
+
+type WeComConfig struct {
 	RateLimitPerMinute int           `mapstructure:"rate_limit_per_minute"`
-	MonthlyQuota       int           `mapstructure:"monthly_quota"`
-	RequestTimeout     time.Duration `mapstructure:"request_timeout"`
-	RetryAttempts      int           `mapstructure:"retry_attempts"`
+	RateLimitMaxWait   time.Duration `mapstructure:"rate_limit_max_wait"`
 }
 
 // MaskSensitive 返回一个掩码后的配置副本，用于日志输出
@@ -45,6 +159,12 @@ func (c *Config) MaskSensitive() Config {
 	if masked.EncryptionKey != "" {
 		masked.EncryptionKey = "****"
 	}
+	if masked.GitLabServiceToken != "" {
+		masked.GitLabServiceToken = "****"
+	}
+	if masked.UserLookup.AdminToken != "" {
+		masked.UserLookup.AdminToken = "****"
+	}
 	return masked
 }
 
@@ -78,10 +198,45 @@ func Load() (*Config, error) {
 	viper.SetDefault("log_level", "info")
 	viper.SetDefault("database_path", "./data/gitlab-merge-alert.db")
 	viper.SetDefault("jwt_duration", "24h")
+	viper.SetDefault("notification.webhook_timeout", "5s")
+	viper.SetDefault("notification.ops_alerts.min_severity", "warning")
+	viper.SetDefault("notification.ops_alerts.dedup_window", "1h")
 	viper.SetDefault("notification.dingtalk.rate_limit_per_minute", 20)
+	viper.SetDefault("notification.dingtalk.rate_limit_max_wait", "5s")
 	viper.SetDefault("notification.dingtalk.monthly_quota", 5000)
+	viper.SetDefault("notification.dingtalk.quota_warning_thresholds", []int{80, 95})
 	viper.SetDefault("notification.dingtalk.request_timeout", "5s")
 	viper.SetDefault("notification.dingtalk.retry_attempts", 3)
+	viper.SetDefault("notification.wecom.rate_limit_per_minute", 20)
+	viper.SetDefault("notification.wecom.rate_limit_max_wait", "5s")
+	viper.SetDefault("notification.circuit_breaker.enabled", true)
+	viper.SetDefault("notification.circuit_breaker.failure_threshold", 5)
+	viper.SetDefault("notification.circuit_breaker.cooldown", "5m")
+	viper.SetDefault("notification.circuit_breaker.alert_after", "1h")
+	viper.SetDefault("notification.circuit_breaker.auto_disable", false)
+	viper.SetDefault("reminder.enabled", true)
+	viper.SetDefault("reminder.check_interval", "10m")
+	viper.SetDefault("group_sync.enabled", true)
+	viper.SetDefault("group_sync.interval", "30m")
+	viper.SetDefault("hook_reconcile.enable_ssl_verification", false)
+	viper.SetDefault("hook_reconcile.enabled", false)
+	viper.SetDefault("hook_reconcile.interval", "1h")
+	viper.SetDefault("hook_reconcile.auto_repair", false)
+	viper.SetDefault("gitlab_api.timeout", "30s")
+	viper.SetDefault("gitlab_api.max_retries", 3)
+	viper.SetDefault("gitlab_api.max_retry_wait", "1m")
+	viper.SetDefault("user_sync.enabled", true)
+	viper.SetDefault("user_sync.interval", "6h")
+	viper.SetDefault("user_lookup.enabled", true)
+	viper.SetDefault("user_lookup.timeout", "3s")
+	viper.SetDefault("user_lookup.miss_ttl", "1h")
+	viper.SetDefault("mr_enrichment.enabled", false)
+	viper.SetDefault("mr_enrichment.timeout", "2s")
+	viper.SetDefault("mr_enrichment.cache_ttl", "5m")
+	viper.SetDefault("mr_enrichment.diff_stats", true)
+	viper.SetDefault("webhook_status.enabled", true)
+	viper.SetDefault("webhook_status.interval", "15m")
+	viper.SetDefault("webhook_status.concurrency", 10)
 
 	// 环境变量绑定（优先级最高）
 	viper.SetEnvPrefix("GMA")
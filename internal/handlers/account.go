@@ -235,6 +235,8 @@ func (h *Handler) UpdateAccount(c *gin.Context) {
 	// 执行更新
 	if req.GitLabPersonalAccessToken != nil {
 		token := strings.TrimSpace(*req.GitLabPersonalAccessToken)
+		// 更换令牌后重新获取令牌所属的 GitLab 用户名
+		updates["gitlab_username"] = ""
 		if token == "" {
 			updates["gitlab_access_token"] = ""
 		} else {
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
@@ -25,9 +26,9 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	if err := h.gitlabService.TestConnection(h.config.GitLabURL, token); err != nil {
+	if err := h.gitlabService.TestConnection(c.Request.Context(), h.config.GitLabURL, token); err != nil {
 		errMsg := err.Error()
-		if strings.Contains(errMsg, "连接失败") {
+		if errors.Is(err, services.ErrGitLabUnavailable) {
 			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "GitLab 服务暂不可用: " + errMsg})
 			return
 		}
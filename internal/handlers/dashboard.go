@@ -83,6 +83,7 @@ func (h *Handler) GetNotifications(c *gin.Context) {
 
 		responses = append(responses, models.NotificationResponse{
 			ID:               notification.ID,
+			EventType:        notification.EventType,
 			ProjectID:        notification.ProjectID,
 			ProjectName:      notification.Project.Name,
 			MergeRequestID:   notification.MergeRequestID,
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/middleware"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetEscalationPolicies 获取项目的升级策略列表
+func (h *Handler) GetEscalationPolicies(c *gin.Context) {
+	project, ok := h.loadEscalationProject(c)
+	if !ok {
+		return
+	}
+
+	policies, err := h.escalationService.ListPolicies(project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch escalation policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": policies})
+}
+
+// CreateEscalationPolicy 为项目创建升级策略
+func (h *Handler) CreateEscalationPolicy(c *gin.Context) {
+	project, ok := h.loadEscalationProject(c)
+	if !ok {
+		return
+	}
+
+	var req models.EscalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.checkEscalationWebhook(c, req.EscalationWebhookID) {
+		return
+	}
+
+	policy, err := h.escalationService.CreatePolicy(project.ID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "升级策略配置无效: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": policy})
+}
+
+// UpdateEscalationPolicy 更新项目的升级策略
+func (h *Handler) UpdateEscalationPolicy(c *gin.Context) {
+	project, ok := h.loadEscalationProject(c)
+	if !ok {
+		return
+	}
+
+	policyID, err := strconv.ParseUint(c.Param("policy_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid policy ID"})
+		return
+	}
+
+	var req models.EscalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.checkEscalationWebhook(c, req.EscalationWebhookID) {
+		return
+	}
+
+	policy, err := h.escalationService.UpdatePolicy(project.ID, uint(policyID), &req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Escalation policy not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "升级策略配置无效: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": policy})
+}
+
+// DeleteEscalationPolicy 删除项目的升级策略及其触发记录
+func (h *Handler) DeleteEscalationPolicy(c *gin.Context) {
+	project, ok := h.loadEscalationProject(c)
+	if !ok {
+		return
+	}
+
+	policyID, err := strconv.ParseUint(c.Param("policy_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid policy ID"})
+		return
+	}
+
+	if err := h.escalationService.DeletePolicy(project.ID, uint(policyID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Escalation policy not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete escalation policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Escalation policy deleted successfully"})
+}
+
+// GetEscalationHistory 获取项目的升级触发记录
+func (h *Handler) GetEscalationHistory(c *gin.Context) {
+	project, ok := h.loadEscalationProject(c)
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	history, err := h.escalationService.ListHistory(project.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch escalation history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": history})
+}
+
+func (h *Handler) loadEscalationProject(c *gin.Context) (*models.Project, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return nil, false
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+	return &project, true
+}
+
+// checkEscalationWebhook 校验升级渠道存在且当前用户有权使用
+func (h *Handler) checkEscalationWebhook(c *gin.Context, webhookID *uint) bool {
+	if webhookID == nil || *webhookID == 0 {
+		return true
+	}
+
+	query := h.db.Model(&models.Webhook{}).Where("id = ?", *webhookID)
+	if !middleware.IsAdmin(c) {
+		accountID, _ := middleware.GetAccountID(c)
+		query = query.Where("created_by = ?", accountID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "升级渠道不存在"})
+		return false
+	}
+	return true
+}
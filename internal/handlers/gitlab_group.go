@@ -0,0 +1,463 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/middleware"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/services"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetGitLabGroups 获取登记的 GitLab 组（仅管理员）
+func (h *Handler) GetGitLabGroups(c *gin.Context) {
+	groups, err := h.gitlabGroups.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch GitLab groups"})
+		return
+	}
+
+	responses := make([]models.GitLabGroupResponse, 0, len(groups))
+	for idx := range groups {
+		responses = append(responses, h.buildGitLabGroupResponse(&groups[idx]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses})
+}
+
+// CreateGitLabGroup 登记 GitLab 组，组 Hook 模式下在组中创建 Webhook（仅管理员，组级 Webhook 需要 GitLab Premium）
+func (h *Handler) CreateGitLabGroup(c *gin.Context) {
+	var req models.CreateGitLabGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数无效: " + err.Error()})
+		return
+	}
+
+	parsed := h.gitlabService.ParseGitLabURL(req.URL)
+	if !parsed.IsValid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "URL解析失败: " + parsed.Error})
+		return
+	}
+
+	instanceID, err := h.projectInstanceID(parsed)
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to resolve GitLab instance for group: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "识别 GitLab 实例失败"})
+		return
+	}
+
+	token, ok := h.groupHookToken(c, req.AccessToken, instanceID)
+	if !ok {
+		return
+	}
+
+	groupInfo, err := h.gitlabService.GetGroupByPath(c.Request.Context(), parsed.BaseURL, parsed.ProjectPath, token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "获取组信息失败: " + err.Error()})
+		return
+	}
+
+	accountID, _ := middleware.GetAccountID(c)
+	group := &models.GitLabGroup{
+		GitLabInstanceID: instanceID,
+		GitLabGroupID:    groupInfo.ID,
+		Name:             groupInfo.Name,
+		FullPath:         groupInfo.FullPath,
+		WebURL:           groupInfo.WebURL,
+		Watched:          req.Watched,
+		HookMode:         models.GroupHookModeGroup,
+		TokenSource:      models.GroupTokenSourceInstance,
+		CreatedBy:        &accountID,
+
+		DefaultMergeRequestEvents: true,
+	}
+	req.GitLabGroupSettings.Apply(group)
+
+	// 只有自定义令牌来源才保存请求中的令牌
+	groupToken := ""
+	if group.TokenSource == models.GroupTokenSourceCustom {
+		groupToken = req.AccessToken
+	}
+	if err := h.gitlabGroups.Create(group, req.WebhookIDs, groupToken); err != nil {
+		h.respondGitLabGroupError(c, err)
+		return
+	}
+
+	if group.HookMode == models.GroupHookModeGroup {
+		if err := h.syncGroupHook(c.Request.Context(), group, token); err != nil {
+			logger.GetLogger().Warnf("创建组 %s 的 GitLab Webhook 失败: %v", group.FullPath, err)
+		}
+	}
+	if group.Watched {
+		go h.syncGroupInBackground(group.ID)
+	}
+
+	logger.GetLogger().Infof("Registered GitLab group [ID: %d, Path: %s, Watched: %v, HookMode: %s]", group.ID, group.FullPath, group.Watched, group.HookMode)
+	c.JSON(http.StatusCreated, gin.H{"data": h.buildGitLabGroupResponse(group)})
+}
+
+// UpdateGitLabGroup 更新组的自动登记开关、接入方式、令牌来源与默认配置（仅管理员）
+func (h *Handler) UpdateGitLabGroup(c *gin.Context) {
+	group, ok := h.loadGitLabGroup(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateGitLabGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	previousMode := group.HookMode
+	if req.Watched != nil {
+		group.Watched = *req.Watched
+	}
+	req.GitLabGroupSettings.Apply(group)
+	if err := h.gitlabGroups.Update(group, req.WebhookIDs, req.AccessToken); err != nil {
+		h.respondGitLabGroupError(c, err)
+		return
+	}
+
+	if previousMode != group.HookMode && (previousMode == models.GroupHookModeGroup || group.HookMode == models.GroupHookModeGroup) {
+		token, ok := h.groupHookToken(c, "", group.GitLabInstanceID)
+		if !ok {
+			return
+		}
+		if group.HookMode == models.GroupHookModeGroup {
+			if err := h.syncGroupHook(c.Request.Context(), group, token); err != nil {
+				logger.GetLogger().Warnf("创建组 %s 的 GitLab Webhook 失败: %v", group.FullPath, err)
+			}
+		} else {
+			h.detachGroupHook(c.Request.Context(), group, token)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.buildGitLabGroupResponse(group)})
+}
+
+// SyncGitLabGroupHook 重新同步组中的 Webhook（仅管理员）
+func (h *Handler) SyncGitLabGroupHook(c *gin.Context) {
+	group, ok := h.loadGitLabGroup(c)
+	if !ok {
+		return
+	}
+	if group.HookMode != models.GroupHookModeGroup {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该组未使用组级 Webhook"})
+		return
+	}
+
+	token, ok := h.groupHookToken(c, "", group.GitLabInstanceID)
+	if !ok {
+		return
+	}
+
+	if err := h.syncGroupHook(c.Request.Context(), group, token); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "同步组 Webhook 失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.buildGitLabGroupResponse(group)})
+}
+
+// DeleteGitLabGroup 删除组中的 Webhook 并取消登记，组内已登记的项目恢复使用项目级 Webhook（仅管理员）
+func (h *Handler) DeleteGitLabGroup(c *gin.Context) {
+	group, ok := h.loadGitLabGroup(c)
+	if !ok {
+		return
+	}
+
+	token, ok := h.groupHookToken(c, "", group.GitLabInstanceID)
+	if !ok {
+		return
+	}
+
+	restored := 0
+	if group.HookMode == models.GroupHookModeGroup {
+		restored = h.detachGroupHook(c.Request.Context(), group, token)
+	}
+
+	if err := h.gitlabGroups.Delete(group.ID); err != nil {
+		h.respondGitLabGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "GitLab group deleted successfully", "restored_projects": restored})
+}
+
+// SyncGitLabGroup 立即同步组内项目并返回本次的变更报告（仅管理员）
+func (h *Handler) SyncGitLabGroup(c *gin.Context) {
+	group, ok := h.loadGitLabGroup(c)
+	if !ok {
+		return
+	}
+
+	run, err := h.gitlabGroups.SyncGroup(c.Request.Context(), group, models.GroupSyncTriggerManual)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "同步组内项目失败: " + err.Error(), "data": run})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": run})
+}
+
+// GetGitLabGroupSyncRuns 获取组最近的同步记录（仅管理员）
+func (h *Handler) GetGitLabGroupSyncRuns(c *gin.Context) {
+	group, ok := h.loadGitLabGroup(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	runs, err := h.gitlabGroups.ListSyncRuns(group.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sync runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": runs})
+}
+
+// syncGroupInBackground 新登记的关注组立即执行一次同步，不等待定时任务
+func (h *Handler) syncGroupInBackground(groupID uint) {
+	group, err := h.gitlabGroups.Get(groupID)
+	if err != nil {
+		logger.GetLogger().Warnf("查询组 %d 失败: %v", groupID, err)
+		return
+	}
+	if _, err := h.gitlabGroups.SyncGroup(context.Background(), group, models.GroupSyncTriggerManual); err != nil {
+		logger.GetLogger().Warnf("同步组 %s 失败: %v", group.FullPath, err)
+	}
+}
+
+// detachGroupHook 删除组中的 Webhook，并为组内已登记的项目重新创建项目级 Webhook，返回恢复的项目数
+func (h *Handler) detachGroupHook(ctx context.Context, group *models.GitLabGroup, token string) int {
+	covered, err := h.gitlabGroups.CoveredProjects(group)
+	if err != nil {
+		logger.GetLogger().Warnf("查询组 %s 下的项目失败: %v", group.FullPath, err)
+	}
+
+	target := h.hookTarget(&models.Project{GitLabInstanceID: group.GitLabInstanceID, URL: group.WebURL}, token)
+	if target.baseURL != "" {
+		deletedCount, err := h.gitlabService.DeleteGroupWebhooksByURL(ctx, target.baseURL, group.GitLabGroupID, target.webhookURL, target.token)
+		if err != nil {
+			logger.GetLogger().Warnf("删除组 %s 的 GitLab Webhook 失败: %v (已删除 %d 个)", group.FullPath, err, deletedCount)
+		}
+	}
+	// 先标记组 Hook 未同步，组内项目不再视为已覆盖
+	if err := h.gitlabGroups.SaveHookStatus(group, nil, nil); err != nil {
+		logger.GetLogger().Warnf("保存组 %s 的 Webhook 状态失败: %v", group.FullPath, err)
+	}
+
+	// 组 Hook 删除后，组内项目重新创建项目级 Webhook
+	if len(covered) > 0 {
+		go func(projects []models.Project) {
+			for idx := range projects {
+				h.autoCreateGitLabWebhook(context.Background(), &projects[idx], token)
+			}
+		}(covered)
+	}
+	return len(covered)
+}
+
+// syncGroupHook 在组中创建或更新指向本服务的 Webhook，成功后清理组内项目的项目级 Webhook，避免重复推送
+func (h *Handler) syncGroupHook(ctx context.Context, group *models.GitLabGroup, token string) error {
+	target := h.hookTarget(&models.Project{GitLabInstanceID: group.GitLabInstanceID, URL: group.WebURL}, token)
+
+	events := services.GroupHookEvents()
+	events.SecretToken = target.secretToken
+	events.SSLVerification = target.sslVerification
+
+	var hookID *int
+	webhook, created, err := h.gitlabService.SyncGroupWebhook(ctx, target.baseURL, group.GitLabGroupID, target.webhookURL, events, target.token)
+	if err == nil {
+		hookID = &webhook.ID
+	}
+	if saveErr := h.gitlabGroups.SaveHookStatus(group, hookID, err); saveErr != nil {
+		logger.GetLogger().Warnf("保存组 %s 的 Webhook 状态失败: %v", group.FullPath, saveErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if created {
+		logger.GetLogger().Infof("组 %s 的 GitLab Webhook 创建成功，ID: %d", group.FullPath, webhook.ID)
+	}
+	go h.removeCoveredProjectHooks(context.Background(), group, token)
+	return nil
+}
+
+// removeCoveredProjectHooks 删除组内已登记项目的项目级 Webhook，事件改由组 Hook 推送
+func (h *Handler) removeCoveredProjectHooks(ctx context.Context, group *models.GitLabGroup, token string) {
+	projects, err := h.gitlabGroups.CoveredProjects(group)
+	if err != nil {
+		logger.GetLogger().Warnf("查询组 %s 下的项目失败: %v", group.FullPath, err)
+		return
+	}
+
+	var removed int
+	for idx := range projects {
+		project := &projects[idx]
+		if !project.WebhookSynced && project.GitLabWebhookID == nil {
+			continue
+		}
+
+		target := h.hookTarget(project, token)
+		if target.baseURL == "" {
+			continue
+		}
+		if _, err := h.gitlabService.DeleteAllWebhooksByURL(ctx, target.baseURL, project.GitLabProjectID, target.webhookURL, target.token); err != nil {
+			logger.GetLogger().Warnf("删除项目 %d 的项目级 Webhook 失败: %v", project.ID, err)
+			continue
+		}
+
+		now := time.Now()
+		if err := h.db.Model(project).Updates(map[string]interface{}{
+			"webhook_synced":    false,
+			"gitlab_webhook_id": nil,
+			"last_sync_at":      &now,
+		}).Error; err != nil {
+			logger.GetLogger().Warnf("保存项目 %d webhook状态失败: %v", project.ID, err)
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		logger.GetLogger().Infof("组 %s 已覆盖 %d 个项目，已删除其项目级 Webhook", group.FullPath, removed)
+	}
+}
+
+// ensureGroupEventProject 组 Hook 推送了未登记项目的事件时：组开启了自动登记则登记项目，否则直接忽略，
+// 避免 GitLab 因连续失败停用组 Hook。返回 false 表示已经响应请求
+func (h *Handler) ensureGroupEventProject(c *gin.Context, instanceID uint, header *models.GitLabEventHeader) bool {
+	if header.Project.ID == 0 {
+		return true
+	}
+
+	var count int64
+	if err := h.db.Model(&models.Project{}).
+		Where("gitlab_instance_id = ? AND gitlab_project_id = ?", instanceID, header.Project.ID).
+		Count(&count).Error; err != nil || count > 0 {
+		return true
+	}
+
+	project, group, err := h.gitlabGroups.AutoRegisterProject(instanceID, &header.Project)
+	switch {
+	case err != nil:
+		logger.GetLogger().Errorf("Failed to auto register project %d: %v", header.Project.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
+		return false
+	case project != nil:
+		return true
+	case group != nil:
+		logger.GetLogger().Debugf("组 %s 未开启自动登记，忽略未登记项目 %s 的事件", group.FullPath, header.Project.PathWithNamespace)
+		c.JSON(http.StatusOK, gin.H{"message": "Event ignored"})
+		return false
+	}
+	return true
+}
+
+// projectCoveringGroup 返回覆盖该项目的组，未被组 Hook 覆盖时返回 nil
+func (h *Handler) projectCoveringGroup(project *models.Project) *models.GitLabGroup {
+	return h.gitlabGroups.CoverageForProjects([]models.Project{*project})[project.ID]
+}
+
+// groupHookToken 解析管理组 Webhook 使用的令牌，失败时直接响应
+func (h *Handler) groupHookToken(c *gin.Context, provided string, instanceID uint) (string, bool) {
+	token, err := h.resolveInstanceToken(c, provided, instanceID)
+	if err == nil {
+		return token, true
+	}
+
+	switch {
+	case errors.Is(err, errUnauthorized):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	case errors.Is(err, errGitLabTokenMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请先在账户管理中配置 GitLab Personal Access Token"})
+	default:
+		logger.GetLogger().Errorf("Failed to resolve GitLab token for group hook: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解析凭证失败"})
+	}
+	return "", false
+}
+
+func (h *Handler) loadGitLabGroup(c *gin.Context) (*models.GitLabGroup, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return nil, false
+	}
+
+	group, err := h.gitlabGroups.Get(uint(id))
+	if err != nil {
+		h.respondGitLabGroupError(c, err)
+		return nil, false
+	}
+	return group, true
+}
+
+func (h *Handler) respondGitLabGroupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrGitLabGroupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "GitLab group not found"})
+	case errors.Is(err, services.ErrGitLabGroupExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "该组已登记"})
+	case errors.Is(err, services.ErrGitLabGroupTokenNeeded):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "令牌来源为 custom 时必须提供 access_token"})
+	default:
+		logger.GetLogger().Errorf("GitLab group operation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *Handler) buildGitLabGroupResponse(group *models.GitLabGroup) models.GitLabGroupResponse {
+	var instance *models.GitLabInstance
+	if found, err := h.gitlabInstances.Get(group.GitLabInstanceID); err == nil {
+		instance = found
+	}
+
+	covered, err := h.gitlabGroups.CoveredProjects(group)
+	if err != nil {
+		logger.GetLogger().Warnf("查询组 %s 下的项目失败: %v", group.FullPath, err)
+	}
+
+	response := models.GitLabGroupResponse{
+		ID:                  group.ID,
+		GitLabInstanceID:    group.GitLabInstanceID,
+		GitLabGroupID:       group.GitLabGroupID,
+		Name:                group.Name,
+		FullPath:            group.FullPath,
+		WebURL:              group.WebURL,
+		GitLabHookID:        group.GitLabHookID,
+		HookSynced:          group.HookSynced,
+		WebhookURL:          h.instanceWebhookURL(instance),
+		LastSyncAt:          group.LastSyncAt,
+		LastSyncError:       group.LastSyncError,
+		Watched:             group.Watched,
+		HookMode:            group.HookMode,
+		TokenSource:         group.TokenSource,
+		HasAccessToken:      group.AccessToken != "",
+		LastScanAt:          group.LastScanAt,
+		LastScanError:       group.LastScanError,
+		CoveredProjectCount: len(covered),
+		CreatedAt:           group.CreatedAt,
+		UpdatedAt:           group.UpdatedAt,
+
+		DefaultMergeRequestEvents: group.DefaultMergeRequestEvents,
+		DefaultPushEvents:         group.DefaultPushEvents,
+		DefaultTagPushEvents:      group.DefaultTagPushEvents,
+		DefaultReleaseEvents:      group.DefaultReleaseEvents,
+		DefaultPushBranchFilters:  group.DefaultPushBranchFilters,
+		DefaultTagFilters:         group.DefaultTagFilters,
+	}
+	for idx := range group.Webhooks {
+		response.Webhooks = append(response.Webhooks, buildWebhookResponse(&group.Webhooks[idx]))
+	}
+	return response
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/services"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetGitLabInstances 获取已登记的 GitLab 实例（仅管理员）
+func (h *Handler) GetGitLabInstances(c *gin.Context) {
+	instances, err := h.gitlabInstances.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch GitLab instances"})
+		return
+	}
+
+	responses := make([]models.GitLabInstanceResponse, 0, len(instances))
+	for idx := range instances {
+		responses = append(responses, h.buildGitLabInstanceResponse(&instances[idx]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses})
+}
+
+// CreateGitLabInstance 登记新的 GitLab 实例（仅管理员）
+func (h *Handler) CreateGitLabInstance(c *gin.Context) {
+	var req models.GitLabInstanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	instance, err := h.gitlabInstances.Create(&req)
+	if err != nil {
+		h.respondGitLabInstanceError(c, err)
+		return
+	}
+
+	logger.GetLogger().Infof("Created GitLab instance [ID: %d, Name: %s, URL: %s]", instance.ID, instance.Name, instance.BaseURL)
+	c.JSON(http.StatusCreated, gin.H{"data": h.buildGitLabInstanceResponse(instance)})
+}
+
+// UpdateGitLabInstance 更新 GitLab 实例，令牌与密钥字段不传时保持不变、传空字符串时清除（仅管理员）
+func (h *Handler) UpdateGitLabInstance(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid instance ID"})
+		return
+	}
+
+	var req models.GitLabInstanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	instance, err := h.gitlabInstances.Update(uint(id), &req)
+	if err != nil {
+		h.respondGitLabInstanceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.buildGitLabInstanceResponse(instance)})
+}
+
+// DeleteGitLabInstance 删除没有项目的 GitLab 实例（仅管理员）
+func (h *Handler) DeleteGitLabInstance(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid instance ID"})
+		return
+	}
+
+	if err := h.gitlabInstances.Delete(uint(id)); err != nil {
+		h.respondGitLabInstanceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "GitLab instance deleted successfully"})
+}
+
+func (h *Handler) respondGitLabInstanceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrGitLabInstanceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "GitLab instance not found"})
+	case errors.Is(err, services.ErrGitLabInstanceExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "实例名称或地址已存在"})
+	case errors.Is(err, services.ErrGitLabInstanceInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "实例下仍有项目，请先删除或迁移这些项目"})
+	case errors.Is(err, services.ErrGitLabInstanceDefault):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "默认实例由配置中的 gitlab_url 管理，不能删除或修改地址"})
+	case errors.Is(err, services.ErrInvalidGitLabBaseURL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "实例地址格式无效，应为 http(s)://host[:port][/path]"})
+	default:
+		logger.GetLogger().Errorf("GitLab instance operation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *Handler) buildGitLabInstanceResponse(instance *models.GitLabInstance) models.GitLabInstanceResponse {
+	count, err := h.gitlabInstances.ProjectCount(instance.ID)
+	if err != nil {
+		logger.GetLogger().Warnf("Failed to count projects of GitLab instance %d: %v", instance.ID, err)
+	}
+
+	return models.GitLabInstanceResponse{
+		ID:                instance.ID,
+		Name:              instance.Name,
+		BaseURL:           instance.BaseURL,
+		IsDefault:         instance.IsDefault,
+		HasDefaultToken:   instance.DefaultToken != "",
+		HasWebhookSecret:  instance.WebhookSecret != "",
+		ProjectCount:      count,
+		InboundWebhookURL: h.instanceWebhookURL(instance),
+		CreatedAt:         instance.CreatedAt,
+		UpdatedAt:         instance.UpdatedAt,
+	}
+}
+
+// instanceWebhookURL 实例的回调地址
+func (h *Handler) instanceWebhookURL(instance *models.GitLabInstance) string {
+	return h.gitlabInstances.InboundWebhookURL(instance)
+}
+
+// gitlabHookTarget 项目所属实例的回调地址、访问令牌与 Secret Token
+type gitlabHookTarget struct {
+	baseURL         string
+	webhookURL      string
+	token           string
+	secretToken     string
+	sslVerification bool
+}
+
+// hookTarget 解析项目所属实例的 hook 配置；accountToken 为当前账户的个人令牌，
+// 非默认实例优先使用实例的默认令牌，默认实例优先使用个人令牌，缺失时互为兜底
+func (h *Handler) hookTarget(project *models.Project, accountToken string) gitlabHookTarget {
+	var instance *models.GitLabInstance
+	if project.GitLabInstanceID != 0 {
+		if found, err := h.gitlabInstances.Get(project.GitLabInstanceID); err == nil {
+			instance = found
+		} else {
+			logger.GetLogger().Warnf("查询项目 %d 所属的 GitLab 实例失败: %v", project.ID, err)
+		}
+	}
+
+	target := gitlabHookTarget{
+		webhookURL:      h.instanceWebhookURL(instance),
+		token:           strings.TrimSpace(accountToken),
+		sslVerification: h.config.HookReconcile.EnableSSLVerification,
+	}
+	// 项目地址能解析时沿用解析出的基础URL（已登记实例即为实例地址），否则使用所属实例的地址
+	if parsed := h.gitlabService.ParseGitLabURL(project.URL); parsed.IsValid {
+		target.baseURL = parsed.BaseURL
+	}
+	if instance == nil {
+		return target
+	}
+
+	if target.baseURL == "" {
+		target.baseURL = instance.BaseURL
+	}
+	target.secretToken = h.gitlabInstances.WebhookSecret(instance.ID)
+	if instanceToken := h.gitlabInstances.DefaultToken(instance.ID); instanceToken != "" && (!instance.IsDefault || target.token == "") {
+		target.token = instanceToken
+	}
+	return target
+}
+
+// hookTargetsByInstance 按实例缓存 hook 配置，供批量检查项目时复用
+func (h *Handler) hookTargetsByInstance(projects []models.Project, accountToken string) map[uint]gitlabHookTarget {
+	targets := make(map[uint]gitlabHookTarget)
+	for idx := range projects {
+		if _, ok := targets[projects[idx].GitLabInstanceID]; ok {
+			continue
+		}
+		targets[projects[idx].GitLabInstanceID] = h.hookTarget(&projects[idx], accountToken)
+	}
+	return targets
+}
+
+// resolveInstanceToken 解析访问指定实例使用的令牌：请求中显式提供的令牌优先，其余规则同 hookTarget
+func (h *Handler) resolveInstanceToken(c *gin.Context, provided string, instanceID uint) (string, error) {
+	if token := strings.TrimSpace(provided); token != "" {
+		return token, nil
+	}
+
+	accountToken, err := h.resolveGitLabToken(c, "")
+	if err != nil && !errors.Is(err, errGitLabTokenMissing) {
+		return "", err
+	}
+
+	target := h.hookTarget(&models.Project{GitLabInstanceID: instanceID}, accountToken)
+	if target.token == "" {
+		return "", errGitLabTokenMissing
+	}
+	return target.token, nil
+}
+
+// projectInstanceID 返回解析结果对应的实例，未登记的地址归入默认实例
+func (h *Handler) projectInstanceID(parsed *services.ParsedGitLabURL) (uint, error) {
+	if parsed.InstanceID != 0 {
+		return parsed.InstanceID, nil
+	}
+	instance, err := h.gitlabInstances.Default()
+	if err != nil {
+		return 0, err
+	}
+	return instance.ID, nil
+}
+
+// hookEvents 项目订阅的 hook 事件，附带实例配置的 Secret Token 与期望的 SSL 校验开关
+func (t gitlabHookTarget) hookEvents(project *models.Project) services.GitLabHookEvents {
+	events := services.HookEventsForProject(project)
+	events.SecretToken = t.secretToken
+	events.SSLVerification = t.sslVerification
+	return events
+}
+
+func (t gitlabHookTarget) service() services.HookTarget {
+	return services.HookTarget{
+		BaseURL:     t.baseURL,
+		WebhookURL:  t.webhookURL,
+		Token:       t.token,
+		SecretToken: t.secretToken,
+	}
+}
+
+// isProjectUniqueConflict 判断是否违反 (gitlab_instance_id, gitlab_project_id) 唯一约束
+func isProjectUniqueConflict(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") && strings.Contains(err.Error(), "projects.gitlab_project_id")
+}
@@ -47,7 +47,7 @@ func (h *Handler) TestGitLabToken(c *gin.Context) {
 		return
 	}
 
-	if err := h.gitlabService.TestConnection(baseURL, token); err != nil {
+	if err := h.gitlabService.TestConnection(c.Request.Context(), baseURL, token); err != nil {
 		c.JSON(http.StatusOK, gin.H{"data": models.GitLabConnectionTestResponse{Success: false, Message: err.Error()}})
 		return
 	}
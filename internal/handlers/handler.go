@@ -6,6 +6,7 @@ import (
 
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/middleware"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/scheduler"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -13,23 +14,54 @@ import (
 )
 
 type Handler struct {
-	db               *gorm.DB
-	config           *config.Config
-	gitlabService    services.GitLabService
-	wechatService    services.WeChatService
-	senderFactory    services.SenderFactory
-	notifyService    services.NotificationService
-	authService      services.AuthService
-	authMiddleware   *middleware.AuthMiddleware
-	ownershipChecker *middleware.OwnershipChecker
-	response         *middleware.ResponseHelper
+	db                *gorm.DB
+	config            *config.Config
+	gitlabService     services.GitLabService
+	gitlabInstances   services.GitLabInstanceService
+	gitlabGroups      services.GitLabGroupService
+	hookReconciler    services.HookReconcileService
+	webhookStatus     services.WebhookStatusService
+	userSync          services.UserSyncService
+	userAliases       services.UserAliasService
+	userImport        services.UserImportService
+	preferences       services.NotificationPreferenceService
+	wechatService     services.WeChatService
+	senderFactory     services.SenderFactory
+	notifyService     services.NotificationService
+	mrTracker         services.MergeRequestTracker
+	reminderService   services.ReminderService
+	digestService     services.DigestService
+	escalationService services.EscalationService
+	deliveryQueue     services.DeliveryQueueService
+	opsAlerts         services.OpsAlertService
+	quotaService      services.QuotaService
+	circuitBreaker    services.CircuitBreakerService
+	authService       services.AuthService
+	authMiddleware    *middleware.AuthMiddleware
+	ownershipChecker  *middleware.OwnershipChecker
+	response          *middleware.ResponseHelper
 }
 
 func New(db *gorm.DB, cfg *config.Config) *Handler {
-	gitlabService := services.NewGitLabService(cfg.GitLabURL, "")
+	gitlabInstances := services.NewGitLabInstanceService(db, cfg)
+	gitlabService := services.NewGitLabService(cfg.GitLabURL, "", gitlabInstances, cfg.GitLabAPI)
 	wechatService := services.NewWeChatService()
 	senderFactory := services.NewMessageSenderFactory(db, cfg, wechatService)
-	notifyService := services.NewNotificationService(db, senderFactory)
+	mrTracker := services.NewMergeRequestTracker(db)
+	opsAlerts := services.NewOpsAlertService(db, cfg, senderFactory)
+	gitlabGroups := services.NewGitLabGroupService(db, cfg, gitlabService, gitlabInstances, opsAlerts)
+	hookReconciler := services.NewHookReconcileService(db, cfg, gitlabService, gitlabInstances, gitlabGroups, opsAlerts)
+	webhookStatus := services.NewWebhookStatusService(db, cfg, gitlabService, gitlabGroups, hookReconciler)
+	deliveryQueue := services.NewDeliveryQueueService(db, senderFactory, opsAlerts)
+	userSync := services.NewUserSyncService(db, cfg, gitlabService, hookReconciler, opsAlerts)
+	mrEnricher := services.NewMergeRequestEnricher(cfg, gitlabService, hookReconciler)
+	userResolver := services.NewGitLabUserResolver(db, cfg, gitlabService, gitlabInstances, hookReconciler)
+	notifyService := services.NewNotificationService(db, senderFactory, mrTracker, deliveryQueue, mrEnricher, userResolver)
+	reminderService := services.NewReminderService(db, cfg, gitlabService, mrTracker, notifyService, opsAlerts, gitlabInstances)
+	digestService := services.NewDigestService(db, senderFactory, deliveryQueue)
+	escalationService := services.NewEscalationService(db, senderFactory, notifyService)
+	quotaService := services.NewQuotaService(db, cfg, opsAlerts)
+	circuitBreaker := services.NewCircuitBreakerService(db, cfg, opsAlerts)
 
 	// 使用配置中的 JWT 设置，如果没有则使用默认值
 	jwtSecret := cfg.JWTSecret
@@ -46,16 +78,32 @@ func New(db *gorm.DB, cfg *config.Config) *Handler {
 	ownershipChecker := middleware.NewOwnershipChecker(db)
 
 	return &Handler{
-		db:               db,
-		config:           cfg,
-		gitlabService:    gitlabService,
-		wechatService:    wechatService,
-		senderFactory:    senderFactory,
-		notifyService:    notifyService,
-		authService:      authService,
-		authMiddleware:   authMiddleware,
-		ownershipChecker: ownershipChecker,
-		response:         middleware.NewResponseHelper(),
+		db:                db,
+		config:            cfg,
+		gitlabService:     gitlabService,
+		gitlabInstances:   gitlabInstances,
+		gitlabGroups:      gitlabGroups,
+		hookReconciler:    hookReconciler,
+		webhookStatus:     webhookStatus,
+		userSync:          userSync,
+		userAliases:       services.NewUserAliasService(db),
+		userImport:        services.NewUserImportService(db),
+		preferences:       services.NewNotificationPreferenceService(db, cfg, gitlabService, gitlabInstances, opsAlerts),
+		wechatService:     wechatService,
+		senderFactory:     senderFactory,
+		notifyService:     notifyService,
+		mrTracker:         mrTracker,
+		reminderService:   reminderService,
+		digestService:     digestService,
+		escalationService: escalationService,
+		deliveryQueue:     deliveryQueue,
+		opsAlerts:         opsAlerts,
+		quotaService:      quotaService,
+		circuitBreaker:    circuitBreaker,
+		authService:       authService,
+		authMiddleware:    authMiddleware,
+		ownershipChecker:  ownershipChecker,
+		response:          middleware.NewResponseHelper(),
 	}
 }
 
@@ -73,6 +121,112 @@ func (h *Handler) InitializeAdminAccount() error {
 	return h.authService.InitializeAdminAccount()
 }
 
+// EnsureDefaultGitLabInstance 按配置的 gitlab_url 维护默认 GitLab 实例
+func (h *Handler) EnsureDefaultGitLabInstance() error {
+	_, err := h.gitlabInstances.EnsureDefault(h.config.GitLabURL)
+	return err
+}
+
+// RegisterScheduledJobs 注册后台定时任务
+func (h *Handler) RegisterScheduledJobs(s *scheduler.Scheduler) {
+	if h.config.Reminder.Enabled {
+		interval := h.config.Reminder.CheckInterval
+		if interval <= 0 {
+			interval = 10 * time.Minute
+		}
+		s.Register(scheduler.Job{
+			Name:     "merge_request_reminders",
+			Interval: interval,
+			Run:      h.reminderService.RemindStaleMergeRequests,
+		})
+	}
+
+	s.Register(scheduler.Job{
+		Name:     "webhook_digests",
+		Interval: time.Minute,
+		Run:      h.digestService.SendDueDigests,
+	})
+
+	s.Register(scheduler.Job{
+		Name:     "merge_request_escalations",
+		Interval: 5 * time.Minute,
+		Run:      h.escalationService.EvaluateEscalations,
+	})
+
+	s.Register(scheduler.Job{
+		Name:     "delivery_queue_flush",
+		Interval: 30 * time.Second,
+		Timeout:  2 * time.Minute,
+		Run:      h.deliveryQueue.FlushDue,
+	})
+
+	s.Register(scheduler.Job{
+		Name:     "quota_warnings",
+		Interval: time.Minute,
+		Run:      h.quotaService.CheckQuotaWarnings,
+	})
+
+	if h.config.Notification.CircuitBreaker.Enabled {
+		s.Register(scheduler.Job{
+			Name:     "webhook_circuit_breakers",
+			Interval: time.Minute,
+			Run:      h.circuitBreaker.CheckOpenCircuits,
+		})
+	}
+
+	if h.config.GroupSync.Enabled {
+		interval := h.config.GroupSync.Interval
+		if interval <= 0 {
+			interval = 30 * time.Minute
+		}
+		s.Register(scheduler.Job{
+			Name:     "gitlab_group_sync",
+			Interval: interval,
+			Timeout:  20 * time.Minute,
+			Run:      h.gitlabGroups.SyncWatchedGroups,
+		})
+	}
+
+	if h.config.HookReconcile.Enabled {
+		interval := h.config.HookReconcile.Interval
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		s.Register(scheduler.Job{
+			Name:     "gitlab_hook_reconcile",
+			Interval: interval,
+			Timeout:  30 * time.Minute,
+			Run:      h.hookReconciler.ReconcileAll,
+		})
+	}
+
+	if h.config.WebhookStatus.Enabled {
+		interval := h.config.WebhookStatus.Interval
+		if interval <= 0 {
+			interval = 15 * time.Minute
+		}
+		s.Register(scheduler.Job{
+			Name:     "webhook_status_refresh",
+			Interval: interval,
+			Timeout:  15 * time.Minute,
+			Run:      h.webhookStatus.RefreshAll,
+		})
+	}
+
+	if h.config.UserSync.Enabled {
+		interval := h.config.UserSync.Interval
+		if interval <= 0 {
+			interval = 6 * time.Hour
+		}
+		s.Register(scheduler.Job{
+			Name:     "user_directory_sync",
+			Interval: interval,
+			Timeout:  30 * time.Minute,
+			Run:      h.userSync.SyncAll,
+		})
+	}
+}
+
 // GetAuthMiddleware 获取认证中间件
 func (h *Handler) GetAuthMiddleware() *middleware.AuthMiddleware {
 	return h.authMiddleware
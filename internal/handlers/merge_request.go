@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/middleware"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultMergeRequestListLimit = 50
+
+// GetMergeRequests 获取跟踪中的合并请求，支持按项目和状态过滤
+func (h *Handler) GetMergeRequests(c *gin.Context) {
+	query := h.db.Preload("Project").Order("last_activity_at DESC")
+	query = middleware.ApplyOwnershipFilter(c, query, "merge_requests")
+
+	if projectID := c.Query("project_id"); projectID != "" {
+		query = query.Where("merge_requests.project_id = ?", projectID)
+	}
+	if state := c.Query("state"); state != "" {
+		query = query.Where("merge_requests.state = ?", state)
+	}
+
+	limit := defaultMergeRequestListLimit
+	if raw := c.Query("limit"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 && parsed <= 500 {
+			limit = parsed
+		}
+	}
+
+	var mergeRequests []models.MergeRequest
+	if err := query.Limit(limit).Find(&mergeRequests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch merge requests"})
+		return
+	}
+
+	responses := make([]models.MergeRequestResponse, 0, len(mergeRequests))
+	for i := range mergeRequests {
+		responses = append(responses, mergeRequests[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses})
+}
+
+// GetMergeRequest 获取单个合并请求及其状态变更记录
+func (h *Handler) GetMergeRequest(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merge request ID"})
+		return
+	}
+
+	query := middleware.ApplyOwnershipFilter(c, h.db.Preload("Project"), "merge_requests")
+
+	var mergeRequest models.MergeRequest
+	if err := query.First(&mergeRequest, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Merge request not found"})
+		return
+	}
+
+	transitions, err := h.mrTracker.ListTransitions(mergeRequest.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch merge request transitions"})
+		return
+	}
+
+	response := mergeRequest.ToResponse()
+	response.Transitions = transitions
+
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/middleware"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/services"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserNotificationPreference 获取用户的通知偏好
+func (h *Handler) GetUserNotificationPreference(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	preference, err := h.preferences.Get(uint(id))
+	if err != nil {
+		h.respondPreferenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": preference})
+}
+
+// UpdateUserNotificationPreference 设置用户的通知偏好
+func (h *Handler) UpdateUserNotificationPreference(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	h.updateNotificationPreference(c, uint(id))
+}
+
+// GetMyNotificationPreference 当前账户通过 GitLab 用户名关联的用户的通知偏好
+func (h *Handler) GetMyNotificationPreference(c *gin.Context) {
+	user, ok := h.linkedUser(c)
+	if !ok {
+		return
+	}
+
+	preference, err := h.preferences.Get(user.ID)
+	if err != nil {
+		h.respondPreferenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"user": user.ToResponse(), "preference": preference}})
+}
+
+// UpdateMyNotificationPreference 自助设置免打扰时间、分支过滤与休假静音
+func (h *Handler) UpdateMyNotificationPreference(c *gin.Context) {
+	user, ok := h.linkedUser(c)
+	if !ok {
+		return
+	}
+
+	h.updateNotificationPreference(c, user.ID)
+}
+
+func (h *Handler) updateNotificationPreference(c *gin.Context, userID uint) {
+	var req models.UserNotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preference, err := h.preferences.Update(userID, &req)
+	if err != nil {
+		h.respondPreferenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": preference})
+}
+
+// linkedUser 查找当前账户关联的用户，失败时已写入响应
+func (h *Handler) linkedUser(c *gin.Context) (*models.User, bool) {
+	accountID, exists := middleware.GetAccountID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	user, err := h.preferences.LinkedUser(c.Request.Context(), accountID)
+	if err != nil {
+		h.respondPreferenceError(c, err)
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *Handler) respondPreferenceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrInvalidNotificationPreference):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAccountGitLabLinkMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请先在个人设置中配置 GitLab Token，用于关联 GitLab 用户名"})
+	case errors.Is(err, services.ErrAccountUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户管理中没有与当前账户 GitLab 用户名对应的用户"})
+	default:
+		logger.GetLogger().Errorf("Notification preference operation failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOpsAlerts 获取最近的系统告警，管理员可查看全部，其他账户只能查看自己资源的告警
+func (h *Handler) GetOpsAlerts(c *gin.Context) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	var scope *uint
+	if !middleware.IsAdmin(c) {
+		accountID, ok := middleware.GetAccountID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
+			return
+		}
+		scope = &accountID
+	}
+
+	alerts, err := h.opsAlerts.ListRecent(scope, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ops alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": alerts})
+}
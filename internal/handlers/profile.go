@@ -34,6 +34,8 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 	}
 	if req.GitLabPersonalAccessToken != nil {
 		token := strings.TrimSpace(*req.GitLabPersonalAccessToken)
+		// 更换令牌后重新获取令牌所属的 GitLab 用户名
+		updates["gitlab_username"] = ""
 		if token == "" {
 			updates["gitlab_access_token"] = ""
 		} else {
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -50,6 +51,7 @@ func (h *Handler) resolveGitLabToken(c *gin.Context, provided string) (string, e
 	var token string
 	if err != nil {
 		logger.GetLogger().Warnf("Failed to decrypt GitLab token for account %d, fallback to legacy plaintext: %v", accountID, err)
+		h.opsAlerts.Notify(services.TokenDecryptFallbackAlert(accountID, err))
 		token = strings.TrimSpace(account.GitLabAccessToken)
 	} else {
 		token = strings.TrimSpace(decrypted)
@@ -73,27 +75,28 @@ func (h *Handler) GetProjects(c *gin.Context) {
 		return
 	}
 
-	// 始终实时同步 GitLab Webhook 状态（若凭证可用）
-	var (
-		gitlabToken string
-		webhookURL  string
-	)
+	// 已由组级 Webhook 覆盖的项目不再检查项目级 webhook
+	coverage := h.gitlabGroups.CoverageForProjects(projects)
+
+	// 始终实时同步 GitLab Webhook 状态（若凭证可用），令牌与回调地址按项目所属实例解析
+	var targets map[uint]gitlabHookTarget
 
 	if len(projects) > 0 && h.gitlabService != nil {
-		if token, err := h.resolveGitLabToken(c, ""); err == nil {
-			gitlabToken = token
-			webhookURL = h.gitlabService.BuildWebhookURL(h.config.PublicWebhookURL)
-		} else {
+		token, err := h.resolveGitLabToken(c, "")
+		if err != nil {
 			if errors.Is(err, errUnauthorized) || errors.Is(err, errGitLabTokenMissing) {
-				logger.GetLogger().Warnf("Skip realtime webhook status sync: %v", err)
+				logger.GetLogger().Warnf("Realtime webhook status sync without personal token: %v", err)
 			} else {
 				logger.GetLogger().Errorf("Failed to resolve GitLab token for realtime sync: %v", err)
 			}
 		}
+		if !errors.Is(err, errUnauthorized) {
+			targets = h.hookTargetsByInstance(projects, token)
+		}
 	}
 
 	// 并发刷新 GitLab webhook 状态
-	if gitlabToken != "" && webhookURL != "" && len(projects) > 0 {
+	if len(targets) > 0 {
 		// 使用 channel 和 goroutines 并发获取状态
 		type webhookStatusResult struct {
 			projectID     uint
@@ -109,20 +112,23 @@ func (h *Handler) GetProjects(c *gin.Context) {
 
 		for idx := range projects {
 			project := &projects[idx]
+			if coverage[project.ID] != nil {
+				continue
+			}
 			wg.Add(1)
 			go func(p *models.Project) {
 				defer wg.Done()
 				semaphore <- struct{}{}
 				defer func() { <-semaphore }()
 
-				parsed := h.gitlabService.ParseGitLabURL(p.URL)
-				if !parsed.IsValid {
-					logger.GetLogger().Debugf("Skip invalid URL for project %d: %s", p.ID, p.URL)
+				target := targets[p.GitLabInstanceID]
+				if target.baseURL == "" || target.token == "" {
+					logger.GetLogger().Debugf("Skip webhook status check for project %d: no usable instance or token", p.ID)
 					return
 				}
 
 				existingWebhook, err := h.gitlabService.FindWebhookByURL(
-					parsed.BaseURL, p.GitLabProjectID, webhookURL, gitlabToken)
+					c.Request.Context(), target.baseURL, p.GitLabProjectID, target.webhookURL, target.token)
 				if err != nil {
 					logger.GetLogger().Debugf("Failed to check webhook for project %d: %v", p.ID, err)
 					// 即使查询失败，也返回当前数据库中的状态
@@ -215,17 +221,25 @@ func (h *Handler) GetProjects(c *gin.Context) {
 		}
 
 		response := models.ProjectResponse{
-			ID:              project.ID,
-			GitLabProjectID: project.GitLabProjectID,
-			Name:            project.Name,
-			URL:             project.URL,
-			Description:     project.Description,
-			GitLabWebhookID: project.GitLabWebhookID,
-			WebhookSynced:   project.WebhookSynced,
-			LastSyncAt:      project.LastSyncAt,
-			CreatedAt:       project.CreatedAt,
-			UpdatedAt:       project.UpdatedAt,
+			ID:               project.ID,
+			GitLabInstanceID: project.GitLabInstanceID,
+			GitLabProjectID:  project.GitLabProjectID,
+			Name:             project.Name,
+			URL:              project.URL,
+			Description:      project.Description,
+			GitLabWebhookID:  project.GitLabWebhookID,
+			WebhookSynced:    project.WebhookSynced,
+			LastSyncAt:       project.LastSyncAt,
+			SourceGroupID:    project.SourceGroupID,
+			GitLabState:      project.GitLabState,
+			WebhookDrift:     project.WebhookDrift,
+			CreatedAt:        project.CreatedAt,
+			UpdatedAt:        project.UpdatedAt,
+		}
+		if group := coverage[project.ID]; group != nil {
+			response.CoveredByGroupID = &group.ID
 		}
+		project.ApplyEventSubscriptionResponse(&response)
 
 		// 转换关联的webhooks
 		for idx := range project.Webhooks {
@@ -245,7 +259,20 @@ func (h *Handler) CreateProject(c *gin.Context) {
 		return
 	}
 
-	token, err := h.resolveGitLabToken(c, req.AccessToken)
+	// 识别项目所属的 GitLab 实例
+	parsed := h.gitlabService.ParseGitLabURL(req.URL)
+	if !parsed.IsValid {
+		h.response.ValidationError(c, "项目URL格式无效: "+parsed.Error)
+		return
+	}
+	instanceID, err := h.projectInstanceID(parsed)
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to resolve GitLab instance for %s: %v", req.URL, err)
+		h.response.InternalError(c, "识别 GitLab 实例失败")
+		return
+	}
+
+	token, err := h.resolveInstanceToken(c, req.AccessToken, instanceID)
 	if err != nil {
 		switch {
 		case errors.Is(err, errUnauthorized):
@@ -261,17 +288,17 @@ func (h *Handler) CreateProject(c *gin.Context) {
 
 	// 验证GitLab项目是否存在
 	if h.gitlabService != nil {
-		// 使用解析后的token进行验证
-		if _, err := h.gitlabService.GetProject(req.GitLabProjectID, token); err != nil {
+		// 使用解析后的token在项目所属实例上验证
+		if _, err := h.gitlabService.GetProjectByPath(c.Request.Context(), parsed.BaseURL, strconv.Itoa(req.GitLabProjectID), token); err != nil {
 			logger.GetLogger().Errorf("Failed to fetch GitLab project [ID: %d]: %v", req.GitLabProjectID, err)
 			h.response.ErrorWithMessage(c, "保存项目失败: GitLab项目不存在或访问被拒绝")
 			return
 		}
 	}
 
-	// 检查项目是否已存在
+	// 检查项目是否已存在（GitLab 项目 ID 只在同一实例内唯一）
 	var existingProject models.Project
-	err = h.db.Where(&models.Project{GitLabProjectID: req.GitLabProjectID}).First(&existingProject).Error
+	err = h.db.Where(&models.Project{GitLabInstanceID: instanceID, GitLabProjectID: req.GitLabProjectID}).First(&existingProject).Error
 	if err == nil {
 		// 项目已存在
 		logger.GetLogger().Warnf("Attempt to create existing project [GitLab ID: %d, Name: %s] from IP: %s", req.GitLabProjectID, req.Name, c.ClientIP())
@@ -290,19 +317,21 @@ func (h *Handler) CreateProject(c *gin.Context) {
 	// 创建新项目
 	projectURL := strings.TrimRight(req.URL, "/")
 	project := &models.Project{
-		GitLabProjectID: req.GitLabProjectID,
-		Name:            req.Name,
-		URL:             projectURL,
-		Description:     req.Description,
-		WebhookSynced:   false,
-		CreatedBy:       &accountID,
+		GitLabInstanceID:   instanceID,
+		GitLabProjectID:    req.GitLabProjectID,
+		Name:               req.Name,
+		URL:                projectURL,
+		Description:        req.Description,
+		WebhookSynced:      false,
+		MergeRequestEvents: true,
+		CreatedBy:          &accountID,
 	}
 
 	if err := h.db.Create(project).Error; err != nil {
 		logger.GetLogger().Errorf("Failed to create project [GitLab ID: %d]: %v", req.GitLabProjectID, err)
 
 		// 处理UNIQUE约束冲突
-		if strings.Contains(err.Error(), "UNIQUE constraint failed: projects.gitlab_project_id") {
+		if isProjectUniqueConflict(err) {
 			h.response.Conflict(c, "GitLab项目ID已存在，如需重新配置请先删除现有项目")
 		} else {
 			h.response.InternalError(c, "创建项目���败")
@@ -324,23 +353,28 @@ func (h *Handler) CreateProject(c *gin.Context) {
 	}
 
 	// 自动尝试创建GitLab webhook
-	h.autoCreateGitLabWebhook(project, token)
+	h.autoCreateGitLabWebhook(c.Request.Context(), project, token)
 
 	logger.GetLogger().Infof("Successfully created project [ID: %d, GitLab ID: %d, Name: %s] from IP: %s",
 		project.ID, project.GitLabProjectID, project.Name, c.ClientIP())
 
 	response := models.ProjectResponse{
-		ID:              project.ID,
-		GitLabProjectID: project.GitLabProjectID,
-		Name:            project.Name,
-		URL:             project.URL,
-		Description:     project.Description,
-		GitLabWebhookID: project.GitLabWebhookID,
-		WebhookSynced:   project.WebhookSynced,
-		LastSyncAt:      project.LastSyncAt,
-		CreatedAt:       project.CreatedAt,
-		UpdatedAt:       project.UpdatedAt,
-	}
+		ID:               project.ID,
+		GitLabInstanceID: project.GitLabInstanceID,
+		GitLabProjectID:  project.GitLabProjectID,
+		Name:             project.Name,
+		URL:              project.URL,
+		Description:      project.Description,
+		GitLabWebhookID:  project.GitLabWebhookID,
+		WebhookSynced:    project.WebhookSynced,
+		LastSyncAt:       project.LastSyncAt,
+		SourceGroupID:    project.SourceGroupID,
+		GitLabState:      project.GitLabState,
+		WebhookDrift:     project.WebhookDrift,
+		CreatedAt:        project.CreatedAt,
+		UpdatedAt:        project.UpdatedAt,
+	}
+	project.ApplyEventSubscriptionResponse(&response)
 
 	h.response.Created(c, response)
 }
@@ -369,11 +403,17 @@ func (h *Handler) UpdateProject(c *gin.Context) {
 		project.Name = req.Name
 	}
 	if req.URL != "" {
+		// GitLab 项目 ID 只在实例内有效，不允许把项目改到其他实例
+		if parsed := h.gitlabService.ParseGitLabURL(req.URL); parsed.IsValid && parsed.InstanceID != 0 && parsed.InstanceID != project.GitLabInstanceID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "项目地址属于其他 GitLab 实例，请删除后在对应实例下重新添加"})
+			return
+		}
 		project.URL = strings.TrimRight(req.URL, "/")
 	}
 	if req.Description != "" {
 		project.Description = req.Description
 	}
+	applyProjectEventSubscriptions(&project, &req)
 	providedToken := strings.TrimSpace(req.AccessToken)
 
 	// 更新 Webhook 关联
@@ -409,17 +449,17 @@ func (h *Handler) UpdateProject(c *gin.Context) {
 	}
 
 	// 自动更新 GitLab webhook
-	token, err := h.resolveGitLabToken(c, providedToken)
+	token, err := h.resolveInstanceToken(c, providedToken, project.GitLabInstanceID)
 	if err != nil {
 		logger.GetLogger().Warnf("Failed to resolve GitLab token: %v", err)
 	} else {
-		h.autoCreateGitLabWebhook(&project, token)
+		h.autoCreateGitLabWebhook(c.Request.Context(), &project, token)
 	}
 
 	if err := h.db.Save(&project).Error; err != nil {
 		logger.GetLogger().Errorf("Failed to update project [ID: %d]: %v", id, err)
 
-		if strings.Contains(err.Error(), "UNIQUE constraint failed: projects.gitlab_project_id") {
+		if isProjectUniqueConflict(err) {
 			c.JSON(http.StatusConflict, gin.H{"error": "GitLab项目ID已存在"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新项目失败"})
@@ -436,17 +476,22 @@ func (h *Handler) UpdateProject(c *gin.Context) {
 	}
 
 	response := models.ProjectResponse{
-		ID:              project.ID,
-		GitLabProjectID: project.GitLabProjectID,
-		Name:            project.Name,
-		URL:             project.URL,
-		Description:     project.Description,
-		GitLabWebhookID: project.GitLabWebhookID,
-		WebhookSynced:   project.WebhookSynced,
-		LastSyncAt:      project.LastSyncAt,
-		CreatedAt:       project.CreatedAt,
-		UpdatedAt:       project.UpdatedAt,
-	}
+		ID:               project.ID,
+		GitLabInstanceID: project.GitLabInstanceID,
+		GitLabProjectID:  project.GitLabProjectID,
+		Name:             project.Name,
+		URL:              project.URL,
+		Description:      project.Description,
+		GitLabWebhookID:  project.GitLabWebhookID,
+		WebhookSynced:    project.WebhookSynced,
+		LastSyncAt:       project.LastSyncAt,
+		SourceGroupID:    project.SourceGroupID,
+		GitLabState:      project.GitLabState,
+		WebhookDrift:     project.WebhookDrift,
+		CreatedAt:        project.CreatedAt,
+		UpdatedAt:        project.UpdatedAt,
+	}
+	project.ApplyEventSubscriptionResponse(&response)
 
 	// 转换关联的webhooks
 	for idx := range project.Webhooks {
@@ -472,7 +517,7 @@ func (h *Handler) DeleteProject(c *gin.Context) {
 
 	// 如果有GitLab webhook，尝试删除
 	if project.GitLabWebhookID != nil {
-		token, tokenErr := h.resolveGitLabToken(c, "")
+		token, tokenErr := h.resolveInstanceToken(c, "", project.GitLabInstanceID)
 		if tokenErr != nil {
 			if errors.Is(tokenErr, errUnauthorized) {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
@@ -484,7 +529,7 @@ func (h *Handler) DeleteProject(c *gin.Context) {
 				logger.GetLogger().Warnf("Skip GitLab webhook cleanup for project %d due to token error: %v", project.ID, tokenErr)
 			}
 		} else if token != "" {
-			h.autoDeleteGitLabWebhook(&project, token)
+			h.autoDeleteGitLabWebhook(c.Request.Context(), &project, token)
 		}
 	}
 
@@ -507,7 +552,20 @@ func (h *Handler) ParseProjectURL(c *gin.Context) {
 		return
 	}
 
-	token, err := h.resolveGitLabToken(c, req.AccessToken)
+	// 解析URL并识别所属实例
+	parsed := h.gitlabService.ParseGitLabURL(req.URL)
+	if !parsed.IsValid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "URL解析失败: " + parsed.Error})
+		return
+	}
+	instanceID, err := h.projectInstanceID(parsed)
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to resolve GitLab instance for parse URL: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "识别 GitLab 实例失败"})
+		return
+	}
+
+	token, err := h.resolveInstanceToken(c, req.AccessToken, instanceID)
 	if err != nil {
 		switch {
 		case errors.Is(err, errUnauthorized):
@@ -521,35 +579,28 @@ func (h *Handler) ParseProjectURL(c *gin.Context) {
 		return
 	}
 
-	// 解析URL
-	parsed := h.gitlabService.ParseGitLabURL(req.URL)
-	if !parsed.IsValid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "URL解析失败: " + parsed.Error})
-		return
-	}
-
 	// 使用GitLab服务解析URL并获取项目信息
-	projectInfo, err := h.gitlabService.GetProjectByURL(req.URL, token)
+	projectInfo, err := h.gitlabService.GetProjectByURL(c.Request.Context(), req.URL, token)
 	if err != nil {
 		// 检查是否是组URL
-		if err.Error() == "GROUP_URL" {
+		if errors.Is(err, services.ErrGitLabGroupURL) {
 			// 尝试作为组处理
-			groupInfo, groupErr := h.gitlabService.GetGroupByPath(parsed.BaseURL, parsed.ProjectPath, token)
+			groupInfo, groupErr := h.gitlabService.GetGroupByPath(c.Request.Context(), parsed.BaseURL, parsed.ProjectPath, token)
 			if groupErr != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "无法识别为项目或组: " + groupErr.Error()})
 				return
 			}
 
 			// 获取组下所有项目
-			projects, projectsErr := h.gitlabService.GetGroupProjects(parsed.BaseURL, parsed.ProjectPath, token)
+			projects, projectsErr := h.gitlabService.GetGroupProjects(c.Request.Context(), parsed.BaseURL, parsed.ProjectPath, token, true)
 			if projectsErr != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "获取组项目失败: " + projectsErr.Error()})
 				return
 			}
 
-			// 获取已存在的项目ID列表
+			// 获取同一实例下已存在的项目ID列表
 			var existingProjectIDs []int
-			if err := h.db.Model(&models.Project{}).Pluck("gitlab_project_id", &existingProjectIDs).Error; err != nil {
+			if err := h.db.Model(&models.Project{}).Where("gitlab_instance_id = ?", instanceID).Pluck("gitlab_project_id", &existingProjectIDs).Error; err != nil {
 				logger.GetLogger().Errorf("Failed to get existing project IDs: %v", err)
 			}
 
@@ -583,8 +634,9 @@ func (h *Handler) ParseProjectURL(c *gin.Context) {
 
 			// 返回扫描组的响应格式，前端可以识别并处理
 			response := models.ScanGroupProjectsResponse{
-				GroupInfo: (*models.GitLabGroupInfo)(groupInfo),
-				Projects:  projectInfos,
+				GitLabInstanceID: instanceID,
+				GroupInfo:        (*models.GitLabGroupInfo)(groupInfo),
+				Projects:         projectInfos,
 			}
 
 			// 使用特殊的响应格式，让前端知道这是一个组
@@ -599,6 +651,7 @@ func (h *Handler) ParseProjectURL(c *gin.Context) {
 
 	// 转换为响应格式（单个项目）
 	response := models.ParseProjectURLResponse{
+		GitLabInstanceID:  instanceID,
 		GitLabProjectID:   projectInfo.ID,
 		Name:              projectInfo.Name,
 		Description:       projectInfo.Description,
@@ -630,7 +683,7 @@ func (h *Handler) TestGitLabConnection(c *gin.Context) {
 		return
 	}
 
-	token, err := h.resolveGitLabToken(c, req.AccessToken)
+	token, err := h.resolveInstanceToken(c, req.AccessToken, parsed.InstanceID)
 	if err != nil {
 		switch {
 		case errors.Is(err, errUnauthorized):
@@ -645,7 +698,7 @@ func (h *Handler) TestGitLabConnection(c *gin.Context) {
 	}
 
 	// 测试连接
-	err = h.gitlabService.TestConnection(parsed.BaseURL, token)
+	err = h.gitlabService.TestConnection(c.Request.Context(), parsed.BaseURL, token)
 
 	response := models.GitLabConnectionTestResponse{}
 	if err != nil {
@@ -674,7 +727,14 @@ func (h *Handler) ScanGroupProjects(c *gin.Context) {
 		return
 	}
 
-	token, err := h.resolveGitLabToken(c, req.AccessToken)
+	instanceID, err := h.projectInstanceID(parsed)
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to resolve GitLab instance for scanning group: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "识别 GitLab 实例失败"})
+		return
+	}
+
+	token, err := h.resolveInstanceToken(c, req.AccessToken, instanceID)
 	if err != nil {
 		switch {
 		case errors.Is(err, errUnauthorized):
@@ -689,10 +749,10 @@ func (h *Handler) ScanGroupProjects(c *gin.Context) {
 	}
 
 	// 首先尝试作为组解析
-	groupInfo, err := h.gitlabService.GetGroupByPath(parsed.BaseURL, parsed.ProjectPath, token)
+	groupInfo, err := h.gitlabService.GetGroupByPath(c.Request.Context(), parsed.BaseURL, parsed.ProjectPath, token)
 	if err != nil {
 		// 如果不是组，尝试作为项目解析
-		projectInfo, projectErr := h.gitlabService.GetProjectByPath(parsed.BaseURL, parsed.ProjectPath, token)
+		projectInfo, projectErr := h.gitlabService.GetProjectByPath(c.Request.Context(), parsed.BaseURL, parsed.ProjectPath, token)
 		if projectErr != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "无法识别为组或项目: " + err.Error()})
 			return
@@ -700,7 +760,8 @@ func (h *Handler) ScanGroupProjects(c *gin.Context) {
 
 		// 是单个项目，返回单个项目信息
 		response := models.ScanGroupProjectsResponse{
-			GroupInfo: nil,
+			GitLabInstanceID: instanceID,
+			GroupInfo:        nil,
 			Projects: []*models.GitLabProjectInfo{
 				{
 					ID:                projectInfo.ID,
@@ -719,7 +780,7 @@ func (h *Handler) ScanGroupProjects(c *gin.Context) {
 	}
 
 	// 是组，获取组下所有项目
-	projects, err := h.gitlabService.GetGroupProjects(parsed.BaseURL, parsed.ProjectPath, token)
+	projects, err := h.gitlabService.GetGroupProjects(c.Request.Context(), parsed.BaseURL, parsed.ProjectPath, token, true)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "获取组项目失败: " + err.Error()})
 		return
@@ -741,6 +802,7 @@ func (h *Handler) ScanGroupProjects(c *gin.Context) {
 	}
 
 	response := models.ScanGroupProjectsResponse{
+		GitLabInstanceID: instanceID,
 		GroupInfo: &models.GitLabGroupInfo{
 			ID:       groupInfo.ID,
 			Name:     groupInfo.Name,
@@ -828,9 +890,27 @@ func (h *Handler) BatchCreateProjects(c *gin.Context) {
 
 		var projectToAssociate *models.Project
 
+		// 识别项目所属的 GitLab 实例
+		parsed := h.gitlabService.ParseGitLabURL(projectInfo.URL)
+		if !parsed.IsValid {
+			result.Success = false
+			result.Error = "项目URL格式无效: " + parsed.Error
+			results = append(results, result)
+			failureCount++
+			continue
+		}
+		instanceID, err := h.projectInstanceID(parsed)
+		if err != nil {
+			result.Success = false
+			result.Error = "识别 GitLab 实例失败: " + err.Error()
+			results = append(results, result)
+			failureCount++
+			continue
+		}
+
 		// 检查项目是否已存在
 		var existingProject models.Project
-		err := tx.Where(&models.Project{GitLabProjectID: projectInfo.GitLabProjectID}).First(&existingProject).Error
+		err = tx.Where(&models.Project{GitLabInstanceID: instanceID, GitLabProjectID: projectInfo.GitLabProjectID}).First(&existingProject).Error
 		if err == nil {
 			// 项目已存在
 			result.Success = false
@@ -849,12 +929,14 @@ func (h *Handler) BatchCreateProjects(c *gin.Context) {
 
 		// 创建新项目
 		project := &models.Project{
-			GitLabProjectID: projectInfo.GitLabProjectID,
-			Name:            projectInfo.Name,
-			URL:             projectInfo.URL,
-			Description:     projectInfo.Description,
-			WebhookSynced:   false,
-			CreatedBy:       &accountID,
+			GitLabInstanceID:   instanceID,
+			GitLabProjectID:    projectInfo.GitLabProjectID,
+			Name:               projectInfo.Name,
+			URL:                projectInfo.URL,
+			Description:        projectInfo.Description,
+			WebhookSynced:      false,
+			MergeRequestEvents: true,
+			CreatedBy:          &accountID,
 		}
 
 		if err := tx.Create(project).Error; err != nil {
@@ -873,7 +955,7 @@ func (h *Handler) BatchCreateProjects(c *gin.Context) {
 
 		// 在事务提交后异步创建GitLab webhook
 		defer func(p *models.Project) {
-			go h.autoCreateGitLabWebhook(p, token)
+			go h.autoCreateGitLabWebhook(context.Background(), p, token)
 		}(project)
 
 		// 关联webhook
@@ -961,19 +1043,17 @@ func (h *Handler) SyncGitLabWebhook(c *gin.Context) {
 		return
 	}
 
-	// 所有项目都支持同步 GitLab Webhook
-
-	// 解析GitLab URL以获取基础URL
-	parsed := h.gitlabService.ParseGitLabURL(project.URL)
-	if !parsed.IsValid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "项目URL格式无效: " + parsed.Error})
+	// 组级 Webhook 已覆盖的项目无需再创建项目级 webhook
+	if group := h.projectCoveringGroup(&project); group != nil {
+		c.JSON(http.StatusOK, gin.H{"data": models.SyncGitLabWebhookResponse{
+			Success: true,
+			Message: fmt.Sprintf("项目已由组 %s 的 Webhook 覆盖，无需创建项目级 Webhook", group.FullPath),
+		}})
 		return
 	}
 
-	// 构建webhook URL
-	webhookURL := h.gitlabService.BuildWebhookURL(h.config.PublicWebhookURL)
-
-	token, err := h.resolveGitLabToken(c, "")
+	// 所有项目都支持同步 GitLab Webhook
+	token, err := h.resolveInstanceToken(c, "", project.GitLabInstanceID)
 	if err != nil {
 		if errors.Is(err, errUnauthorized) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
@@ -986,48 +1066,38 @@ func (h *Handler) SyncGitLabWebhook(c *gin.Context) {
 		return
 	}
 
-	// 检查是否已存在相同的webhook
-	existingWebhook, err := h.gitlabService.FindWebhookByURL(parsed.BaseURL, project.GitLabProjectID, webhookURL, token)
+	// 按项目所属实例解析基础URL与回调地址
+	target := h.hookTarget(&project, token)
+	if target.baseURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "项目URL格式无效"})
+		return
+	}
+	webhookURL := target.webhookURL
+
+	// 确保GitLab中存在webhook，并按项目订阅同步事件开关
+	webhook, created, err := h.gitlabService.SyncProjectWebhook(c.Request.Context(), target.baseURL, project.GitLabProjectID, webhookURL, target.hookEvents(&project), target.token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查现有webhook失败: " + err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "同步GitLab webhook失败: " + err.Error()})
 		return
 	}
 
-	var response models.SyncGitLabWebhookResponse
+	// 更新项目状态
 	now := time.Now()
+	project.GitLabWebhookID = &webhook.ID
+	project.WebhookSynced = true
+	project.LastSyncAt = &now
+	project.ClearWebhookDrift(now)
 
-	if existingWebhook != nil {
-		// webhook已存在，更新项目状态
-		project.GitLabWebhookID = &existingWebhook.ID
-		project.WebhookSynced = true
-		project.LastSyncAt = &now
-
-		response = models.SyncGitLabWebhookResponse{
-			Success:         true,
-			Message:         "Webhook已存在，状态已更新",
-			GitLabWebhookID: &existingWebhook.ID,
-			WebhookURL:      webhookURL,
-		}
-	} else {
-		// 创建新的webhook
-		gitlabService := services.NewGitLabService(parsed.BaseURL, token)
-		webhook, err := gitlabService.CreateProjectWebhook(parsed.BaseURL, project.GitLabProjectID, webhookURL, token)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建GitLab webhook失败: " + err.Error()})
-			return
-		}
-
-		// 更新项目状态
-		project.GitLabWebhookID = &webhook.ID
-		project.WebhookSynced = true
-		project.LastSyncAt = &now
+	message := "Webhook已存在，状态已更新"
+	if created {
+		message = "GitLab webhook创建成功"
+	}
 
-		response = models.SyncGitLabWebhookResponse{
-			Success:         true,
-			Message:         "GitLab webhook创建成功",
-			GitLabWebhookID: &webhook.ID,
-			WebhookURL:      webhookURL,
-		}
+	response := models.SyncGitLabWebhookResponse{
+		Success:         true,
+		Message:         message,
+		GitLabWebhookID: &webhook.ID,
+		WebhookURL:      webhookURL,
 	}
 
 	// 保存项目状态
@@ -1053,17 +1123,7 @@ func (h *Handler) DeleteGitLabWebhook(c *gin.Context) {
 		return
 	}
 
-	// 解析GitLab URL以获取基础URL
-	parsed := h.gitlabService.ParseGitLabURL(project.URL)
-	if !parsed.IsValid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "项目URL格式无效: " + parsed.Error})
-		return
-	}
-
-	// 构建webhook URL
-	webhookURL := h.gitlabService.BuildWebhookURL(h.config.PublicWebhookURL)
-
-	token, err := h.resolveGitLabToken(c, "")
+	token, err := h.resolveInstanceToken(c, "", project.GitLabInstanceID)
 	if err != nil {
 		if errors.Is(err, errUnauthorized) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
@@ -1076,9 +1136,15 @@ func (h *Handler) DeleteGitLabWebhook(c *gin.Context) {
 		return
 	}
 
+	// 按项目所属实例解析基础URL与回调地址
+	target := h.hookTarget(&project, token)
+	if target.baseURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "项目URL格式无效"})
+		return
+	}
+
 	// 删除GitLab中所有匹配的webhook
-	gitlabService := services.NewGitLabService(parsed.BaseURL, token)
-	deletedCount, err := gitlabService.DeleteAllWebhooksByURL(parsed.BaseURL, project.GitLabProjectID, webhookURL, token)
+	deletedCount, err := h.gitlabService.DeleteAllWebhooksByURL(c.Request.Context(), target.baseURL, project.GitLabProjectID, target.webhookURL, target.token)
 
 	var responseMessage string
 	if err != nil {
@@ -1129,13 +1195,27 @@ func (h *Handler) GetGitLabWebhookStatus(c *gin.Context) {
 		return
 	}
 
-	webhookURL := h.gitlabService.BuildWebhookURL(h.config.PublicWebhookURL)
+	if group := h.projectCoveringGroup(&project); group != nil {
+		target := h.hookTarget(&project, "")
+		c.JSON(http.StatusOK, gin.H{"data": models.GitLabWebhookStatusResponse{
+			ProjectID:        project.ID,
+			WebhookSynced:    true,
+			WebhookURL:       target.webhookURL,
+			LastSyncAt:       group.LastSyncAt,
+			CoveredByGroupID: &group.ID,
+			CoveredByGroup:   group.FullPath,
+		}})
+		return
+	}
 
 	// 检查是否有权限管理webhook（通过测试连接来判断）
 	canManage := false
 	actualSynced := project.WebhookSynced // 默认使用数据库中的状态
+	var drift []models.WebhookDriftItem
 
-	token, tokenErr := h.resolveGitLabToken(c, "")
+	token, tokenErr := h.resolveInstanceToken(c, "", project.GitLabInstanceID)
+	target := h.hookTarget(&project, token)
+	webhookURL := target.webhookURL
 	if tokenErr != nil {
 		if errors.Is(tokenErr, errUnauthorized) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
@@ -1144,20 +1224,19 @@ func (h *Handler) GetGitLabWebhookStatus(c *gin.Context) {
 		if tokenErr != errGitLabTokenMissing {
 			logger.GetLogger().Warnf("Failed to resolve GitLab token when checking webhook status for project %d: %v", project.ID, tokenErr)
 		}
-	} else if token != "" {
-		parsed := h.gitlabService.ParseGitLabURL(project.URL)
-		if parsed.IsValid {
+	} else if target.token != "" {
+		if target.baseURL != "" {
 			// 测试连接以确认是否有权限
-			if err := h.gitlabService.TestConnection(parsed.BaseURL, token); err == nil {
+			if err := h.gitlabService.TestConnection(c.Request.Context(), target.baseURL, target.token); err == nil {
 				canManage = true
 
-				// 实时检查 GitLab 上的 webhook 状态
-				existingWebhook, err := h.gitlabService.FindWebhookByURL(
-					parsed.BaseURL,
-					project.GitLabProjectID,
-					webhookURL,
-					token,
-				)
+				// 实时检查 GitLab 上的 webhook 状态，并对比事件开关、SSL 校验与令牌是否被改动
+				var existingWebhook *services.GitLabWebhook
+				inspection, err := h.hookReconciler.Inspect(c.Request.Context(), &project, target.service())
+				if err == nil {
+					existingWebhook = inspection.Hook
+					drift = inspection.Drift
+				}
 
 				// 根据实际情况更新状态
 				actualSynced = (existingWebhook != nil && err == nil)
@@ -1206,52 +1285,97 @@ func (h *Handler) GetGitLabWebhookStatus(c *gin.Context) {
 		WebhookURL:      webhookURL,
 		LastSyncAt:      project.LastSyncAt,
 		CanManage:       canManage,
+		Drift:           drift,
+		DriftCheckedAt:  project.DriftCheckedAt,
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data": response})
 }
 
-// autoCreateGitLabWebhook 自动创建GitLab webhook
-func (h *Handler) autoCreateGitLabWebhook(project *models.Project, token string) {
-	// 解析GitLab URL
-	parsed := h.gitlabService.ParseGitLabURL(project.URL)
-	if !parsed.IsValid {
-		logger.GetLogger().Warnf("项目 %d URL格式无效，跳过webhook创建: %s", project.ID, parsed.Error)
+// RepairGitLabWebhook 按期望配置修复项目 hook：重新开启被关闭的事件、恢复 SSL 校验与 Secret Token，hook 被删除时重新创建
+func (h *Handler) RepairGitLabWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if group := h.projectCoveringGroup(&project); group != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("项目已由组 %s 的 Webhook 覆盖，请在组管理中同步组 Hook", group.FullPath)})
+		return
+	}
+
+	token, err := h.resolveInstanceToken(c, "", project.GitLabInstanceID)
+	if err != nil {
+		if errors.Is(err, errUnauthorized) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		} else if errors.Is(err, errGitLabTokenMissing) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "当前账户未配置 GitLab Personal Access Token"})
+		} else {
+			logger.GetLogger().Errorf("Failed to resolve GitLab token for repair [project ID: %d]: %v", project.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "修复失败: 无法解析凭证"})
+		}
 		return
 	}
 
-	// 构建webhook URL
-	webhookURL := h.gitlabService.BuildWebhookURL(h.config.PublicWebhookURL)
+	target := h.hookTarget(&project, token)
+	if target.baseURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "项目URL格式无效"})
+		return
+	}
 
-	// 检查是否已存在相同的webhook
-	existingWebhook, err := h.gitlabService.FindWebhookByURL(parsed.BaseURL, project.GitLabProjectID, webhookURL, token)
+	inspection, err := h.hookReconciler.Repair(c.Request.Context(), &project, target.service())
 	if err != nil {
-		logger.GetLogger().Warnf("检查项目 %d 现有webhook失败: %v", project.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "修复GitLab webhook失败: " + err.Error()})
 		return
 	}
 
-	now := time.Now()
+	c.JSON(http.StatusOK, gin.H{"data": models.WebhookRepairResponse{
+		ProjectID:       project.ID,
+		GitLabWebhookID: project.GitLabWebhookID,
+		WebhookURL:      target.webhookURL,
+		Repaired:        inspection.Drift,
+	}})
+}
 
-	if existingWebhook != nil {
-		// webhook已存在，更新项目状态
-		project.GitLabWebhookID = &existingWebhook.ID
-		project.WebhookSynced = true
-		project.LastSyncAt = &now
-		logger.GetLogger().Infof("项目 %d 的GitLab webhook已存在，状态已更新", project.ID)
-	} else {
-		// 创建新的webhook
-		gitlabService := services.NewGitLabService(parsed.BaseURL, token)
-		webhook, err := gitlabService.CreateProjectWebhook(parsed.BaseURL, project.GitLabProjectID, webhookURL, token)
-		if err != nil {
-			logger.GetLogger().Warnf("为项目 %d 创建GitLab webhook失败: %v", project.ID, err)
-			return
-		}
+// autoCreateGitLabWebhook 自动创建GitLab webhook
+func (h *Handler) autoCreateGitLabWebhook(ctx context.Context, project *models.Project, token string) {
+	if group := h.projectCoveringGroup(project); group != nil {
+		logger.GetLogger().Infof("项目 %d 已由组 %s 的 Webhook 覆盖，跳过项目级 webhook 创建", project.ID, group.FullPath)
+		return
+	}
 
-		// 更新项目状态
-		project.GitLabWebhookID = &webhook.ID
-		project.WebhookSynced = true
-		project.LastSyncAt = &now
+	// 按项目所属实例解析基础URL、回调地址与令牌
+	target := h.hookTarget(project, token)
+	if target.baseURL == "" {
+		logger.GetLogger().Warnf("项目 %d URL格式无效，跳过webhook创建: %s", project.ID, project.URL)
+		return
+	}
+
+	// 确保GitLab中存在webhook，并按项目订阅同步事件开关
+	webhook, created, err := h.gitlabService.SyncProjectWebhook(ctx, target.baseURL, project.GitLabProjectID, target.webhookURL, target.hookEvents(project), target.token)
+	if err != nil {
+		logger.GetLogger().Warnf("同步项目 %d 的GitLab webhook失败: %v", project.ID, err)
+		h.alertHookSyncFailure(project, "同步", err)
+		return
+	}
+
+	// 更新项目状态
+	now := time.Now()
+	project.GitLabWebhookID = &webhook.ID
+	project.WebhookSynced = true
+	project.LastSyncAt = &now
+	project.ClearWebhookDrift(now)
+	if created {
 		logger.GetLogger().Infof("项目 %d 的GitLab webhook创建成功，ID: %d", project.ID, webhook.ID)
+	} else {
+		logger.GetLogger().Infof("项目 %d 的GitLab webhook已存在，状态已更新", project.ID)
 	}
 
 	// 保存项目状态（忽略错误，避免影响主流程）
@@ -1261,22 +1385,19 @@ func (h *Handler) autoCreateGitLabWebhook(project *models.Project, token string)
 }
 
 // autoDeleteGitLabWebhook 自动删除GitLab webhook（支持删除多个重复的webhook）
-func (h *Handler) autoDeleteGitLabWebhook(project *models.Project, token string) {
-	// 解析GitLab URL
-	parsed := h.gitlabService.ParseGitLabURL(project.URL)
-	if !parsed.IsValid {
-		logger.GetLogger().Warnf("项目 %d URL格式无效，跳过webhook删除: %s", project.ID, parsed.Error)
+func (h *Handler) autoDeleteGitLabWebhook(ctx context.Context, project *models.Project, token string) {
+	// 按项目所属实例解析基础URL、回调地址与令牌
+	target := h.hookTarget(project, token)
+	if target.baseURL == "" {
+		logger.GetLogger().Warnf("项目 %d URL格式无效，跳过webhook删除: %s", project.ID, project.URL)
 		return
 	}
 
-	// 构建webhook URL
-	webhookURL := h.gitlabService.BuildWebhookURL(h.config.PublicWebhookURL)
-
 	// 删除GitLab中所有匹配的webhook
-	gitlabService := services.NewGitLabService(parsed.BaseURL, token)
-	deletedCount, err := gitlabService.DeleteAllWebhooksByURL(parsed.BaseURL, project.GitLabProjectID, webhookURL, token)
+	deletedCount, err := h.gitlabService.DeleteAllWebhooksByURL(ctx, target.baseURL, project.GitLabProjectID, target.webhookURL, target.token)
 	if err != nil {
 		logger.GetLogger().Warnf("删除项目 %d 的GitLab webhook失败: %v (已删除 %d 个)", project.ID, err, deletedCount)
+		h.alertHookSyncFailure(project, "删除", err)
 		// 即使删除失败也继续，可能是webhook已经被手动删除
 	} else if deletedCount > 0 {
 		if deletedCount == 1 {
@@ -1289,176 +1410,116 @@ func (h *Handler) autoDeleteGitLabWebhook(project *models.Project, token string)
 	}
 }
 
-// BatchCheckWebhookStatus 批量并发检查所有项目的webhook状态
-func (h *Handler) BatchCheckWebhookStatus(c *gin.Context) {
-	var projects []models.Project
-
-	// 获取用户的所有项目
-	query := h.db.Preload("Webhooks").Preload("Webhooks.Settings")
-	query = middleware.ApplyOwnershipFilter(c, query, "projects")
+// alertHookSyncFailure 后台同步 GitLab Hook 失败时发送系统告警，同一项目的告警合并去重
+func (h *Handler) alertHookSyncFailure(project *models.Project, action string, err error) {
+	h.opsAlerts.Notify(services.OpsAlertEvent{
+		Severity: models.OpsSeverityWarning,
+		Category: models.OpsCategoryGitLabHookSync,
+		Key:      fmt.Sprintf("gitlab_hook_sync:project:%d", project.ID),
+		Title:    fmt.Sprintf("项目 %s 的 GitLab Hook %s失败", project.Name, action),
+		Detail:   fmt.Sprintf("Project: %s\nGitLab Project ID: %d\nError: %v", project.URL, project.GitLabProjectID, err),
+	})
+}
 
-	if err := query.Find(&projects).Error; err != nil {
-		logger.GetLogger().Errorf("Failed to fetch projects for batch webhook check: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
-		return
-	}
+// GetWebhookStatusOverview 返回后台任务缓存的项目 webhook 状态与最近一次刷新的进度
+func (h *Handler) GetWebhookStatusOverview(c *gin.Context) {
+	h.respondWebhookStatus(c, http.StatusOK, "")
+}
 
-	if len(projects) == 0 {
-		c.JSON(http.StatusOK, gin.H{"data": []interface{}{}, "message": "No projects to check"})
+// RefreshWebhookStatus 立即在后台刷新所有项目的 webhook 状态，已有刷新进行中时返回该次进度
+func (h *Handler) RefreshWebhookStatus(c *gin.Context) {
+	if _, err := h.webhookStatus.TriggerRefresh(); err != nil {
+		if errors.Is(err, services.ErrWebhookStatusRunning) {
+			h.respondWebhookStatus(c, http.StatusOK, "刷新正在进行中")
+			return
+		}
+		logger.GetLogger().Errorf("Failed to trigger webhook status refresh: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to trigger webhook status refresh"})
 		return
 	}
+	h.respondWebhookStatus(c, http.StatusAccepted, "已开始刷新")
+}
 
-	// 获取 GitLab token
-	token, tokenErr := h.resolveGitLabToken(c, "")
-	if tokenErr != nil {
-		if errors.Is(tokenErr, errUnauthorized) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			return
-		}
-		if errors.Is(tokenErr, errGitLabTokenMissing) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "当前账户未配置 GitLab Personal Access Token"})
+// BatchCheckWebhookStatus 兼容旧接口：触发后台刷新并立即返回缓存的状态，不再同步请求 GitLab
+func (h *Handler) BatchCheckWebhookStatus(c *gin.Context) {
+	message := "已开始后台刷新，当前返回缓存的检查结果"
+	if _, err := h.webhookStatus.TriggerRefresh(); err != nil {
+		if !errors.Is(err, services.ErrWebhookStatusRunning) {
+			logger.GetLogger().Errorf("Failed to trigger webhook status refresh: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to trigger webhook status refresh"})
 			return
 		}
-		logger.GetLogger().Errorf("Failed to resolve GitLab token for batch check: %v", tokenErr)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "解析凭证失败"})
-		return
+		message = "后台刷新正在进行中，当前返回缓存的检查结果"
 	}
+	h.respondWebhookStatus(c, http.StatusOK, message)
+}
 
-	webhookURL := h.gitlabService.BuildWebhookURL(h.config.PublicWebhookURL)
-
-	// 定义结果结构
-	type CheckResult struct {
-		ProjectID       uint   `json:"project_id"`
-		ProjectName     string `json:"project_name"`
-		WebhookSynced   bool   `json:"webhook_synced"`
-		PreviousStatus  bool   `json:"previous_status"`
-		StatusChanged   bool   `json:"status_changed"`
-		Error           string `json:"error,omitempty"`
-		GitLabWebhookID *int   `json:"gitlab_webhook_id,omitempty"`
+func (h *Handler) respondWebhookStatus(c *gin.Context, status int, message string) {
+	var projects []models.Project
+	query := middleware.ApplyOwnershipFilter(c, h.db.Model(&models.Project{}), "projects")
+	if err := query.Find(&projects).Error; err != nil {
+		logger.GetLogger().Errorf("Failed to fetch projects for webhook status: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
+		return
 	}
 
-	// 使用 channel 收集结果
-	resultChan := make(chan CheckResult, len(projects))
-	var wg sync.WaitGroup
-
-	// 限制并发数为 10，避免对 GitLab API 造成过大压力
-	semaphore := make(chan struct{}, 10)
-
-	// 启动 goroutines 进行并发检查
-	for _, project := range projects {
-		wg.Add(1)
-		go func(p models.Project) {
-			defer wg.Done()
-
-			// 获取信号量
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			result := CheckResult{
-				ProjectID:      p.ID,
-				ProjectName:    p.Name,
-				PreviousStatus: p.WebhookSynced,
-			}
-
-			// 解析项目 URL
-			parsed := h.gitlabService.ParseGitLabURL(p.URL)
-			if !parsed.IsValid {
-				result.Error = "Invalid project URL"
-				result.WebhookSynced = p.WebhookSynced
-				resultChan <- result
-				return
-			}
-
-			// 检查 GitLab 上的实际 webhook
-			existingWebhook, err := h.gitlabService.FindWebhookByURL(
-				parsed.BaseURL,
-				p.GitLabProjectID,
-				webhookURL,
-				token,
-			)
-
-			if err != nil {
-				result.Error = fmt.Sprintf("Failed to check webhook: %v", err)
-				result.WebhookSynced = p.WebhookSynced
-				logger.GetLogger().Warnf("Failed to check webhook for project %d: %v", p.ID, err)
-			} else {
-				// 设置实际状态
-				result.WebhookSynced = (existingWebhook != nil)
-				if existingWebhook != nil {
-					result.GitLabWebhookID = &existingWebhook.ID
-				}
-
-				// 检查状态是否改变
-				result.StatusChanged = (result.WebhookSynced != p.WebhookSynced)
-
-				// 如果状态发生变化，更新数据库
-				if result.StatusChanged {
-					updateData := map[string]interface{}{
-						"webhook_synced": result.WebhookSynced,
-					}
-
-					if result.WebhookSynced && existingWebhook != nil {
-						updateData["gitlab_webhook_id"] = existingWebhook.ID
-					} else {
-						updateData["gitlab_webhook_id"] = nil
-					}
-
-					now := time.Now()
-					updateData["last_sync_at"] = &now
-
-					if err := h.db.Model(&p).Updates(updateData).Error; err != nil {
-						logger.GetLogger().Errorf("Failed to update project %d webhook status: %v", p.ID, err)
-						result.Error = "Failed to update database"
-					} else {
-						logger.GetLogger().Infof("Batch check updated project %d webhook status from %v to %v",
-							p.ID, p.WebhookSynced, result.WebhookSynced)
-					}
-				}
-			}
-
-			resultChan <- result
-		}(project)
+	run, err := h.webhookStatus.LatestRun()
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to fetch webhook status run: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook status run"})
+		return
 	}
 
-	// 等待所有 goroutine 完成
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	// 收集所有结果
-	var results []CheckResult
-	var successCount, errorCount, changedCount int
-
-	for result := range resultChan {
-		results = append(results, result)
-		if result.Error == "" {
-			successCount++
-			if result.StatusChanged {
-				changedCount++
-			}
-		} else {
+	results := h.webhookStatus.CachedResults(projects)
+	var syncedCount, errorCount, changedCount int
+	for _, result := range results {
+		if result.Error != "" {
 			errorCount++
+		} else if result.WebhookSynced {
+			syncedCount++
 		}
 	}
+	if run != nil {
+		changedCount = run.Changed
+	}
 
-	// 构建响应
 	response := gin.H{
 		"data": results,
 		"summary": gin.H{
-			"total":          len(projects),
-			"success":        successCount,
+			"total":          len(results),
+			"success":        len(results) - errorCount,
+			"synced":         syncedCount,
 			"errors":         errorCount,
 			"status_changed": changedCount,
 		},
-		"message": fmt.Sprintf("检查完成: %d 个项目, %d 个成功, %d 个失败, %d 个状态已更新",
-			len(projects), successCount, errorCount, changedCount),
+		"job": run,
 	}
+	if message != "" {
+		response["message"] = message
+	}
+	c.JSON(status, response)
+}
 
-	logger.GetLogger().Infof("Batch webhook status check completed: %d projects, %d success, %d errors, %d changed",
-		len(projects), successCount, errorCount, changedCount)
-
-	c.JSON(http.StatusOK, response)
+// applyProjectEventSubscriptions 按请求更新项目的事件订阅配置
+func applyProjectEventSubscriptions(project *models.Project, req *models.UpdateProjectRequest) {
+	if req.MergeRequestEvents != nil {
+		project.MergeRequestEvents = *req.MergeRequestEvents
+	}
+	if req.PushEvents != nil {
+		project.PushEvents = *req.PushEvents
+	}
+	if req.TagPushEvents != nil {
+		project.TagPushEvents = *req.TagPushEvents
+	}
+	if req.ReleaseEvents != nil {
+		project.ReleaseEvents = *req.ReleaseEvents
+	}
+	if req.PushBranchFilters != nil {
+		project.PushBranchFilters = models.ToStringList(*req.PushBranchFilters)
+	}
+	if req.TagFilters != nil {
+		project.TagFilters = models.ToStringList(*req.TagFilters)
+	}
 }
 
 // dedupeProjectWebhooks 在返回层面去重，避免因历史重复记录导致前端显示多个同名标签
@@ -1482,14 +1543,14 @@ func dedupeProjectWebhooks(webhooks []models.Webhook) []models.Webhook {
 }
 
 // refreshProjectWebhookStatus 调用 GitLab 实时确认 webhook 是否存在，并同步到本地状态
-func (h *Handler) refreshProjectWebhookStatus(project *models.Project, token, webhookURL string) {
+func (h *Handler) refreshProjectWebhookStatus(ctx context.Context, project *models.Project, token, webhookURL string) {
 	parsed := h.gitlabService.ParseGitLabURL(project.URL)
 	if !parsed.IsValid {
 		logger.GetLogger().Warnf("Skip webhook status refresh for project %d: %s", project.ID, parsed.Error)
 		return
 	}
 
-	existingWebhook, err := h.gitlabService.FindWebhookByURL(parsed.BaseURL, project.GitLabProjectID, webhookURL, token)
+	existingWebhook, err := h.gitlabService.FindWebhookByURL(ctx, parsed.BaseURL, project.GitLabProjectID, webhookURL, token)
 	if err != nil {
 		logger.GetLogger().Warnf("Failed to refresh webhook status for project %d: %v", project.ID, err)
 		return
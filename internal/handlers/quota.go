@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/middleware"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetWebhookQuotaUsage 获取当前用户可见的所有 Webhook 本月配额使用情况
+func (h *Handler) GetWebhookQuotaUsage(c *gin.Context) {
+	var webhooks []models.Webhook
+	query := middleware.ApplyOwnershipFilter(c, h.db.Model(&models.Webhook{}), "webhooks")
+	if err := query.Order("id ASC").Find(&webhooks).Error; err != nil {
+		logger.GetLogger().Errorf("Failed to fetch webhooks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+
+	usages, err := h.quotaService.GetUsage(webhooks, time.Now())
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to compute quota usage: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取配额使用情况失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": usages})
+}
+
+// GetWebhookQuotaUsageByID 获取单个 Webhook 本月配额使用情况
+func (h *Handler) GetWebhookQuotaUsageByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	var webhook models.Webhook
+	query := middleware.ApplyOwnershipFilter(c, h.db.Model(&models.Webhook{}), "webhooks")
+	if err := query.First(&webhook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	usages, err := h.quotaService.GetUsage([]models.Webhook{webhook}, time.Now())
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to compute quota usage for webhook [ID: %d]: %v", webhook.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取配额使用情况失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": usages[0]})
+}
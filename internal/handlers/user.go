@@ -8,6 +8,7 @@ import (
 
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/middleware"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/services"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -20,8 +21,11 @@ func (h *Handler) GetUsers(c *gin.Context) {
 	// 应用所有权过滤
 	query := h.db
 	query = middleware.ApplyOwnershipFilter(c, query, "users")
+	if c.Query("mapping_pending") == "true" {
+		query = query.Where("gitlab_mapping_pending = ?", true)
+	}
 
-	if err := query.Find(&users).Error; err != nil {
+	if err := query.Preload("Identities").Preload("Aliases").Find(&users).Error; err != nil {
 		logger.GetLogger().Errorf("Failed to fetch users: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
@@ -31,16 +35,8 @@ func (h *Handler) GetUsers(c *gin.Context) {
 
 	// 转换为响应格式
 	responses := make([]models.UserResponse, 0, len(users))
-	for _, user := range users {
-		responses = append(responses, models.UserResponse{
-			ID:             user.ID,
-			Email:          user.Email,
-			Phone:          user.Phone,
-			Name:           user.Name,
-			GitLabUsername: user.GitLabUsername,
-			CreatedAt:      user.CreatedAt,
-			UpdatedAt:      user.UpdatedAt,
-		})
+	for idx := range users {
+		responses = append(responses, users[idx].ToResponse())
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data": responses})
@@ -63,6 +59,12 @@ func (h *Handler) CreateUser(c *gin.Context) {
 		GitLabUsername: req.GitLabUsername,
 		CreatedBy:      &accountID,
 	}
+	if user.GitLabUsername != "" {
+		user.GitLabMappingSource = models.GitLabMappingSourceManual
+	}
+	if !h.primaryIdentityAvailable(c, user.Email, user.GitLabUsername, 0) {
+		return
+	}
 
 	if err := h.db.Create(user).Error; err != nil {
 		logger.GetLogger().Errorf("Failed to create user [Email: %s, Phone: %s]: %v", req.Email, req.Phone, err)
@@ -78,17 +80,7 @@ func (h *Handler) CreateUser(c *gin.Context) {
 
 	logger.GetLogger().Infof("Successfully created user [ID: %d, Email: %s]", user.ID, user.Email)
 
-	response := models.UserResponse{
-		ID:             user.ID,
-		Email:          user.Email,
-		Phone:          user.Phone,
-		Name:           user.Name,
-		GitLabUsername: user.GitLabUsername,
-		CreatedAt:      user.CreatedAt,
-		UpdatedAt:      user.UpdatedAt,
-	}
-
-	c.JSON(http.StatusCreated, gin.H{"data": response})
+	c.JSON(http.StatusCreated, gin.H{"data": user.ToResponse()})
 }
 
 func (h *Handler) UpdateUser(c *gin.Context) {
@@ -126,8 +118,18 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 	if req.Name != "" {
 		user.Name = req.Name
 	}
-	// 允许清空 GitLab 用户名
+	// 允许清空 GitLab 用户名；手动修改后不再是待确认的自动映射
+	if user.GitLabUsername != req.GitLabUsername {
+		user.GitLabMappingSource = ""
+		if req.GitLabUsername != "" {
+			user.GitLabMappingSource = models.GitLabMappingSourceManual
+		}
+		user.GitLabMappingPending = false
+	}
 	user.GitLabUsername = req.GitLabUsername
+	if !h.primaryIdentityAvailable(c, user.Email, user.GitLabUsername, user.ID) {
+		return
+	}
 
 	if err := h.db.Save(&user).Error; err != nil {
 		logger.GetLogger().Errorf("Failed to update user [ID: %d]: %v", id, err)
@@ -143,17 +145,7 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 
 	logger.GetLogger().Infof("Successfully updated user [ID: %d, Email: %s]", user.ID, user.Email)
 
-	response := models.UserResponse{
-		ID:             user.ID,
-		Email:          user.Email,
-		Phone:          user.Phone,
-		Name:           user.Name,
-		GitLabUsername: user.GitLabUsername,
-		CreatedAt:      user.CreatedAt,
-		UpdatedAt:      user.UpdatedAt,
-	}
-
-	c.JSON(http.StatusOK, gin.H{"data": response})
+	c.JSON(http.StatusOK, gin.H{"data": user.ToResponse()})
 }
 
 func (h *Handler) DeleteUser(c *gin.Context) {
@@ -163,7 +155,18 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Delete(&models.User{}, id).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserAlias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserNotificationPreference{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, id).Error
+	}); err != nil {
 		logger.GetLogger().Errorf("Failed to delete user [ID: %d]: %v", id, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除用户失败"})
 		return
@@ -173,3 +176,54 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
+
+// ConfirmUserGitLabMapping 确认通过 GitLab 用户接口自动发现的用户名映射
+func (h *Handler) ConfirmUserGitLabMapping(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	if user.GitLabUsername == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户未配置 GitLab 用户名"})
+		return
+	}
+
+	if err := h.db.Model(&models.User{}).Where("id = ?", user.ID).Update("gitlab_mapping_pending", false).Error; err != nil {
+		logger.GetLogger().Errorf("Failed to confirm GitLab mapping of user [ID: %d]: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "确认映射失败"})
+		return
+	}
+	user.GitLabMappingPending = false
+
+	logger.GetLogger().Infof("Confirmed GitLab mapping of user [ID: %d, GitLab username: %s]", user.ID, user.GitLabUsername)
+	c.JSON(http.StatusOK, gin.H{"data": user.ToResponse()})
+}
+
+// primaryIdentityAvailable 检查邮箱与 GitLab 用户名未被其他用户使用（包括别名），冲突时已写入响应
+func (h *Handler) primaryIdentityAvailable(c *gin.Context, email, gitlabUsername string, userID uint) bool {
+	err := h.userAliases.CheckAvailable(models.UserAliasKindEmail, email, userID)
+	if err == nil {
+		err = h.userAliases.CheckAvailable(models.UserAliasKindGitLabUsername, gitlabUsername, userID)
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrUserAliasTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "邮箱或 GitLab 用户名已被其他用户使用"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/middleware"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/services"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserAliases 获取用户的邮箱与 GitLab 用户名别名
+func (h *Handler) GetUserAliases(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	aliases, err := h.userAliases.ListAliases(uint(id))
+	if err != nil {
+		h.respondUserAliasError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": aliases})
+}
+
+// CreateUserAlias 为用户添加别名
+func (h *Handler) CreateUserAlias(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UserAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alias, err := h.userAliases.AddAlias(uint(id), &req)
+	if err != nil {
+		h.respondUserAliasError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": alias})
+}
+
+// DeleteUserAlias 删除用户的别名
+func (h *Handler) DeleteUserAlias(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	aliasID, err := strconv.ParseUint(c.Param("alias_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alias ID"})
+		return
+	}
+
+	if err := h.userAliases.DeleteAlias(uint(id), uint(aliasID)); err != nil {
+		h.respondUserAliasError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alias deleted successfully"})
+}
+
+// MergeUsers 将重复的用户合并到路径中的用户，被合并的用户会被删除
+func (h *Handler) MergeUsers(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.MergeUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 路径中的用户由中间件校验，被合并的用户同样需要有权限
+	if !middleware.IsAdmin(c) {
+		var count int64
+		query := middleware.ApplyOwnershipFilter(c, h.db.Model(&models.User{}), "users")
+		if err := query.Where("id = ?", req.SourceUserID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+	}
+
+	user, err := h.userAliases.MergeUsers(uint(id), req.SourceUserID)
+	if err != nil {
+		h.respondUserAliasError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user.ToResponse()})
+}
+
+func (h *Handler) respondUserAliasError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrUserAliasNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alias not found"})
+	case errors.Is(err, services.ErrUserAliasTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "该邮箱或 GitLab 用户名已被使用"})
+	case errors.Is(err, services.ErrUserMergeSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能将用户合并到自身"})
+	default:
+		logger.GetLogger().Errorf("User alias operation failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
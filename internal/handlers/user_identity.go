@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetUserIdentities 获取用户在各通知渠道中的账号标识
+func (h *Handler) GetUserIdentities(c *gin.Context) {
+	userID, ok := h.findIdentityUser(c)
+	if !ok {
+		return
+	}
+
+	var identities []models.UserIdentity
+	if err := h.db.Where("user_id = ?", userID).Order("channel").Find(&identities).Error; err != nil {
+		logger.GetLogger().Errorf("Failed to fetch identities of user [ID: %d]: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取渠道身份失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": identities})
+}
+
+// SetUserIdentity 设置用户在指定渠道中的账号标识，已存在时覆盖
+func (h *Handler) SetUserIdentity(c *gin.Context) {
+	userID, ok := h.findIdentityUser(c)
+	if !ok {
+		return
+	}
+
+	channel := c.Param("channel")
+	if !models.IsIdentityChannel(channel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的渠道: " + channel})
+		return
+	}
+
+	var req models.UserIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	identifier := strings.TrimSpace(req.Identifier)
+	if identifier == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "账号标识不能为空"})
+		return
+	}
+
+	identity := models.UserIdentity{UserID: userID, Channel: channel, Identifier: identifier}
+	if err := h.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"identifier", "updated_at"}),
+	}).Create(&identity).Error; err != nil {
+		logger.GetLogger().Errorf("Failed to save %s identity of user [ID: %d]: %v", channel, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存渠道身份失败"})
+		return
+	}
+
+	if err := h.db.Where("user_id = ? AND channel = ?", userID, channel).First(&identity).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	logger.GetLogger().Infof("Saved %s identity of user [ID: %d]", channel, userID)
+	c.JSON(http.StatusOK, gin.H{"data": identity})
+}
+
+// DeleteUserIdentity 删除用户在指定渠道中的账号标识，之后该渠道回退为使用手机号 @
+func (h *Handler) DeleteUserIdentity(c *gin.Context) {
+	userID, ok := h.findIdentityUser(c)
+	if !ok {
+		return
+	}
+
+	channel := c.Param("channel")
+	result := h.db.Where("user_id = ? AND channel = ?", userID, channel).Delete(&models.UserIdentity{})
+	if result.Error != nil {
+		logger.GetLogger().Errorf("Failed to delete %s identity of user [ID: %d]: %v", channel, userID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除渠道身份失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "渠道身份不存在"})
+		return
+	}
+
+	logger.GetLogger().Infof("Deleted %s identity of user [ID: %d]", channel, userID)
+	c.JSON(http.StatusOK, gin.H{"message": "Identity deleted successfully"})
+}
+
+// findIdentityUser 解析路径中的用户 ID 并确认用户存在，失败时已写入响应
+func (h *Handler) findIdentityUser(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+
+	var user models.User
+	if err := h.db.Select("id").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return 0, false
+	}
+	return user.ID, true
+}
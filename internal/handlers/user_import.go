@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/middleware"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/services"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userImportMaxFileSize 导入文件的大小上限
+const userImportMaxFileSize = 5 * 1024 * 1024
+
+var userTableContentTypes = map[string]string{
+	models.UserTableFormatCSV:  "text/csv; charset=utf-8",
+	models.UserTableFormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ExportUsers 按导入格式导出当前账户可见的用户，?format=csv|xlsx，默认 csv
+func (h *Handler) ExportUsers(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", models.UserTableFormatCSV))
+	contentType, ok := userTableContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format 只支持 csv 或 xlsx"})
+		return
+	}
+
+	var users []models.User
+	query := middleware.ApplyOwnershipFilter(c, h.db, "users")
+	if err := query.Order("id ASC").Find(&users).Error; err != nil {
+		logger.GetLogger().Errorf("Failed to fetch users for export: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.userImport.Export(users, format, &buf); err != nil {
+		logger.GetLogger().Errorf("Failed to export users: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出用户失败"})
+		return
+	}
+
+	filename := fmt.Sprintf("users-%s.%s", time.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// ImportUsers 从 CSV/XLSX 文件批量导入用户
+// 文件字段为 file，格式按 ?format= 或文件扩展名识别；?dry_run=true 只预览差异，?upsert=true 更新已存在的用户
+func (h *Handler) ImportUsers(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无法获取上传文件"})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > userImportMaxFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件大小不能超过5MB"})
+		return
+	}
+
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	rows, err := h.userImport.Parse(format, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserTableFormat):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "只支持 csv 或 xlsx 文件"})
+		case errors.Is(err, services.ErrUserTableHeader):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "表头需包含 email 列，可选 phone、name、gitlab_username"})
+		case errors.Is(err, services.ErrUserImportTooLarge):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "单次导入的行数过多"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	accountID, _ := middleware.GetAccountID(c)
+	opts := models.UserImportOptions{
+		DryRun:    c.Query("dry_run") == "true",
+		Upsert:    c.Query("upsert") == "true",
+		AccountID: accountID,
+	}
+	// 非管理员只能更新自己可见的用户
+	if !middleware.IsAdmin(c) {
+		var ids []uint
+		if err := middleware.ApplyOwnershipFilter(c, h.db.Model(&models.User{}), "users").Pluck("id", &ids).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		opts.EditableUserIDs = make(map[uint]bool, len(ids))
+		for _, id := range ids {
+			opts.EditableUserIDs[id] = true
+		}
+	}
+
+	result, err := h.userImport.Import(rows, opts)
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to import users: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/middleware"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/services"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserSyncSources 获取用户目录同步的成员来源（仅管理员）
+func (h *Handler) GetUserSyncSources(c *gin.Context) {
+	sources, err := h.userSync.ListSources()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user sync sources"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sources})
+}
+
+// CreateUserSyncSource 登记 GitLab 组或项目作为成员来源（仅管理员）
+func (h *Handler) CreateUserSyncSource(c *gin.Context) {
+	var req models.CreateUserSyncSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数无效: " + err.Error()})
+		return
+	}
+
+	parsed := h.gitlabService.ParseGitLabURL(req.URL)
+	if !parsed.IsValid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "URL解析失败: " + parsed.Error})
+		return
+	}
+
+	instanceID, err := h.projectInstanceID(parsed)
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to resolve GitLab instance for user sync source: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "识别 GitLab 实例失败"})
+		return
+	}
+
+	token, ok := h.groupHookToken(c, req.AccessToken, instanceID)
+	if !ok {
+		return
+	}
+
+	accountID, _ := middleware.GetAccountID(c)
+	source := &models.UserSyncSource{
+		GitLabInstanceID: instanceID,
+		SourceType:       req.SourceType,
+		CreatedBy:        &accountID,
+	}
+	if req.SourceType == models.UserSyncSourceGroup {
+		group, err := h.gitlabService.GetGroupByPath(c.Request.Context(), parsed.BaseURL, parsed.ProjectPath, token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "获取组信息失败: " + err.Error()})
+			return
+		}
+		source.GitLabID, source.Name, source.FullPath, source.WebURL = group.ID, group.Name, group.FullPath, group.WebURL
+	} else {
+		project, err := h.gitlabService.GetProjectByPath(c.Request.Context(), parsed.BaseURL, parsed.ProjectPath, token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "获取项目信息失败: " + err.Error()})
+			return
+		}
+		source.GitLabID, source.Name, source.FullPath, source.WebURL = project.ID, project.Name, project.PathWithNamespace, project.WebURL
+	}
+
+	if err := h.userSync.CreateSource(source); err != nil {
+		h.respondUserSyncError(c, err)
+		return
+	}
+
+	logger.GetLogger().Infof("Registered user sync source [ID: %d, Type: %s, Path: %s]", source.ID, source.SourceType, source.FullPath)
+	c.JSON(http.StatusCreated, gin.H{"data": source})
+}
+
+// DeleteUserSyncSource 取消登记成员来源，已同步的用户保留（仅管理员）
+func (h *Handler) DeleteUserSyncSource(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source ID"})
+		return
+	}
+
+	if err := h.userSync.DeleteSource(uint(id)); err != nil {
+		h.respondUserSyncError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User sync source deleted successfully"})
+}
+
+// SyncUserDirectory 立即同步用户目录，可通过 source_id 只同步一个来源（仅管理员）
+func (h *Handler) SyncUserDirectory(c *gin.Context) {
+	var sourceIDs []uint
+	if raw := c.Query("source_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source ID"})
+			return
+		}
+		if _, err := h.userSync.GetSource(uint(id)); err != nil {
+			h.respondUserSyncError(c, err)
+			return
+		}
+		sourceIDs = append(sourceIDs, uint(id))
+	}
+
+	run, err := h.userSync.Sync(c.Request.Context(), models.UserSyncTriggerManual, sourceIDs...)
+	if err != nil {
+		h.respondUserSyncError(c, err)
+		return
+	}
+	if run.Status == models.UserSyncStatusFailed {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "同步用户目录失败: " + run.Error, "data": run})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": run})
+}
+
+// GetUserSyncRuns 获取最近的用户目录同步记录（仅管理员）
+func (h *Handler) GetUserSyncRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	runs, err := h.userSync.ListRuns(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sync runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": runs})
+}
+
+// GetUserSyncReport 最近一次同步跳过的成员与缺少手机号的已同步用户（仅管理员）
+func (h *Handler) GetUserSyncReport(c *gin.Context) {
+	report, err := h.userSync.Report()
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to build user sync report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build user sync report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+func (h *Handler) respondUserSyncError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserSyncSourceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User sync source not found"})
+	case errors.Is(err, services.ErrUserSyncSourceExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "该组或项目已登记为成员来源"})
+	case errors.Is(err, services.ErrUserSyncRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": "用户目录同步正在进行，请稍后再试"})
+	default:
+		logger.GetLogger().Errorf("User sync operation failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,18 +1,107 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/services"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
+// defaultWebhookTimeout 合并请求回调中查询 GitLab 的默认总时长
+const defaultWebhookTimeout = 5 * time.Second
+
 func (h *Handler) HandleGitLabWebhook(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to read webhook body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
+		return
+	}
+
+	var header models.GitLabEventHeader
+	if err := json.Unmarshal(body, &header); err != nil {
+		logger.GetLogger().Errorf("Failed to parse webhook data: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
+		return
+	}
+
+	instance, ok := h.resolveWebhookInstance(c, &header)
+	if !ok {
+		return
+	}
+
+	// 组 Hook 会推送组内所有项目的事件，未登记的项目按组配置自动登记或忽略
+	if !h.ensureGroupEventProject(c, instance.ID, &header) {
+		return
+	}
+
+	switch header.ObjectKind {
+	case models.EventTypeMergeRequest:
+		h.handleMergeRequestEvent(c, instance.ID, body)
+	case models.EventTypePush, models.EventTypeTagPush:
+		h.handlePushEvent(c, instance.ID, header.ObjectKind, body)
+	case models.EventTypeRelease:
+		h.handleReleaseEvent(c, instance.ID, body)
+	case models.EventTypePipeline:
+		h.handlePipelineEvent(c, instance.ID, body)
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Event ignored"})
+	}
+}
+
+// resolveWebhookInstance 识别回调所属的 GitLab 实例并校验 Secret Token
+// 依次按路径中的实例、X-Gitlab-Instance 头、事件中的项目地址识别实例；
+// 只登记了默认实例时无法识别的回调归入默认实例，登记了多个实例时返回 404
+func (h *Handler) resolveWebhookInstance(c *gin.Context, header *models.GitLabEventHeader) (*models.GitLabInstance, bool) {
+	var (
+		instance *models.GitLabInstance
+		err      error
+	)
+
+	if ref := c.Param("instance"); ref != "" {
+		instance, err = h.gitlabInstances.ResolveRef(ref)
+	} else if ref := c.GetHeader("X-Gitlab-Instance"); ref != "" {
+		instance, err = h.gitlabInstances.ResolveRef(ref)
+		// 头中的地址可能是 GitLab 的内部地址，此时按项目地址识别
+		if errors.Is(err, services.ErrGitLabInstanceNotFound) {
+			logger.GetLogger().Debugf("无法按 X-Gitlab-Instance 识别实例 %s: %v", ref, err)
+			instance, err = h.gitlabInstances.ResolveEvent(header.Project.WebURL)
+		}
+	} else {
+		instance, err = h.gitlabInstances.ResolveEvent(header.Project.WebURL)
+	}
+
+	if err != nil {
+		if errors.Is(err, services.ErrGitLabInstanceNotFound) {
+			logger.GetLogger().Warnf("收到未登记 GitLab 实例的回调，项目: %s", header.Project.WebURL)
+			c.JSON(http.StatusNotFound, gin.H{"error": "GitLab instance not found"})
+			return nil, false
+		}
+		logger.GetLogger().Errorf("Failed to resolve GitLab instance: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
+		return nil, false
+	}
+
+	if !h.gitlabInstances.VerifyWebhookSecret(instance, c.GetHeader("X-Gitlab-Token")) {
+		logger.GetLogger().Warnf("GitLab 实例 %s 的回调 Secret Token 校验失败，来源: %s", instance.Name, c.ClientIP())
+		h.hookReconciler.RecordTokenRejected(instance.ID, c.GetHeader("X-Gitlab-Event"), header.Project)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook token"})
+		return nil, false
+	}
+
+	return instance, true
+}
+
+func (h *Handler) handleMergeRequestEvent(c *gin.Context, instanceID uint, body []byte) {
 	var webhookData models.GitLabWebhookData
-	if err := c.ShouldBindJSON(&webhookData); err != nil {
+	if err := json.Unmarshal(body, &webhookData); err != nil {
 		logger.GetLogger().Errorf("Failed to parse webhook data: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
 		return
@@ -39,13 +128,15 @@ func (h *Handler) HandleGitLabWebhook(c *gin.Context) {
 		logger.GetLogger().Warnf("此合并请求没有指派人")
 	}
 
-	// 只处理合并请求事件
-	if webhookData.ObjectKind != "merge_request" {
-		c.JSON(http.StatusOK, gin.H{"message": "Event ignored"})
-		return
+	// 查询 GitLab 的总耗时需小于 GitLab 的回调超时，否则 GitLab 会重试并重复通知
+	timeout := h.config.Notification.WebhookTimeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
 	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
 
-	if err := h.notifyService.ProcessMergeRequest(&webhookData); err != nil {
+	if err := h.notifyService.ProcessMergeRequest(ctx, instanceID, &webhookData); err != nil {
 		logger.GetLogger().Errorf("Failed to process merge request: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
 		return
@@ -53,3 +144,66 @@ func (h *Handler) HandleGitLabWebhook(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Webhook processed successfully"})
 }
+
+func (h *Handler) handlePushEvent(c *gin.Context, instanceID uint, kind string, body []byte) {
+	var event models.GitLabPushEventData
+	if err := json.Unmarshal(body, &event); err != nil {
+		logger.GetLogger().Errorf("Failed to parse %s event: %v", kind, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
+		return
+	}
+
+	logger.GetLogger().Infof("收到 GitLab %s 事件 - 项目: %s (ID: %d), ref: %s, 提交数: %d",
+		kind, event.Project.Name, event.Project.ID, event.Ref, event.TotalCommitsCount)
+
+	var err error
+	if kind == models.EventTypeTagPush {
+		err = h.notifyService.ProcessTagPushEvent(instanceID, &event)
+	} else {
+		err = h.notifyService.ProcessPushEvent(instanceID, &event)
+	}
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to process %s event: %v", kind, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook processed successfully"})
+}
+
+func (h *Handler) handleReleaseEvent(c *gin.Context, instanceID uint, body []byte) {
+	var event models.GitLabReleaseEventData
+	if err := json.Unmarshal(body, &event); err != nil {
+		logger.GetLogger().Errorf("Failed to parse release event: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
+		return
+	}
+
+	logger.GetLogger().Infof("收到 GitLab release 事件 - 项目: %s (ID: %d), tag: %s, action: %s",
+		event.Project.Name, event.Project.ID, event.Tag, event.Action)
+
+	if err := h.notifyService.ProcessReleaseEvent(instanceID, &event); err != nil {
+		logger.GetLogger().Errorf("Failed to process release event: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook processed successfully"})
+}
+
+func (h *Handler) handlePipelineEvent(c *gin.Context, instanceID uint, body []byte) {
+	var event models.GitLabPipelineEventData
+	if err := json.Unmarshal(body, &event); err != nil {
+		logger.GetLogger().Errorf("Failed to parse pipeline event: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
+		return
+	}
+
+	if err := h.notifyService.ProcessPipelineEvent(instanceID, &event); err != nil {
+		logger.GetLogger().Errorf("Failed to process pipeline event: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook processed successfully"})
+}
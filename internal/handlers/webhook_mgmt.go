@@ -77,6 +77,20 @@ func (h *Handler) CreateWebhook(c *gin.Context) {
 	webhook.Type = channel
 	webhook.ApplyDefaults()
 
+	if err := webhook.ApplyDeliveryWindow(&req.DeliveryWindowRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "投递时间窗口配置无效: " + err.Error()})
+		return
+	}
+
+	if req.RateLimitPerMinute != nil {
+		webhook.RateLimitPerMinute = *req.RateLimitPerMinute
+	}
+
+	if err := webhook.ApplyDigestSchedule(req.DigestEnabled, req.DigestCron, req.DigestTimezone, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "汇总配置无效: " + err.Error()})
+		return
+	}
+
 	if err := h.db.Create(webhook).Error; err != nil {
 		logger.GetLogger().Errorf("Failed to create webhook [Name: %s, URL: %s]: %v", req.Name, req.URL, err)
 
@@ -136,6 +150,8 @@ func (h *Handler) UpdateWebhook(c *gin.Context) {
 	}
 
 	targetURL := webhook.URL
+	previousURL := webhook.URL
+	wasActive := webhook.IsActive
 
 	if req.Name != "" {
 		webhook.Name = req.Name
@@ -151,6 +167,11 @@ func (h *Handler) UpdateWebhook(c *gin.Context) {
 		webhook.IsActive = *req.IsActive
 	}
 
+	// 重新启用或更换地址后重新计算熔断状态
+	if (webhook.IsActive && !wasActive) || webhook.URL != previousURL {
+		webhook.ResetCircuit()
+	}
+
 	if req.Type != "" {
 		channel := strings.ToLower(strings.TrimSpace(req.Type))
 		if channel == models.WebhookTypeAuto {
@@ -184,6 +205,23 @@ func (h *Handler) UpdateWebhook(c *gin.Context) {
 		headersPtr = &headers
 	}
 
+	if err := webhook.ApplyDeliveryWindow(&req.DeliveryWindowRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "投递时间窗口配置无效: " + err.Error()})
+		return
+	}
+
+	if req.RateLimitPerMinute != nil {
+		webhook.RateLimitPerMinute = *req.RateLimitPerMinute
+	}
+
+	// 渠道时区变化会影响未单独设置时区的汇总计划
+	if req.DigestEnabled != nil || req.DigestCron != nil || req.DigestTimezone != nil || req.Timezone != nil {
+		if err := webhook.ApplyDigestSchedule(req.DigestEnabled, req.DigestCron, req.DigestTimezone, time.Now()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "汇总配置无效: " + err.Error()})
+			return
+		}
+	}
+
 	webhook.ApplyDefaults()
 
 	if err := h.db.Save(&webhook).Error; err != nil {
@@ -387,7 +425,8 @@ func (h *Handler) SendTestMessage(c *gin.Context) {
 		URL:          h.config.PublicWebhookURL,
 	}
 
-	if err := sender.Send(c.Request.Context(), &webhook, payload); err != nil {
+	// 测试消息作为人工试探，熔断中也会发送，成功后关闭熔断
+	if err := sender.Send(services.WithCircuitProbe(c.Request.Context()), &webhook, payload); err != nil {
 		logger.GetLogger().Errorf("Failed to send test message to webhook [ID: %d, Name: %s]: %v", webhook.ID, webhook.Name, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "发送测试消息失败",
@@ -418,6 +457,54 @@ func (h *Handler) SendTestMessage(c *gin.Context) {
 	})
 }
 
+// SendWebhookDigest 立即发送 Webhook 的打开中合并请求汇总，用于测试汇总效果
+func (h *Handler) SendWebhookDigest(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	var webhook models.Webhook
+	query := middleware.ApplyOwnershipFilter(c, h.db.Model(&models.Webhook{}), "webhooks")
+	if err := query.First(&webhook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	result, err := h.digestService.SendDigest(c.Request.Context(), webhook.ID)
+	if err != nil {
+		logger.GetLogger().Errorf("Failed to send digest to webhook [ID: %d, Name: %s]: %v", webhook.ID, webhook.Name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "发送汇总失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if result.Queued {
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "当前不在投递时间内或速率受限，汇总已放入投递队列",
+			"webhook_name":   webhook.Name,
+			"merge_requests": result.MergeRequests,
+			"queued":         true,
+			"release_at":     result.ReleaseAt.In(webhook.Location()).Format("2006-01-02 15:04:05"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "汇总发送成功",
+		"webhook_name":   webhook.Name,
+		"merge_requests": result.MergeRequests,
+		"sent_at":        time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
 func buildWebhookResponse(webhook *models.Webhook) models.WebhookResponse {
 	if webhook == nil {
 		return models.WebhookResponse{}
@@ -435,29 +522,48 @@ func buildWebhookResponse(webhook *models.Webhook) models.WebhookResponse {
 	}
 
 	response := models.WebhookResponse{
-		ID:               webhook.ID,
-		Name:             webhook.Name,
-		URL:              webhook.URL,
-		Description:      webhook.Description,
-		Type:             webhook.Type,
-		SignatureMethod:  signatureMethod,
-		Secret:           secret,
-		SecurityKeywords: webhook.SecurityKeywordsAsSlice(),
-		CustomHeaders:    webhook.CustomHeadersAsMap(),
-		IsActive:         webhook.IsActive,
-		CreatedAt:        webhook.CreatedAt,
-		UpdatedAt:        webhook.UpdatedAt,
+		ID:                    webhook.ID,
+		Name:                  webhook.Name,
+		URL:                   webhook.URL,
+		Description:           webhook.Description,
+		Type:                  webhook.Type,
+		SignatureMethod:       signatureMethod,
+		Secret:                secret,
+		SecurityKeywords:      webhook.SecurityKeywordsAsSlice(),
+		CustomHeaders:         webhook.CustomHeadersAsMap(),
+		IsActive:              webhook.IsActive,
+		Timezone:              webhook.Timezone,
+		WorkingHours:          webhook.WorkingHours,
+		Holidays:              webhook.Holidays,
+		UrgentBranchPatterns:  webhook.UrgentBranchPatterns,
+		BatchHeldMessages:     webhook.BatchHeldMessages,
+		CoalesceWindowSeconds: webhook.CoalesceWindowSeconds,
+		RateLimitPerMinute:    webhook.RateLimitPerMinute,
+		CircuitState:          webhook.CircuitState(),
+		ConsecutiveFailures:   webhook.ConsecutiveFailures,
+		CircuitOpenedAt:       webhook.CircuitOpenedAt,
+		CircuitSkippedCount:   webhook.CircuitSkippedCount,
+		LastFailureMessage:    webhook.LastFailureMessage,
+		DisabledReason:        webhook.DisabledReason,
+		DigestEnabled:         webhook.DigestEnabled,
+		DigestCron:            webhook.DigestCron,
+		DigestTimezone:        webhook.DigestTimezone,
+		DigestNextRunAt:       webhook.DigestNextRunAt,
+		DigestLastSentAt:      webhook.DigestLastSentAt,
+		CreatedAt:             webhook.CreatedAt,
+		UpdatedAt:             webhook.UpdatedAt,
 	}
 
 	for _, project := range webhook.Projects {
 		response.Projects = append(response.Projects, models.ProjectResponse{
-			ID:              project.ID,
-			GitLabProjectID: project.GitLabProjectID,
-			Name:            project.Name,
-			URL:             project.URL,
-			Description:     project.Description,
-			CreatedAt:       project.CreatedAt,
-			UpdatedAt:       project.UpdatedAt,
+			ID:               project.ID,
+			GitLabInstanceID: project.GitLabInstanceID,
+			GitLabProjectID:  project.GitLabProjectID,
+			Name:             project.Name,
+			URL:              project.URL,
+			Description:      project.Description,
+			CreatedAt:        project.CreatedAt,
+			UpdatedAt:        project.UpdatedAt,
 		})
 	}
 
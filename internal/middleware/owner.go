@@ -175,8 +175,8 @@ func ApplyOwnershipFilter(c *gin.Context, query *gorm.DB, tableName string) *gor
 
 	// 根据不同的表使用不同的字段
 	switch tableName {
-	case "notifications":
-		// notifications 表：通过项目权限控制，查看用户有权限访问的项目的通知
+	case "notifications", "merge_requests":
+		// notifications / merge_requests 表：通过项目权限控制，查看用户有权限访问的项目的通知
 		return query.Where(tableName+".project_id IN (SELECT id FROM projects WHERE created_by = ? OR id IN (SELECT resource_id FROM resource_managers WHERE manager_id = ? AND resource_type = 'project'))", accountID, accountID)
 	case "projects":
 		// projects 表：查询用户创建的或被分配管理的项目
@@ -0,0 +1,43 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type Migration012AddProjectEventSubscriptions struct{}
+
+func (m Migration012AddProjectEventSubscriptions) ID() string {
+	return "012_add_project_event_subscriptions"
+}
+
+func (m Migration012AddProjectEventSubscriptions) Description() string {
+	return "Add push/tag_push/release event subscriptions to projects and event type to notifications"
+}
+
+func (m Migration012AddProjectEventSubscriptions) Up(db *gorm.DB) error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"projects", "merge_request_events", "BOOLEAN DEFAULT 1"},
+		{"projects", "push_events", "BOOLEAN DEFAULT 0"},
+		{"projects", "tag_push_events", "BOOLEAN DEFAULT 0"},
+		{"projects", "release_events", "BOOLEAN DEFAULT 0"},
+		{"projects", "push_branch_filters", "JSON"},
+		{"projects", "tag_filters", "JSON"},
+		{"notifications", "event_type", "TEXT NOT NULL DEFAULT 'merge_request'"},
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, col := range columns {
+			if err := addColumnIfNotExists(tx, col.table, col.column, col.definition); err != nil {
+				return err
+			}
+		}
+		return tx.Exec("CREATE INDEX IF NOT EXISTS idx_notifications_event_type ON notifications(event_type)").Error
+	})
+}
+
+func (m Migration012AddProjectEventSubscriptions) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列，保留字段仅删除索引
+	return db.Exec("DROP INDEX IF EXISTS idx_notifications_event_type").Error
+}
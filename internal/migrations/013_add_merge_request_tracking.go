@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"gorm.io/gorm"
+)
+
+type Migration013AddMergeRequestTracking struct{}
+
+func (m Migration013AddMergeRequestTracking) ID() string {
+	return "013_add_merge_request_tracking"
+}
+
+func (m Migration013AddMergeRequestTracking) Description() string {
+	return "Create merge_requests and merge_request_transitions tables"
+}
+
+func (m Migration013AddMergeRequestTracking) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.MergeRequest{}, &models.MergeRequestTransition{}); err != nil {
+		return fmt.Errorf("auto migrate merge request tables failed: %w", err)
+	}
+	return nil
+}
+
+func (m Migration013AddMergeRequestTracking) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.MergeRequestTransition{}, &models.MergeRequest{})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"gorm.io/gorm"
+)
+
+type Migration014AddMergeRequestReminders struct{}
+
+func (m Migration014AddMergeRequestReminders) ID() string {
+	return "014_add_merge_request_reminders"
+}
+
+func (m Migration014AddMergeRequestReminders) Description() string {
+	return "Add reminder settings, scheduled job leases and reminder counters on merge requests"
+}
+
+func (m Migration014AddMergeRequestReminders) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.ProjectReminderSetting{}, &models.ScheduledJob{}); err != nil {
+		return fmt.Errorf("auto migrate reminder tables failed: %w", err)
+	}
+
+	if err := addColumnIfNotExists(db, "merge_requests", "reminder_count", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	return addColumnIfNotExists(db, "merge_requests", "last_reminded_at", "DATETIME")
+}
+
+func (m Migration014AddMergeRequestReminders) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.ScheduledJob{}, &models.ProjectReminderSetting{})
+}
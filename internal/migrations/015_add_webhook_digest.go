@@ -0,0 +1,41 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type Migration015AddWebhookDigest struct{}
+
+func (m Migration015AddWebhookDigest) ID() string {
+	return "015_add_webhook_digest"
+}
+
+func (m Migration015AddWebhookDigest) Description() string {
+	return "Add digest schedule to webhooks and pipeline status to merge requests"
+}
+
+func (m Migration015AddWebhookDigest) Up(db *gorm.DB) error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"webhooks", "digest_enabled", "BOOLEAN DEFAULT 0"},
+		{"webhooks", "digest_cron", "TEXT"},
+		{"webhooks", "digest_timezone", "TEXT"},
+		{"webhooks", "digest_next_run_at", "DATETIME"},
+		{"webhooks", "digest_last_sent_at", "DATETIME"},
+		{"merge_requests", "pipeline_id", "INTEGER NOT NULL DEFAULT 0"},
+		{"merge_requests", "pipeline_status", "TEXT"},
+	}
+
+	for _, col := range columns {
+		if err := addColumnIfNotExists(db, col.table, col.column, col.definition); err != nil {
+			return err
+		}
+	}
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_webhooks_digest_next_run_at ON webhooks(digest_next_run_at)").Error
+}
+
+func (m Migration015AddWebhookDigest) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列，保留字段仅删除索引
+	return db.Exec("DROP INDEX IF EXISTS idx_webhooks_digest_next_run_at").Error
+}
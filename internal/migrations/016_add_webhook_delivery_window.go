@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"gorm.io/gorm"
+)
+
+type Migration016AddWebhookDeliveryWindow struct{}
+
+func (m Migration016AddWebhookDeliveryWindow) ID() string {
+	return "016_add_webhook_delivery_window"
+}
+
+func (m Migration016AddWebhookDeliveryWindow) Description() string {
+	return "Add working hours, holidays and urgent rules to webhooks and create delivery_queue table"
+}
+
+func (m Migration016AddWebhookDeliveryWindow) Up(db *gorm.DB) error {
+	columns := []struct {
+		column     string
+		definition string
+	}{
+		{"timezone", "TEXT"},
+		{"working_hours", "JSON"},
+		{"holidays", "JSON"},
+		{"urgent_branch_patterns", "JSON"},
+		{"batch_held_messages", "BOOLEAN DEFAULT 0"},
+	}
+
+	for _, col := range columns {
+		if err := addColumnIfNotExists(db, "webhooks", col.column, col.definition); err != nil {
+			return err
+		}
+	}
+
+	if err := db.AutoMigrate(&models.DeliveryQueueItem{}); err != nil {
+		return fmt.Errorf("auto migrate delivery queue failed: %w", err)
+	}
+	return nil
+}
+
+func (m Migration016AddWebhookDeliveryWindow) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.DeliveryQueueItem{})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type Migration017AddEscalationPolicies struct{}
+
+func (m Migration017AddEscalationPolicies) ID() string {
+	return "017_add_escalation_policies"
+}
+
+func (m Migration017AddEscalationPolicies) Description() string {
+	return "Create escalation policy and history tables and track merge request approval time"
+}
+
+func (m Migration017AddEscalationPolicies) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.EscalationPolicy{}, &models.EscalationEvent{}); err != nil {
+		return fmt.Errorf("auto migrate escalation tables failed: %w", err)
+	}
+
+	return addColumnIfNotExists(db, "merge_requests", "approved_at", "DATETIME")
+}
+
+func (m Migration017AddEscalationPolicies) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.EscalationEvent{}, &models.EscalationPolicy{})
+}
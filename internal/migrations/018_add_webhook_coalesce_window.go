@@ -0,0 +1,25 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type Migration018AddWebhookCoalesceWindow struct{}
+
+func (m Migration018AddWebhookCoalesceWindow) ID() string {
+	return "018_add_webhook_coalesce_window"
+}
+
+func (m Migration018AddWebhookCoalesceWindow) Description() string {
+	return "Add merge request coalescing window to webhooks and hold reason to delivery_queue"
+}
+
+func (m Migration018AddWebhookCoalesceWindow) Up(db *gorm.DB) error {
+	if err := addColumnIfNotExists(db, "webhooks", "coalesce_window_seconds", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	return addColumnIfNotExists(db, "delivery_queue", "reason", "TEXT NOT NULL DEFAULT 'delivery_window'")
+}
+
+func (m Migration018AddWebhookCoalesceWindow) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列，保留字段
+	return nil
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type Migration019AddWebhookRateLimit struct{}
+
+func (m Migration019AddWebhookRateLimit) ID() string {
+	return "019_add_webhook_rate_limit"
+}
+
+func (m Migration019AddWebhookRateLimit) Description() string {
+	return "Add per-webhook rate limit override to webhooks"
+}
+
+func (m Migration019AddWebhookRateLimit) Up(db *gorm.DB) error {
+	return addColumnIfNotExists(db, "webhooks", "rate_limit_per_minute", "INTEGER NOT NULL DEFAULT 0")
+}
+
+func (m Migration019AddWebhookRateLimit) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列，保留字段
+	return nil
+}
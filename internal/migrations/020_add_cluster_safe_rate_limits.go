@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type Migration020AddClusterSafeRateLimits struct{}
+
+func (m Migration020AddClusterSafeRateLimits) ID() string {
+	return "020_add_cluster_safe_rate_limits"
+}
+
+func (m Migration020AddClusterSafeRateLimits) Description() string {
+	return "Deduplicate webhook delivery stats, add unique period index and create rate_limit_buckets table"
+}
+
+func (m Migration020AddClusterSafeRateLimits) Up(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 并发创建可能产生同一周期的多条记录，合并计数后只保留最早的一条
+		if err := tx.Exec(`
+			UPDATE webhook_delivery_stats
+			SET count = (
+				SELECT SUM(s.count) FROM webhook_delivery_stats s
+				WHERE s.webhook_id = webhook_delivery_stats.webhook_id
+				AND s.period_start = webhook_delivery_stats.period_start
+			)
+			WHERE id IN (
+				SELECT MIN(id) FROM webhook_delivery_stats
+				GROUP BY webhook_id, period_start
+				HAVING COUNT(*) > 1
+			)`).Error; err != nil {
+			return fmt.Errorf("merge duplicate delivery stats failed: %w", err)
+		}
+
+		if err := tx.Exec(`
+			DELETE FROM webhook_delivery_stats
+			WHERE id NOT IN (
+				SELECT MIN(id) FROM webhook_delivery_stats
+				GROUP BY webhook_id, period_start
+			)`).Error; err != nil {
+			return fmt.Errorf("delete duplicate delivery stats failed: %w", err)
+		}
+
+		if err := tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_delivery_stats_period ON webhook_delivery_stats(webhook_id, period_start)").Error; err != nil {
+			return fmt.Errorf("create delivery stats unique index failed: %w", err)
+		}
+
+		if err := tx.AutoMigrate(&models.RateLimitBucket{}); err != nil {
+			return fmt.Errorf("auto migrate rate limit buckets failed: %w", err)
+		}
+		return nil
+	})
+}
+
+func (m Migration020AddClusterSafeRateLimits) Down(db *gorm.DB) error {
+	if err := db.Exec("DROP INDEX IF EXISTS idx_webhook_delivery_stats_period").Error; err != nil {
+		return err
+	}
+	return db.Migrator().DropTable(&models.RateLimitBucket{})
+}
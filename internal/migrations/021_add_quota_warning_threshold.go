@@ -0,0 +1,22 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type Migration021AddQuotaWarningThreshold struct{}
+
+func (m Migration021AddQuotaWarningThreshold) ID() string {
+	return "021_add_quota_warning_threshold"
+}
+
+func (m Migration021AddQuotaWarningThreshold) Description() string {
+	return "Track the highest quota warning threshold sent per webhook delivery period"
+}
+
+func (m Migration021AddQuotaWarningThreshold) Up(db *gorm.DB) error {
+	return addColumnIfNotExists(db, "webhook_delivery_stats", "warned_threshold", "INTEGER NOT NULL DEFAULT 0")
+}
+
+func (m Migration021AddQuotaWarningThreshold) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列，保留字段
+	return nil
+}
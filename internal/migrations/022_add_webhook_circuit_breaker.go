@@ -0,0 +1,41 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type Migration022AddWebhookCircuitBreaker struct{}
+
+func (m Migration022AddWebhookCircuitBreaker) ID() string {
+	return "022_add_webhook_circuit_breaker"
+}
+
+func (m Migration022AddWebhookCircuitBreaker) Description() string {
+	return "Add circuit breaker state and disabled reason to webhooks"
+}
+
+func (m Migration022AddWebhookCircuitBreaker) Up(db *gorm.DB) error {
+	columns := []struct {
+		column     string
+		definition string
+	}{
+		{"consecutive_failures", "INTEGER NOT NULL DEFAULT 0"},
+		{"circuit_opened_at", "DATETIME"},
+		{"circuit_probe_at", "DATETIME"},
+		{"circuit_skipped_count", "INTEGER NOT NULL DEFAULT 0"},
+		{"circuit_alerted_at", "DATETIME"},
+		{"last_failure_message", "TEXT"},
+		{"disabled_reason", "TEXT"},
+	}
+
+	for _, col := range columns {
+		if err := addColumnIfNotExists(db, "webhooks", col.column, col.definition); err != nil {
+			return err
+		}
+	}
+
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_webhooks_circuit_opened_at ON webhooks(circuit_opened_at)").Error
+}
+
+func (m Migration022AddWebhookCircuitBreaker) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列，保留字段
+	return nil
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type Migration023CreateOpsAlerts struct{}
+
+func (m Migration023CreateOpsAlerts) ID() string {
+	return "023_create_ops_alerts"
+}
+
+func (m Migration023CreateOpsAlerts) Description() string {
+	return "Create ops_alerts table for deduplicated system alerts"
+}
+
+func (m Migration023CreateOpsAlerts) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.OpsAlert{}); err != nil {
+		return fmt.Errorf("auto migrate ops alerts failed: %w", err)
+	}
+	return nil
+}
+
+func (m Migration023CreateOpsAlerts) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.OpsAlert{})
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type Migration024AddGitLabInstances struct{}
+
+func (m Migration024AddGitLabInstances) ID() string {
+	return "024_add_gitlab_instances"
+}
+
+func (m Migration024AddGitLabInstances) Description() string {
+	return "Create gitlab_instances table and scope project uniqueness to instance"
+}
+
+func (m Migration024AddGitLabInstances) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.GitLabInstance{}); err != nil {
+		return fmt.Errorf("auto migrate gitlab instances failed: %w", err)
+	}
+
+	// 已有项目的实例 ID 保持为 0，启动时由默认实例回填
+	if err := addColumnIfNotExists(db, "projects", "gitlab_instance_id", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 旧版本由 AutoMigrate 创建的单列唯一索引名为 idx_projects_git_lab_project_id，按列查找以兼容不同的命名
+		if err := dropSingleColumnUniqueIndexes(tx, "projects", "gitlab_project_id"); err != nil {
+			return err
+		}
+		return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_instance_project ON projects(gitlab_instance_id, gitlab_project_id)").Error
+	})
+}
+
+func (m Migration024AddGitLabInstances) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列，保留 projects.gitlab_instance_id 与组合唯一索引
+	return db.Migrator().DropTable(&models.GitLabInstance{})
+}
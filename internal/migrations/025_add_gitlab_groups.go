@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type Migration025AddGitLabGroups struct{}
+
+func (m Migration025AddGitLabGroups) ID() string {
+	return "025_add_gitlab_groups"
+}
+
+func (m Migration025AddGitLabGroups) Description() string {
+	return "Create gitlab_groups and gitlab_group_webhooks tables for group-level GitLab webhooks"
+}
+
+func (m Migration025AddGitLabGroups) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.GitLabGroup{}); err != nil {
+		return fmt.Errorf("auto migrate gitlab groups failed: %w", err)
+	}
+	return nil
+}
+
+func (m Migration025AddGitLabGroups) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable("gitlab_group_webhooks", &models.GitLabGroup{})
+}
@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type Migration026AddWatchedGroupSync struct{}
+
+func (m Migration026AddWatchedGroupSync) ID() string {
+	return "026_add_watched_group_sync"
+}
+
+func (m Migration026AddWatchedGroupSync) Description() string {
+	return "Add hook mode, token source and default subscriptions to gitlab_groups, track project source group and state, and create gitlab_group_sync_runs table"
+}
+
+func (m Migration026AddWatchedGroupSync) Up(db *gorm.DB) error {
+	groupColumns := []struct {
+		name       string
+		definition string
+	}{
+		{"hook_mode", "TEXT NOT NULL DEFAULT 'group'"},
+		{"token_source", "TEXT NOT NULL DEFAULT 'instance'"},
+		{"access_token", "TEXT"},
+		{"default_merge_request_events", "BOOLEAN NOT NULL DEFAULT 1"},
+		{"default_push_events", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"default_tag_push_events", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"default_release_events", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"default_push_branch_filters", "JSON"},
+		{"default_tag_filters", "JSON"},
+		{"last_scan_at", "DATETIME"},
+		{"last_scan_error", "TEXT"},
+	}
+	for _, column := range groupColumns {
+		if err := addColumnIfNotExists(db, "gitlab_groups", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	if err := addColumnIfNotExists(db, "projects", "source_group_id", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists(db, "projects", "gitlab_state", "TEXT NOT NULL DEFAULT 'active'"); err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_projects_source_group_id ON projects(source_group_id)").Error; err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(&models.GitLabGroupSyncRun{}); err != nil {
+		return fmt.Errorf("auto migrate gitlab group sync runs failed: %w", err)
+	}
+	return nil
+}
+
+func (m Migration026AddWatchedGroupSync) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列
+	return db.Migrator().DropTable(&models.GitLabGroupSyncRun{})
+}
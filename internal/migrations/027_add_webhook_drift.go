@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration027AddWebhookDrift struct{}
+
+func (m Migration027AddWebhookDrift) ID() string {
+	return "027_add_webhook_drift"
+}
+
+func (m Migration027AddWebhookDrift) Description() string {
+	return "Add webhook drift tracking columns to projects"
+}
+
+func (m Migration027AddWebhookDrift) Up(db *gorm.DB) error {
+	columns := []struct {
+		name       string
+		definition string
+	}{
+		{"webhook_drift", "JSON"},
+		{"drift_checked_at", "DATETIME"},
+		{"webhook_token_rejected_at", "DATETIME"},
+	}
+	for _, column := range columns {
+		if err := addColumnIfNotExists(db, "projects", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m Migration027AddWebhookDrift) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列
+	return nil
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type Migration028AddWebhookStatusRuns struct{}
+
+func (m Migration028AddWebhookStatusRuns) ID() string {
+	return "028_add_webhook_status_runs"
+}
+
+func (m Migration028AddWebhookStatusRuns) Description() string {
+	return "Cache background webhook status checks on projects and create webhook_status_runs table"
+}
+
+func (m Migration028AddWebhookStatusRuns) Up(db *gorm.DB) error {
+	if err := addColumnIfNotExists(db, "projects", "webhook_checked_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists(db, "projects", "webhook_check_error", "TEXT"); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(&models.WebhookStatusRun{}); err != nil {
+		return fmt.Errorf("auto migrate webhook status runs failed: %w", err)
+	}
+	return nil
+}
+
+func (m Migration028AddWebhookStatusRuns) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列
+	return db.Migrator().DropTable(&models.WebhookStatusRun{})
+}
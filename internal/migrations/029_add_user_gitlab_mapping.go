@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration029AddUserGitLabMapping struct{}
+
+func (m Migration029AddUserGitLabMapping) ID() string {
+	return "029_add_user_gitlab_mapping"
+}
+
+func (m Migration029AddUserGitLabMapping) Description() string {
+	return "Record the source of GitLab username mappings on users and flag discovered mappings for confirmation"
+}
+
+func (m Migration029AddUserGitLabMapping) Up(db *gorm.DB) error {
+	if err := addColumnIfNotExists(db, "users", "gitlab_mapping_source", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists(db, "users", "gitlab_mapping_pending", "BOOLEAN NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	// 已有的映射都是手动填写的
+	return db.Exec("UPDATE users SET gitlab_mapping_source = 'manual' WHERE gitlab_username != '' AND gitlab_mapping_source = ''").Error
+}
+
+func (m Migration029AddUserGitLabMapping) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列
+	return nil
+}
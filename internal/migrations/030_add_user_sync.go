@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type Migration030AddUserSync struct{}
+
+func (m Migration030AddUserSync) ID() string {
+	return "030_add_user_sync"
+}
+
+func (m Migration030AddUserSync) Description() string {
+	return "Link users to GitLab user IDs and create user_sync_sources and user_sync_runs tables"
+}
+
+func (m Migration030AddUserSync) Up(db *gorm.DB) error {
+	if err := addColumnIfNotExists(db, "users", "gitlab_instance_id", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists(db, "users", "gitlab_user_id", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_users_gitlab_user ON users(gitlab_instance_id, gitlab_user_id)").Error; err != nil {
+		return fmt.Errorf("create users gitlab user index failed: %w", err)
+	}
+
+	if err := db.AutoMigrate(&models.UserSyncSource{}, &models.UserSyncRun{}); err != nil {
+		return fmt.Errorf("auto migrate user sync tables failed: %w", err)
+	}
+	return nil
+}
+
+func (m Migration030AddUserSync) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列
+	return db.Migrator().DropTable(&models.UserSyncRun{}, &models.UserSyncSource{})
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type Migration031CreateUserIdentities struct{}
+
+func (m Migration031CreateUserIdentities) ID() string {
+	return "031_create_user_identities"
+}
+
+func (m Migration031CreateUserIdentities) Description() string {
+	return "Create user_identities table for per-channel mention identifiers"
+}
+
+func (m Migration031CreateUserIdentities) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.UserIdentity{}); err != nil {
+		return fmt.Errorf("auto migrate user identities failed: %w", err)
+	}
+	return nil
+}
+
+func (m Migration031CreateUserIdentities) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.UserIdentity{})
+}
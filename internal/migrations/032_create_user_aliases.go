@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type Migration032CreateUserAliases struct{}
+
+func (m Migration032CreateUserAliases) ID() string {
+	return "032_create_user_aliases"
+}
+
+func (m Migration032CreateUserAliases) Description() string {
+	return "Create user_aliases table for alternative emails and GitLab usernames"
+}
+
+func (m Migration032CreateUserAliases) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.UserAlias{}); err != nil {
+		return fmt.Errorf("auto migrate user aliases failed: %w", err)
+	}
+	return nil
+}
+
+func (m Migration032CreateUserAliases) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.UserAlias{})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type Migration033CreateUserNotificationPreferences struct{}
+
+func (m Migration033CreateUserNotificationPreferences) ID() string {
+	return "033_create_user_notification_preferences"
+}
+
+func (m Migration033CreateUserNotificationPreferences) Description() string {
+	return "Create user_notification_preferences table and link accounts to GitLab usernames"
+}
+
+func (m Migration033CreateUserNotificationPreferences) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.UserNotificationPreference{}); err != nil {
+		return fmt.Errorf("auto migrate user notification preferences failed: %w", err)
+	}
+	return addColumnIfNotExists(db, "accounts", "gitlab_username", "TEXT NOT NULL DEFAULT ''")
+}
+
+func (m Migration033CreateUserNotificationPreferences) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列，只删除偏好表
+	return db.Migrator().DropTable(&models.UserNotificationPreference{})
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration034AddOpsAlertAccount struct{}
+
+func (m Migration034AddOpsAlertAccount) ID() string {
+	return "034_add_ops_alert_account"
+}
+
+func (m Migration034AddOpsAlertAccount) Description() string {
+	return "Scope ops alerts to the account that owns the affected resource"
+}
+
+func (m Migration034AddOpsAlertAccount) Up(db *gorm.DB) error {
+	if err := addColumnIfNotExists(db, "ops_alerts", "account_id", "INTEGER"); err != nil {
+		return err
+	}
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_ops_alerts_account_id ON ops_alerts(account_id)").Error
+}
+
+func (m Migration034AddOpsAlertAccount) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列，保留字段仅删除索引
+	return db.Exec("DROP INDEX IF EXISTS idx_ops_alerts_account_id").Error
+}
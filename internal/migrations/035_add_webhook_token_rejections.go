@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration035AddWebhookTokenRejections struct{}
+
+func (m Migration035AddWebhookTokenRejections) ID() string {
+	return "035_add_webhook_token_rejections"
+}
+
+func (m Migration035AddWebhookTokenRejections) Description() string {
+	return "Count rejected webhook secret tokens per project"
+}
+
+func (m Migration035AddWebhookTokenRejections) Up(db *gorm.DB) error {
+	return addColumnIfNotExists(db, "projects", "webhook_token_rejections", "INTEGER NOT NULL DEFAULT 0")
+}
+
+func (m Migration035AddWebhookTokenRejections) Down(db *gorm.DB) error {
+	// SQLite 不支持直接删除列
+	return nil
+}
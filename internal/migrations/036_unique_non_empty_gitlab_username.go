@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration036UniqueNonEmptyGitLabUsername struct{}
+
+func (m Migration036UniqueNonEmptyGitLabUsername) ID() string {
+	return "036_unique_non_empty_gitlab_username"
+}
+
+func (m Migration036UniqueNonEmptyGitLabUsername) Description() string {
+	return "Only require gitlab_username to be unique when it is set"
+}
+
+func (m Migration036UniqueNonEmptyGitLabUsername) Up(db *gorm.DB) error {
+	// 新建数据库时 AutoMigrate 按模型标签创建了整列唯一索引，多个未填写 GitLab 用户名的用户无法共存
+	if err := db.Exec("DROP INDEX IF EXISTS idx_users_git_lab_username").Error; err != nil {
+		return err
+	}
+	return db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_gitlab_username ON users(gitlab_username) WHERE gitlab_username != ''").Error
+}
+
+func (m Migration036UniqueNonEmptyGitLabUsername) Down(db *gorm.DB) error {
+	// 已有多个未填写 GitLab 用户名的用户时无法恢复整列唯一索引，保留部分唯一索引
+	return nil
+}
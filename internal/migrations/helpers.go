@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// columnExists 检查表中是否已存在指定字段
+func columnExists(db *gorm.DB, table, column string) (bool, error) {
+	var exists bool
+	if err := db.Raw("SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&exists).Error; err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// addColumnIfNotExists 字段不存在时才追加，兼容通过 AutoMigrate 初始化的新库
+func addColumnIfNotExists(db *gorm.DB, table, column, definition string) error {
+	exists, err := columnExists(db, table, column)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	return db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition).Error
+}
+
+// dropSingleColumnUniqueIndexes 删除表中只包含指定字段的唯一索引，建表语句中的 UNIQUE 约束无法删除，不做处理
+func dropSingleColumnUniqueIndexes(db *gorm.DB, table, column string) error {
+	var names []string
+	if err := db.Raw(`SELECT il.name FROM pragma_index_list(?) AS il
+		WHERE il."unique" = 1 AND il.origin = 'c'
+		AND (SELECT COUNT(*) FROM pragma_index_info(il.name)) = 1
+		AND (SELECT name FROM pragma_index_info(il.name)) = ?`, table, column).Scan(&names).Error; err != nil {
+		return fmt.Errorf("查询 %s.%s 的唯一索引失败: %w", table, column, err)
+	}
+	for _, name := range names {
+		if err := db.Exec(fmt.Sprintf("DROP INDEX IF EXISTS `%s`", name)).Error; err != nil {
+			return fmt.Errorf("删除索引 %s 失败: %w", name, err)
+		}
+	}
+	return nil
+}
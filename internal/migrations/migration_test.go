@@ -0,0 +1,121 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "upgrade.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// loadBaselineSchema 建立基线版本的数据库结构，迁移 001-011 已记为执行
+func loadBaselineSchema(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "baseline_schema.sql"))
+	if err != nil {
+		t.Fatalf("read baseline schema: %v", err)
+	}
+	for _, statement := range strings.Split(string(data), ";\n") {
+		var lines []string
+		for _, line := range strings.Split(statement, "\n") {
+			if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+				lines = append(lines, line)
+			}
+		}
+		statement = strings.TrimSpace(strings.Join(lines, "\n"))
+		if statement == "" {
+			continue
+		}
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("load baseline schema: %v\n%s", err, statement)
+		}
+	}
+}
+
+func uniqueIndexes(t *testing.T, db *gorm.DB, table string) map[string][]string {
+	t.Helper()
+	var names []string
+	if err := db.Raw(`SELECT name FROM pragma_index_list(?) WHERE "unique" = 1 AND origin = 'c'`, table).Scan(&names).Error; err != nil {
+		t.Fatalf("list indexes of %s: %v", table, err)
+	}
+	indexes := make(map[string][]string, len(names))
+	for _, name := range names {
+		var columns []string
+		if err := db.Raw("SELECT name FROM pragma_index_info(?) ORDER BY seqno", name).Scan(&columns).Error; err != nil {
+			t.Fatalf("list columns of %s: %v", name, err)
+		}
+		indexes[name] = columns
+	}
+	return indexes
+}
+
+func TestUpgradeFromBaselineSchema(t *testing.T) {
+	db := openTestDB(t)
+	loadBaselineSchema(t, db)
+	if err := db.Exec("INSERT INTO projects (gitlab_project_id, name, url) VALUES (42, 'demo', 'https://gitlab.example.com/g/demo')").Error; err != nil {
+		t.Fatalf("seed project: %v", err)
+	}
+	if err := db.Exec("INSERT INTO users (email, gitlab_username) VALUES ('alice@example.com', '')").Error; err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+
+	if err := SetupMigrator(db).Up(); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+
+	cases := []struct {
+		table string
+		want  map[string][]string
+	}{
+		{"projects", map[string][]string{"idx_projects_instance_project": {"gitlab_instance_id", "gitlab_project_id"}}},
+		{"users", map[string][]string{"idx_users_email": {"email"}, "idx_users_gitlab_username": {"gitlab_username"}}},
+	}
+	for _, tc := range cases {
+		if got := uniqueIndexes(t, db, tc.table); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected unique indexes %v, got %v", tc.table, tc.want, got)
+		}
+	}
+
+	// 不同实例可以登记相同的 GitLab 项目 ID，同一实例内仍然唯一
+	if err := db.Exec("INSERT INTO projects (gitlab_instance_id, gitlab_project_id, name, url) VALUES (2, 42, 'demo', 'https://other.example.com/g/demo')").Error; err != nil {
+		t.Errorf("same project id on another instance: %v", err)
+	}
+	if err := db.Exec("INSERT INTO projects (gitlab_instance_id, gitlab_project_id, name, url) VALUES (2, 42, 'copy', 'https://other.example.com/g/copy')").Error; err == nil {
+		t.Error("expected duplicate project id on the same instance to fail")
+	}
+	if err := db.Exec("INSERT INTO users (email, gitlab_username) VALUES ('bob@example.com', '')").Error; err != nil {
+		t.Errorf("second user without gitlab username: %v", err)
+	}
+}
+
+func TestFreshInstallIndexes(t *testing.T) {
+	db := openTestDB(t)
+	if err := SetupMigrator(db).Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	want := map[string][]string{"idx_projects_instance_project": {"gitlab_instance_id", "gitlab_project_id"}}
+	if got := uniqueIndexes(t, db, "projects"); !reflect.DeepEqual(got, want) {
+		t.Errorf("projects: expected unique indexes %v, got %v", want, got)
+	}
+}
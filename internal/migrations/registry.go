@@ -18,6 +18,31 @@ func GetAllMigrations() []Migration {
 		&Migration009RemoveAutoManageWebhook{},
 		&Migration010AddAdminInitializationFields{},
 		&Migration011AddWebhookMultiChannel{},
+		&Migration012AddProjectEventSubscriptions{},
+		&Migration013AddMergeRequestTracking{},
+		&Migration014AddMergeRequestReminders{},
+		&Migration015AddWebhookDigest{},
+		&Migration016AddWebhookDeliveryWindow{},
+		&Migration017AddEscalationPolicies{},
+		&Migration018AddWebhookCoalesceWindow{},
+		&Migration019AddWebhookRateLimit{},
+		&Migration020AddClusterSafeRateLimits{},
+		&Migration021AddQuotaWarningThreshold{},
+		&Migration022AddWebhookCircuitBreaker{},
+		&Migration023CreateOpsAlerts{},
+		&Migration024AddGitLabInstances{},
+		&Migration025AddGitLabGroups{},
+		&Migration026AddWatchedGroupSync{},
+		&Migration027AddWebhookDrift{},
+		&Migration028AddWebhookStatusRuns{},
+		&Migration029AddUserGitLabMapping{},
+		&Migration030AddUserSync{},
+		&Migration031CreateUserIdentities{},
+		&Migration032CreateUserAliases{},
+		&Migration033CreateUserNotificationPreferences{},
+		&Migration034AddOpsAlertAccount{},
+		&Migration035AddWebhookTokenRejections{},
+		&Migration036UniqueNonEmptyGitLabUsername{},
 	}
 }
 
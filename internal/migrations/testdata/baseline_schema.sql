@@ -0,0 +1,60 @@
+-- 基线版本（99f32d7）执行迁移 001-011 后的数据库结构，用于测试升级迁移
+CREATE TABLE accounts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username VARCHAR(50) NOT NULL UNIQUE,
+			password_hash TEXT NOT NULL,
+			email VARCHAR(255) NOT NULL UNIQUE,
+			role VARCHAR(20) DEFAULT 'user',
+			is_active BOOLEAN DEFAULT TRUE,
+			last_login_at DATETIME,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		, avatar TEXT, gitlab_access_token TEXT, force_password_reset BOOLEAN NOT NULL DEFAULT 0, password_initialized_at DATETIME, admin_setup_token_hash TEXT, admin_setup_token_generated_at DATETIME);
+CREATE TABLE `notifications` (`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer NOT NULL DEFAULT 0,`merge_request_id` integer NOT NULL DEFAULT 0,`title` text,`source_branch` text,`target_branch` text,`author_email` text,`assignee_emails` text,`status` text,`notification_sent` numeric DEFAULT false,`error_message` text,`owner_id` integer,`created_at` datetime,`updated_at` datetime,CONSTRAINT `fk_notifications_project` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`));
+CREATE TABLE `project_webhooks` (`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer NOT NULL DEFAULT 0,`webhook_id` integer NOT NULL DEFAULT 0,`created_at` datetime,CONSTRAINT `fk_project_webhooks_project` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`),CONSTRAINT `fk_project_webhooks_webhook` FOREIGN KEY (`webhook_id`) REFERENCES `webhooks`(`id`));
+CREATE TABLE "projects"  (id INTEGER PRIMARY KEY AUTOINCREMENT,gitlab_project_id INTEGER NOT NULL DEFAULT 0,`name` text NOT NULL DEFAULT "",`url` text NOT NULL DEFAULT "",description TEXT,access_token TEXT,gitlab_webhook_id INTEGER,webhook_synced BOOLEAN DEFAULT 0,last_sync_at DATETIME,created_by INTEGER,`created_at` datetime,`updated_at` datetime);
+CREATE TABLE resource_managers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			resource_id INTEGER NOT NULL,
+			resource_type VARCHAR(20) NOT NULL,
+			manager_id INTEGER NOT NULL,
+			created_by INTEGER NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (manager_id) REFERENCES accounts(id) ON DELETE CASCADE,
+			FOREIGN KEY (created_by) REFERENCES accounts(id) ON DELETE CASCADE,
+			UNIQUE(resource_id, resource_type, manager_id)
+		);
+CREATE TABLE `schema_migrations` (`id` text,`applied_at` datetime NOT NULL,PRIMARY KEY (`id`));
+CREATE TABLE `users` (`id` integer PRIMARY KEY AUTOINCREMENT,`email` text NOT NULL DEFAULT "",`phone` text NOT NULL DEFAULT "",`name` text,`gitlab_username` text DEFAULT "",`created_by` integer,`created_at` datetime,`updated_at` datetime);
+CREATE TABLE `webhook_delivery_stats` (`id` integer PRIMARY KEY AUTOINCREMENT,`webhook_id` integer NOT NULL,`channel` text NOT NULL DEFAULT "",`period_start` datetime NOT NULL,`count` integer NOT NULL DEFAULT 0,`created_at` datetime,`updated_at` datetime);
+CREATE TABLE `webhook_settings` (`id` integer PRIMARY KEY AUTOINCREMENT,`webhook_id` integer,`signature_method` text NOT NULL DEFAULT "hmac_sha256",`secret` text,`security_keywords` json,`custom_headers` json,`created_at` datetime,`updated_at` datetime,CONSTRAINT `fk_webhooks_settings` FOREIGN KEY (`webhook_id`) REFERENCES `webhooks`(`id`) ON DELETE CASCADE);
+CREATE TABLE `webhooks` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL DEFAULT "",`url` text NOT NULL DEFAULT "",`description` text,`type` text NOT NULL DEFAULT "wechat",`is_active` numeric DEFAULT true,`created_by` integer,`created_at` datetime,`updated_at` datetime);
+CREATE INDEX idx_accounts_email ON accounts(email);
+CREATE INDEX idx_accounts_role ON accounts(role);
+CREATE INDEX idx_accounts_username ON accounts(username);
+CREATE INDEX `idx_notifications_owner_id` ON `notifications`(`owner_id`);
+CREATE INDEX `idx_projects_created_by` ON `projects`(`created_by`);
+CREATE UNIQUE INDEX `idx_projects_git_lab_project_id` ON `projects`(`gitlab_project_id`);
+CREATE INDEX `idx_projects_git_lab_webhook_id` ON `projects`(`gitlab_webhook_id`);
+CREATE INDEX idx_manager ON resource_managers (manager_id);
+CREATE INDEX idx_resource ON resource_managers (resource_id, resource_type);
+CREATE INDEX `idx_users_created_by` ON `users`(`created_by`);
+CREATE UNIQUE INDEX `idx_users_email` ON `users`(`email`);
+CREATE UNIQUE INDEX `idx_users_git_lab_username` ON `users`(`gitlab_username`);
+CREATE INDEX `idx_webhook_delivery_stats_channel` ON `webhook_delivery_stats`(`channel`);
+CREATE INDEX `idx_webhook_delivery_stats_period_start` ON `webhook_delivery_stats`(`period_start`);
+CREATE INDEX `idx_webhook_delivery_stats_webhook_id` ON `webhook_delivery_stats`(`webhook_id`);
+CREATE UNIQUE INDEX `idx_webhook_settings_webhook_id` ON `webhook_settings`(`webhook_id`);
+CREATE INDEX `idx_webhooks_created_by` ON `webhooks`(`created_by`);
+INSERT INTO schema_migrations (id, applied_at) VALUES ('001_create_initial_tables', '2025-01-01 00:00:00');
+INSERT INTO schema_migrations (id, applied_at) VALUES ('002_fix_not_null_constraints', '2025-01-01 00:00:00');
+INSERT INTO schema_migrations (id, applied_at) VALUES ('003_fix_gitlab_webhook_id_column_name', '2025-01-01 00:00:00');
+INSERT INTO schema_migrations (id, applied_at) VALUES ('004_add_gitlab_username_to_users', '2025-01-01 00:00:00');
+INSERT INTO schema_migrations (id, applied_at) VALUES ('005_create_accounts_table', '2025-01-01 00:00:00');
+INSERT INTO schema_migrations (id, applied_at) VALUES ('006_add_created_by_fields', '2025-01-01 00:00:00');
+INSERT INTO schema_migrations (id, applied_at) VALUES ('007_add_user_auth_and_resource_management', '2025-01-01 00:00:00');
+INSERT INTO schema_migrations (id, applied_at) VALUES ('008_add_gitlab_access_token_to_accounts', '2025-01-01 00:00:00');
+INSERT INTO schema_migrations (id, applied_at) VALUES ('009_remove_auto_manage_webhook', '2025-01-01 00:00:00');
+INSERT INTO schema_migrations (id, applied_at) VALUES ('010_add_admin_initialization_fields', '2025-01-01 00:00:00');
+INSERT INTO schema_migrations (id, applied_at) VALUES ('011_add_webhook_multi_channel', '2025-01-01 00:00:00');
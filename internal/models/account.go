@@ -13,6 +13,7 @@ type Account struct {
 	Role                       string     `json:"role" gorm:"column:role;default:'user'"`
 	Avatar                     string     `json:"avatar" gorm:"column:avatar;type:text"`
 	GitLabAccessToken          string     `json:"-" gorm:"column:gitlab_access_token"`
+	GitLabUsername             string     `json:"gitlab_username" gorm:"column:gitlab_username;not null;default:''"`
 	IsActive                   bool       `json:"is_active" gorm:"column:is_active;default:true"`
 	LastLoginAt                *time.Time `json:"last_login_at,omitempty" gorm:"column:last_login_at"`
 	ForcePasswordReset         bool       `json:"force_password_reset" gorm:"column:force_password_reset;default:false"`
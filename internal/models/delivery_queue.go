@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	DeliveryStatusPending = "pending"
+	DeliveryStatusSent    = "sent"
+	DeliveryStatusFailed  = "failed"
+
+	// DeliveryReasonWindow 非投递时间收到的消息
+	DeliveryReasonWindow = "delivery_window"
+	// DeliveryReasonCoalesce 合并窗口内等待合并的合并请求消息
+	DeliveryReasonCoalesce = "coalesce"
+	// DeliveryReasonRateLimited 渠道速率受限等待重试的消息
+	DeliveryReasonRateLimited = "rate_limited"
+)
+
+// DeliveryQueueItem 延迟投递的消息，例如非工作时间收到的通知
+type DeliveryQueueItem struct {
+	ID             uint       `json:"id" gorm:"column:id;primarykey"`
+	WebhookID      uint       `json:"webhook_id" gorm:"column:webhook_id;not null;index"`
+	ProjectID      uint       `json:"project_id" gorm:"column:project_id;not null;default:0"`
+	NotificationID *uint      `json:"notification_id,omitempty" gorm:"column:notification_id;index"`
+	EventType      string     `json:"event_type" gorm:"column:event_type"`
+	Reason         string     `json:"reason" gorm:"column:reason;not null;default:'delivery_window'"`
+	Payload        string     `json:"payload" gorm:"column:payload;type:text"`
+	Status         string     `json:"status" gorm:"column:status;not null;default:'pending';index:idx_delivery_queue_status_release"`
+	ReleaseAt      time.Time  `json:"release_at" gorm:"column:release_at;not null;index:idx_delivery_queue_status_release"`
+	Attempts       int        `json:"attempts" gorm:"column:attempts;not null;default:0"`
+	LastError      string     `json:"last_error" gorm:"column:last_error"`
+	SentAt         *time.Time `json:"sent_at,omitempty" gorm:"column:sent_at"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (DeliveryQueueItem) TableName() string {
+	return "delivery_queue"
+}
@@ -0,0 +1,307 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultWebhookTimezone = "Asia/Shanghai"
+
+	// MinCoalesceWindowSeconds 合并窗口的下限，与投递队列的检查间隔一致，更短的窗口实际也要等到下次检查才发送
+	MinCoalesceWindowSeconds = 30
+	// MaxCoalesceWindowSeconds 合并请求突发合并窗口的上限
+	MaxCoalesceWindowSeconds = 3600
+)
+
+// 查找下一个可投递时间的最大天数，防止配置为全年节假日时死循环
+const maxDeliveryWindowSearchDays = 366
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// DeliveryWindow 工作时间窗口，例如 "mon-fri 09:00-18:00"
+type DeliveryWindow struct {
+	Days  [7]bool
+	Start int // 从 0 点开始的分钟数
+	End   int // 小于 Start 表示跨越午夜
+}
+
+// DeliveryWindowRequest Webhook 投递时间窗口相关的请求字段
+type DeliveryWindowRequest struct {
+	Timezone              *string  `json:"timezone"`
+	WorkingHours          []string `json:"working_hours"`
+	Holidays              []string `json:"holidays"`
+	UrgentBranchPatterns  []string `json:"urgent_branch_patterns"`
+	BatchHeldMessages     *bool    `json:"batch_held_messages"`
+	CoalesceWindowSeconds *int     `json:"coalesce_window_seconds"`
+}
+
+// ParseDeliveryWindow 解析工作时间窗口，支持 "09:00-18:00"、"mon-fri 09:00-18:00"、"1-5 09:00-18:00"、"sat,sun 10:00-12:00"
+func ParseDeliveryWindow(spec string) (DeliveryWindow, error) {
+	var window DeliveryWindow
+
+	fields := strings.Fields(spec)
+	var dayExpr, timeExpr string
+	switch len(fields) {
+	case 1:
+		dayExpr, timeExpr = "*", fields[0]
+	case 2:
+		dayExpr, timeExpr = fields[0], fields[1]
+	default:
+		return window, fmt.Errorf("工作时间格式无效: %q", spec)
+	}
+
+	if err := window.parseDays(dayExpr); err != nil {
+		return window, fmt.Errorf("工作时间 %q 的星期无效: %w", spec, err)
+	}
+
+	startExpr, endExpr, ok := strings.Cut(timeExpr, "-")
+	if !ok {
+		return window, fmt.Errorf("工作时间 %q 缺少结束时间", spec)
+	}
+	var err error
+	if window.Start, err = parseClock(startExpr); err != nil {
+		return window, fmt.Errorf("工作时间 %q: %w", spec, err)
+	}
+	if window.End, err = parseClock(endExpr); err != nil {
+		return window, fmt.Errorf("工作时间 %q: %w", spec, err)
+	}
+	if window.Start == window.End {
+		return window, fmt.Errorf("工作时间 %q 的开始与结束时间相同", spec)
+	}
+
+	return window, nil
+}
+
+func (w *DeliveryWindow) parseDays(expr string) error {
+	if expr == "*" {
+		for i := range w.Days {
+			w.Days[i] = true
+		}
+		return nil
+	}
+
+	for _, part := range strings.Split(expr, ",") {
+		startExpr, endExpr, isRange := strings.Cut(part, "-")
+		start, err := parseWeekday(startExpr)
+		if err != nil {
+			return err
+		}
+		end := start
+		if isRange {
+			if end, err = parseWeekday(endExpr); err != nil {
+				return err
+			}
+		}
+
+		// 支持 fri-mon 这样的跨周范围
+		for day := start; ; day = (day + 1) % 7 {
+			w.Days[day] = true
+			if day == end {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func parseWeekday(expr string) (time.Weekday, error) {
+	expr = strings.ToLower(strings.TrimSpace(expr))
+	if day, ok := weekdayNames[expr]; ok {
+		return day, nil
+	}
+	value, err := strconv.Atoi(expr)
+	if err != nil || value < 0 || value > 7 {
+		return 0, fmt.Errorf("%q", expr)
+	}
+	return time.Weekday(value % 7), nil
+}
+
+func parseClock(expr string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(expr))
+	if err != nil {
+		if strings.TrimSpace(expr) == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, fmt.Errorf("时间 %q 格式应为 HH:MM", expr)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains 判断某天某分钟是否落在窗口内；跨午夜的窗口在次日凌晨部分归属前一天的星期
+func (w DeliveryWindow) Contains(day time.Weekday, minute int) bool {
+	if w.Start < w.End {
+		return w.Days[day] && minute >= w.Start && minute < w.End
+	}
+	if w.Days[day] && minute >= w.Start {
+		return true
+	}
+	return w.Days[(day+6)%7] && minute < w.End
+}
+
+// Location 返回 Webhook 配置的时区，未配置或无效时使用默认时区
+func (w *Webhook) Location() *time.Location {
+	name := w.Timezone
+	if name == "" {
+		name = DefaultWebhookTimezone
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// HasDeliveryWindow 是否配置了工作时间或节假日
+func (w *Webhook) HasDeliveryWindow() bool {
+	return len(w.WorkingHours) > 0 || len(w.Holidays) > 0
+}
+
+// IsUrgentBranch 目标分支命中紧急规则时不受工作时间限制
+func (w *Webhook) IsUrgentBranch(branch string) bool {
+	if branch == "" {
+		return false
+	}
+	for _, pattern := range w.UrgentBranchPatterns {
+		if MatchRefPattern(pattern, branch) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextDeliveryTime 返回不早于 t 的最近可投递时间；当前就在窗口内时直接返回 t
+func (w *Webhook) NextDeliveryTime(t time.Time) (time.Time, error) {
+	if !w.HasDeliveryWindow() {
+		return t, nil
+	}
+
+	windows, err := w.parseWorkingHours()
+	if err != nil {
+		return time.Time{}, err
+	}
+	holidays := make(map[string]bool, len(w.Holidays))
+	for _, day := range w.Holidays {
+		holidays[strings.TrimSpace(day)] = true
+	}
+
+	local := t.In(w.Location())
+	if w.allowedAt(local, windows, holidays) {
+		return t, nil
+	}
+
+	// 按窗口开始时间与零点跳跃查找，避免逐分钟检查
+	deadline := local.AddDate(0, 0, maxDeliveryWindowSearchDays)
+	for local.Before(deadline) {
+		local = w.nextBoundary(local, windows)
+		if w.allowedAt(local, windows, holidays) {
+			return local.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("一年内没有可投递的工作时间")
+}
+
+// ValidateDeliveryWindow 校验时区、工作时间与节假日配置
+func (w *Webhook) ValidateDeliveryWindow() error {
+	if w.Timezone != "" {
+		if _, err := time.LoadLocation(w.Timezone); err != nil {
+			return fmt.Errorf("时区无效: %s", w.Timezone)
+		}
+	}
+	if _, err := w.parseWorkingHours(); err != nil {
+		return err
+	}
+	for _, day := range w.Holidays {
+		if _, err := time.Parse("2006-01-02", strings.TrimSpace(day)); err != nil {
+			return fmt.Errorf("节假日 %q 格式应为 YYYY-MM-DD", day)
+		}
+	}
+	if w.HasDeliveryWindow() {
+		if _, err := w.NextDeliveryTime(time.Now()); err != nil {
+			return err
+		}
+	}
+	if w.CoalesceWindowSeconds != 0 && (w.CoalesceWindowSeconds < MinCoalesceWindowSeconds || w.CoalesceWindowSeconds > MaxCoalesceWindowSeconds) {
+		return fmt.Errorf("合并窗口需为 0（不合并）或 %d 到 %d 秒之间", MinCoalesceWindowSeconds, MaxCoalesceWindowSeconds)
+	}
+	return nil
+}
+
+// CoalesceWindow 返回合并请求事件的合并窗口
+func (w *Webhook) CoalesceWindow() time.Duration {
+	return time.Duration(w.CoalesceWindowSeconds) * time.Second
+}
+
+// ApplyDeliveryWindow 合并请求中的投递窗口配置并校验
+func (w *Webhook) ApplyDeliveryWindow(req *DeliveryWindowRequest) error {
+	if req.Timezone != nil {
+		w.Timezone = strings.TrimSpace(*req.Timezone)
+	}
+	if req.WorkingHours != nil {
+		w.WorkingHours = ToStringList(req.WorkingHours)
+	}
+	if req.Holidays != nil {
+		w.Holidays = ToStringList(req.Holidays)
+	}
+	if req.UrgentBranchPatterns != nil {
+		w.UrgentBranchPatterns = ToStringList(req.UrgentBranchPatterns)
+	}
+	if req.BatchHeldMessages != nil {
+		w.BatchHeldMessages = *req.BatchHeldMessages
+	}
+	if req.CoalesceWindowSeconds != nil {
+		w.CoalesceWindowSeconds = *req.CoalesceWindowSeconds
+	}
+	return w.ValidateDeliveryWindow()
+}
+
+func (w *Webhook) parseWorkingHours() ([]DeliveryWindow, error) {
+	windows := make([]DeliveryWindow, 0, len(w.WorkingHours))
+	for _, spec := range w.WorkingHours {
+		window, err := ParseDeliveryWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+func (w *Webhook) allowedAt(local time.Time, windows []DeliveryWindow, holidays map[string]bool) bool {
+	if holidays[local.Format("2006-01-02")] {
+		return false
+	}
+	if len(windows) == 0 {
+		return true
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	for _, window := range windows {
+		if window.Contains(local.Weekday(), minute) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextBoundary 返回下一个可能改变投递状态的时间点：窗口开始时间或次日零点
+func (w *Webhook) nextBoundary(local time.Time, windows []DeliveryWindow) time.Time {
+	midnight := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, local.Location())
+	next := midnight
+
+	minute := local.Hour()*60 + local.Minute()
+	for _, window := range windows {
+		if window.Start > minute {
+			candidate := time.Date(local.Year(), local.Month(), local.Day(), 0, window.Start, 0, 0, local.Location())
+			if candidate.Before(next) {
+				next = candidate
+			}
+		}
+	}
+	return next
+}
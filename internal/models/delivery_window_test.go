@@ -0,0 +1,227 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDeliveryWindow(t *testing.T) {
+	weekdays := [7]bool{false, true, true, true, true, true, false}
+	everyDay := [7]bool{true, true, true, true, true, true, true}
+
+	cases := []struct {
+		spec  string
+		days  [7]bool
+		start int
+		end   int
+	}{
+		{"09:00-18:00", everyDay, 9 * 60, 18 * 60},
+		{"mon-fri 09:00-18:00", weekdays, 9 * 60, 18 * 60},
+		{"1-5 09:00-18:00", weekdays, 9 * 60, 18 * 60},
+		{"MON-FRI 09:30-18:15", weekdays, 9*60 + 30, 18*60 + 15},
+		{"sat,sun 10:00-12:00", [7]bool{true, false, false, false, false, false, true}, 10 * 60, 12 * 60},
+		{"7 00:00-24:00", [7]bool{true, false, false, false, false, false, false}, 0, 24 * 60},
+		// 跨周的星期范围与跨午夜的时间段
+		{"fri-mon 22:00-06:00", [7]bool{true, true, false, false, false, true, true}, 22 * 60, 6 * 60},
+		{"0,3 23:00-01:00", [7]bool{true, false, false, true, false, false, false}, 23 * 60, 1 * 60},
+	}
+
+	for _, tc := range cases {
+		window, err := ParseDeliveryWindow(tc.spec)
+		if err != nil {
+			t.Errorf("parse %q: %v", tc.spec, err)
+			continue
+		}
+		if window.Days != tc.days || window.Start != tc.start || window.End != tc.end {
+			t.Errorf("%q: expected days=%v %d-%d, got days=%v %d-%d",
+				tc.spec, tc.days, tc.start, tc.end, window.Days, window.Start, window.End)
+		}
+	}
+}
+
+func TestParseDeliveryWindowInvalid(t *testing.T) {
+	cases := []string{
+		"",
+		"mon-fri",
+		"mon-fri 09:00",
+		"mon-fri 09:00-18:00 extra",
+		"xyz 09:00-18:00",
+		"8 09:00-18:00",
+		"mon- 09:00-18:00",
+		"09:00-09:00",
+		"25:00-26:00",
+		"09:60-18:00",
+		"9am-6pm",
+	}
+
+	for _, spec := range cases {
+		if _, err := ParseDeliveryWindow(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestNextDeliveryTime(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	// 2026-10-19 为周一
+	cases := []struct {
+		name     string
+		timezone string
+		hours    []string
+		holidays []string
+		from     time.Time
+		want     time.Time
+	}{
+		{
+			name: "no window",
+			from: time.Date(2026, 10, 18, 3, 0, 0, 0, shanghai),
+			want: time.Date(2026, 10, 18, 3, 0, 0, 0, shanghai),
+		},
+		{
+			name:  "inside weekday window",
+			hours: []string{"mon-fri 09:00-18:00"},
+			from:  time.Date(2026, 10, 19, 10, 0, 0, 0, shanghai),
+			want:  time.Date(2026, 10, 19, 10, 0, 0, 0, shanghai),
+		},
+		{
+			name:  "before weekday window",
+			hours: []string{"mon-fri 09:00-18:00"},
+			from:  time.Date(2026, 10, 19, 8, 0, 0, 0, shanghai),
+			want:  time.Date(2026, 10, 19, 9, 0, 0, 0, shanghai),
+		},
+		{
+			name:  "window end is exclusive",
+			hours: []string{"mon-fri 09:00-18:00"},
+			from:  time.Date(2026, 10, 19, 18, 0, 0, 0, shanghai),
+			want:  time.Date(2026, 10, 20, 9, 0, 0, 0, shanghai),
+		},
+		{
+			name:  "friday evening waits for monday",
+			hours: []string{"mon-fri 09:00-18:00"},
+			from:  time.Date(2026, 10, 23, 19, 0, 0, 0, shanghai),
+			want:  time.Date(2026, 10, 26, 9, 0, 0, 0, shanghai),
+		},
+		{
+			name:  "second window on the same day",
+			hours: []string{"mon-fri 09:00-12:00", "mon-fri 14:00-18:00"},
+			from:  time.Date(2026, 10, 19, 12, 30, 0, 0, shanghai),
+			want:  time.Date(2026, 10, 19, 14, 0, 0, 0, shanghai),
+		},
+		{
+			name:  "overnight window before midnight",
+			hours: []string{"22:00-06:00"},
+			from:  time.Date(2026, 10, 19, 23, 0, 0, 0, shanghai),
+			want:  time.Date(2026, 10, 19, 23, 0, 0, 0, shanghai),
+		},
+		{
+			name:  "overnight window after midnight",
+			hours: []string{"22:00-06:00"},
+			from:  time.Date(2026, 10, 20, 5, 30, 0, 0, shanghai),
+			want:  time.Date(2026, 10, 20, 5, 30, 0, 0, shanghai),
+		},
+		{
+			name:  "overnight window waits for evening",
+			hours: []string{"22:00-06:00"},
+			from:  time.Date(2026, 10, 20, 12, 0, 0, 0, shanghai),
+			want:  time.Date(2026, 10, 20, 22, 0, 0, 0, shanghai),
+		},
+		{
+			name:  "overnight weekday window spills into saturday",
+			hours: []string{"mon-fri 22:00-06:00"},
+			from:  time.Date(2026, 10, 24, 3, 0, 0, 0, shanghai),
+			want:  time.Date(2026, 10, 24, 3, 0, 0, 0, shanghai),
+		},
+		{
+			name:  "overnight weekday window skips the weekend",
+			hours: []string{"mon-fri 22:00-06:00"},
+			from:  time.Date(2026, 10, 24, 7, 0, 0, 0, shanghai),
+			want:  time.Date(2026, 10, 26, 22, 0, 0, 0, shanghai),
+		},
+		{
+			name:     "holiday on a working day",
+			hours:    []string{"mon-fri 09:00-18:00"},
+			holidays: []string{"2026-10-19"},
+			from:     time.Date(2026, 10, 19, 10, 0, 0, 0, shanghai),
+			want:     time.Date(2026, 10, 20, 9, 0, 0, 0, shanghai),
+		},
+		{
+			name:     "consecutive holidays before the weekend",
+			hours:    []string{"mon-fri 09:00-18:00"},
+			holidays: []string{"2026-10-22", "2026-10-23"},
+			from:     time.Date(2026, 10, 21, 18, 30, 0, 0, shanghai),
+			want:     time.Date(2026, 10, 26, 9, 0, 0, 0, shanghai),
+		},
+		{
+			name:     "holidays without working hours",
+			holidays: []string{"2026-10-19"},
+			from:     time.Date(2026, 10, 19, 10, 0, 0, 0, shanghai),
+			want:     time.Date(2026, 10, 20, 0, 0, 0, 0, shanghai),
+		},
+		{
+			name:     "holiday interrupts an overnight window",
+			hours:    []string{"22:00-06:00"},
+			holidays: []string{"2026-10-20"},
+			from:     time.Date(2026, 10, 20, 1, 0, 0, 0, shanghai),
+			want:     time.Date(2026, 10, 21, 0, 0, 0, 0, shanghai),
+		},
+		{
+			name:  "utc input rolls over to the next local day",
+			hours: []string{"mon-fri 09:00-18:00"},
+			from:  time.Date(2026, 10, 19, 17, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, 10, 20, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "utc friday is already local saturday",
+			hours: []string{"mon-fri 09:00-18:00"},
+			from:  time.Date(2026, 10, 23, 16, 30, 0, 0, time.UTC),
+			want:  time.Date(2026, 10, 26, 9, 0, 0, 0, shanghai),
+		},
+		{
+			name:     "holiday is a local date",
+			hours:    []string{"09:00-18:00"},
+			holidays: []string{"2026-10-20"},
+			from:     time.Date(2026, 10, 19, 17, 0, 0, 0, time.UTC),
+			want:     time.Date(2026, 10, 21, 9, 0, 0, 0, shanghai),
+		},
+		{
+			name:     "daylight saving time ends",
+			timezone: "America/New_York",
+			hours:    []string{"09:00-17:00"},
+			from:     time.Date(2026, 10, 31, 22, 0, 0, 0, newYork),
+			want:     time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range cases {
+		webhook := &Webhook{Timezone: tc.timezone, WorkingHours: tc.hours, Holidays: tc.holidays}
+		got, err := webhook.NextDeliveryTime(tc.from)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestNextDeliveryTimeInvalid(t *testing.T) {
+	cases := []*Webhook{
+		{WorkingHours: []string{"mon-fri"}},
+		{WorkingHours: []string{"09:00-18:00", "bad"}},
+	}
+
+	for _, webhook := range cases {
+		if _, err := webhook.NextDeliveryTime(time.Now()); err == nil {
+			t.Errorf("expected error for working hours %v", webhook.WorkingHours)
+		}
+	}
+}
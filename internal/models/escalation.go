@@ -0,0 +1,156 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultEscalationBranchPattern = "release/*"
+
+	// EscalationStepMentionAll 在项目渠道中 @所有人 再次提醒
+	EscalationStepMentionAll = 1
+	// EscalationStepWebhook 通知升级渠道
+	EscalationStepWebhook = 2
+)
+
+// EscalationPolicy 项目的合并请求升级策略，目标分支命中且长时间未获批准时逐级升级提醒
+type EscalationPolicy struct {
+	ID                   uint       `json:"id" gorm:"column:id;primarykey"`
+	ProjectID            uint       `json:"project_id" gorm:"column:project_id;not null;index"`
+	Name                 string     `json:"name" gorm:"column:name;not null"`
+	Enabled              bool       `json:"enabled" gorm:"column:enabled"`
+	TargetBranchPatterns StringList `json:"target_branch_patterns" gorm:"column:target_branch_patterns;type:json"`
+	SkipDrafts           bool       `json:"skip_drafts" gorm:"column:skip_drafts"`
+	MentionAllAfterHours int        `json:"mention_all_after_hours" gorm:"column:mention_all_after_hours;not null"`
+	EscalateAfterHours   int        `json:"escalate_after_hours" gorm:"column:escalate_after_hours;not null"`
+	EscalationWebhookID  *uint      `json:"escalation_webhook_id" gorm:"column:escalation_webhook_id"`
+	CreatedAt            time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt            time.Time  `json:"updated_at" gorm:"column:updated_at"`
+
+	Project           Project  `json:"-" gorm:"foreignKey:ProjectID"`
+	EscalationWebhook *Webhook `json:"-" gorm:"foreignKey:EscalationWebhookID"`
+}
+
+// EscalationEvent 升级策略触发记录，同一合并请求的每一级只触发一次
+type EscalationEvent struct {
+	ID             uint      `json:"id" gorm:"column:id;primarykey"`
+	PolicyID       uint      `json:"policy_id" gorm:"column:policy_id;not null;uniqueIndex:idx_escalation_event_step"`
+	MergeRequestID uint      `json:"merge_request_id" gorm:"column:merge_request_id;not null;uniqueIndex:idx_escalation_event_step"`
+	Step           int       `json:"step" gorm:"column:step;not null;uniqueIndex:idx_escalation_event_step"`
+	ProjectID      uint      `json:"project_id" gorm:"column:project_id;not null;index"`
+	WebhookID      *uint     `json:"webhook_id" gorm:"column:webhook_id"`
+	Sent           bool      `json:"sent" gorm:"column:sent"`
+	ErrorMessage   string    `json:"error_message" gorm:"column:error_message;type:text"`
+	FiredAt        time.Time `json:"fired_at" gorm:"column:fired_at;not null"`
+
+	Policy       EscalationPolicy `json:"-" gorm:"foreignKey:PolicyID"`
+	MergeRequest MergeRequest     `json:"-" gorm:"foreignKey:MergeRequestID"`
+}
+
+type EscalationPolicyRequest struct {
+	Name                 *string  `json:"name"`
+	Enabled              *bool    `json:"enabled"`
+	TargetBranchPatterns []string `json:"target_branch_patterns"`
+	SkipDrafts           *bool    `json:"skip_drafts"`
+	MentionAllAfterHours *int     `json:"mention_all_after_hours" binding:"omitempty,min=1"`
+	EscalateAfterHours   *int     `json:"escalate_after_hours" binding:"omitempty,min=0"`
+	EscalationWebhookID  *uint    `json:"escalation_webhook_id"`
+}
+
+type EscalationEventResponse struct {
+	ID                uint      `json:"id"`
+	PolicyID          uint      `json:"policy_id"`
+	PolicyName        string    `json:"policy_name"`
+	MergeRequestID    uint      `json:"merge_request_id"`
+	MergeRequestIID   int       `json:"merge_request_iid"`
+	MergeRequestTitle string    `json:"merge_request_title"`
+	Step              int       `json:"step"`
+	WebhookID         *uint     `json:"webhook_id"`
+	Sent              bool      `json:"sent"`
+	ErrorMessage      string    `json:"error_message"`
+	FiredAt           time.Time `json:"fired_at"`
+}
+
+// NewEscalationPolicy 返回带默认值的升级策略
+func NewEscalationPolicy(projectID uint) EscalationPolicy {
+	return EscalationPolicy{
+		ProjectID:            projectID,
+		Name:                 "release",
+		Enabled:              true,
+		TargetBranchPatterns: StringList{DefaultEscalationBranchPattern},
+		SkipDrafts:           true,
+		MentionAllAfterHours: 4,
+		EscalateAfterHours:   8,
+	}
+}
+
+// Apply 将请求合并到策略并校验
+func (p *EscalationPolicy) Apply(req *EscalationPolicyRequest) error {
+	if req.Name != nil {
+		p.Name = *req.Name
+	}
+	if req.Enabled != nil {
+		p.Enabled = *req.Enabled
+	}
+	if req.TargetBranchPatterns != nil {
+		p.TargetBranchPatterns = ToStringList(req.TargetBranchPatterns)
+	}
+	if req.SkipDrafts != nil {
+		p.SkipDrafts = *req.SkipDrafts
+	}
+	if req.MentionAllAfterHours != nil {
+		p.MentionAllAfterHours = *req.MentionAllAfterHours
+	}
+	if req.EscalateAfterHours != nil {
+		p.EscalateAfterHours = *req.EscalateAfterHours
+	}
+	if req.EscalationWebhookID != nil {
+		// 传 0 表示取消升级渠道
+		if *req.EscalationWebhookID == 0 {
+			p.EscalationWebhookID = nil
+		} else {
+			id := *req.EscalationWebhookID
+			p.EscalationWebhookID = &id
+		}
+	}
+	return p.Validate()
+}
+
+// Validate 校验策略配置
+func (p *EscalationPolicy) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("策略名称不能为空")
+	}
+	if len(p.TargetBranchPatterns) == 0 {
+		return fmt.Errorf("至少需要一个目标分支规则")
+	}
+	if p.MentionAllAfterHours < 1 {
+		return fmt.Errorf("@所有人 的等待时间至少为 1 小时")
+	}
+	if p.EscalationWebhookID != nil && p.EscalateAfterHours <= p.MentionAllAfterHours {
+		return fmt.Errorf("升级渠道的等待时间必须大于 @所有人 的等待时间")
+	}
+	return nil
+}
+
+// MatchesTargetBranch 判断目标分支是否适用该策略
+func (p *EscalationPolicy) MatchesTargetBranch(branch string) bool {
+	for _, pattern := range p.TargetBranchPatterns {
+		if MatchRefPattern(pattern, branch) {
+			return true
+		}
+	}
+	return false
+}
+
+// StepDue 返回合并请求等待时长达到的最高升级级别，0 表示尚未到达
+func (p *EscalationPolicy) StepDue(waiting time.Duration) int {
+	if p.EscalationWebhookID != nil && waiting >= time.Duration(p.EscalateAfterHours)*time.Hour {
+		return EscalationStepWebhook
+	}
+	if waiting >= time.Duration(p.MentionAllAfterHours)*time.Hour {
+		return EscalationStepMentionAll
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"strings"
+)
+
+// GitLabEventHeader 仅用于识别 webhook 的事件类型与所属实例
+type GitLabEventHeader struct {
+	ObjectKind string        `json:"object_kind"`
+	Project    GitLabProject `json:"project"`
+}
+
+// GitLabPushEventData push 与 tag_push 事件共用的数据结构
+type GitLabPushEventData struct {
+	ObjectKind        string         `json:"object_kind"`
+	EventName         string         `json:"event_name"`
+	Before            string         `json:"before"`
+	After             string         `json:"after"`
+	Ref               string         `json:"ref"`
+	CheckoutSHA       string         `json:"checkout_sha"`
+	UserID            int            `json:"user_id"`
+	UserName          string         `json:"user_name"`
+	UserUsername      string         `json:"user_username"`
+	UserEmail         string         `json:"user_email"`
+	ProjectID         int            `json:"project_id"`
+	Project           GitLabProject  `json:"project"`
+	Commits           []GitLabCommit `json:"commits"`
+	TotalCommitsCount int            `json:"total_commits_count"`
+}
+
+type GitLabCommit struct {
+	ID        string             `json:"id"`
+	Message   string             `json:"message"`
+	Title     string             `json:"title"`
+	Timestamp string             `json:"timestamp"`
+	URL       string             `json:"url"`
+	Author    GitLabCommitAuthor `json:"author"`
+}
+
+type GitLabCommitAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// GitLabReleaseEventData release 事件数据结构
+type GitLabReleaseEventData struct {
+	ObjectKind  string        `json:"object_kind"`
+	ID          int           `json:"id"`
+	Name        string        `json:"name"`
+	Tag         string        `json:"tag"`
+	Description string        `json:"description"`
+	URL         string        `json:"url"`
+	Action      string        `json:"action"`
+	CreatedAt   string        `json:"created_at"`
+	ReleasedAt  string        `json:"released_at"`
+	Project     GitLabProject `json:"project"`
+}
+
+// GitLabPipelineEventData pipeline 事件数据结构
+type GitLabPipelineEventData struct {
+	ObjectKind       string                   `json:"object_kind"`
+	ObjectAttributes GitLabPipelineAttributes `json:"object_attributes"`
+	MergeRequest     *GitLabPipelineMR        `json:"merge_request"`
+	User             GitLabUser               `json:"user"`
+	Project          GitLabProject            `json:"project"`
+}
+
+type GitLabPipelineAttributes struct {
+	ID         int    `json:"id"`
+	Ref        string `json:"ref"`
+	Tag        bool   `json:"tag"`
+	SHA        string `json:"sha"`
+	Status     string `json:"status"`
+	Source     string `json:"source"`
+	CreatedAt  string `json:"created_at"`
+	FinishedAt string `json:"finished_at"`
+}
+
+// GitLabPipelineMR 合并请求流水线关联的合并请求
+type GitLabPipelineMR struct {
+	ID           int    `json:"id"`
+	IID          int    `json:"iid"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	State        string `json:"state"`
+	URL          string `json:"url"`
+}
+
+// BranchName 返回 push 事件对应的分支名
+func (e *GitLabPushEventData) BranchName() string {
+	return strings.TrimPrefix(e.Ref, "refs/heads/")
+}
+
+// TagName 返回 tag_push 事件对应的标签名
+func (e *GitLabPushEventData) TagName() string {
+	return strings.TrimPrefix(e.Ref, "refs/tags/")
+}
+
+// IsDeletion 判断是否为删除分支/标签的推送（GitLab 使用全 0 的 SHA 表示）
+func (e *GitLabPushEventData) IsDeletion() bool {
+	return strings.Trim(e.After, "0") == ""
+}
+
+// IsCreation 判断是否为新建分支/标签的推送
+func (e *GitLabPushEventData) IsCreation() bool {
+	return strings.Trim(e.Before, "0") == ""
+}
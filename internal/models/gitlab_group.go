@@ -0,0 +1,262 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	// GroupHookModeGroup 在组上登记一个 Webhook，组内所有项目共用
+	GroupHookModeGroup = "group"
+	// GroupHookModeProject 为同步登记的每个项目创建项目级 Webhook，适用于不支持组级 Webhook 的 GitLab 版本
+	GroupHookModeProject = "project"
+	// GroupHookModeNone 只登记项目，不创建 Webhook
+	GroupHookModeNone = "none"
+
+	// GroupTokenSourceInstance 使用实例的默认令牌（默认实例还会使用 gitlab_service_token）
+	GroupTokenSourceInstance = "instance"
+	// GroupTokenSourceAccount 使用登记组的管理员的个人令牌
+	GroupTokenSourceAccount = "account"
+	// GroupTokenSourceCustom 使用为组单独配置的令牌
+	GroupTokenSourceCustom = "custom"
+
+	GroupSyncTriggerSchedule = "schedule"
+	GroupSyncTriggerManual   = "manual"
+
+	GroupSyncStatusSuccess = "success"
+	GroupSyncStatusFailed  = "failed"
+
+	GroupSyncActionAdded       = "added"
+	GroupSyncActionArchived    = "archived"
+	GroupSyncActionMissing     = "missing"
+	GroupSyncActionRestored    = "restored"
+	GroupSyncActionHookCreated = "hook_created"
+	GroupSyncActionHookFailed  = "hook_failed"
+)
+
+// GitLabGroup 登记的 GitLab 组：可以通过组级 Webhook 接收组内（含子组）所有项目的事件，
+// 开启 Watched 后还会定时同步组内项目，自动登记新项目并标记已归档或已删除的项目
+type GitLabGroup struct {
+	ID               uint   `json:"id" gorm:"column:id;primarykey"`
+	GitLabInstanceID uint   `json:"gitlab_instance_id" gorm:"column:gitlab_instance_id;uniqueIndex:idx_gitlab_groups_instance_group,priority:1;not null"`
+	GitLabGroupID    int    `json:"gitlab_group_id" gorm:"column:gitlab_group_id;uniqueIndex:idx_gitlab_groups_instance_group,priority:2;not null"`
+	Name             string `json:"name" gorm:"column:name;not null;default:''"`
+	FullPath         string `json:"full_path" gorm:"column:full_path;not null;default:''"`
+	WebURL           string `json:"web_url" gorm:"column:web_url;not null;default:''"`
+
+	// 组 Hook 同步状态
+	GitLabHookID  *int       `json:"gitlab_hook_id,omitempty" gorm:"column:gitlab_hook_id"`
+	HookSynced    bool       `json:"hook_synced" gorm:"column:hook_synced;not null;default:false"`
+	LastSyncAt    *time.Time `json:"last_sync_at,omitempty" gorm:"column:last_sync_at"`
+	LastSyncError string     `json:"last_sync_error" gorm:"column:last_sync_error"`
+
+	// Watched 为 true 时，组内未登记的项目在首次收到事件或定时同步时自动登记，并应用组的默认通知渠道与事件订阅
+	Watched bool `json:"watched" gorm:"column:watched;not null;default:false"`
+	// HookMode 组内项目事件的接入方式：group、project 或 none
+	HookMode string `json:"hook_mode" gorm:"column:hook_mode;not null;default:'group'"`
+	// TokenSource 定时同步与创建 Webhook 使用的令牌来源：instance、account 或 custom
+	TokenSource string `json:"token_source" gorm:"column:token_source;not null;default:'instance'"`
+	// AccessToken TokenSource 为 custom 时使用的令牌（加密存储）
+	AccessToken string `json:"-" gorm:"column:access_token"`
+
+	// 自动登记项目的默认事件订阅，default_merge_request_events 列由迁移创建并带有数据库默认值 true
+	DefaultMergeRequestEvents bool       `json:"default_merge_request_events" gorm:"column:default_merge_request_events;-:migration"`
+	DefaultPushEvents         bool       `json:"default_push_events" gorm:"column:default_push_events;not null;default:false"`
+	DefaultTagPushEvents      bool       `json:"default_tag_push_events" gorm:"column:default_tag_push_events;not null;default:false"`
+	DefaultReleaseEvents      bool       `json:"default_release_events" gorm:"column:default_release_events;not null;default:false"`
+	DefaultPushBranchFilters  StringList `json:"default_push_branch_filters" gorm:"column:default_push_branch_filters;type:json"`
+	DefaultTagFilters         StringList `json:"default_tag_filters" gorm:"column:default_tag_filters;type:json"`
+
+	// 最近一次定时同步
+	LastScanAt    *time.Time `json:"last_scan_at,omitempty" gorm:"column:last_scan_at"`
+	LastScanError string     `json:"last_scan_error" gorm:"column:last_scan_error"`
+
+	CreatedBy *uint     `json:"created_by,omitempty" gorm:"column:created_by;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+
+	// Webhooks 自动登记的项目默认关联的通知渠道
+	Webhooks []Webhook `json:"webhooks,omitempty" gorm:"many2many:gitlab_group_webhooks;"`
+}
+
+func (GitLabGroup) TableName() string {
+	return "gitlab_groups"
+}
+
+// Covers 判断项目路径是否位于组（含子组）下
+func (g *GitLabGroup) Covers(projectPath string) bool {
+	projectPath = strings.Trim(projectPath, "/")
+	if g.FullPath == "" || projectPath == "" {
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(projectPath), strings.ToLower(g.FullPath)+"/")
+}
+
+// NewProject 按组的默认配置生成待登记的项目
+func (g *GitLabGroup) NewProject(gitlabProjectID int, name, webURL, description string) *Project {
+	return &Project{
+		GitLabInstanceID:   g.GitLabInstanceID,
+		GitLabProjectID:    gitlabProjectID,
+		Name:               name,
+		URL:                webURL,
+		Description:        description,
+		SourceGroupID:      &g.ID,
+		GitLabState:        ProjectGitLabStateActive,
+		MergeRequestEvents: g.DefaultMergeRequestEvents,
+		PushEvents:         g.DefaultPushEvents,
+		TagPushEvents:      g.DefaultTagPushEvents,
+		ReleaseEvents:      g.DefaultReleaseEvents,
+		PushBranchFilters:  append(StringList{}, g.DefaultPushBranchFilters...),
+		TagFilters:         append(StringList{}, g.DefaultTagFilters...),
+		CreatedBy:          g.CreatedBy,
+		Webhooks:           g.Webhooks,
+	}
+}
+
+// GitLabGroupSyncRun 组定时同步的执行记录与变更报告
+type GitLabGroupSyncRun struct {
+	ID         uint             `json:"id" gorm:"column:id;primarykey"`
+	GroupID    uint             `json:"group_id" gorm:"column:group_id;index;not null"`
+	Trigger    string           `json:"trigger" gorm:"column:triggered_by;not null;default:'schedule'"`
+	Status     string           `json:"status" gorm:"column:status;not null;default:'success'"`
+	Scanned    int              `json:"scanned" gorm:"column:scanned;not null;default:0"`
+	Added      int              `json:"added" gorm:"column:added;not null;default:0"`
+	Archived   int              `json:"archived" gorm:"column:archived;not null;default:0"`
+	Missing    int              `json:"missing" gorm:"column:missing;not null;default:0"`
+	Restored   int              `json:"restored" gorm:"column:restored;not null;default:0"`
+	Changes    GroupSyncChanges `json:"changes" gorm:"column:changes;type:json"`
+	Error      string           `json:"error,omitempty" gorm:"column:error"`
+	StartedAt  time.Time        `json:"started_at" gorm:"column:started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty" gorm:"column:finished_at"`
+}
+
+func (GitLabGroupSyncRun) TableName() string {
+	return "gitlab_group_sync_runs"
+}
+
+// GroupSyncChange 同步中单个项目的变更
+type GroupSyncChange struct {
+	Action          string `json:"action"`
+	ProjectID       uint   `json:"project_id,omitempty"`
+	GitLabProjectID int    `json:"gitlab_project_id"`
+	Path            string `json:"path"`
+	Detail          string `json:"detail,omitempty"`
+}
+
+type GroupSyncChanges []GroupSyncChange
+
+func (c *GroupSyncChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		if len(v) == 0 {
+			*c = nil
+			return nil
+		}
+		return json.Unmarshal(v, c)
+	case string:
+		if v == "" {
+			*c = nil
+			return nil
+		}
+		return json.Unmarshal([]byte(v), c)
+	default:
+		*c = nil
+		return nil
+	}
+}
+
+func (c GroupSyncChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]GroupSyncChange(c))
+}
+
+type CreateGitLabGroupRequest struct {
+	URL         string `json:"url" binding:"required,url"`
+	AccessToken string `json:"access_token"`
+	Watched     bool   `json:"watched"`
+	WebhookIDs  []uint `json:"webhook_ids"`
+	GitLabGroupSettings
+}
+
+type UpdateGitLabGroupRequest struct {
+	Watched     *bool   `json:"watched"`
+	WebhookIDs  *[]uint `json:"webhook_ids"`
+	AccessToken *string `json:"access_token"`
+	GitLabGroupSettings
+}
+
+// GitLabGroupSettings 组的接入方式、令牌来源与自动登记项目的默认事件订阅，字段不传时保持不变
+type GitLabGroupSettings struct {
+	HookMode                  *string   `json:"hook_mode" binding:"omitempty,oneof=group project none"`
+	TokenSource               *string   `json:"token_source" binding:"omitempty,oneof=instance account custom"`
+	DefaultMergeRequestEvents *bool     `json:"default_merge_request_events"`
+	DefaultPushEvents         *bool     `json:"default_push_events"`
+	DefaultTagPushEvents      *bool     `json:"default_tag_push_events"`
+	DefaultReleaseEvents      *bool     `json:"default_release_events"`
+	DefaultPushBranchFilters  *[]string `json:"default_push_branch_filters"`
+	DefaultTagFilters         *[]string `json:"default_tag_filters"`
+}
+
+// Apply 将请求中的设置写入组
+func (s *GitLabGroupSettings) Apply(group *GitLabGroup) {
+	if s.HookMode != nil {
+		group.HookMode = *s.HookMode
+	}
+	if s.TokenSource != nil {
+		group.TokenSource = *s.TokenSource
+	}
+	if s.DefaultMergeRequestEvents != nil {
+		group.DefaultMergeRequestEvents = *s.DefaultMergeRequestEvents
+	}
+	if s.DefaultPushEvents != nil {
+		group.DefaultPushEvents = *s.DefaultPushEvents
+	}
+	if s.DefaultTagPushEvents != nil {
+		group.DefaultTagPushEvents = *s.DefaultTagPushEvents
+	}
+	if s.DefaultReleaseEvents != nil {
+		group.DefaultReleaseEvents = *s.DefaultReleaseEvents
+	}
+	if s.DefaultPushBranchFilters != nil {
+		group.DefaultPushBranchFilters = StringList(*s.DefaultPushBranchFilters)
+	}
+	if s.DefaultTagFilters != nil {
+		group.DefaultTagFilters = StringList(*s.DefaultTagFilters)
+	}
+}
+
+type GitLabGroupResponse struct {
+	ID                  uint       `json:"id"`
+	GitLabInstanceID    uint       `json:"gitlab_instance_id"`
+	GitLabGroupID       int        `json:"gitlab_group_id"`
+	Name                string     `json:"name"`
+	FullPath            string     `json:"full_path"`
+	WebURL              string     `json:"web_url"`
+	GitLabHookID        *int       `json:"gitlab_hook_id,omitempty"`
+	HookSynced          bool       `json:"hook_synced"`
+	WebhookURL          string     `json:"webhook_url"`
+	LastSyncAt          *time.Time `json:"last_sync_at,omitempty"`
+	LastSyncError       string     `json:"last_sync_error,omitempty"`
+	Watched             bool       `json:"watched"`
+	HookMode            string     `json:"hook_mode"`
+	TokenSource         string     `json:"token_source"`
+	HasAccessToken      bool       `json:"has_access_token"`
+	LastScanAt          *time.Time `json:"last_scan_at,omitempty"`
+	LastScanError       string     `json:"last_scan_error,omitempty"`
+	CoveredProjectCount int        `json:"covered_project_count"`
+
+	DefaultMergeRequestEvents bool     `json:"default_merge_request_events"`
+	DefaultPushEvents         bool     `json:"default_push_events"`
+	DefaultTagPushEvents      bool     `json:"default_tag_push_events"`
+	DefaultReleaseEvents      bool     `json:"default_release_events"`
+	DefaultPushBranchFilters  []string `json:"default_push_branch_filters"`
+	DefaultTagFilters         []string `json:"default_tag_filters"`
+
+	Webhooks  []WebhookResponse `json:"webhooks,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GitLabInstance 接入的 GitLab 实例，项目按实例区分 GitLab 项目 ID
+type GitLabInstance struct {
+	ID      uint   `json:"id" gorm:"column:id;primarykey"`
+	Name    string `json:"name" gorm:"column:name;uniqueIndex;not null"`
+	BaseURL string `json:"base_url" gorm:"column:base_url;uniqueIndex;not null"`
+	// DefaultToken 后台任务与未配置个人令牌时使用的访问令牌（加密存储）
+	DefaultToken string `json:"-" gorm:"column:default_token"`
+	// WebhookSecret 写入 GitLab Hook 的 Secret Token，回调时通过 X-Gitlab-Token 校验（加密存储）
+	WebhookSecret string `json:"-" gorm:"column:webhook_secret"`
+	// IsDefault 默认实例对应配置中的 gitlab_url，无法识别实例的回调归入默认实例
+	IsDefault bool      `json:"is_default" gorm:"column:is_default;not null;default:false"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (GitLabInstance) TableName() string {
+	return "gitlab_instances"
+}
+
+// MatchesURL 判断 URL 是否属于该实例，实例地址可以带子路径
+func (i *GitLabInstance) MatchesURL(rawURL string) bool {
+	normalized := NormalizeGitLabBaseURL(rawURL)
+	if normalized == "" || i.BaseURL == "" {
+		return false
+	}
+	return normalized == i.BaseURL || strings.HasPrefix(normalized, i.BaseURL+"/")
+}
+
+type GitLabInstanceRequest struct {
+	Name          string  `json:"name" binding:"required"`
+	BaseURL       string  `json:"base_url" binding:"required,url"`
+	DefaultToken  *string `json:"default_token"`
+	WebhookSecret *string `json:"webhook_secret"`
+}
+
+type GitLabInstanceResponse struct {
+	ID                uint      `json:"id"`
+	Name              string    `json:"name"`
+	BaseURL           string    `json:"base_url"`
+	IsDefault         bool      `json:"is_default"`
+	HasDefaultToken   bool      `json:"has_default_token"`
+	HasWebhookSecret  bool      `json:"has_webhook_secret"`
+	ProjectCount      int64     `json:"project_count"`
+	InboundWebhookURL string    `json:"inbound_webhook_url"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// NormalizeGitLabBaseURL 统一实例地址格式：小写协议与主机、去掉默认端口、查询参数和末尾斜杠
+func NormalizeGitLabBaseURL(rawURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return ""
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	host := strings.ToLower(parsed.Host)
+	if port := parsed.Port(); (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		host = strings.TrimSuffix(host, ":"+port)
+	}
+
+	return scheme + "://" + host + strings.TrimRight(parsed.Path, "/")
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	MergeRequestStateOpened = "opened"
+	MergeRequestStateMerged = "merged"
+	MergeRequestStateClosed = "closed"
+	MergeRequestStateLocked = "locked"
+)
+
+// MergeRequest 记录合并请求的最新状态，按 (project_id, iid) 唯一
+type MergeRequest struct {
+	ID             uint       `json:"id" gorm:"column:id;primarykey"`
+	ProjectID      uint       `json:"project_id" gorm:"column:project_id;not null;uniqueIndex:idx_merge_requests_project_iid"`
+	IID            int        `json:"iid" gorm:"column:iid;not null;uniqueIndex:idx_merge_requests_project_iid"`
+	GitLabMRID     int        `json:"gitlab_mr_id" gorm:"column:gitlab_mr_id;not null;default:0"`
+	Title          string     `json:"title" gorm:"column:title"`
+	URL            string     `json:"url" gorm:"column:url"`
+	SourceBranch   string     `json:"source_branch" gorm:"column:source_branch"`
+	TargetBranch   string     `json:"target_branch" gorm:"column:target_branch"`
+	State          string     `json:"state" gorm:"column:state;not null;default:'opened';index"`
+	Draft          bool       `json:"draft" gorm:"column:draft;default:false"`
+	AuthorID       int        `json:"author_id" gorm:"column:author_id;not null;default:0"`
+	AuthorName     string     `json:"author_name" gorm:"column:author_name"`
+	AuthorUsername string     `json:"author_username" gorm:"column:author_username"`
+	Assignees      StringList `json:"assignees" gorm:"column:assignees;type:json"` // GitLab 用户名
+	Reviewers      StringList `json:"reviewers" gorm:"column:reviewers;type:json"` // GitLab 用户名
+	Labels         StringList `json:"labels" gorm:"column:labels;type:json"`
+	OpenedAt       *time.Time `json:"opened_at,omitempty" gorm:"column:opened_at"`
+	MergedAt       *time.Time `json:"merged_at,omitempty" gorm:"column:merged_at"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty" gorm:"column:closed_at"`
+	LastActivityAt time.Time  `json:"last_activity_at" gorm:"column:last_activity_at;index"`
+	PipelineID     int        `json:"pipeline_id" gorm:"column:pipeline_id;not null;default:0"`
+	PipelineStatus string     `json:"pipeline_status" gorm:"column:pipeline_status"`
+	ReminderCount  int        `json:"reminder_count" gorm:"column:reminder_count;not null;default:0"`
+	LastRemindedAt *time.Time `json:"last_reminded_at,omitempty" gorm:"column:last_reminded_at"`
+	ApprovedAt     *time.Time `json:"approved_at,omitempty" gorm:"column:approved_at"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"column:updated_at"`
+
+	// 关联关系
+	Project Project `json:"-" gorm:"foreignKey:ProjectID"`
+}
+
+// MergeRequestTransition 合并请求状态变更日志，只追加不修改
+type MergeRequestTransition struct {
+	ID             uint      `json:"id" gorm:"column:id;primarykey"`
+	MergeRequestID uint      `json:"merge_request_id" gorm:"column:merge_request_id;not null;index"`
+	FromState      string    `json:"from_state" gorm:"column:from_state"`
+	ToState        string    `json:"to_state" gorm:"column:to_state"`
+	Action         string    `json:"action" gorm:"column:action"`
+	ActorUsername  string    `json:"actor_username" gorm:"column:actor_username"`
+	OccurredAt     time.Time `json:"occurred_at" gorm:"column:occurred_at;index"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+type MergeRequestResponse struct {
+	ID             uint                     `json:"id"`
+	ProjectID      uint                     `json:"project_id"`
+	ProjectName    string                   `json:"project_name"`
+	IID            int                      `json:"iid"`
+	Title          string                   `json:"title"`
+	URL            string                   `json:"url"`
+	SourceBranch   string                   `json:"source_branch"`
+	TargetBranch   string                   `json:"target_branch"`
+	State          string                   `json:"state"`
+	Draft          bool                     `json:"draft"`
+	AuthorName     string                   `json:"author_name"`
+	AuthorUsername string                   `json:"author_username"`
+	Assignees      []string                 `json:"assignees"`
+	Reviewers      []string                 `json:"reviewers"`
+	Labels         []string                 `json:"labels"`
+	OpenedAt       *time.Time               `json:"opened_at,omitempty"`
+	MergedAt       *time.Time               `json:"merged_at,omitempty"`
+	ClosedAt       *time.Time               `json:"closed_at,omitempty"`
+	LastActivityAt time.Time                `json:"last_activity_at"`
+	PipelineStatus string                   `json:"pipeline_status"`
+	ReminderCount  int                      `json:"reminder_count"`
+	LastRemindedAt *time.Time               `json:"last_reminded_at,omitempty"`
+	ApprovedAt     *time.Time               `json:"approved_at,omitempty"`
+	Transitions    []MergeRequestTransition `json:"transitions,omitempty"`
+}
+
+// IsOpen 判断合并请求是否仍处于打开状态
+func (m *MergeRequest) IsOpen() bool {
+	return m.State == MergeRequestStateOpened
+}
+
+// ToResponse 转换为接口响应
+func (m *MergeRequest) ToResponse() MergeRequestResponse {
+	return MergeRequestResponse{
+		ID:             m.ID,
+		ProjectID:      m.ProjectID,
+		ProjectName:    m.Project.Name,
+		IID:            m.IID,
+		Title:          m.Title,
+		URL:            m.URL,
+		SourceBranch:   m.SourceBranch,
+		TargetBranch:   m.TargetBranch,
+		State:          m.State,
+		Draft:          m.Draft,
+		AuthorName:     m.AuthorName,
+		AuthorUsername: m.AuthorUsername,
+		Assignees:      append([]string{}, m.Assignees...),
+		Reviewers:      append([]string{}, m.Reviewers...),
+		Labels:         append([]string{}, m.Labels...),
+		OpenedAt:       m.OpenedAt,
+		MergedAt:       m.MergedAt,
+		ClosedAt:       m.ClosedAt,
+		LastActivityAt: m.LastActivityAt,
+		PipelineStatus: m.PipelineStatus,
+		ReminderCount:  m.ReminderCount,
+		LastRemindedAt: m.LastRemindedAt,
+		ApprovedAt:     m.ApprovedAt,
+	}
+}
+
+// Mentions 返回需要提醒的 GitLab 用户名：审查人优先，其次指派人
+func (m *MergeRequest) Mentions() []string {
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, list := range []StringList{m.Reviewers, m.Assignees} {
+		for _, username := range list {
+			if username == "" || seen[username] {
+				continue
+			}
+			seen[username] = true
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
+
+// ParseGitLabTime 解析 GitLab webhook 中的时间，兼容 RFC3339 与 "2006-01-02 15:04:05 UTC" 两种格式
+func ParseGitLabTime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	layouts := []string{
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05 MST",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02T15:04:05.000-07:00",
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
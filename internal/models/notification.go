@@ -4,8 +4,20 @@ import (
 	"time"
 )
 
+const (
+	EventTypeMergeRequest = "merge_request"
+	EventTypePush         = "push"
+	EventTypeTagPush      = "tag_push"
+	EventTypeRelease      = "release"
+	EventTypeMRReminder   = "merge_request_reminder"
+	EventTypeMREscalation = "merge_request_escalation"
+	EventTypePipeline     = "pipeline"
+	EventTypeDigest       = "digest"
+)
+
 type Notification struct {
 	ID               uint      `json:"id" gorm:"column:id;primarykey"`
+	EventType        string    `json:"event_type" gorm:"column:event_type;not null;default:'merge_request';index"`
 	ProjectID        uint      `json:"project_id" gorm:"column:project_id;not null;default:0"`
 	MergeRequestID   int       `json:"merge_request_id" gorm:"column:merge_request_id;not null;default:0"`
 	Title            string    `json:"title" gorm:"column:title"`
@@ -31,6 +43,8 @@ type GitLabWebhookData struct {
 	Repository       GitLabRepository   `json:"repository"`
 	ObjectAttributes GitLabMergeRequest `json:"object_attributes"`
 	Assignees        []GitLabUser       `json:"assignees"`
+	Reviewers        []GitLabUser       `json:"reviewers"`
+	Labels           []GitLabLabel      `json:"labels"`
 }
 
 type GitLabUser struct {
@@ -41,10 +55,17 @@ type GitLabUser struct {
 }
 
 type GitLabProject struct {
-	ID        int    `json:"id"`
-	Name      string `json:"name"`
-	WebURL    string `json:"web_url"`
-	Namespace string `json:"namespace"`
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	WebURL            string `json:"web_url"`
+	Namespace         string `json:"namespace"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	Description       string `json:"description"`
+}
+
+type GitLabLabel struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
 }
 
 type GitLabRepository struct {
@@ -55,24 +76,33 @@ type GitLabRepository struct {
 }
 
 type GitLabMergeRequest struct {
-	ID           int    `json:"id"`
-	IID          int    `json:"iid"`
-	Title        string `json:"title"`
-	Description  string `json:"description"`
-	State        string `json:"state"`
-	SourceBranch string `json:"source_branch"`
-	TargetBranch string `json:"target_branch"`
-	URL          string `json:"url"`
+	ID             int    `json:"id"`
+	IID            int    `json:"iid"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	State          string `json:"state"`
+	SourceBranch   string `json:"source_branch"`
+	TargetBranch   string `json:"target_branch"`
+	URL            string `json:"url"`
+	Action         string `json:"action"`
+	AuthorID       int    `json:"author_id"`
+	Draft          bool   `json:"draft"`
+	WorkInProgress bool   `json:"work_in_progress"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
 }
 
 // AssigneeInfo 用于在通知处理过程中传递指派人信息
 type AssigneeInfo struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
+	// UserID GitLab 用户 ID，邮箱被隐藏时用于查询用户接口
+	UserID int `json:"user_id,omitempty"`
 }
 
 type NotificationResponse struct {
 	ID               uint      `json:"id"`
+	EventType        string    `json:"event_type"`
 	ProjectID        uint      `json:"project_id"`
 	ProjectName      string    `json:"project_name"`
 	MergeRequestID   int       `json:"merge_request_id"`
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// 系统告警级别
+const (
+	OpsSeverityInfo     = "info"
+	OpsSeverityWarning  = "warning"
+	OpsSeverityCritical = "critical"
+)
+
+// 系统告警类别
+const (
+	OpsCategoryGitLabHookSync     = "gitlab_hook_sync"
+	OpsCategoryDeliveryDeadLetter = "delivery_dead_letter"
+	OpsCategoryQuota              = "quota"
+	OpsCategoryTokenDecrypt       = "token_decrypt"
+	OpsCategoryCircuitBreaker     = "circuit_breaker"
+	OpsCategoryGroupSync          = "gitlab_group_sync"
+	OpsCategoryHookDrift          = "gitlab_hook_drift"
+	OpsCategoryUserSync           = "user_sync"
+)
+
+var opsSeverityRanks = map[string]int{
+	OpsSeverityInfo:     1,
+	OpsSeverityWarning:  2,
+	OpsSeverityCritical: 3,
+}
+
+// OpsAlert 系统级告警，相同 DedupKey 的告警在去重窗口内只通知一次
+type OpsAlert struct {
+	ID          uint   `json:"id" gorm:"column:id;primarykey"`
+	DedupKey    string `json:"dedup_key" gorm:"column:dedup_key;uniqueIndex;not null"`
+	Category    string `json:"category" gorm:"column:category;index;not null"`
+	Severity    string `json:"severity" gorm:"column:severity;not null"`
+	Title       string `json:"title" gorm:"column:title"`
+	Detail      string `json:"detail" gorm:"column:detail;type:text"`
+	Occurrences int    `json:"occurrences" gorm:"column:occurrences;not null;default:0"`
+	// PendingCount 上次通知之后新发生的次数
+	PendingCount int        `json:"pending_count" gorm:"column:pending_count;not null;default:0"`
+	FirstSeenAt  time.Time  `json:"first_seen_at" gorm:"column:first_seen_at"`
+	LastSeenAt   time.Time  `json:"last_seen_at" gorm:"column:last_seen_at;index"`
+	LastSentAt   *time.Time `json:"last_sent_at,omitempty" gorm:"column:last_sent_at"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"column:updated_at"`
+	// AccountID 告警涉及资源的所属账户，该账户可在告警列表中查看，为空表示仅管理员可见
+	AccountID *uint `json:"account_id,omitempty" gorm:"column:account_id;index"`
+}
+
+func (OpsAlert) TableName() string {
+	return "ops_alerts"
+}
+
+// OpsSeverityRank 返回告警级别的排序值，未知级别为 0
+func OpsSeverityRank(severity string) int {
+	return opsSeverityRanks[severity]
+}
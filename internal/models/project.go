@@ -1,22 +1,49 @@
 package models
 
 import (
+	"path"
+	"strings"
 	"time"
 )
 
 type Project struct {
-	ID              uint   `json:"id" gorm:"column:id;primarykey"`
-	GitLabProjectID int    `json:"gitlab_project_id" gorm:"column:gitlab_project_id;uniqueIndex;not null;default:0"`
-	Name            string `json:"name" gorm:"column:name;not null;default:''"`
-	URL             string `json:"url" gorm:"column:url;not null;default:''"`
-	Description     string `json:"description" gorm:"column:description"`
-	AccessToken     string `json:"-" gorm:"column:access_token"` // 不在JSON中显示敏感信息
+	ID uint `json:"id" gorm:"column:id;primarykey"`
+	// GitLabInstanceID 所属 GitLab 实例，GitLab 项目 ID 只在同一实例内唯一
+	GitLabInstanceID uint   `json:"gitlab_instance_id" gorm:"column:gitlab_instance_id;uniqueIndex:idx_projects_instance_project,priority:1;not null;default:0"`
+	GitLabProjectID  int    `json:"gitlab_project_id" gorm:"column:gitlab_project_id;uniqueIndex:idx_projects_instance_project,priority:2;not null;default:0"`
+	Name             string `json:"name" gorm:"column:name;not null;default:''"`
+	URL              string `json:"url" gorm:"column:url;not null;default:''"`
+	Description      string `json:"description" gorm:"column:description"`
+	AccessToken      string `json:"-" gorm:"column:access_token"` // 不在JSON中显示敏感信息
 
 	// GitLab Webhook相关字段
 	GitLabWebhookID *int       `json:"gitlab_webhook_id,omitempty" gorm:"column:gitlab_webhook_id;index"` // GitLab中webhook的ID
 	WebhookSynced   bool       `json:"webhook_synced" gorm:"column:webhook_synced;default:false"`         // webhook同步状态
 	LastSyncAt      *time.Time `json:"last_sync_at,omitempty" gorm:"column:last_sync_at"`                 // 最后同步时间
 
+	// 事件订阅配置
+	MergeRequestEvents bool       `json:"merge_request_events" gorm:"column:merge_request_events;-:migration"` // 列由迁移创建并带有数据库默认值 true
+	PushEvents         bool       `json:"push_events" gorm:"column:push_events;default:false"`
+	TagPushEvents      bool       `json:"tag_push_events" gorm:"column:tag_push_events;default:false"`
+	ReleaseEvents      bool       `json:"release_events" gorm:"column:release_events;default:false"`
+	PushBranchFilters  StringList `json:"push_branch_filters" gorm:"column:push_branch_filters;type:json"` // 为空时匹配所有分支，支持通配符
+	TagFilters         StringList `json:"tag_filters" gorm:"column:tag_filters;type:json"`                 // 为空时匹配所有标签，支持通配符
+
+	// SourceGroupID 由关注的 GitLab 组自动登记时记录来源组
+	SourceGroupID *uint `json:"source_group_id,omitempty" gorm:"column:source_group_id;index"`
+	// GitLabState 组同步发现的项目状态：active、archived 或 missing（在 GitLab 中已删除或移出组）
+	GitLabState string `json:"gitlab_state" gorm:"column:gitlab_state;not null;default:'active'"`
+
+	// WebhookDrift 最近一次检查发现的 hook 配置偏差字段，为空表示与期望一致
+	WebhookDrift   StringList `json:"webhook_drift" gorm:"column:webhook_drift;type:json"`
+	DriftCheckedAt *time.Time `json:"drift_checked_at,omitempty" gorm:"column:drift_checked_at"`
+	// WebhookTokenRejectedAt 收到该项目的回调但 Secret Token 校验失败的最近时间，WebhookTokenRejections 为累计次数，修复 hook 后清空
+	WebhookTokenRejectedAt *time.Time `json:"webhook_token_rejected_at,omitempty" gorm:"column:webhook_token_rejected_at"`
+	WebhookTokenRejections int        `json:"webhook_token_rejections" gorm:"column:webhook_token_rejections;not null;default:0"`
+	// WebhookCheckedAt 后台任务最近一次检查 hook 的时间，WebhookCheckError 为检查失败的原因
+	WebhookCheckedAt  *time.Time `json:"webhook_checked_at,omitempty" gorm:"column:webhook_checked_at"`
+	WebhookCheckError string     `json:"webhook_check_error,omitempty" gorm:"column:webhook_check_error"`
+
 	CreatedBy *uint     `json:"created_by,omitempty" gorm:"column:created_by;index"`
 	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
@@ -25,6 +52,20 @@ type Project struct {
 	Webhooks []Webhook `json:"webhooks,omitempty" gorm:"many2many:project_webhooks;"`
 }
 
+// ClearWebhookDrift hook 按期望配置同步后清除偏差记录
+func (p *Project) ClearWebhookDrift(checkedAt time.Time) {
+	p.WebhookDrift = StringList{}
+	p.DriftCheckedAt = &checkedAt
+	p.WebhookTokenRejectedAt = nil
+	p.WebhookTokenRejections = 0
+}
+
+const (
+	ProjectGitLabStateActive   = "active"
+	ProjectGitLabStateArchived = "archived"
+	ProjectGitLabStateMissing  = "missing"
+)
+
 type CreateProjectRequest struct {
 	GitLabProjectID int    `json:"gitlab_project_id" binding:"required"`
 	Name            string `json:"name" binding:"required"`
@@ -40,20 +81,84 @@ type UpdateProjectRequest struct {
 	Description string `json:"description"`
 	AccessToken string `json:"access_token"`
 	WebhookIDs  []uint `json:"webhook_ids"`
+
+	MergeRequestEvents *bool     `json:"merge_request_events"`
+	PushEvents         *bool     `json:"push_events"`
+	TagPushEvents      *bool     `json:"tag_push_events"`
+	ReleaseEvents      *bool     `json:"release_events"`
+	PushBranchFilters  *[]string `json:"push_branch_filters"`
+	TagFilters         *[]string `json:"tag_filters"`
 }
 
 type ProjectResponse struct {
-	ID              uint              `json:"id"`
-	GitLabProjectID int               `json:"gitlab_project_id"`
-	Name            string            `json:"name"`
-	URL             string            `json:"url"`
-	Description     string            `json:"description"`
-	GitLabWebhookID *int              `json:"gitlab_webhook_id,omitempty"`
-	WebhookSynced   bool              `json:"webhook_synced"`
-	LastSyncAt      *time.Time        `json:"last_sync_at,omitempty"`
-	CreatedAt       time.Time         `json:"created_at"`
-	UpdatedAt       time.Time         `json:"updated_at"`
-	Webhooks        []WebhookResponse `json:"webhooks,omitempty"`
+	ID               uint              `json:"id"`
+	GitLabInstanceID uint              `json:"gitlab_instance_id"`
+	GitLabProjectID  int               `json:"gitlab_project_id"`
+	Name             string            `json:"name"`
+	URL              string            `json:"url"`
+	Description      string            `json:"description"`
+	GitLabWebhookID  *int              `json:"gitlab_webhook_id,omitempty"`
+	WebhookSynced    bool              `json:"webhook_synced"`
+	LastSyncAt       *time.Time        `json:"last_sync_at,omitempty"`
+	CoveredByGroupID *uint             `json:"covered_by_group_id,omitempty"` // 已由组级 Webhook 覆盖时为组 ID
+	SourceGroupID    *uint             `json:"source_group_id,omitempty"`
+	GitLabState      string            `json:"gitlab_state"`
+	WebhookDrift     []string          `json:"webhook_drift,omitempty"` // 最近一次检查发现的 hook 偏差字段
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	Webhooks         []WebhookResponse `json:"webhooks,omitempty"`
+
+	MergeRequestEvents bool     `json:"merge_request_events"`
+	PushEvents         bool     `json:"push_events"`
+	TagPushEvents      bool     `json:"tag_push_events"`
+	ReleaseEvents      bool     `json:"release_events"`
+	PushBranchFilters  []string `json:"push_branch_filters"`
+	TagFilters         []string `json:"tag_filters"`
+}
+
+// MatchesPushBranch 判断分支是否命中 push 事件的分支过滤
+func (p *Project) MatchesPushBranch(branch string) bool {
+	return matchRefPatterns(p.PushBranchFilters, branch)
+}
+
+// MatchesTag 判断标签是否命中 tag_push/release 事件的标签过滤
+func (p *Project) MatchesTag(tag string) bool {
+	return matchRefPatterns(p.TagFilters, tag)
+}
+
+// ApplyEventSubscriptionResponse 填充响应中的事件订阅字段
+func (p *Project) ApplyEventSubscriptionResponse(resp *ProjectResponse) {
+	resp.MergeRequestEvents = p.MergeRequestEvents
+	resp.PushEvents = p.PushEvents
+	resp.TagPushEvents = p.TagPushEvents
+	resp.ReleaseEvents = p.ReleaseEvents
+	resp.PushBranchFilters = append([]string{}, p.PushBranchFilters...)
+	resp.TagFilters = append([]string{}, p.TagFilters...)
+}
+
+// MatchRefPattern 使用 glob 规则匹配分支或标签名，例如 release/*、hotfix/*
+func MatchRefPattern(pattern, ref string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return false
+	}
+	if pattern == ref {
+		return true
+	}
+	matched, err := path.Match(pattern, ref)
+	return err == nil && matched
+}
+
+func matchRefPatterns(patterns []string, ref string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if MatchRefPattern(pattern, ref) {
+			return true
+		}
+	}
+	return false
 }
 
 // ParseProjectURLRequest 解析GitLab项目URL的请求结构
@@ -64,6 +169,7 @@ type ParseProjectURLRequest struct {
 
 // ParseProjectURLResponse 解析GitLab项目URL的响应结构
 type ParseProjectURLResponse struct {
+	GitLabInstanceID  uint   `json:"gitlab_instance_id"`
 	GitLabProjectID   int    `json:"gitlab_project_id"`
 	Name              string `json:"name"`
 	Description       string `json:"description"`
@@ -93,8 +199,9 @@ type ScanGroupProjectsRequest struct {
 
 // ScanGroupProjectsResponse 扫描组项目的响应结构
 type ScanGroupProjectsResponse struct {
-	GroupInfo *GitLabGroupInfo     `json:"group_info"`
-	Projects  []*GitLabProjectInfo `json:"projects"`
+	GitLabInstanceID uint                 `json:"gitlab_instance_id"`
+	GroupInfo        *GitLabGroupInfo     `json:"group_info"`
+	Projects         []*GitLabProjectInfo `json:"projects"`
 }
 
 // GitLabGroupInfo GitLab组信息
@@ -184,4 +291,37 @@ type GitLabWebhookStatusResponse struct {
 	WebhookURL      string     `json:"webhook_url,omitempty"`
 	LastSyncAt      *time.Time `json:"last_sync_at,omitempty"`
 	CanManage       bool       `json:"can_manage"` // 是否有权限管理webhook
+	// 项目已由组级 Webhook 覆盖时返回所属组，不再检查项目级 webhook
+	CoveredByGroupID *uint  `json:"covered_by_group_id,omitempty"`
+	CoveredByGroup   string `json:"covered_by_group,omitempty"`
+	// Drift 实时检查发现的 hook 配置偏差，为空表示与期望一致
+	Drift          []WebhookDriftItem `json:"drift,omitempty"`
+	DriftCheckedAt *time.Time         `json:"drift_checked_at,omitempty"`
+}
+
+// Hook 偏差字段
+const (
+	WebhookDriftFieldHook                = "hook"
+	WebhookDriftFieldMergeRequestsEvents = "merge_requests_events"
+	WebhookDriftFieldPushEvents          = "push_events"
+	WebhookDriftFieldTagPushEvents       = "tag_push_events"
+	WebhookDriftFieldReleasesEvents      = "releases_events"
+	WebhookDriftFieldPipelineEvents      = "pipeline_events"
+	WebhookDriftFieldSSLVerification     = "enable_ssl_verification"
+	WebhookDriftFieldToken               = "token"
+)
+
+// WebhookDriftItem GitLab 中 hook 的某项配置与期望不一致
+type WebhookDriftItem struct {
+	Field    string      `json:"field"`
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+}
+
+// WebhookRepairResponse 修复 hook 的结果，Repaired 为修复前发现的偏差
+type WebhookRepairResponse struct {
+	ProjectID       uint               `json:"project_id"`
+	GitLabWebhookID *int               `json:"gitlab_webhook_id,omitempty"`
+	WebhookURL      string             `json:"webhook_url"`
+	Repaired        []WebhookDriftItem `json:"repaired"`
 }
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+const (
+	DefaultReminderStaleAfterHours     = 24
+	DefaultReminderRepeatIntervalHours = 24
+	DefaultReminderMaxReminders        = 3
+)
+
+// ProjectReminderSetting 项目级别的合并请求催办配置
+type ProjectReminderSetting struct {
+	ID                  uint      `json:"id" gorm:"column:id;primarykey"`
+	ProjectID           uint      `json:"project_id" gorm:"column:project_id;not null;uniqueIndex"`
+	Enabled             bool      `json:"enabled" gorm:"column:enabled;default:false"`
+	StaleAfterHours     int       `json:"stale_after_hours" gorm:"column:stale_after_hours;not null;default:24"`
+	RepeatIntervalHours int       `json:"repeat_interval_hours" gorm:"column:repeat_interval_hours;not null;default:24"`
+	MaxReminders        int       `json:"max_reminders" gorm:"column:max_reminders;not null;default:3"`
+	SkipDrafts          bool      `json:"skip_drafts" gorm:"column:skip_drafts"`
+	PollGitLab          bool      `json:"poll_gitlab" gorm:"column:poll_gitlab;default:false"`
+	CreatedAt           time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt           time.Time `json:"updated_at" gorm:"column:updated_at"`
+
+	Project Project `json:"-" gorm:"foreignKey:ProjectID"`
+}
+
+type UpdateReminderSettingRequest struct {
+	Enabled             *bool `json:"enabled"`
+	StaleAfterHours     *int  `json:"stale_after_hours" binding:"omitempty,min=1"`
+	RepeatIntervalHours *int  `json:"repeat_interval_hours" binding:"omitempty,min=1"`
+	MaxReminders        *int  `json:"max_reminders" binding:"omitempty,min=1"`
+	SkipDrafts          *bool `json:"skip_drafts"`
+	PollGitLab          *bool `json:"poll_gitlab"`
+}
+
+// DefaultReminderSetting 返回项目未配置时使用的默认催办配置
+func DefaultReminderSetting(projectID uint) ProjectReminderSetting {
+	return ProjectReminderSetting{
+		ProjectID:           projectID,
+		StaleAfterHours:     DefaultReminderStaleAfterHours,
+		RepeatIntervalHours: DefaultReminderRepeatIntervalHours,
+		MaxReminders:        DefaultReminderMaxReminders,
+		SkipDrafts:          true,
+	}
+}
+
+// Apply 将更新请求合并到配置
+func (s *ProjectReminderSetting) Apply(req *UpdateReminderSettingRequest) {
+	if req.Enabled != nil {
+		s.Enabled = *req.Enabled
+	}
+	if req.StaleAfterHours != nil {
+		s.StaleAfterHours = *req.StaleAfterHours
+	}
+	if req.RepeatIntervalHours != nil {
+		s.RepeatIntervalHours = *req.RepeatIntervalHours
+	}
+	if req.MaxReminders != nil {
+		s.MaxReminders = *req.MaxReminders
+	}
+	if req.SkipDrafts != nil {
+		s.SkipDrafts = *req.SkipDrafts
+	}
+	if req.PollGitLab != nil {
+		s.PollGitLab = *req.PollGitLab
+	}
+}
+
+// StaleAfter 合并请求打开多久后开始催办
+func (s *ProjectReminderSetting) StaleAfter() time.Duration {
+	return time.Duration(s.StaleAfterHours) * time.Hour
+}
+
+// RepeatInterval 两次催办之间的最小间隔
+func (s *ProjectReminderSetting) RepeatInterval() time.Duration {
+	return time.Duration(s.RepeatIntervalHours) * time.Hour
+}
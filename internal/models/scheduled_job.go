@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ScheduledJob 定时任务的持久化状态，同时作为多实例间的执行租约
+type ScheduledJob struct {
+	Name        string     `json:"name" gorm:"column:name;primarykey;size:128"`
+	NextRunAt   time.Time  `json:"next_run_at" gorm:"column:next_run_at;not null;index"`
+	LockedBy    string     `json:"locked_by" gorm:"column:locked_by"`
+	LockedUntil *time.Time `json:"locked_until,omitempty" gorm:"column:locked_until"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty" gorm:"column:last_run_at"`
+	LastError   string     `json:"last_error" gorm:"column:last_error"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"column:updated_at"`
+}
@@ -4,20 +4,37 @@ import (
 	"time"
 )
 
+// GitLab 用户名映射来源
+const (
+	GitLabMappingSourceManual      = "manual"
+	GitLabMappingSourcePublicEmail = "public_email"
+	GitLabMappingSourceAdminLookup = "admin_lookup"
+	GitLabMappingSourceMemberSync  = "member_sync"
+)
+
 type User struct {
-	ID             uint      `json:"id" gorm:"column:id;primarykey"`
-	Email          string    `json:"email" gorm:"column:email;uniqueIndex;not null;default:''"`
-	Phone          string    `json:"phone" gorm:"column:phone;not null;default:''"`
-	Name           string    `json:"name" gorm:"column:name"`
-	GitLabUsername string    `json:"gitlab_username" gorm:"column:gitlab_username;uniqueIndex;default:''"`
-	CreatedBy      *uint     `json:"created_by,omitempty" gorm:"column:created_by;index"`
-	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at"`
-	UpdatedAt      time.Time `json:"updated_at" gorm:"column:updated_at"`
+	ID             uint   `json:"id" gorm:"column:id;primarykey"`
+	Email          string `json:"email" gorm:"column:email;uniqueIndex;not null;default:''"`
+	Phone          string `json:"phone" gorm:"column:phone;not null;default:''"`
+	Name           string `json:"name" gorm:"column:name"`
+	GitLabUsername string `json:"gitlab_username" gorm:"column:gitlab_username;default:''"`
+	// GitLabMappingSource 用户名映射的来源，通过 GitLab 用户接口自动发现的映射需要人工确认
+	GitLabMappingSource  string `json:"gitlab_mapping_source" gorm:"column:gitlab_mapping_source;not null;default:''"`
+	GitLabMappingPending bool   `json:"gitlab_mapping_pending" gorm:"column:gitlab_mapping_pending;not null;default:false"`
+	// GitLabInstanceID、GitLabUserID 成员同步关联的 GitLab 用户，未关联时为 0
+	GitLabInstanceID uint      `json:"gitlab_instance_id" gorm:"column:gitlab_instance_id;index:idx_users_gitlab_user,priority:1;not null;default:0"`
+	GitLabUserID     int       `json:"gitlab_user_id" gorm:"column:gitlab_user_id;index:idx_users_gitlab_user,priority:2;not null;default:0"`
+	CreatedBy        *uint     `json:"created_by,omitempty" gorm:"column:created_by;index"`
+	CreatedAt        time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt        time.Time `json:"updated_at" gorm:"column:updated_at"`
+
+	Identities []UserIdentity `json:"identities,omitempty" gorm:"foreignKey:UserID"`
+	Aliases    []UserAlias    `json:"aliases,omitempty" gorm:"foreignKey:UserID"`
 }
 
 type CreateUserRequest struct {
 	Email          string `json:"email" binding:"required,email"`
-	Phone          string `json:"phone" binding:"required"`
+	Phone          string `json:"phone"`
 	Name           string `json:"name"`
 	GitLabUsername string `json:"gitlab_username"`
 }
@@ -30,11 +47,37 @@ type UpdateUserRequest struct {
 }
 
 type UserResponse struct {
-	ID             uint      `json:"id"`
-	Email          string    `json:"email"`
-	Phone          string    `json:"phone"`
-	Name           string    `json:"name"`
-	GitLabUsername string    `json:"gitlab_username"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID                   uint      `json:"id"`
+	Email                string    `json:"email"`
+	Phone                string    `json:"phone"`
+	Name                 string    `json:"name"`
+	GitLabUsername       string    `json:"gitlab_username"`
+	GitLabMappingSource  string    `json:"gitlab_mapping_source"`
+	GitLabMappingPending bool      `json:"gitlab_mapping_pending"`
+	GitLabInstanceID     uint      `json:"gitlab_instance_id,omitempty"`
+	GitLabUserID         int       `json:"gitlab_user_id,omitempty"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+
+	// Identities、Aliases 各渠道的账号标识与别名，只有列表接口返回
+	Identities []UserIdentity `json:"identities,omitempty"`
+	Aliases    []UserAlias    `json:"aliases,omitempty"`
+}
+
+func (u *User) ToResponse() UserResponse {
+	return UserResponse{
+		ID:                   u.ID,
+		Email:                u.Email,
+		Phone:                u.Phone,
+		Name:                 u.Name,
+		GitLabUsername:       u.GitLabUsername,
+		GitLabMappingSource:  u.GitLabMappingSource,
+		GitLabMappingPending: u.GitLabMappingPending,
+		GitLabInstanceID:     u.GitLabInstanceID,
+		GitLabUserID:         u.GitLabUserID,
+		Identities:           u.Identities,
+		Aliases:              u.Aliases,
+		CreatedAt:            u.CreatedAt,
+		UpdatedAt:            u.UpdatedAt,
+	}
 }
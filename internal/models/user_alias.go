@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	UserAliasKindEmail          = "email"
+	UserAliasKindGitLabUsername = "gitlab_username"
+)
+
+// UserAlias 用户的其他邮箱或 GitLab 用户名，如旧邮箱、LDAP 与 SSO 两个 GitLab 账号；
+// 匹配指派人时与主邮箱、主用户名同等对待。邮箱别名以小写保存
+type UserAlias struct {
+	ID        uint      `json:"id" gorm:"column:id;primarykey"`
+	UserID    uint      `json:"user_id" gorm:"column:user_id;index;not null"`
+	Kind      string    `json:"kind" gorm:"column:kind;uniqueIndex:idx_user_aliases_kind_value,priority:1;not null"`
+	Value     string    `json:"value" gorm:"column:value;uniqueIndex:idx_user_aliases_kind_value,priority:2;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (UserAlias) TableName() string {
+	return "user_aliases"
+}
+
+type UserAliasRequest struct {
+	Kind  string `json:"kind" binding:"required,oneof=email gitlab_username"`
+	Value string `json:"value" binding:"required"`
+}
+
+// MergeUsersRequest 将 source_user_id 用户合并到路径中的用户
+type MergeUsersRequest struct {
+	SourceUserID uint `json:"source_user_id" binding:"required"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// 用户身份对应的渠道，取值与通知渠道的类型一致；slack、feishu、teams 供后续渠道使用
+const (
+	IdentityChannelWeCom    = WebhookTypeWeCom
+	IdentityChannelDingTalk = WebhookTypeDingTalk
+	IdentityChannelSlack    = "slack"
+	IdentityChannelFeishu   = "feishu"
+	IdentityChannelTeams    = "teams"
+)
+
+// UserIdentity 用户在某个通知渠道中的账号标识，如钉钉 userId、企业微信 userid、Slack member ID；
+// 发送通知时优先使用渠道对应的标识 @ 用户，没有时使用手机号
+type UserIdentity struct {
+	ID         uint      `json:"id" gorm:"column:id;primarykey"`
+	UserID     uint      `json:"user_id" gorm:"column:user_id;uniqueIndex:idx_user_identities_user_channel,priority:1;not null"`
+	Channel    string    `json:"channel" gorm:"column:channel;uniqueIndex:idx_user_identities_user_channel,priority:2;not null"`
+	Identifier string    `json:"identifier" gorm:"column:identifier;not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+type UserIdentityRequest struct {
+	Identifier string `json:"identifier" binding:"required"`
+}
+
+// IsIdentityChannel 判断是否为支持的身份渠道
+func IsIdentityChannel(channel string) bool {
+	switch channel {
+	case IdentityChannelWeCom, IdentityChannelDingTalk, IdentityChannelSlack, IdentityChannelFeishu, IdentityChannelTeams:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package models
+
+const (
+	UserTableFormatCSV  = "csv"
+	UserTableFormatXLSX = "xlsx"
+
+	UserImportActionCreate    = "create"
+	UserImportActionUpdate    = "update"
+	UserImportActionUnchanged = "unchanged"
+	UserImportActionConflict  = "conflict"
+)
+
+// UserTableColumns 导入导出的列，导入时按表头名称识别列的位置
+var UserTableColumns = []string{"email", "phone", "name", "gitlab_username"}
+
+// UserImportRow 导入文件中的一行，Line 为文件中的行号（表头为第 1 行）
+type UserImportRow struct {
+	Line           int
+	Email          string
+	Phone          string
+	Name           string
+	GitLabUsername string
+}
+
+// UserImportOptions 导入选项；EditableUserIDs 为 nil 时可以更新任意已有用户
+type UserImportOptions struct {
+	DryRun          bool
+	Upsert          bool
+	AccountID       uint
+	EditableUserIDs map[uint]bool
+}
+
+// UserImportRowResult 单行的导入结果：新建、更新、无变化或冲突
+type UserImportRowResult struct {
+	Line           int      `json:"line"`
+	Email          string   `json:"email"`
+	GitLabUsername string   `json:"gitlab_username,omitempty"`
+	Action         string   `json:"action"`
+	UserID         uint     `json:"user_id,omitempty"`
+	Changes        []string `json:"changes,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// UserImportResult 导入结果；DryRun 为 true 时只预览差异，不写入数据库
+type UserImportResult struct {
+	DryRun    bool                  `json:"dry_run"`
+	Upsert    bool                  `json:"upsert"`
+	Created   int                   `json:"created"`
+	Updated   int                   `json:"updated"`
+	Unchanged int                   `json:"unchanged"`
+	Conflicts int                   `json:"conflicts"`
+	Rows      []UserImportRowResult `json:"rows"`
+}
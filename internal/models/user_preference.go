@@ -0,0 +1,101 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MentionPreference 用户被 @ 的条件；随 @ 对象一起进入延迟投递队列，在实际发送时判断，
+// 不满足条件时只是不 @ 该用户，消息本身照常发送
+type MentionPreference struct {
+	// Timezone MentionHours 所在时区，为空时使用默认时区
+	Timezone string `json:"timezone" gorm:"column:timezone;not null;default:''"`
+	// MentionHours 只在这些时间 @，格式与 Webhook 工作时间相同，如 "mon-fri 09:00-18:00"；为空表示不限
+	MentionHours StringList `json:"mention_hours" gorm:"column:mention_hours;type:json"`
+	// MentionBranchPatterns 只在合并请求的目标分支命中这些规则时 @；为空表示不限
+	MentionBranchPatterns StringList `json:"mention_branch_patterns" gorm:"column:mention_branch_patterns;type:json"`
+	// MutedUntil 休假等临时免打扰，在此之前不 @
+	MutedUntil *time.Time `json:"muted_until" gorm:"column:muted_until"`
+}
+
+// UserNotificationPreference 用户的通知偏好，每个用户一条
+type UserNotificationPreference struct {
+	ID     uint `json:"id" gorm:"column:id;primarykey"`
+	UserID uint `json:"user_id" gorm:"column:user_id;uniqueIndex;not null"`
+	MentionPreference
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (UserNotificationPreference) TableName() string {
+	return "user_notification_preferences"
+}
+
+// UserNotificationPreferenceRequest 整体替换通知偏好，muted_until 为空表示取消免打扰
+type UserNotificationPreferenceRequest struct {
+	Timezone              string     `json:"timezone"`
+	MentionHours          []string   `json:"mention_hours"`
+	MentionBranchPatterns []string   `json:"mention_branch_patterns"`
+	MutedUntil            *time.Time `json:"muted_until"`
+}
+
+// IsEmpty 没有任何限制时无需保存
+func (p *MentionPreference) IsEmpty() bool {
+	return len(p.MentionHours) == 0 && len(p.MentionBranchPatterns) == 0 && p.MutedUntil == nil
+}
+
+// Validate 校验时区与 @ 时间段
+func (p *MentionPreference) Validate() error {
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("时区无效: %s", p.Timezone)
+		}
+	}
+	for _, spec := range p.MentionHours {
+		if _, err := ParseDeliveryWindow(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllowsMention 判断 now 时刻、目标分支为 branch 的消息能否 @ 该用户；branch 为空的消息不按分支过滤
+func (p *MentionPreference) AllowsMention(branch string, now time.Time) bool {
+	if p.MutedUntil != nil && now.Before(*p.MutedUntil) {
+		return false
+	}
+	if branch != "" && len(p.MentionBranchPatterns) > 0 && !matchRefPatterns(p.MentionBranchPatterns, branch) {
+		return false
+	}
+	if len(p.MentionHours) == 0 {
+		return true
+	}
+
+	name := p.Timezone
+	if name == "" {
+		name = DefaultWebhookTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	for _, spec := range p.MentionHours {
+		// 无效的时间段在保存时已被拒绝，这里忽略
+		if window, err := ParseDeliveryWindow(spec); err == nil && window.Contains(local.Weekday(), minute) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyRequest 用请求整体替换偏好并校验
+func (p *MentionPreference) ApplyRequest(req *UserNotificationPreferenceRequest) error {
+	p.Timezone = strings.TrimSpace(req.Timezone)
+	p.MentionHours = ToStringList(req.MentionHours)
+	p.MentionBranchPatterns = ToStringList(req.MentionBranchPatterns)
+	p.MutedUntil = req.MutedUntil
+	return p.Validate()
+}
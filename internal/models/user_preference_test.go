@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMentionPreferenceAllowsMention(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	// 2026-10-19 为周一
+	monday10 := time.Date(2026, 10, 19, 10, 0, 0, 0, shanghai)
+	later := monday10.Add(time.Hour)
+	earlier := monday10.Add(-time.Hour)
+
+	cases := []struct {
+		name   string
+		pref   MentionPreference
+		branch string
+		now    time.Time
+		want   bool
+	}{
+		{"no preference", MentionPreference{}, "main", monday10, true},
+		{"muted", MentionPreference{MutedUntil: &later}, "main", monday10, false},
+		{"mute ends at muted_until", MentionPreference{MutedUntil: &monday10}, "main", monday10, true},
+		{"mute expired", MentionPreference{MutedUntil: &earlier}, "main", monday10, true},
+		{
+			name:   "mute wins over matching hours and branch",
+			pref:   MentionPreference{MutedUntil: &later, MentionHours: StringList{"09:00-18:00"}, MentionBranchPatterns: StringList{"main"}},
+			branch: "main",
+			now:    monday10,
+			want:   false,
+		},
+		{"exact branch", MentionPreference{MentionBranchPatterns: StringList{"main"}}, "main", monday10, true},
+		{"wildcard branch", MentionPreference{MentionBranchPatterns: StringList{"main", "release/*"}}, "release/1.2", monday10, true},
+		{"branch not matched", MentionPreference{MentionBranchPatterns: StringList{"main", "release/*"}}, "feature/login", monday10, false},
+		{"wildcard does not cross slashes", MentionPreference{MentionBranchPatterns: StringList{"release/*"}}, "release/1.2/hotfix", monday10, false},
+		{"message without branch", MentionPreference{MentionBranchPatterns: StringList{"main"}}, "", monday10, true},
+		{"inside mention hours", MentionPreference{MentionHours: StringList{"mon-fri 09:00-18:00"}}, "main", monday10, true},
+		{"after mention hours", MentionPreference{MentionHours: StringList{"mon-fri 09:00-18:00"}}, "main", monday10.Add(10 * time.Hour), false},
+		{"weekend", MentionPreference{MentionHours: StringList{"mon-fri 09:00-18:00"}}, "main", monday10.AddDate(0, 0, -2), false},
+		{"any of several hours", MentionPreference{MentionHours: StringList{"mon-fri 07:00-08:00", "mon 09:30-10:30"}}, "main", monday10, true},
+		{"overnight hours after midnight", MentionPreference{MentionHours: StringList{"mon 22:00-06:00"}}, "main", monday10.Add(15 * time.Hour), true},
+		{
+			name:   "hours match but branch does not",
+			pref:   MentionPreference{MentionHours: StringList{"09:00-18:00"}, MentionBranchPatterns: StringList{"main"}},
+			branch: "develop",
+			now:    monday10,
+			want:   false,
+		},
+		// 周一 10:00 CST 为纽约周日 22:00，周一 22:00 CST 为纽约周一 10:00
+		{"hours in the preference timezone", MentionPreference{Timezone: "America/New_York", MentionHours: StringList{"mon-fri 09:00-17:00"}}, "main", monday10, false},
+		{"hours roll over to the preference timezone", MentionPreference{Timezone: "America/New_York", MentionHours: StringList{"mon-fri 09:00-17:00"}}, "main", monday10.Add(12 * time.Hour), true},
+		{"utc input uses the default timezone", MentionPreference{MentionHours: StringList{"mon-fri 09:00-18:00"}}, "main", time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC), true},
+		{"invalid timezone falls back to utc", MentionPreference{Timezone: "Mars/Olympus", MentionHours: StringList{"00:00-03:00"}}, "main", monday10, true},
+		{"invalid hours are ignored", MentionPreference{MentionHours: StringList{"bad", "09:00-18:00"}}, "main", monday10, true},
+		{"only invalid hours", MentionPreference{MentionHours: StringList{"bad"}}, "main", monday10, false},
+	}
+
+	for _, tc := range cases {
+		if got := tc.pref.AllowsMention(tc.branch, tc.now); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+const (
+	UserSyncSourceGroup   = "group"
+	UserSyncSourceProject = "project"
+
+	UserSyncTriggerSchedule = "schedule"
+	UserSyncTriggerManual   = "manual"
+
+	UserSyncStatusSuccess = "success"
+	UserSyncStatusPartial = "partial"
+	UserSyncStatusFailed  = "failed"
+
+	UserSyncActionCreated = "created"
+	UserSyncActionUpdated = "updated"
+	UserSyncActionLinked  = "linked"
+	// UserSyncActionSkipped 无法获取邮箱等原因未能登记的成员
+	UserSyncActionSkipped = "skipped"
+	// UserSyncActionSourceFailed 读取成员列表失败的来源
+	UserSyncActionSourceFailed = "source_failed"
+)
+
+// UserSyncSource 用户目录同步的成员来源：GitLab 组（含继承的成员）或项目
+type UserSyncSource struct {
+	ID               uint       `json:"id" gorm:"column:id;primarykey"`
+	GitLabInstanceID uint       `json:"gitlab_instance_id" gorm:"column:gitlab_instance_id;uniqueIndex:idx_user_sync_sources_target,priority:1;not null"`
+	SourceType       string     `json:"source_type" gorm:"column:source_type;uniqueIndex:idx_user_sync_sources_target,priority:2;not null"`
+	GitLabID         int        `json:"gitlab_id" gorm:"column:gitlab_id;uniqueIndex:idx_user_sync_sources_target,priority:3;not null"`
+	Name             string     `json:"name" gorm:"column:name;not null;default:''"`
+	FullPath         string     `json:"full_path" gorm:"column:full_path;not null;default:''"`
+	WebURL           string     `json:"web_url" gorm:"column:web_url;not null;default:''"`
+	LastSyncAt       *time.Time `json:"last_sync_at,omitempty" gorm:"column:last_sync_at"`
+	LastSyncError    string     `json:"last_sync_error" gorm:"column:last_sync_error"`
+	CreatedBy        *uint      `json:"created_by,omitempty" gorm:"column:created_by;index"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (UserSyncSource) TableName() string {
+	return "user_sync_sources"
+}
+
+// CreateUserSyncSourceRequest 登记成员来源，URL 为组或项目地址
+type CreateUserSyncSourceRequest struct {
+	URL         string `json:"url" binding:"required"`
+	SourceType  string `json:"source_type" binding:"required,oneof=group project"`
+	AccessToken string `json:"access_token"`
+}
+
+// UserSyncRun 一次用户目录同步的执行记录与变更报告
+type UserSyncRun struct {
+	ID           uint            `json:"id" gorm:"column:id;primarykey"`
+	Trigger      string          `json:"trigger" gorm:"column:triggered_by;not null;default:'schedule'"`
+	Status       string          `json:"status" gorm:"column:status;not null;default:'success'"`
+	Sources      int             `json:"sources" gorm:"column:sources;not null;default:0"`
+	Members      int             `json:"members" gorm:"column:members;not null;default:0"`
+	Created      int             `json:"created" gorm:"column:created;not null;default:0"`
+	Updated      int             `json:"updated" gorm:"column:updated;not null;default:0"`
+	Linked       int             `json:"linked" gorm:"column:linked;not null;default:0"`
+	Skipped      int             `json:"skipped" gorm:"column:skipped;not null;default:0"`
+	MissingPhone int             `json:"missing_phone" gorm:"column:missing_phone;not null;default:0"`
+	Changes      UserSyncChanges `json:"changes" gorm:"column:changes;type:json"`
+	Error        string          `json:"error,omitempty" gorm:"column:error"`
+	StartedAt    time.Time       `json:"started_at" gorm:"column:started_at"`
+	FinishedAt   *time.Time      `json:"finished_at,omitempty" gorm:"column:finished_at"`
+}
+
+func (UserSyncRun) TableName() string {
+	return "user_sync_runs"
+}
+
+// UserSyncChange 同步中单个成员或来源的变更
+type UserSyncChange struct {
+	Action       string `json:"action"`
+	UserID       uint   `json:"user_id,omitempty"`
+	SourceID     uint   `json:"source_id,omitempty"`
+	GitLabUserID int    `json:"gitlab_user_id,omitempty"`
+	Username     string `json:"username,omitempty"`
+	Detail       string `json:"detail,omitempty"`
+}
+
+type UserSyncChanges []UserSyncChange
+
+func (c *UserSyncChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		if len(v) == 0 {
+			*c = nil
+			return nil
+		}
+		return json.Unmarshal(v, c)
+	case string:
+		if v == "" {
+			*c = nil
+			return nil
+		}
+		return json.Unmarshal([]byte(v), c)
+	default:
+		*c = nil
+		return nil
+	}
+}
+
+func (c UserSyncChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]UserSyncChange(c))
+}
+
+// UserSyncReport 最近一次同步与缺少手机号且没有渠道身份（无法被 @）的已同步用户
+type UserSyncReport struct {
+	LatestRun    *UserSyncRun     `json:"latest_run"`
+	MissingPhone []UserResponse   `json:"missing_phone"`
+	Skipped      []UserSyncChange `json:"skipped"`
+}
@@ -3,9 +3,12 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/cron"
 )
 
 const (
@@ -15,6 +18,12 @@ const (
 	WebhookTypeAuto     = "auto"
 
 	SignatureMethodHMACSHA256 = "hmac_sha256"
+
+	CircuitStateClosed = "closed"
+	CircuitStateOpen   = "open"
+
+	DefaultDigestCron     = "0 10 * * 1-5"
+	DefaultDigestTimezone = DefaultWebhookTimezone
 )
 
 type StringList []string
@@ -94,6 +103,35 @@ type Webhook struct {
 	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at"`
 
+	// 投递时间窗口：非工作时间或节假日收到的通知进入队列，窗口开启后再发送
+	Timezone             string     `json:"timezone" gorm:"column:timezone"`
+	WorkingHours         StringList `json:"working_hours" gorm:"column:working_hours;type:json"`
+	Holidays             StringList `json:"holidays" gorm:"column:holidays;type:json"`
+	UrgentBranchPatterns StringList `json:"urgent_branch_patterns" gorm:"column:urgent_branch_patterns;type:json"`
+	BatchHeldMessages    bool       `json:"batch_held_messages" gorm:"column:batch_held_messages;default:false"`
+
+	// 合并请求突发合并：窗口内到达的合并请求事件合并为一条汇总消息，0 表示不合并
+	CoalesceWindowSeconds int `json:"coalesce_window_seconds" gorm:"column:coalesce_window_seconds;not null;default:0"`
+
+	// RateLimitPerMinute 该机器人每分钟最多发送的消息数，0 表示使用渠道默认值
+	RateLimitPerMinute int `json:"rate_limit_per_minute" gorm:"column:rate_limit_per_minute;not null;default:0"`
+
+	// 熔断状态：连续失败达到阈值后打开并跳过发送，冷却后放行一次试探
+	ConsecutiveFailures int        `json:"consecutive_failures" gorm:"column:consecutive_failures;not null;default:0"`
+	CircuitOpenedAt     *time.Time `json:"circuit_opened_at,omitempty" gorm:"column:circuit_opened_at;index"`
+	CircuitProbeAt      *time.Time `json:"circuit_probe_at,omitempty" gorm:"column:circuit_probe_at"`
+	CircuitSkippedCount int        `json:"circuit_skipped_count" gorm:"column:circuit_skipped_count;not null;default:0"`
+	CircuitAlertedAt    *time.Time `json:"circuit_alerted_at,omitempty" gorm:"column:circuit_alerted_at"`
+	LastFailureMessage  string     `json:"last_failure_message" gorm:"column:last_failure_message"`
+	DisabledReason      string     `json:"disabled_reason" gorm:"column:disabled_reason"`
+
+	// 打开中合并请求的定时汇总
+	DigestEnabled    bool       `json:"digest_enabled" gorm:"column:digest_enabled;default:false"`
+	DigestCron       string     `json:"digest_cron" gorm:"column:digest_cron"`
+	DigestTimezone   string     `json:"digest_timezone" gorm:"column:digest_timezone"`
+	DigestNextRunAt  *time.Time `json:"digest_next_run_at,omitempty" gorm:"column:digest_next_run_at;index"`
+	DigestLastSentAt *time.Time `json:"digest_last_sent_at,omitempty" gorm:"column:digest_last_sent_at"`
+
 	Settings *WebhookSetting `json:"settings,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
 
 	Projects []Project `json:"projects,omitempty" gorm:"many2many:project_webhooks;"`
@@ -121,43 +159,71 @@ type ProjectWebhook struct {
 }
 
 type CreateWebhookRequest struct {
-	Name             string            `json:"name" binding:"required"`
-	URL              string            `json:"url" binding:"required,url"`
-	Description      string            `json:"description"`
-	Type             string            `json:"type" binding:"omitempty,oneof=wechat dingtalk custom auto"`
-	SignatureMethod  string            `json:"signature_method" binding:"omitempty,oneof=hmac_sha256"`
-	Secret           string            `json:"secret"`
-	SecurityKeywords []string          `json:"security_keywords"`
-	CustomHeaders    map[string]string `json:"custom_headers"`
-	IsActive         *bool             `json:"is_active"`
+	Name               string            `json:"name" binding:"required"`
+	URL                string            `json:"url" binding:"required,url"`
+	Description        string            `json:"description"`
+	Type               string            `json:"type" binding:"omitempty,oneof=wechat dingtalk custom auto"`
+	SignatureMethod    string            `json:"signature_method" binding:"omitempty,oneof=hmac_sha256"`
+	Secret             string            `json:"secret"`
+	SecurityKeywords   []string          `json:"security_keywords"`
+	CustomHeaders      map[string]string `json:"custom_headers"`
+	IsActive           *bool             `json:"is_active"`
+	DigestEnabled      *bool             `json:"digest_enabled"`
+	DigestCron         *string           `json:"digest_cron"`
+	DigestTimezone     *string           `json:"digest_timezone"`
+	RateLimitPerMinute *int              `json:"rate_limit_per_minute" binding:"omitempty,min=0,max=600"`
+	DeliveryWindowRequest
 }
 
 type UpdateWebhookRequest struct {
-	Name             string            `json:"name"`
-	URL              string            `json:"url" binding:"omitempty,url"`
-	Description      string            `json:"description"`
-	Type             string            `json:"type" binding:"omitempty,oneof=wechat dingtalk custom auto"`
-	SignatureMethod  string            `json:"signature_method" binding:"omitempty,oneof=hmac_sha256"`
-	Secret           *string           `json:"secret"`
-	SecurityKeywords []string          `json:"security_keywords"`
-	CustomHeaders    map[string]string `json:"custom_headers"`
-	IsActive         *bool             `json:"is_active"`
+	Name               string            `json:"name"`
+	URL                string            `json:"url" binding:"omitempty,url"`
+	Description        string            `json:"description"`
+	Type               string            `json:"type" binding:"omitempty,oneof=wechat dingtalk custom auto"`
+	SignatureMethod    string            `json:"signature_method" binding:"omitempty,oneof=hmac_sha256"`
+	Secret             *string           `json:"secret"`
+	SecurityKeywords   []string          `json:"security_keywords"`
+	CustomHeaders      map[string]string `json:"custom_headers"`
+	IsActive           *bool             `json:"is_active"`
+	DigestEnabled      *bool             `json:"digest_enabled"`
+	DigestCron         *string           `json:"digest_cron"`
+	DigestTimezone     *string           `json:"digest_timezone"`
+	RateLimitPerMinute *int              `json:"rate_limit_per_minute" binding:"omitempty,min=0,max=600"`
+	DeliveryWindowRequest
 }
 
 type WebhookResponse struct {
-	ID               uint              `json:"id"`
-	Name             string            `json:"name"`
-	URL              string            `json:"url"`
-	Description      string            `json:"description"`
-	Type             string            `json:"type"`
-	SignatureMethod  string            `json:"signature_method"`
-	Secret           string            `json:"secret,omitempty"`
-	SecurityKeywords []string          `json:"security_keywords,omitempty"`
-	CustomHeaders    map[string]string `json:"custom_headers,omitempty"`
-	IsActive         bool              `json:"is_active"`
-	CreatedAt        time.Time         `json:"created_at"`
-	UpdatedAt        time.Time         `json:"updated_at"`
-	Projects         []ProjectResponse `json:"projects,omitempty"`
+	ID                    uint              `json:"id"`
+	Name                  string            `json:"name"`
+	URL                   string            `json:"url"`
+	Description           string            `json:"description"`
+	Type                  string            `json:"type"`
+	SignatureMethod       string            `json:"signature_method"`
+	Secret                string            `json:"secret,omitempty"`
+	SecurityKeywords      []string          `json:"security_keywords,omitempty"`
+	CustomHeaders         map[string]string `json:"custom_headers,omitempty"`
+	IsActive              bool              `json:"is_active"`
+	Timezone              string            `json:"timezone"`
+	WorkingHours          []string          `json:"working_hours"`
+	Holidays              []string          `json:"holidays"`
+	UrgentBranchPatterns  []string          `json:"urgent_branch_patterns"`
+	BatchHeldMessages     bool              `json:"batch_held_messages"`
+	CoalesceWindowSeconds int               `json:"coalesce_window_seconds"`
+	RateLimitPerMinute    int               `json:"rate_limit_per_minute"`
+	CircuitState          string            `json:"circuit_state"`
+	ConsecutiveFailures   int               `json:"consecutive_failures"`
+	CircuitOpenedAt       *time.Time        `json:"circuit_opened_at,omitempty"`
+	CircuitSkippedCount   int               `json:"circuit_skipped_count"`
+	LastFailureMessage    string            `json:"last_failure_message,omitempty"`
+	DisabledReason        string            `json:"disabled_reason,omitempty"`
+	DigestEnabled         bool              `json:"digest_enabled"`
+	DigestCron            string            `json:"digest_cron"`
+	DigestTimezone        string            `json:"digest_timezone"`
+	DigestNextRunAt       *time.Time        `json:"digest_next_run_at,omitempty"`
+	DigestLastSentAt      *time.Time        `json:"digest_last_sent_at,omitempty"`
+	CreatedAt             time.Time         `json:"created_at"`
+	UpdatedAt             time.Time         `json:"updated_at"`
+	Projects              []ProjectResponse `json:"projects,omitempty"`
 }
 
 type LinkProjectWebhookRequest struct {
@@ -190,6 +256,61 @@ func (w *Webhook) EnsureSettings() *WebhookSetting {
 	return w.Settings
 }
 
+// ApplyDigestSchedule 更新汇总配置并校验 cron 与时区，开启时重新计算下次发送时间
+func (w *Webhook) ApplyDigestSchedule(enabled *bool, cronExpr, timezone *string, now time.Time) error {
+	if enabled != nil {
+		w.DigestEnabled = *enabled
+	}
+	if cronExpr != nil {
+		w.DigestCron = strings.TrimSpace(*cronExpr)
+	}
+	if timezone != nil {
+		w.DigestTimezone = strings.TrimSpace(*timezone)
+	}
+
+	if !w.DigestEnabled {
+		w.DigestNextRunAt = nil
+		return nil
+	}
+
+	if w.DigestCron == "" {
+		w.DigestCron = DefaultDigestCron
+	}
+	if w.DigestTimezone == "" && w.Timezone == "" {
+		w.DigestTimezone = DefaultDigestTimezone
+	}
+
+	next, err := w.NextDigestRun(now)
+	if err != nil {
+		return err
+	}
+	w.DigestNextRunAt = &next
+	return nil
+}
+
+// NextDigestRun 按汇总的 cron 与时区计算 after 之后的下次发送时间（UTC）
+func (w *Webhook) NextDigestRun(after time.Time) (time.Time, error) {
+	schedule, err := cron.Parse(w.DigestCron)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	timezone := w.DigestTimezone
+	if timezone == "" {
+		timezone = w.Timezone
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("时区无效: %s", timezone)
+	}
+
+	next := schedule.Next(after.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron 表达式 %q 不会触发", w.DigestCron)
+	}
+	return next.UTC(), nil
+}
+
 func DetectWebhookType(rawURL string) string {
 	parsed, err := url.Parse(rawURL)
 	if err != nil {
@@ -252,3 +373,22 @@ func (m StringMap) MarshalJSON() ([]byte, error) {
 	}
 	return json.Marshal(map[string]string(m))
 }
+
+// CircuitState 返回熔断状态：closed 正常，open 熔断中
+func (w *Webhook) CircuitState() string {
+	if w.CircuitOpenedAt != nil {
+		return CircuitStateOpen
+	}
+	return CircuitStateClosed
+}
+
+// ResetCircuit 清除熔断状态与停用原因，用于重新启用或更换地址后
+func (w *Webhook) ResetCircuit() {
+	w.ConsecutiveFailures = 0
+	w.CircuitOpenedAt = nil
+	w.CircuitProbeAt = nil
+	w.CircuitSkippedCount = 0
+	w.CircuitAlertedAt = nil
+	w.LastFailureMessage = ""
+	w.DisabledReason = ""
+}
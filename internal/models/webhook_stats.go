@@ -5,14 +5,43 @@ import "time"
 // WebhookDeliveryStat 记录渠道发送配额和次数
 type WebhookDeliveryStat struct {
 	ID          uint      `json:"id" gorm:"column:id;primarykey"`
-	WebhookID   uint      `json:"webhook_id" gorm:"column:webhook_id;index;not null"`
+	WebhookID   uint      `json:"webhook_id" gorm:"column:webhook_id;index;not null;uniqueIndex:idx_webhook_delivery_stats_period"`
 	Channel     string    `json:"channel" gorm:"column:channel;not null;default:'';index"`
-	PeriodStart time.Time `json:"period_start" gorm:"column:period_start;not null;index"`
+	PeriodStart time.Time `json:"period_start" gorm:"column:period_start;not null;index;uniqueIndex:idx_webhook_delivery_stats_period"`
 	Count       uint      `json:"count" gorm:"column:count;not null;default:0"`
-	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at"`
+	// WarnedThreshold 本周期已发送过预警的最高百分比阈值，避免重复预警
+	WarnedThreshold int       `json:"warned_threshold" gorm:"column:warned_threshold;not null;default:0"`
+	CreatedAt       time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"column:updated_at"`
 }
 
 func (WebhookDeliveryStat) TableName() string {
 	return "webhook_delivery_stats"
 }
+
+// QuotaUsageResponse Webhook 当月配额使用情况
+type QuotaUsageResponse struct {
+	WebhookID          uint       `json:"webhook_id"`
+	WebhookName        string     `json:"webhook_name"`
+	Channel            string     `json:"channel"`
+	PeriodStart        time.Time  `json:"period_start"`
+	PeriodEnd          time.Time  `json:"period_end"`
+	Used               uint       `json:"used"`
+	Quota              int        `json:"quota"` // 0 表示该渠道没有月度配额
+	Remaining          int        `json:"remaining"`
+	UsagePercent       float64    `json:"usage_percent"`
+	DailyRate          float64    `json:"daily_rate"`
+	ProjectedExhaustAt *time.Time `json:"projected_exhaust_at,omitempty"` // 按当前速率预计耗尽时间，本周期内不会耗尽时为空
+	WarnedThreshold    int        `json:"warned_threshold"`
+}
+
+// RateLimitBucket 数据库中的令牌桶，多实例共享同一份速率限制
+type RateLimitBucket struct {
+	Key        string  `json:"key" gorm:"column:bucket_key;primarykey"`
+	Tokens     float64 `json:"tokens" gorm:"column:tokens;not null"`
+	RefilledAt int64   `json:"refilled_at" gorm:"column:refilled_at;not null"` // Unix 毫秒
+}
+
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+const (
+	WebhookStatusRunRunning   = "running"
+	WebhookStatusRunCompleted = "completed"
+	WebhookStatusRunFailed    = "failed"
+
+	WebhookStatusTriggerSchedule = "schedule"
+	WebhookStatusTriggerManual   = "manual"
+)
+
+// WebhookStatusRun 后台刷新项目 hook 状态的一次执行，执行中实时更新进度
+type WebhookStatusRun struct {
+	ID      uint   `json:"id" gorm:"column:id;primarykey"`
+	Trigger string `json:"trigger" gorm:"column:triggered_by;not null;default:'schedule'"`
+	Status  string `json:"status" gorm:"column:status;index;not null;default:'running'"`
+	Total   int    `json:"total" gorm:"column:total;not null;default:0"`
+	Checked int    `json:"checked" gorm:"column:checked;not null;default:0"`
+	Synced  int    `json:"synced" gorm:"column:synced;not null;default:0"`
+	Missing int    `json:"missing" gorm:"column:missing;not null;default:0"`
+	Errors  int    `json:"errors" gorm:"column:errors;not null;default:0"`
+	// Changed 状态与上次记录不一致的项目数
+	Changed    int        `json:"changed" gorm:"column:changed;not null;default:0"`
+	Error      string     `json:"error,omitempty" gorm:"column:error"`
+	StartedAt  time.Time  `json:"started_at" gorm:"column:started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" gorm:"column:finished_at"`
+}
+
+func (WebhookStatusRun) TableName() string {
+	return "webhook_status_runs"
+}
+
+// WebhookStatusResult 项目 hook 状态的缓存结果
+type WebhookStatusResult struct {
+	ProjectID        uint       `json:"project_id"`
+	ProjectName      string     `json:"project_name"`
+	WebhookSynced    bool       `json:"webhook_synced"`
+	GitLabWebhookID  *int       `json:"gitlab_webhook_id,omitempty"`
+	CoveredByGroupID *uint      `json:"covered_by_group_id,omitempty"`
+	CheckedAt        *time.Time `json:"checked_at,omitempty"`
+	Error            string     `json:"error,omitempty"`
+}
@@ -0,0 +1,249 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPollInterval = 30 * time.Second
+	defaultJobTimeout   = 10 * time.Minute
+)
+
+// Job 定时任务定义
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// Timeout 单次执行的最长时间，同时也是租约时长，超时后其他实例可以接管
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// Scheduler 基于数据库租约的定时任务调度器
+//
+// 任务的下次执行时间保存在 scheduled_jobs 表中，重启后会继续按原计划执行；
+// 多个实例同时运行时，通过条件更新抢占租约，保证同一任务同一时刻只会执行一次。
+type Scheduler struct {
+	db           *gorm.DB
+	instanceID   string
+	pollInterval time.Duration
+
+	mu      sync.Mutex
+	jobs    []Job
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+func New(db *gorm.DB) *Scheduler {
+	return &Scheduler{
+		db:           db,
+		instanceID:   newInstanceID(),
+		pollInterval: defaultPollInterval,
+	}
+}
+
+// Register 注册任务，需在 Start 之前调用
+func (s *Scheduler) Register(job Job) {
+	if job.Timeout <= 0 {
+		job.Timeout = defaultJobTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, job)
+}
+
+// Start 启动调度循环
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return nil
+	}
+
+	for _, job := range s.jobs {
+		if err := s.ensureJob(job); err != nil {
+			return fmt.Errorf("初始化定时任务 %s 失败: %w", job.Name, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.running = true
+
+	s.wg.Add(1)
+	go s.loop(ctx)
+
+	logger.GetLogger().Infof("定时任务调度器已启动，实例 %s，共 %d 个任务", s.instanceID, len(s.jobs))
+	return nil
+}
+
+// Stop 停止调度并等待正在执行的任务结束
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.cancel()
+	s.running = false
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	s.runDueJobs(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runDueJobs(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) runDueJobs(ctx context.Context) {
+	s.mu.Lock()
+	jobs := append([]Job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		acquired, err := s.acquire(job, time.Now().UTC())
+		if err != nil {
+			logger.GetLogger().Errorf("抢占定时任务 %s 失败: %v", job.Name, err)
+			continue
+		}
+		if !acquired {
+			continue
+		}
+
+		// 每个任务在独立的 goroutine 中执行，耗时较长的任务不会阻塞其他到期任务；
+		// 同一任务的重复执行由数据库租约保证不会发生
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+			s.execute(ctx, job)
+		}(job)
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, job Job) {
+	startedAt := time.Now().UTC()
+	jobCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+
+	runErr := safeRun(jobCtx, job)
+	if runErr != nil {
+		logger.GetLogger().Errorf("定时任务 %s 执行失败: %v", job.Name, runErr)
+	} else {
+		logger.GetLogger().Debugf("定时任务 %s 执行完成，耗时 %s", job.Name, time.Since(startedAt))
+	}
+
+	if err := s.release(job, startedAt, runErr); err != nil {
+		logger.GetLogger().Errorf("释放定时任务 %s 租约失败: %v", job.Name, err)
+	}
+}
+
+func safeRun(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// ensureJob 任务首次注册时写入记录，已有记录保留原来的下次执行时间
+func (s *Scheduler) ensureJob(job Job) error {
+	record := models.ScheduledJob{
+		Name:      job.Name,
+		NextRunAt: time.Now().UTC(),
+	}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error
+}
+
+// acquire 通过条件更新抢占租约，只有到期且未被其他实例持有的任务才能抢占成功
+func (s *Scheduler) acquire(job Job, now time.Time) (bool, error) {
+	lockedUntil := now.Add(job.Timeout)
+	result := s.db.Model(&models.ScheduledJob{}).
+		Where("name = ? AND next_run_at <= ?", job.Name, now).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Updates(map[string]interface{}{
+			"locked_by":    s.instanceID,
+			"locked_until": lockedUntil,
+			"updated_at":   now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (s *Scheduler) release(job Job, startedAt time.Time, runErr error) error {
+	lastError := ""
+	if runErr != nil {
+		lastError = runErr.Error()
+	}
+
+	now := time.Now().UTC()
+	nextRunAt := startedAt.Add(job.Interval)
+	if nextRunAt.Before(now) {
+		nextRunAt = now
+	}
+
+	result := s.db.Model(&models.ScheduledJob{}).
+		Where("name = ? AND locked_by = ?", job.Name, s.instanceID).
+		Updates(map[string]interface{}{
+			"next_run_at":  nextRunAt,
+			"last_run_at":  startedAt,
+			"last_error":   lastError,
+			"locked_by":    "",
+			"locked_until": nil,
+			"updated_at":   now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("租约已被其他实例接管")
+	}
+	return nil
+}
+
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(buf))
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	deliveryMaxAttempts = 5
+	deliveryRetryDelay  = time.Minute
+)
+
+type deliveryQueueService struct {
+	db            *gorm.DB
+	senderFactory SenderFactory
+	alerts        OpsAlertService
+}
+
+func NewDeliveryQueueService(db *gorm.DB, factory SenderFactory, alerts OpsAlertService) DeliveryQueueService {
+	return &deliveryQueueService{
+		db:            db,
+		senderFactory: factory,
+		alerts:        alerts,
+	}
+}
+
+// Hold 将消息放入队列，在 releaseAt 之后投递
+func (s *deliveryQueueService) Hold(webhook *models.Webhook, projectID uint, message *OutboundMessage, releaseAt time.Time, reason string) (*models.DeliveryQueueItem, error) {
+	return s.enqueue(webhook, projectID, message, releaseAt, reason)
+}
+
+// Coalesce 将合并请求消息放入合并窗口：窗口由第一条消息开启，窗口内的后续消息与其一起发送
+//
+// 先写入消息再将未到期的合并消息统一对齐到最早的窗口，写入与对齐在同一事务中，同时到达的消息不会各自开启窗口
+func (s *deliveryQueueService) Coalesce(webhook *models.Webhook, projectID uint, message *OutboundMessage, now time.Time) (*models.DeliveryQueueItem, error) {
+	item, err := newDeliveryQueueItem(webhook, projectID, message, now.Add(webhook.CoalesceWindow()), models.DeliveryReasonCoalesce)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(item).Error; err != nil {
+			return fmt.Errorf("写入投递队列失败: %w", err)
+		}
+
+		pending := tx.Model(&models.DeliveryQueueItem{}).
+			Where("webhook_id = ? AND status = ? AND reason = ? AND release_at > ?",
+				webhook.ID, models.DeliveryStatusPending, models.DeliveryReasonCoalesce, now.UTC())
+
+		var open models.DeliveryQueueItem
+		if err := pending.Session(&gorm.Session{}).Order("release_at ASC, id ASC").First(&open).Error; err != nil {
+			return fmt.Errorf("查询合并窗口失败: %w", err)
+		}
+		item.ReleaseAt = open.ReleaseAt
+		if err := pending.Session(&gorm.Session{}).Where("release_at > ?", open.ReleaseAt).Update("release_at", open.ReleaseAt).Error; err != nil {
+			return fmt.Errorf("对齐合并窗口失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *deliveryQueueService) enqueue(webhook *models.Webhook, projectID uint, message *OutboundMessage, releaseAt time.Time, reason string) (*models.DeliveryQueueItem, error) {
+	item, err := newDeliveryQueueItem(webhook, projectID, message, releaseAt, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(item).Error; err != nil {
+		return nil, fmt.Errorf("写入投递队列失败: %w", err)
+	}
+	return item, nil
+}
+
+func newDeliveryQueueItem(webhook *models.Webhook, projectID uint, message *OutboundMessage, releaseAt time.Time, reason string) (*models.DeliveryQueueItem, error) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("序列化待投递消息失败: %w", err)
+	}
+
+	return &models.DeliveryQueueItem{
+		WebhookID: webhook.ID,
+		ProjectID: projectID,
+		EventType: message.EventType,
+		Reason:    reason,
+		Payload:   string(payload),
+		Status:    models.DeliveryStatusPending,
+		ReleaseAt: releaseAt.UTC(),
+	}, nil
+}
+
+// AttachNotification 关联通知记录，队列消息发送成功后回写通知状态
+func (s *deliveryQueueService) AttachNotification(itemIDs []uint, notificationID uint) error {
+	if len(itemIDs) == 0 {
+		return nil
+	}
+	return s.db.Model(&models.DeliveryQueueItem{}).Where("id IN ?", itemIDs).Update("notification_id", notificationID).Error
+}
+
+// FlushDue 投递所有到期的消息，按 Webhook 分组，开启合并时一个 Webhook 只发送一条消息
+func (s *deliveryQueueService) FlushDue(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	var items []models.DeliveryQueueItem
+	if err := s.db.Where("status = ? AND release_at <= ?", models.DeliveryStatusPending, now).
+		Order("id ASC").
+		Find(&items).Error; err != nil {
+		return fmt.Errorf("查询待投递消息失败: %w", err)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	var webhookOrder []uint
+	byWebhook := make(map[uint][]models.DeliveryQueueItem)
+	for _, item := range items {
+		if _, ok := byWebhook[item.WebhookID]; !ok {
+			webhookOrder = append(webhookOrder, item.WebhookID)
+		}
+		byWebhook[item.WebhookID] = append(byWebhook[item.WebhookID], item)
+	}
+
+	var errs []error
+	for _, webhookID := range webhookOrder {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.flushWebhook(ctx, webhookID, byWebhook[webhookID], now); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %d: %w", webhookID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *deliveryQueueService) flushWebhook(ctx context.Context, webhookID uint, items []models.DeliveryQueueItem, now time.Time) error {
+	var webhook models.Webhook
+	if err := s.db.Preload("Settings").First(&webhook, webhookID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.markFailed(items, "webhook 已删除", models.OpsSeverityWarning)
+		}
+		return err
+	}
+	if !webhook.IsActive {
+		return s.markFailed(items, "webhook 已停用", models.OpsSeverityWarning)
+	}
+
+	messages := make([]*OutboundMessage, 0, len(items))
+	valid := make([]models.DeliveryQueueItem, 0, len(items))
+	for _, item := range items {
+		var message OutboundMessage
+		if err := json.Unmarshal([]byte(item.Payload), &message); err != nil {
+			if markErr := s.markFailed([]models.DeliveryQueueItem{item}, "消息解析失败: "+err.Error(), models.OpsSeverityCritical); markErr != nil {
+				return markErr
+			}
+			continue
+		}
+		messages = append(messages, &message)
+		valid = append(valid, item)
+	}
+
+	// 窗口可能再次关闭（例如新增了节假日），重新计算投递时间；紧急分支的消息不受投递时间限制，照常发送
+	releaseAt, err := webhook.NextDeliveryTime(now)
+	if err == nil && releaseAt.After(now) {
+		var held []models.DeliveryQueueItem
+		urgentMessages := messages[:0]
+		urgent := valid[:0]
+		for i, message := range messages {
+			if webhook.IsUrgentBranch(message.TargetBranch) {
+				urgentMessages = append(urgentMessages, message)
+				urgent = append(urgent, valid[i])
+			} else {
+				held = append(held, valid[i])
+			}
+		}
+		if len(held) > 0 {
+			if err := s.db.Model(&models.DeliveryQueueItem{}).Where("id IN ?", queueItemIDs(held)).Update("release_at", releaseAt).Error; err != nil {
+				return err
+			}
+		}
+		messages, valid = urgentMessages, urgent
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	webhook.ApplyDefaults()
+	sender, err := s.senderFactory.SenderFor(&webhook)
+	if err != nil {
+		return s.markFailed(valid, err.Error(), models.OpsSeverityCritical)
+	}
+
+	// 合并窗口内的消息总是合并，非投递时间积压的消息按配置决定是否合并
+	if len(messages) > 1 && (webhook.BatchHeldMessages || hasCoalescedItem(valid)) {
+		content := FormatHeldMessagesBatchText(messages)
+		if payloads, ok := mergeRequestPayloads(messages); ok {
+			content = FormatMergeRequestBurstText(payloads)
+		}
+		batch := &TextMessage{
+			Content:          content,
+			Mentions:         mergeMentions(messages),
+			MentionedMobiles: mergeMobiles(messages),
+			AtAll:            mergeMentionsAll(messages),
+		}
+		logger.GetLogger().Infof("合并发送 Webhook %s (%d) 队列中的 %d 条消息", webhook.Name, webhook.ID, len(messages))
+		return s.recordResult(valid, sender.SendText(ctx, &webhook, batch), now)
+	}
+
+	var errs []error
+	for i, message := range messages {
+		if err := s.recordResult([]models.DeliveryQueueItem{valid[i]}, message.SendVia(ctx, sender, &webhook), now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recordResult 记录投递结果：成功时回写通知状态，失败时按次数退避重试
+func (s *deliveryQueueService) recordResult(items []models.DeliveryQueueItem, sendErr error, now time.Time) error {
+	ids := queueItemIDs(items)
+
+	if sendErr == nil {
+		if err := s.db.Model(&models.DeliveryQueueItem{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":     models.DeliveryStatusSent,
+			"sent_at":    now,
+			"last_error": "",
+		}).Error; err != nil {
+			return err
+		}
+
+		var notificationIDs []uint
+		for _, item := range items {
+			if item.NotificationID != nil {
+				notificationIDs = append(notificationIDs, *item.NotificationID)
+			}
+		}
+		if len(notificationIDs) > 0 {
+			return s.db.Model(&models.Notification{}).Where("id IN ?", notificationIDs).Updates(map[string]interface{}{
+				"notification_sent": true,
+				"error_message":     "",
+			}).Error
+		}
+		return nil
+	}
+
+	if errors.Is(sendErr, ErrRateLimited) {
+		// 速率受限不计入失败次数，下个周期再试
+		logger.GetLogger().Infof("队列消息投递速率受限，稍后重试: %v", sendErr)
+		return s.db.Model(&models.DeliveryQueueItem{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"last_error": sendErr.Error(),
+			"release_at": now.Add(rateLimitedRetryDelay),
+		}).Error
+	}
+
+	logger.GetLogger().Warnf("队列消息投递失败: %v", sendErr)
+	var exhausted []models.DeliveryQueueItem
+	for _, item := range items {
+		attempts := item.Attempts + 1
+		updates := map[string]interface{}{
+			"attempts":   attempts,
+			"last_error": sendErr.Error(),
+			"release_at": now.Add(deliveryRetryDelay * time.Duration(attempts)),
+		}
+		if attempts >= deliveryMaxAttempts {
+			updates["status"] = models.DeliveryStatusFailed
+			exhausted = append(exhausted, item)
+		}
+		if err := s.db.Model(&models.DeliveryQueueItem{}).Where("id = ?", item.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	if len(exhausted) > 0 {
+		s.alertDeadLetters(exhausted, fmt.Sprintf("重试 %d 次后仍失败: %v", deliveryMaxAttempts, sendErr), models.OpsSeverityCritical)
+	}
+	return sendErr
+}
+
+func (s *deliveryQueueService) markFailed(items []models.DeliveryQueueItem, reason string, severity string) error {
+	logger.GetLogger().Warnf("放弃投递 %d 条队列消息: %s", len(items), reason)
+	if err := s.db.Model(&models.DeliveryQueueItem{}).Where("id IN ?", queueItemIDs(items)).Updates(map[string]interface{}{
+		"status":     models.DeliveryStatusFailed,
+		"last_error": reason,
+	}).Error; err != nil {
+		return err
+	}
+	s.alertDeadLetters(items, reason, severity)
+	return nil
+}
+
+// alertDeadLetters 放弃投递的消息发送系统告警，同一 Webhook 的告警合并去重
+func (s *deliveryQueueService) alertDeadLetters(items []models.DeliveryQueueItem, reason string, severity string) {
+	if s.alerts == nil || len(items) == 0 {
+		return
+	}
+
+	webhookID := items[0].WebhookID
+	s.alerts.Notify(OpsAlertEvent{
+		Severity: severity,
+		Category: models.OpsCategoryDeliveryDeadLetter,
+		Key:      fmt.Sprintf("delivery_dead_letter:webhook:%d", webhookID),
+		Title:    fmt.Sprintf("Webhook %d 有 %d 条队列消息放弃投递", webhookID, len(items)),
+		Detail:   fmt.Sprintf("Reason: %s\nQueue: %v", reason, queueItemIDs(items)),
+	})
+}
+
+func queueItemIDs(items []models.DeliveryQueueItem) []uint {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func mergeMobiles(messages []*OutboundMessage) []string {
+	seen := make(map[string]bool)
+	var mobiles []string
+	for _, message := range messages {
+		for _, mobile := range message.Mobiles() {
+			if mobile != "" && !seen[mobile] {
+				seen[mobile] = true
+				mobiles = append(mobiles, mobile)
+			}
+		}
+	}
+	return mobiles
+}
+
+func mergeMentions(messages []*OutboundMessage) []Mention {
+	lists := make([][]Mention, 0, len(messages))
+	for _, message := range messages {
+		// 合并后无法区分各条消息的目标分支，先按各自的分支过滤
+		lists = append(lists, activeMentions(message.MentionList(), message.TargetBranch, time.Now()))
+	}
+	return mergeMentionLists(lists...)
+}
+
+func mergeMentionsAll(messages []*OutboundMessage) bool {
+	for _, message := range messages {
+		if message.MentionsAll() {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCoalescedItem(items []models.DeliveryQueueItem) bool {
+	for _, item := range items {
+		if item.Reason == models.DeliveryReasonCoalesce {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeRequestPayloads 消息全部为合并请求通知时返回其内容
+func mergeRequestPayloads(messages []*OutboundMessage) ([]*MergeRequestPayload, bool) {
+	payloads := make([]*MergeRequestPayload, 0, len(messages))
+	for _, message := range messages {
+		if message.MergeRequest == nil {
+			return nil, false
+		}
+		payloads = append(payloads, message.MergeRequest)
+	}
+	return payloads, true
+}
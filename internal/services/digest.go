@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// DigestProjectGroup 汇总消息中按项目分组的合并请求
+type DigestProjectGroup struct {
+	ProjectName   string
+	MergeRequests []models.MergeRequest
+}
+
+// DigestResult 汇总发送结果，Queued 为 true 时汇总已放入投递队列，于 ReleaseAt 投递
+type DigestResult struct {
+	MergeRequests int
+	Queued        bool
+	ReleaseAt     *time.Time
+}
+
+type digestService struct {
+	db            *gorm.DB
+	senderFactory SenderFactory
+	queue         DeliveryQueueService
+}
+
+func NewDigestService(db *gorm.DB, factory SenderFactory, queue DeliveryQueueService) DigestService {
+	return &digestService{
+		db:            db,
+		senderFactory: factory,
+		queue:         queue,
+	}
+}
+
+// SendDueDigests 发送所有到期的汇总，并按 cron 计算下次发送时间
+func (s *digestService) SendDueDigests(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	var webhooks []models.Webhook
+	if err := s.db.Preload("Settings").
+		Where("digest_enabled = ? AND is_active = ? AND digest_next_run_at <= ?", true, true, now).
+		Find(&webhooks).Error; err != nil {
+		return fmt.Errorf("查询待发送汇总失败: %w", err)
+	}
+
+	var errs []error
+	for i := range webhooks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		webhook := &webhooks[i]
+		// 先推进下次发送时间，发送失败也不会在下个周期前重复发送
+		if err := s.advanceSchedule(webhook, now); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if _, err := s.send(ctx, webhook, false); err != nil {
+			logger.GetLogger().Warnf("发送 Webhook %s (%d) 汇总失败: %v", webhook.Name, webhook.ID, err)
+			errs = append(errs, fmt.Errorf("webhook %d: %w", webhook.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// SendDigest 立即发送指定 Webhook 的汇总，不影响定时计划；与其他消息一样受投递时间与速率限制约束
+func (s *digestService) SendDigest(ctx context.Context, webhookID uint) (*DigestResult, error) {
+	var webhook models.Webhook
+	if err := s.db.Preload("Settings").First(&webhook, webhookID).Error; err != nil {
+		return nil, fmt.Errorf("webhook not found: %w", err)
+	}
+	if !webhook.IsActive {
+		return nil, fmt.Errorf("Webhook 未启用")
+	}
+
+	return s.send(ctx, &webhook, true)
+}
+
+func (s *digestService) advanceSchedule(webhook *models.Webhook, now time.Time) error {
+	updates := map[string]interface{}{}
+	next, err := webhook.NextDigestRun(now)
+	if err != nil {
+		// 配置失效时关闭汇总，避免每分钟重复报错
+		logger.GetLogger().Warnf("Webhook %d 汇总配置无效，已关闭汇总: %v", webhook.ID, err)
+		updates["digest_enabled"] = false
+		updates["digest_next_run_at"] = nil
+	} else {
+		updates["digest_next_run_at"] = next
+	}
+
+	if dbErr := s.db.Model(&models.Webhook{}).Where("id = ?", webhook.ID).Updates(updates).Error; dbErr != nil {
+		return fmt.Errorf("更新 Webhook %d 汇总计划失败: %w", webhook.ID, dbErr)
+	}
+	return err
+}
+
+// send 通过投递队列的统一路径发送汇总：非投递时间放入队列，速率受限时稍后重试
+func (s *digestService) send(ctx context.Context, webhook *models.Webhook, force bool) (*DigestResult, error) {
+	groups, total, err := s.collectOpenMergeRequests(webhook.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DigestResult{MergeRequests: total}
+	// 定时汇总在没有打开的合并请求时不打扰
+	if total == 0 && !force {
+		logger.GetLogger().Infof("Webhook %s (%d) 没有打开的合并请求，跳过汇总", webhook.Name, webhook.ID)
+		return result, nil
+	}
+
+	loc := webhook.Location()
+	if webhook.DigestTimezone != "" {
+		if parsed, err := time.LoadLocation(webhook.DigestTimezone); err == nil {
+			loc = parsed
+		}
+	}
+
+	webhook.ApplyDefaults()
+	sender, err := s.senderFactory.SenderFor(webhook)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sender for webhook %d: %w", webhook.ID, err)
+	}
+
+	now := time.Now()
+	message := &OutboundMessage{
+		EventType: models.EventTypeDigest,
+		Text:      &TextMessage{Content: FormatOpenMergeRequestDigestText(now.In(loc), groups, total)},
+	}
+	item, err := deliverOrHold(ctx, s.queue, sender, webhook, 0, message, now)
+	if err != nil {
+		return nil, err
+	}
+	if item != nil {
+		result.Queued = true
+		result.ReleaseAt = &item.ReleaseAt
+		logger.GetLogger().Infof("Webhook %s (%d) 汇总已放入投递队列，共 %d 个合并请求", webhook.Name, webhook.ID, total)
+		return result, nil
+	}
+
+	if err := s.db.Model(&models.Webhook{}).Where("id = ?", webhook.ID).Update("digest_last_sent_at", now.UTC()).Error; err != nil {
+		logger.GetLogger().Warnf("更新 Webhook %d 汇总发送时间失败: %v", webhook.ID, err)
+	}
+
+	logger.GetLogger().Infof("已发送 Webhook %s (%d) 汇总，共 %d 个合并请求", webhook.Name, webhook.ID, total)
+	return result, nil
+}
+
+func (s *digestService) collectOpenMergeRequests(webhookID uint) ([]DigestProjectGroup, int, error) {
+	var projects []models.Project
+	if err := s.db.Where("id IN (SELECT project_id FROM project_webhooks WHERE webhook_id = ?)", webhookID).
+		Order("name ASC").
+		Find(&projects).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询 Webhook 关联项目失败: %w", err)
+	}
+	if len(projects) == 0 {
+		return nil, 0, nil
+	}
+
+	projectIDs := make([]uint, 0, len(projects))
+	for _, project := range projects {
+		projectIDs = append(projectIDs, project.ID)
+	}
+
+	var mergeRequests []models.MergeRequest
+	if err := s.db.Where("project_id IN ? AND state = ?", projectIDs, models.MergeRequestStateOpened).
+		Order("opened_at ASC").
+		Find(&mergeRequests).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询打开的合并请求失败: %w", err)
+	}
+
+	byProject := make(map[uint][]models.MergeRequest)
+	for _, mr := range mergeRequests {
+		byProject[mr.ProjectID] = append(byProject[mr.ProjectID], mr)
+	}
+
+	groups := make([]DigestProjectGroup, 0, len(byProject))
+	for _, project := range projects {
+		if mrs := byProject[project.ID]; len(mrs) > 0 {
+			groups = append(groups, DigestProjectGroup{ProjectName: project.Name, MergeRequests: mrs})
+		}
+	}
+
+	return groups, len(mergeRequests), nil
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type escalationService struct {
+	db            *gorm.DB
+	senderFactory SenderFactory
+	notifier      NotificationService
+}
+
+func NewEscalationService(db *gorm.DB, factory SenderFactory, notifier NotificationService) EscalationService {
+	return &escalationService{
+		db:            db,
+		senderFactory: factory,
+		notifier:      notifier,
+	}
+}
+
+// EvaluateEscalations 遍历启用的升级策略，对长时间未获批准的合并请求逐级升级提醒
+func (s *escalationService) EvaluateEscalations(ctx context.Context) error {
+	var policies []models.EscalationPolicy
+	if err := s.db.Where("enabled = ?", true).Order("project_id ASC, id ASC").Find(&policies).Error; err != nil {
+		return fmt.Errorf("查询升级策略失败: %w", err)
+	}
+
+	var errs []error
+	for i := range policies {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.evaluatePolicy(ctx, &policies[i]); err != nil {
+			logger.GetLogger().Warnf("升级策略 %s (%d) 执行失败: %v", policies[i].Name, policies[i].ID, err)
+			errs = append(errs, fmt.Errorf("policy %d: %w", policies[i].ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *escalationService) ListPolicies(projectID uint) ([]models.EscalationPolicy, error) {
+	var policies []models.EscalationPolicy
+	err := s.db.Where("project_id = ?", projectID).Order("id ASC").Find(&policies).Error
+	return policies, err
+}
+
+func (s *escalationService) CreatePolicy(projectID uint, req *models.EscalationPolicyRequest) (*models.EscalationPolicy, error) {
+	policy := models.NewEscalationPolicy(projectID)
+	if err := policy.Apply(req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&policy).Error; err != nil {
+		return nil, fmt.Errorf("保存升级策略失败: %w", err)
+	}
+	return &policy, nil
+}
+
+func (s *escalationService) UpdatePolicy(projectID, policyID uint, req *models.EscalationPolicyRequest) (*models.EscalationPolicy, error) {
+	var policy models.EscalationPolicy
+	if err := s.db.Where("id = ? AND project_id = ?", policyID, projectID).First(&policy).Error; err != nil {
+		return nil, err
+	}
+	if err := policy.Apply(req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&policy).Error; err != nil {
+		return nil, fmt.Errorf("保存升级策略失败: %w", err)
+	}
+	return &policy, nil
+}
+
+func (s *escalationService) DeletePolicy(projectID, policyID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND project_id = ?", policyID, projectID).Delete(&models.EscalationPolicy{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("policy_id = ?", policyID).Delete(&models.EscalationEvent{}).Error
+	})
+}
+
+func (s *escalationService) ListHistory(projectID uint, limit int) ([]models.EscalationEventResponse, error) {
+	var events []models.EscalationEvent
+	if err := s.db.Preload("Policy").Preload("MergeRequest").
+		Where("project_id = ?", projectID).
+		Order("fired_at DESC, id DESC").
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.EscalationEventResponse, 0, len(events))
+	for _, event := range events {
+		responses = append(responses, models.EscalationEventResponse{
+			ID:                event.ID,
+			PolicyID:          event.PolicyID,
+			PolicyName:        event.Policy.Name,
+			MergeRequestID:    event.MergeRequestID,
+			MergeRequestIID:   event.MergeRequest.IID,
+			MergeRequestTitle: event.MergeRequest.Title,
+			Step:              event.Step,
+			WebhookID:         event.WebhookID,
+			Sent:              event.Sent,
+			ErrorMessage:      event.ErrorMessage,
+			FiredAt:           event.FiredAt,
+		})
+	}
+	return responses, nil
+}
+
+func (s *escalationService) evaluatePolicy(ctx context.Context, policy *models.EscalationPolicy) error {
+	now := time.Now().UTC()
+
+	query := s.db.Where("project_id = ? AND state = ? AND approved_at IS NULL", policy.ProjectID, models.MergeRequestStateOpened).
+		Where("opened_at <= ?", now.Add(-time.Duration(policy.MentionAllAfterHours)*time.Hour))
+	if policy.SkipDrafts {
+		query = query.Where("draft = ?", false)
+	}
+
+	var mergeRequests []models.MergeRequest
+	if err := query.Order("opened_at ASC").Find(&mergeRequests).Error; err != nil {
+		return fmt.Errorf("查询待升级合并请求失败: %w", err)
+	}
+	if len(mergeRequests) == 0 {
+		return nil
+	}
+
+	fired, err := s.firedSteps(policy.ID)
+	if err != nil {
+		return err
+	}
+
+	var project *models.Project
+	var errs []error
+	for i := range mergeRequests {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		mr := &mergeRequests[i]
+		if !policy.MatchesTargetBranch(mr.TargetBranch) || mr.OpenedAt == nil {
+			continue
+		}
+
+		waiting := now.Sub(*mr.OpenedAt)
+		due := policy.StepDue(waiting)
+		for step := models.EscalationStepMentionAll; step <= due; step++ {
+			if fired[escalationKey{mr.ID, step}] {
+				continue
+			}
+
+			if project == nil {
+				project = &models.Project{}
+				if err := s.db.Preload("Webhooks").Preload("Webhooks.Settings").First(project, policy.ProjectID).Error; err != nil {
+					return fmt.Errorf("project not found: %w", err)
+				}
+			}
+
+			if err := s.fireStep(ctx, project, policy, mr, step, waiting, now); err != nil {
+				errs = append(errs, fmt.Errorf("!%d step %d: %w", mr.IID, step, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+type escalationKey struct {
+	mergeRequestID uint
+	step           int
+}
+
+func (s *escalationService) firedSteps(policyID uint) (map[escalationKey]bool, error) {
+	var events []models.EscalationEvent
+	if err := s.db.Select("merge_request_id", "step").Where("policy_id = ?", policyID).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("查询升级记录失败: %w", err)
+	}
+
+	fired := make(map[escalationKey]bool, len(events))
+	for _, event := range events {
+		fired[escalationKey{event.MergeRequestID, event.Step}] = true
+	}
+	return fired, nil
+}
+
+// fireStep 先写入升级记录占用该级别，写入成功才发送，避免并发执行时重复升级
+func (s *escalationService) fireStep(ctx context.Context, project *models.Project, policy *models.EscalationPolicy, mr *models.MergeRequest, step int, waiting time.Duration, now time.Time) error {
+	event := models.EscalationEvent{
+		PolicyID:       policy.ID,
+		MergeRequestID: mr.ID,
+		Step:           step,
+		ProjectID:      project.ID,
+		FiredAt:        now,
+	}
+	if step == models.EscalationStepWebhook {
+		event.WebhookID = policy.EscalationWebhookID
+	}
+
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+	if result.Error != nil {
+		return fmt.Errorf("写入升级记录失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	content := FormatMergeRequestEscalationText(project.Name, mr, waiting, policy.Name, step)
+	logger.GetLogger().Infof("合并请求升级提醒 - 项目: %s, !%d, 策略: %s, 第 %d 级", project.Name, mr.IID, policy.Name, step)
+
+	var sendErr error
+	switch step {
+	case models.EscalationStepMentionAll:
+		notification := &models.Notification{
+			EventType:      models.EventTypeMREscalation,
+			MergeRequestID: mr.IID,
+			Title:          mr.Title,
+			SourceBranch:   mr.SourceBranch,
+			TargetBranch:   mr.TargetBranch,
+			AuthorEmail:    mr.AuthorName,
+			Status:         fmt.Sprintf("escalation_%d", step),
+		}
+		sendErr = s.notifier.SendProjectMessage(ctx, project, notification, &TextMessage{Content: content, AtAll: true})
+		if sendErr == nil && notification.ErrorMessage != "" {
+			sendErr = errors.New(notification.ErrorMessage)
+		}
+	case models.EscalationStepWebhook:
+		sendErr = s.sendToEscalationWebhook(ctx, policy, &TextMessage{Content: content, AtAll: true})
+	}
+
+	updates := map[string]interface{}{"sent": sendErr == nil}
+	if sendErr != nil {
+		updates["error_message"] = sendErr.Error()
+	}
+	if err := s.db.Model(&models.EscalationEvent{}).Where("id = ?", event.ID).Updates(updates).Error; err != nil {
+		logger.GetLogger().Warnf("更新升级记录 %d 失败: %v", event.ID, err)
+	}
+
+	return sendErr
+}
+
+// sendToEscalationWebhook 升级渠道直接发送，不受投递时间窗口限制
+func (s *escalationService) sendToEscalationWebhook(ctx context.Context, policy *models.EscalationPolicy, message *TextMessage) error {
+	if policy.EscalationWebhookID == nil {
+		return fmt.Errorf("未配置升级渠道")
+	}
+
+	var webhook models.Webhook
+	if err := s.db.Preload("Settings").First(&webhook, *policy.EscalationWebhookID).Error; err != nil {
+		return fmt.Errorf("升级渠道不存在: %w", err)
+	}
+	if !webhook.IsActive {
+		return fmt.Errorf("升级渠道 %s 未启用", webhook.Name)
+	}
+
+	webhook.ApplyDefaults()
+	sender, err := s.senderFactory.SenderFor(&webhook)
+	if err != nil {
+		return fmt.Errorf("failed to find sender for webhook %d: %w", webhook.ID, err)
+	}
+	return sender.SendText(ctx, &webhook, message)
+}
@@ -1,27 +1,37 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
-	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
 )
 
+// ErrGitLabGroupURL 地址不是项目，可能指向组
+var ErrGitLabGroupURL = errors.New("gitlab: url is not a project")
+
 type gitLabService struct {
 	baseURL     string
 	accessToken string
-	client      *http.Client
+	instances   GitLabInstanceService
+	client      *gitLabClient
 }
 
-func NewGitLabService(baseURL, accessToken string) GitLabService {
+// NewGitLabService 创建 GitLab API 客户端，instances 不为空时 ParseGitLabURL 会识别 URL 所属的实例
+func NewGitLabService(baseURL, accessToken string, instances GitLabInstanceService, apiCfg config.GitLabAPIConfig) GitLabService {
 	return &gitLabService{
 		baseURL:     baseURL,
 		accessToken: accessToken,
-		client:      &http.Client{Timeout: 30 * time.Second},
+		instances:   instances,
+		client:      newGitLabClient(apiCfg),
 	}
 }
 
@@ -33,6 +43,7 @@ type GitLabProjectInfo struct {
 	Description       string `json:"description"`
 	DefaultBranch     string `json:"default_branch"`
 	Visibility        string `json:"visibility"`
+	Archived          bool   `json:"archived"`
 }
 
 type ParsedGitLabURL struct {
@@ -41,6 +52,9 @@ type ParsedGitLabURL struct {
 	IsGroup     bool // 是否为组URL
 	IsValid     bool
 	Error       string
+	// InstanceID URL 所属的 GitLab 实例，未登记的地址为 0
+	InstanceID   uint
+	InstanceName string
 }
 
 // GitLabGroupInfo GitLab组信息
@@ -74,6 +88,118 @@ type GitLabWebhook struct {
 	UpdatedAt                string `json:"updated_at"`
 }
 
+// GitLabMergeRequestInfo GitLab API 返回的合并请求信息
+type GitLabMergeRequestInfo struct {
+	ID             int                 `json:"id"`
+	IID            int                 `json:"iid"`
+	ProjectID      int                 `json:"project_id"`
+	Title          string              `json:"title"`
+	State          string              `json:"state"`
+	Draft          bool                `json:"draft"`
+	WorkInProgress bool                `json:"work_in_progress"`
+	SourceBranch   string              `json:"source_branch"`
+	TargetBranch   string              `json:"target_branch"`
+	WebURL         string              `json:"web_url"`
+	Author         models.GitLabUser   `json:"author"`
+	Assignees      []models.GitLabUser `json:"assignees"`
+	Reviewers      []models.GitLabUser `json:"reviewers"`
+	Labels         []string            `json:"labels"`
+	CreatedAt      string              `json:"created_at"`
+	UpdatedAt      string              `json:"updated_at"`
+}
+
+// GitLabMergeRequestDetail 单个合并请求接口中用于补充通知的字段
+type GitLabMergeRequestDetail struct {
+	IID int `json:"iid"`
+	// ChangesCount 变更文件数，超过 GitLab 的统计上限时为 "1000+"
+	ChangesCount string              `json:"changes_count"`
+	HasConflicts bool                `json:"has_conflicts"`
+	Labels       []string            `json:"labels"`
+	Milestone    *GitLabMilestone    `json:"milestone"`
+	HeadPipeline *GitLabPipelineInfo `json:"head_pipeline"`
+	UpdatedAt    string              `json:"updated_at"`
+}
+
+type GitLabMilestone struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+type GitLabPipelineInfo struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Ref    string `json:"ref"`
+	WebURL string `json:"web_url"`
+}
+
+// GitLabMergeRequestApprovals 合并请求的审批情况
+type GitLabMergeRequestApprovals struct {
+	ApprovalsRequired int `json:"approvals_required"`
+	ApprovalsLeft     int `json:"approvals_left"`
+	ApprovedBy        []struct {
+		User models.GitLabUser `json:"user"`
+	} `json:"approved_by"`
+}
+
+// GitLabMergeRequestDiff 合并请求中单个文件的 diff
+type GitLabMergeRequestDiff struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	Diff        string `json:"diff"`
+	NewFile     bool   `json:"new_file"`
+	DeletedFile bool   `json:"deleted_file"`
+}
+
+// GitLabUserDetail 用户接口返回的字段；Email 仅在使用管理员令牌时返回
+type GitLabUserDetail struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Name        string `json:"name"`
+	PublicEmail string `json:"public_email"`
+	Email       string `json:"email"`
+}
+
+// GitLabMember 组或项目成员；Email 仅在管理员令牌或组开启 SAML 时返回
+type GitLabMember struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	AccessLevel int    `json:"access_level"`
+	Email       string `json:"email"`
+}
+
+// ToWebhookData 转换为 webhook 事件结构，便于复用合并请求状态跟踪逻辑
+func (m *GitLabMergeRequestInfo) ToWebhookData() *models.GitLabWebhookData {
+	labels := make([]models.GitLabLabel, 0, len(m.Labels))
+	for _, title := range m.Labels {
+		labels = append(labels, models.GitLabLabel{Title: title})
+	}
+
+	return &models.GitLabWebhookData{
+		ObjectKind: models.EventTypeMergeRequest,
+		User:       m.Author,
+		Project:    models.GitLabProject{ID: m.ProjectID},
+		ObjectAttributes: models.GitLabMergeRequest{
+			ID:             m.ID,
+			IID:            m.IID,
+			Title:          m.Title,
+			State:          m.State,
+			SourceBranch:   m.SourceBranch,
+			TargetBranch:   m.TargetBranch,
+			URL:            m.WebURL,
+			AuthorID:       m.Author.ID,
+			Draft:          m.Draft,
+			WorkInProgress: m.WorkInProgress,
+			CreatedAt:      m.CreatedAt,
+			UpdatedAt:      m.UpdatedAt,
+		},
+		Assignees: m.Assignees,
+		Reviewers: m.Reviewers,
+		Labels:    labels,
+	}
+}
+
 // CreateWebhookRequest 创建Webhook请求结构
 type CreateWebhookRequest struct {
 	URL                      string `json:"url"`
@@ -93,6 +219,63 @@ type CreateWebhookRequest struct {
 	Token                    string `json:"token,omitempty"`
 }
 
+// GitLabHookEvents 项目 hook 需要开启的事件
+type GitLabHookEvents struct {
+	MergeRequests bool
+	Push          bool
+	TagPush       bool
+	Releases      bool
+	Pipeline      bool
+	// SSLVerification 期望的 SSL 证书校验开关
+	SSLVerification bool
+	// SecretToken 写入 hook 的 Secret Token，GitLab 不回显该值，配置后每次同步都会更新 hook
+	SecretToken string
+}
+
+// HookEventsForProject 根据项目的事件订阅生成 hook 事件配置
+func HookEventsForProject(project *models.Project) GitLabHookEvents {
+	return GitLabHookEvents{
+		MergeRequests: project.MergeRequestEvents,
+		Push:          project.PushEvents,
+		TagPush:       project.TagPushEvents,
+		Releases:      project.ReleaseEvents,
+		// 流水线事件用于更新合并请求的流水线状态
+		Pipeline: project.MergeRequestEvents,
+	}
+}
+
+// Matches 判断已有 hook 的事件开关与 SSL 校验是否与期望一致
+func (e GitLabHookEvents) Matches(hook *GitLabWebhook) bool {
+	return len(e.Drift(hook)) == 0
+}
+
+// Drift 列出已有 hook 与期望不一致的配置，Secret Token 不会被 GitLab 回显，无法在此比较
+func (e GitLabHookEvents) Drift(hook *GitLabWebhook) []models.WebhookDriftItem {
+	var drift []models.WebhookDriftItem
+	compare := func(field string, expected, actual bool) {
+		if expected != actual {
+			drift = append(drift, models.WebhookDriftItem{Field: field, Expected: expected, Actual: actual})
+		}
+	}
+	compare(models.WebhookDriftFieldMergeRequestsEvents, e.MergeRequests, hook.MergeRequestsEvents)
+	compare(models.WebhookDriftFieldPushEvents, e.Push, hook.PushEvents)
+	compare(models.WebhookDriftFieldTagPushEvents, e.TagPush, hook.TagPushEvents)
+	compare(models.WebhookDriftFieldReleasesEvents, e.Releases, hook.ReleasesEvents)
+	compare(models.WebhookDriftFieldPipelineEvents, e.Pipeline, hook.PipelineEvents)
+	compare(models.WebhookDriftFieldSSLVerification, e.SSLVerification, hook.EnableSSLVerification)
+	return drift
+}
+
+func (e GitLabHookEvents) apply(req *CreateWebhookRequest) {
+	req.MergeRequestsEvents = e.MergeRequests
+	req.PushEvents = e.Push
+	req.TagPushEvents = e.TagPush
+	req.ReleasesEvents = e.Releases
+	req.PipelineEvents = e.Pipeline
+	req.EnableSSLVerification = e.SSLVerification
+	req.Token = e.SecretToken
+}
+
 // ParseGitLabURL 解析GitLab项目URL，提取基础URL和项目路径
 func (s *gitLabService) ParseGitLabURL(projectURL string) *ParsedGitLabURL {
 	result := &ParsedGitLabURL{}
@@ -121,16 +304,27 @@ func (s *gitLabService) ParseGitLabURL(projectURL string) *ParsedGitLabURL {
 		return result
 	}
 
-	// 构建基础URL
+	// 构建基础URL，Host 已包含端口
 	result.BaseURL = fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
-	if parsedURL.Port() != "" && parsedURL.Port() != "80" && parsedURL.Port() != "443" {
-		result.BaseURL = fmt.Sprintf("%s:%s", result.BaseURL, parsedURL.Port())
-	}
 
 	// 提取项目路径
 	path := strings.TrimPrefix(parsedURL.Path, "/")
 	path = strings.TrimSuffix(path, "/")
 
+	// 已登记的实例以实例地址为准，部署在子路径下的实例需要从项目路径中去掉子路径
+	if s.instances != nil {
+		if instance := s.instances.ResolveURL(projectURL); instance != nil {
+			result.BaseURL = instance.BaseURL
+			result.InstanceID = instance.ID
+			result.InstanceName = instance.Name
+			if instanceURL, err := url.Parse(instance.BaseURL); err == nil {
+				if prefix := strings.Trim(instanceURL.Path, "/"); prefix != "" {
+					path = strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/")
+				}
+			}
+		}
+	}
+
 	// 移除GitLab特有的路径后缀
 	gitlabSuffixes := []string{
 		"/-/tree/",
@@ -181,152 +375,93 @@ func (s *gitLabService) ParseGitLabURL(projectURL string) *ParsedGitLabURL {
 	return result
 }
 
-// GetProjectByURL 通过URL和token获取项目信息
-func (s *gitLabService) GetProjectByURL(projectURL, accessToken string) (*GitLabProjectInfo, error) {
-	// 解析URL
+// GetProjectByURL 通过URL和token获取项目信息，项目不存在时返回 ErrGitLabGroupURL 以便按组处理
+func (s *gitLabService) GetProjectByURL(ctx context.Context, projectURL, accessToken string) (*GitLabProjectInfo, error) {
 	parsed := s.ParseGitLabURL(projectURL)
 	if !parsed.IsValid {
 		return nil, fmt.Errorf("URL解析失败: %s", parsed.Error)
 	}
 
-	// 首先尝试作为项目获取
-	projectInfo, err := s.GetProjectByPath(parsed.BaseURL, parsed.ProjectPath, accessToken)
-	if err == nil {
-		return projectInfo, nil
+	projectInfo, err := s.GetProjectByPath(ctx, parsed.BaseURL, parsed.ProjectPath, accessToken)
+	if errors.Is(err, ErrGitLabNotFound) {
+		return nil, ErrGitLabGroupURL
 	}
-
-	// 如果失败了，检查是否因为这是一个组URL
-	if strings.Contains(err.Error(), "项目不存在") || strings.Contains(err.Error(), "404") {
-		// 返回特殊错误标识这是组URL
-		return nil, fmt.Errorf("GROUP_URL")
-	}
-
-	// 其他错误直接返回
-	return nil, err
+	return projectInfo, err
 }
 
-// GetProjectByPath 通过路径获取项目信息
-func (s *gitLabService) GetProjectByPath(baseURL, projectPath, accessToken string) (*GitLabProjectInfo, error) {
-	// URL编码项目路径
-	encodedPath := url.QueryEscape(projectPath)
-	apiURL := fmt.Sprintf("%s/api/v4/projects/%s", baseURL, encodedPath)
-
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	// 设置认证头
-	if accessToken != "" {
-		// GitLab支持多种token格式
-		if strings.HasPrefix(accessToken, "glpat-") || strings.HasPrefix(accessToken, "glcbt-") {
-			req.Header.Set("Authorization", "Bearer "+accessToken)
-		} else {
-			req.Header.Set("PRIVATE-TOKEN", accessToken)
-		}
-	}
-
-	req.Header.Set("User-Agent", "GitLab-Merge-Alert/1.0")
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// 处理不同的HTTP状态码
-	switch resp.StatusCode {
-	case http.StatusOK:
-		// 成功，继续处理
-	case http.StatusUnauthorized:
-		return nil, fmt.Errorf("访问令牌无效或已过期")
-	case http.StatusForbidden:
-		return nil, fmt.Errorf("没有权限访问此项目")
-	case http.StatusNotFound:
-		return nil, fmt.Errorf("项目不存在或无权限访问")
-	case http.StatusTooManyRequests:
-		return nil, fmt.Errorf("API请求频率过高，请稍后重试")
-	default:
-		return nil, fmt.Errorf("GitLab API返回错误状态: %d", resp.StatusCode)
-	}
-
+// GetProjectByPath 通过路径或数字ID获取项目信息
+func (s *gitLabService) GetProjectByPath(ctx context.Context, baseURL, projectPath, accessToken string) (*GitLabProjectInfo, error) {
 	var project GitLabProjectInfo
-	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
+	if _, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    "/projects/" + url.PathEscape(projectPath),
+		token:   accessToken,
+	}, &project); err != nil {
+		return nil, err
 	}
-
 	return &project, nil
 }
 
 // GetProject 通过项目ID获取项目信息（保持原有兼容性）
 // 接受一个可选的accessToken参数，如果提供，则优先使用该token进行认证
-func (s *gitLabService) GetProject(projectID int, accessToken ...string) (*GitLabProjectInfo, error) {
+func (s *gitLabService) GetProject(ctx context.Context, projectID int, accessToken ...string) (*GitLabProjectInfo, error) {
 	token := s.accessToken
 	if len(accessToken) > 0 && accessToken[0] != "" {
 		token = accessToken[0]
 	}
-	return s.GetProjectByPath(s.baseURL, fmt.Sprintf("%d", projectID), token)
+	return s.GetProjectByPath(ctx, s.baseURL, strconv.Itoa(projectID), token)
 }
 
 // TestConnection 测试GitLab连接和token有效性
-func (s *gitLabService) TestConnection(baseURL, accessToken string) error {
-	apiURL := fmt.Sprintf("%s/api/v4/user", baseURL)
-
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	if accessToken != "" {
-		if strings.HasPrefix(accessToken, "glpat-") || strings.HasPrefix(accessToken, "glcbt-") {
-			req.Header.Set("Authorization", "Bearer "+accessToken)
-		} else {
-			req.Header.Set("PRIVATE-TOKEN", accessToken)
-		}
-	}
-
-	req.Header.Set("User-Agent", "GitLab-Merge-Alert/1.0")
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("连接失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	switch resp.StatusCode {
-	case http.StatusOK:
-		return nil
-	case http.StatusUnauthorized:
-		return fmt.Errorf("访问令牌无效")
-	case http.StatusForbidden:
-		return fmt.Errorf("访问令牌权限不足")
-	default:
-		return fmt.Errorf("连接测试失败，状态码: %d", resp.StatusCode)
-	}
+func (s *gitLabService) TestConnection(ctx context.Context, baseURL, accessToken string) error {
+	_, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    "/user",
+		token:   accessToken,
+	}, nil)
+	return err
 }
 
 // GetGroupProjects 获取组下所有项目（包括子组项目）
-func (s *gitLabService) GetGroupProjects(baseURL, groupPath, accessToken string) ([]*GitLabProjectInfo, error) {
-	var allProjects []*GitLabProjectInfo
-
-	// 获取组直接下的项目
-	projects, err := s.getGroupDirectProjects(baseURL, groupPath, accessToken)
+// allowPartial 为 true 时子组获取失败只记录日志并返回已获取的项目，适用于一次性导入；
+// 同步等需要完整列表的场景应传 false，避免把未列出的项目误判为已删除
+func (s *gitLabService) GetGroupProjects(ctx context.Context, baseURL, groupPath, accessToken string, allowPartial bool) ([]*GitLabProjectInfo, error) {
+	allProjects, err := gitLabListAll[GitLabProjectInfo](ctx, s.client, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    "/groups/" + url.PathEscape(groupPath) + "/projects",
+		query:   url.Values{"simple": {"false"}},
+		token:   accessToken,
+	})
 	if err != nil {
 		return nil, err
 	}
-	allProjects = append(allProjects, projects...)
 
-	// 获取子组
-	subgroups, err := s.getSubgroups(baseURL, groupPath, accessToken)
+	subgroups, err := gitLabListAll[GitLabGroupInfo](ctx, s.client, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    "/groups/" + url.PathEscape(groupPath) + "/subgroups",
+		token:   accessToken,
+	})
 	if err != nil {
-		return nil, err
+		if ctx.Err() != nil || !allowPartial {
+			return nil, fmt.Errorf("获取组 %s 的子组失败: %w", groupPath, err)
+		}
+		// 无权限访问子组时只返回组直接下的项目
+		logger.GetLogger().Debugf("获取组 %s 的子组失败: %v", groupPath, err)
+		return allProjects, nil
 	}
 
-	// 递归获取子组的项目
+	// 递归获取子组的项目，允许部分结果时单个子组失败不影响其他子组
 	for _, subgroup := range subgroups {
-		subgroupProjects, err := s.GetGroupProjects(baseURL, subgroup.FullPath, accessToken)
+		subgroupProjects, err := s.GetGroupProjects(ctx, baseURL, subgroup.FullPath, accessToken, allowPartial)
 		if err != nil {
-			// 记录错误但继续处理其他子组
+			if ctx.Err() != nil || !allowPartial {
+				return nil, fmt.Errorf("获取子组 %s 的项目失败: %w", subgroup.FullPath, err)
+			}
+			logger.GetLogger().Debugf("获取子组 %s 的项目失败: %v", subgroup.FullPath, err)
 			continue
 		}
 		allProjects = append(allProjects, subgroupProjects...)
@@ -335,408 +470,478 @@ func (s *gitLabService) GetGroupProjects(baseURL, groupPath, accessToken string)
 	return allProjects, nil
 }
 
-// getGroupDirectProjects 获取组直接下的项目
-func (s *gitLabService) getGroupDirectProjects(baseURL, groupPath, accessToken string) ([]*GitLabProjectInfo, error) {
-	encodedPath := url.QueryEscape(groupPath)
-	apiURL := fmt.Sprintf("%s/api/v4/groups/%s/projects", baseURL, encodedPath)
-
-	var allProjects []*GitLabProjectInfo
-	page := 1
-	perPage := 100
-
-	for {
-		// 添加分页参数
-		paginatedURL := fmt.Sprintf("%s?page=%d&per_page=%d&simple=false", apiURL, page, perPage)
-
-		req, err := http.NewRequest("GET", paginatedURL, nil)
-		if err != nil {
-			return nil, fmt.Errorf("创建请求失败: %v", err)
-		}
-
-		// 设置认证头
-		if accessToken != "" {
-			if strings.HasPrefix(accessToken, "glpat-") || strings.HasPrefix(accessToken, "glcbt-") {
-				req.Header.Set("Authorization", "Bearer "+accessToken)
-			} else {
-				req.Header.Set("PRIVATE-TOKEN", accessToken)
-			}
-		}
-
-		req.Header.Set("User-Agent", "GitLab-Merge-Alert/1.0")
+// GetGroupByPath 获取组信息
+func (s *gitLabService) GetGroupByPath(ctx context.Context, baseURL, groupPath, accessToken string) (*GitLabGroupInfo, error) {
+	var group GitLabGroupInfo
+	if _, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    "/groups/" + url.PathEscape(groupPath),
+		token:   accessToken,
+	}, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
 
-		resp, err := s.client.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("请求失败: %v", err)
-		}
-		defer resp.Body.Close()
-
-		// 处理HTTP状态码
-		switch resp.StatusCode {
-		case http.StatusOK:
-			// 成功，继续处理
-		case http.StatusUnauthorized:
-			return nil, fmt.Errorf("访问令牌无效或已过期")
-		case http.StatusForbidden:
-			return nil, fmt.Errorf("没有权限访问此组")
-		case http.StatusNotFound:
-			return nil, fmt.Errorf("组不存在或无权限访问")
-		default:
-			return nil, fmt.Errorf("GitLab API返回错误状态: %d", resp.StatusCode)
-		}
+// ValidateProjectURL 验证项目URL并返回项目ID（保持向后兼容性）
+func (s *gitLabService) ValidateProjectURL(ctx context.Context, projectURL string) (int, error) {
+	parsed := s.ParseGitLabURL(projectURL)
+	if !parsed.IsValid {
+		return 0, errors.New(parsed.Error)
+	}
 
-		var projects []GitLabProjectInfo
-		if err := json.NewDecoder(resp.Body).Decode(&projects); err != nil {
-			return nil, fmt.Errorf("解析响应失败: %v", err)
-		}
+	project, err := s.GetProjectByPath(ctx, parsed.BaseURL, parsed.ProjectPath, s.accessToken)
+	if err != nil {
+		return 0, err
+	}
 
-		// 转换为指针数组
-		for i := range projects {
-			allProjects = append(allProjects, &projects[i])
-		}
+	return project.ID, nil
+}
 
-		// 检查是否还有更多页面
-		if len(projects) < perPage {
-			break
-		}
-		page++
+// CreateProjectWebhook 在GitLab项目中创建webhook
+func (s *gitLabService) CreateProjectWebhook(ctx context.Context, baseURL string, projectID int, webhookURL string, events GitLabHookEvents, accessToken string) (*GitLabWebhook, error) {
+	webhookRequest := CreateWebhookRequest{
+		URL:          webhookURL,
+		IssuesEvents: false,
 	}
+	events.apply(&webhookRequest)
 
-	return allProjects, nil
+	var webhook GitLabWebhook
+	if _, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodPost,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/projects/%d/hooks", projectID),
+		token:   accessToken,
+		body:    webhookRequest,
+	}, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
 }
 
-// getSubgroups 获取子组
-func (s *gitLabService) getSubgroups(baseURL, groupPath, accessToken string) ([]*GitLabGroupInfo, error) {
-	encodedPath := url.QueryEscape(groupPath)
-	apiURL := fmt.Sprintf("%s/api/v4/groups/%s/subgroups", baseURL, encodedPath)
+// UpdateProjectWebhook 更新项目webhook配置
+func (s *gitLabService) UpdateProjectWebhook(ctx context.Context, baseURL string, projectID, webhookID int, webhookRequest *CreateWebhookRequest, accessToken string) (*GitLabWebhook, error) {
+	var webhook GitLabWebhook
+	if _, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodPut,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/projects/%d/hooks/%d", projectID, webhookID),
+		token:   accessToken,
+		body:    webhookRequest,
+	}, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+// SyncProjectWebhook 确保项目中存在指向本服务的webhook，并按订阅开关同步事件
+// 返回值中的 bool 表示是否新建了webhook
+func (s *gitLabService) SyncProjectWebhook(ctx context.Context, baseURL string, projectID int, webhookURL string, events GitLabHookEvents, accessToken string) (*GitLabWebhook, bool, error) {
+	existing, err := s.FindWebhookByURL(ctx, baseURL, projectID, webhookURL, accessToken)
 	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
+		return nil, false, err
 	}
 
-	// 设置认证头
-	if accessToken != "" {
-		if strings.HasPrefix(accessToken, "glpat-") || strings.HasPrefix(accessToken, "glcbt-") {
-			req.Header.Set("Authorization", "Bearer "+accessToken)
-		} else {
-			req.Header.Set("PRIVATE-TOKEN", accessToken)
+	if existing == nil {
+		webhook, err := s.CreateProjectWebhook(ctx, baseURL, projectID, webhookURL, events, accessToken)
+		if err != nil {
+			return nil, false, err
 		}
+		return webhook, true, nil
 	}
 
-	req.Header.Set("User-Agent", "GitLab-Merge-Alert/1.0")
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("请求失败: %v", err)
+	if events.Matches(existing) && events.SecretToken == "" {
+		return existing, false, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return []*GitLabGroupInfo{}, nil // 没有子组或无权限访问，返回空数组
+	updateRequest := &CreateWebhookRequest{
+		URL: existing.URL,
 	}
+	events.apply(updateRequest)
 
-	var groups []GitLabGroupInfo
-	if err := json.NewDecoder(resp.Body).Decode(&groups); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
+	updated, err := s.UpdateProjectWebhook(ctx, baseURL, projectID, existing.ID, updateRequest, accessToken)
+	if err != nil {
+		return nil, false, fmt.Errorf("更新webhook事件配置失败: %w", err)
 	}
 
-	// 转换为指针数组
-	var result []*GitLabGroupInfo
-	for i := range groups {
-		result = append(result, &groups[i])
-	}
+	return updated, false, nil
+}
 
-	return result, nil
+// ListProjectWebhooks 获取项目的所有webhooks
+func (s *gitLabService) ListProjectWebhooks(ctx context.Context, baseURL string, projectID int, accessToken string) ([]*GitLabWebhook, error) {
+	return gitLabListAll[GitLabWebhook](ctx, s.client, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/projects/%d/hooks", projectID),
+		token:   accessToken,
+	})
 }
 
-// GetGroupByPath 获取组信息
-func (s *gitLabService) GetGroupByPath(baseURL, groupPath, accessToken string) (*GitLabGroupInfo, error) {
-	encodedPath := url.QueryEscape(groupPath)
-	apiURL := fmt.Sprintf("%s/api/v4/groups/%s", baseURL, encodedPath)
+// DeleteProjectWebhook 删除项目webhook
+func (s *gitLabService) DeleteProjectWebhook(ctx context.Context, baseURL string, projectID, webhookID int, accessToken string) error {
+	_, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodDelete,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/projects/%d/hooks/%d", projectID, webhookID),
+		token:   accessToken,
+	}, nil)
+	return err
+}
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+// BuildWebhookURL 构建本服务的webhook接收URL
+func (s *gitLabService) BuildWebhookURL(publicBaseURL string) string {
+	return buildWebhookURL(publicBaseURL)
+}
+
+func buildWebhookURL(publicBaseURL string) string {
+	return fmt.Sprintf("%s/api/v1/webhook/gitlab", strings.TrimSuffix(publicBaseURL, "/"))
+}
+
+// FindWebhookByURL 根据URL查找项目中的webhook
+func (s *gitLabService) FindWebhookByURL(ctx context.Context, baseURL string, projectID int, webhookURL, accessToken string) (*GitLabWebhook, error) {
+	webhooks, err := s.ListProjectWebhooks(ctx, baseURL, projectID, accessToken)
 	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
+		return nil, err
 	}
 
-	// 设置认证头
-	if accessToken != "" {
-		if strings.HasPrefix(accessToken, "glpat-") || strings.HasPrefix(accessToken, "glcbt-") {
-			req.Header.Set("Authorization", "Bearer "+accessToken)
-		} else {
-			req.Header.Set("PRIVATE-TOKEN", accessToken)
+	for _, webhook := range webhooks {
+		if webhook.URL == webhookURL {
+			return webhook, nil
 		}
 	}
 
-	req.Header.Set("User-Agent", "GitLab-Merge-Alert/1.0")
+	return nil, nil // 未找到
+}
 
-	resp, err := s.client.Do(req)
+// FindAllWebhooksByURL 根据URL查找项目中所有匹配的webhook
+func (s *gitLabService) FindAllWebhooksByURL(ctx context.Context, baseURL string, projectID int, webhookURL, accessToken string) ([]*GitLabWebhook, error) {
+	webhooks, err := s.ListProjectWebhooks(ctx, baseURL, projectID, accessToken)
 	if err != nil {
-		return nil, fmt.Errorf("请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// 处理不同的HTTP状态码
-	switch resp.StatusCode {
-	case http.StatusOK:
-		// 成功，继续处理
-	case http.StatusUnauthorized:
-		return nil, fmt.Errorf("访问令牌无效或已过期")
-	case http.StatusForbidden:
-		return nil, fmt.Errorf("没有权限访问此组")
-	case http.StatusNotFound:
-		return nil, fmt.Errorf("组不存在或无权限访问")
-	default:
-		return nil, fmt.Errorf("GitLab API返回错误状态: %d", resp.StatusCode)
+		return nil, err
 	}
 
-	var group GitLabGroupInfo
-	if err := json.NewDecoder(resp.Body).Decode(&group); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
+	var matchingWebhooks []*GitLabWebhook
+	for _, webhook := range webhooks {
+		if webhook.URL == webhookURL {
+			matchingWebhooks = append(matchingWebhooks, webhook)
+		}
 	}
 
-	return &group, nil
+	return matchingWebhooks, nil
 }
 
-// ValidateProjectURL 验证项目URL并返回项目ID（保持向后兼容性）
-func (s *gitLabService) ValidateProjectURL(projectURL string) (int, error) {
-	parsed := s.ParseGitLabURL(projectURL)
-	if !parsed.IsValid {
-		return 0, fmt.Errorf(parsed.Error)
-	}
-
-	project, err := s.GetProjectByPath(parsed.BaseURL, parsed.ProjectPath, s.accessToken)
+// DeleteAllWebhooksByURL 删除项目中所有匹配URL的webhook
+func (s *gitLabService) DeleteAllWebhooksByURL(ctx context.Context, baseURL string, projectID int, webhookURL, accessToken string) (int, error) {
+	// 首先找到所有匹配的webhook
+	matchingWebhooks, err := s.FindAllWebhooksByURL(ctx, baseURL, projectID, webhookURL, accessToken)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("查找匹配的webhook失败: %w", err)
 	}
 
-	return project.ID, nil
-}
+	if len(matchingWebhooks) == 0 {
+		return 0, nil // 没有找到匹配的webhook
+	}
 
-// CreateProjectWebhook 在GitLab项目中创建webhook
-func (s *gitLabService) CreateProjectWebhook(baseURL string, projectID int, webhookURL, accessToken string) (*GitLabWebhook, error) {
-	apiURL := fmt.Sprintf("%s/api/v4/projects/%d/hooks", baseURL, projectID)
+	var deletedCount int
+	var errors []string
 
-	webhookRequest := CreateWebhookRequest{
-		URL:                   webhookURL,
-		MergeRequestsEvents:   true, // 只关注合并请求事件
-		PushEvents:            false,
-		IssuesEvents:          false,
-		EnableSSLVerification: false, // 对于测试环境可以关闭SSL验证
+	// 删除每个匹配的webhook
+	for _, webhook := range matchingWebhooks {
+		err := s.DeleteProjectWebhook(ctx, baseURL, projectID, webhook.ID, accessToken)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("删除webhook ID %d 失败: %v", webhook.ID, err))
+		} else {
+			deletedCount++
+		}
 	}
 
-	requestBody, err := json.Marshal(webhookRequest)
-	if err != nil {
-		return nil, fmt.Errorf("序列化请求数据失败: %v", err)
+	// 如果有错误，返回部分成功的结果
+	if len(errors) > 0 {
+		return deletedCount, fmt.Errorf("部分删除失败 (%d/%d 成功): %s", deletedCount, len(matchingWebhooks), strings.Join(errors, "; "))
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
+	return deletedCount, nil
+}
+
+// GroupHookEvents 组 Hook 覆盖组内所有项目，开启本服务处理的全部事件，是否通知由各项目的订阅决定
+func GroupHookEvents() GitLabHookEvents {
+	return GitLabHookEvents{
+		MergeRequests: true,
+		Push:          true,
+		TagPush:       true,
+		Releases:      true,
+		Pipeline:      true,
 	}
+}
 
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
-	if accessToken != "" {
-		if strings.HasPrefix(accessToken, "glpat-") || strings.HasPrefix(accessToken, "glcbt-") {
-			req.Header.Set("Authorization", "Bearer "+accessToken)
-		} else {
-			req.Header.Set("PRIVATE-TOKEN", accessToken)
-		}
+// groupHookError 组级 Webhook 接口的 403/404 通常是权限或版本问题，补充提示
+func groupHookError(err error) error {
+	switch {
+	case errors.Is(err, ErrGitLabForbidden):
+		return fmt.Errorf("管理组 Webhook 需要组 Owner 权限: %w", err)
+	case errors.Is(err, ErrGitLabNotFound):
+		return fmt.Errorf("组不存在、无权限访问，或当前 GitLab 版本不支持组级 Webhook（需要 Premium）: %w", err)
 	}
-	req.Header.Set("User-Agent", "GitLab-Merge-Alert/1.0")
+	return err
+}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// 处理不同的HTTP状态码
-	switch resp.StatusCode {
-	case http.StatusCreated:
-		// 成功创建，继续处理
-	case http.StatusUnauthorized:
-		return nil, fmt.Errorf("访问令牌无效或已过期")
-	case http.StatusForbidden:
-		return nil, fmt.Errorf("没有权限在此项目中创建webhook")
-	case http.StatusNotFound:
-		return nil, fmt.Errorf("项目不存在或无权限访问")
-	case http.StatusUnprocessableEntity:
-		return nil, fmt.Errorf("Webhook URL已存在或格式无效")
-	default:
-		return nil, fmt.Errorf("GitLab API返回错误状态: %d", resp.StatusCode)
+// CreateGroupWebhook 在GitLab组中创建webhook（组级 Webhook 需要 GitLab Premium）
+func (s *gitLabService) CreateGroupWebhook(ctx context.Context, baseURL string, groupID int, webhookURL string, events GitLabHookEvents, accessToken string) (*GitLabWebhook, error) {
+	webhookRequest := CreateWebhookRequest{
+		URL: webhookURL,
 	}
+	events.apply(&webhookRequest)
 
 	var webhook GitLabWebhook
-	if err := json.NewDecoder(resp.Body).Decode(&webhook); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
+	if _, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodPost,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/groups/%d/hooks", groupID),
+		token:   accessToken,
+		body:    webhookRequest,
+	}, &webhook); err != nil {
+		return nil, groupHookError(err)
 	}
-
 	return &webhook, nil
 }
 
-// ListProjectWebhooks 获取项目的所有webhooks
-func (s *gitLabService) ListProjectWebhooks(baseURL string, projectID int, accessToken string) ([]*GitLabWebhook, error) {
-	apiURL := fmt.Sprintf("%s/api/v4/projects/%d/hooks", baseURL, projectID)
+// UpdateGroupWebhook 更新组webhook配置
+func (s *gitLabService) UpdateGroupWebhook(ctx context.Context, baseURL string, groupID, webhookID int, webhookRequest *CreateWebhookRequest, accessToken string) (*GitLabWebhook, error) {
+	var webhook GitLabWebhook
+	if _, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodPut,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/groups/%d/hooks/%d", groupID, webhookID),
+		token:   accessToken,
+		body:    webhookRequest,
+	}, &webhook); err != nil {
+		return nil, groupHookError(err)
+	}
+	return &webhook, nil
+}
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+// SyncGroupWebhook 确保组中存在指向本服务的webhook并同步事件开关，返回值中的 bool 表示是否新建了webhook
+func (s *gitLabService) SyncGroupWebhook(ctx context.Context, baseURL string, groupID int, webhookURL string, events GitLabHookEvents, accessToken string) (*GitLabWebhook, bool, error) {
+	webhooks, err := s.ListGroupWebhooks(ctx, baseURL, groupID, accessToken)
 	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
+		return nil, false, err
 	}
 
-	// 设置认证头
-	if accessToken != "" {
-		if strings.HasPrefix(accessToken, "glpat-") || strings.HasPrefix(accessToken, "glcbt-") {
-			req.Header.Set("Authorization", "Bearer "+accessToken)
-		} else {
-			req.Header.Set("PRIVATE-TOKEN", accessToken)
+	var existing *GitLabWebhook
+	for _, webhook := range webhooks {
+		if webhook.URL == webhookURL {
+			existing = webhook
+			break
 		}
 	}
-	req.Header.Set("User-Agent", "GitLab-Merge-Alert/1.0")
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("请求失败: %v", err)
+	if existing == nil {
+		webhook, err := s.CreateGroupWebhook(ctx, baseURL, groupID, webhookURL, events, accessToken)
+		if err != nil {
+			return nil, false, err
+		}
+		return webhook, true, nil
 	}
-	defer resp.Body.Close()
 
-	// 处理不同的HTTP状态码
-	switch resp.StatusCode {
-	case http.StatusOK:
-		// 成功，继续处理
-	case http.StatusUnauthorized:
-		return nil, fmt.Errorf("访问令牌无效或已过期")
-	case http.StatusForbidden:
-		return nil, fmt.Errorf("没有权限访问此项目的webhooks")
-	case http.StatusNotFound:
-		return nil, fmt.Errorf("项目不存在或无权限访问")
-	default:
-		return nil, fmt.Errorf("GitLab API返回错误状态: %d", resp.StatusCode)
+	if events.Matches(existing) && events.SecretToken == "" {
+		return existing, false, nil
 	}
 
-	var webhooks []GitLabWebhook
-	if err := json.NewDecoder(resp.Body).Decode(&webhooks); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
+	updateRequest := &CreateWebhookRequest{
+		URL: existing.URL,
 	}
+	events.apply(updateRequest)
 
-	// 转换为指针数组
-	var result []*GitLabWebhook
-	for i := range webhooks {
-		result = append(result, &webhooks[i])
+	updated, err := s.UpdateGroupWebhook(ctx, baseURL, groupID, existing.ID, updateRequest, accessToken)
+	if err != nil {
+		return nil, false, fmt.Errorf("更新组webhook事件配置失败: %w", err)
 	}
 
-	return result, nil
+	return updated, false, nil
 }
 
-// DeleteProjectWebhook 删除项目webhook
-func (s *gitLabService) DeleteProjectWebhook(baseURL string, projectID, webhookID int, accessToken string) error {
-	apiURL := fmt.Sprintf("%s/api/v4/projects/%d/hooks/%d", baseURL, projectID, webhookID)
+// ListGroupWebhooks 获取组的所有webhooks
+func (s *gitLabService) ListGroupWebhooks(ctx context.Context, baseURL string, groupID int, accessToken string) ([]*GitLabWebhook, error) {
+	webhooks, err := gitLabListAll[GitLabWebhook](ctx, s.client, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/groups/%d/hooks", groupID),
+		token:   accessToken,
+	})
+	if err != nil {
+		return nil, groupHookError(err)
+	}
+	return webhooks, nil
+}
 
-	req, err := http.NewRequest("DELETE", apiURL, nil)
+// DeleteGroupWebhook 删除组webhook
+func (s *gitLabService) DeleteGroupWebhook(ctx context.Context, baseURL string, groupID, webhookID int, accessToken string) error {
+	_, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodDelete,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/groups/%d/hooks/%d", groupID, webhookID),
+		token:   accessToken,
+	}, nil)
+	return err
+}
+
+// DeleteGroupWebhooksByURL 删除组中所有指向本服务的webhook
+func (s *gitLabService) DeleteGroupWebhooksByURL(ctx context.Context, baseURL string, groupID int, webhookURL, accessToken string) (int, error) {
+	webhooks, err := s.ListGroupWebhooks(ctx, baseURL, groupID, accessToken)
 	if err != nil {
-		return fmt.Errorf("创建请求失败: %v", err)
+		return 0, fmt.Errorf("查找匹配的组webhook失败: %w", err)
 	}
 
-	// 设置认证头
-	if accessToken != "" {
-		if strings.HasPrefix(accessToken, "glpat-") || strings.HasPrefix(accessToken, "glcbt-") {
-			req.Header.Set("Authorization", "Bearer "+accessToken)
+	var deletedCount int
+	var errors []string
+	for _, webhook := range webhooks {
+		if webhook.URL != webhookURL {
+			continue
+		}
+		if err := s.DeleteGroupWebhook(ctx, baseURL, groupID, webhook.ID, accessToken); err != nil {
+			errors = append(errors, fmt.Sprintf("删除组webhook ID %d 失败: %v", webhook.ID, err))
 		} else {
-			req.Header.Set("PRIVATE-TOKEN", accessToken)
+			deletedCount++
 		}
 	}
-	req.Header.Set("User-Agent", "GitLab-Merge-Alert/1.0")
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("请求失败: %v", err)
+	if len(errors) > 0 {
+		return deletedCount, fmt.Errorf("部分删除失败 (已删除 %d 个): %s", deletedCount, strings.Join(errors, "; "))
 	}
-	defer resp.Body.Close()
 
-	// 处理不同的HTTP状态码
-	switch resp.StatusCode {
-	case http.StatusNoContent:
-		// 成功删除
-		return nil
-	case http.StatusUnauthorized:
-		return fmt.Errorf("访问令牌无效或已过期")
-	case http.StatusForbidden:
-		return fmt.Errorf("没有权限删除此项目的webhook")
-	case http.StatusNotFound:
-		return fmt.Errorf("项目或webhook不存在")
-	default:
-		return fmt.Errorf("GitLab API返回错误状态: %d", resp.StatusCode)
-	}
+	return deletedCount, nil
 }
 
-// BuildWebhookURL 构建本服务的webhook接收URL
-func (s *gitLabService) BuildWebhookURL(publicBaseURL string) string {
-	return fmt.Sprintf("%s/api/v1/webhook/gitlab", strings.TrimSuffix(publicBaseURL, "/"))
+// ListOpenMergeRequests 获取项目中所有打开的合并请求
+func (s *gitLabService) ListOpenMergeRequests(ctx context.Context, baseURL string, projectID int, accessToken string) ([]*GitLabMergeRequestInfo, error) {
+	return gitLabListAll[GitLabMergeRequestInfo](ctx, s.client, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/projects/%d/merge_requests", projectID),
+		query:   url.Values{"state": {"opened"}},
+		token:   accessToken,
+	})
 }
 
-// FindWebhookByURL 根据URL查找项目中的webhook
-func (s *gitLabService) FindWebhookByURL(baseURL string, projectID int, webhookURL, accessToken string) (*GitLabWebhook, error) {
-	webhooks, err := s.ListProjectWebhooks(baseURL, projectID, accessToken)
-	if err != nil {
+// GetMergeRequest 获取单个合并请求详情
+func (s *gitLabService) GetMergeRequest(ctx context.Context, baseURL string, projectID, iid int, accessToken string) (*GitLabMergeRequestDetail, error) {
+	var mergeRequest GitLabMergeRequestDetail
+	if _, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/projects/%d/merge_requests/%d", projectID, iid),
+		token:   accessToken,
+	}, &mergeRequest); err != nil {
 		return nil, err
 	}
+	return &mergeRequest, nil
+}
 
-	for _, webhook := range webhooks {
-		if webhook.URL == webhookURL {
-			return webhook, nil
-		}
+// GetMergeRequestApprovals 获取合并请求的审批情况
+func (s *gitLabService) GetMergeRequestApprovals(ctx context.Context, baseURL string, projectID, iid int, accessToken string) (*GitLabMergeRequestApprovals, error) {
+	var approvals GitLabMergeRequestApprovals
+	if _, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/projects/%d/merge_requests/%d/approvals", projectID, iid),
+		token:   accessToken,
+	}, &approvals); err != nil {
+		return nil, err
 	}
-
-	return nil, nil // 未找到
+	return &approvals, nil
 }
 
-// FindAllWebhooksByURL 根据URL查找项目中所有匹配的webhook
-func (s *gitLabService) FindAllWebhooksByURL(baseURL string, projectID int, webhookURL, accessToken string) ([]*GitLabWebhook, error) {
-	webhooks, err := s.ListProjectWebhooks(baseURL, projectID, accessToken)
-	if err != nil {
+// GetLatestMergeRequestPipeline 获取合并请求最近一次流水线，没有流水线时返回 nil
+func (s *gitLabService) GetLatestMergeRequestPipeline(ctx context.Context, baseURL string, projectID, iid int, accessToken string) (*GitLabPipelineInfo, error) {
+	var pipelines []GitLabPipelineInfo
+	if _, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/projects/%d/merge_requests/%d/pipelines", projectID, iid),
+		query:   url.Values{"per_page": {"1"}},
+		token:   accessToken,
+	}, &pipelines); err != nil {
 		return nil, err
 	}
-
-	var matchingWebhooks []*GitLabWebhook
-	for _, webhook := range webhooks {
-		if webhook.URL == webhookURL {
-			matchingWebhooks = append(matchingWebhooks, webhook)
-		}
+	if len(pipelines) == 0 {
+		return nil, nil
 	}
+	return &pipelines[0], nil
+}
 
-	return matchingWebhooks, nil
+// ListMergeRequestDiffs 获取合并请求的所有文件 diff
+func (s *gitLabService) ListMergeRequestDiffs(ctx context.Context, baseURL string, projectID, iid int, accessToken string) ([]*GitLabMergeRequestDiff, error) {
+	return gitLabListAll[GitLabMergeRequestDiff](ctx, s.client, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/projects/%d/merge_requests/%d/diffs", projectID, iid),
+		token:   accessToken,
+	})
 }
 
-// DeleteAllWebhooksByURL 删除项目中所有匹配URL的webhook
-func (s *gitLabService) DeleteAllWebhooksByURL(baseURL string, projectID int, webhookURL, accessToken string) (int, error) {
-	// 首先找到所有匹配的webhook
-	matchingWebhooks, err := s.FindAllWebhooksByURL(baseURL, projectID, webhookURL, accessToken)
-	if err != nil {
-		return 0, fmt.Errorf("查找匹配的webhook失败: %v", err)
+// GetCurrentUser 获取令牌所属的 GitLab 用户
+func (s *gitLabService) GetCurrentUser(ctx context.Context, baseURL, accessToken string) (*GitLabUserDetail, error) {
+	var user GitLabUserDetail
+	if _, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    "/user",
+		token:   accessToken,
+	}, &user); err != nil {
+		return nil, err
 	}
+	return &user, nil
+}
 
-	if len(matchingWebhooks) == 0 {
-		return 0, nil // 没有找到匹配的webhook
+// GetUser 按 ID 获取 GitLab 用户
+func (s *gitLabService) GetUser(ctx context.Context, baseURL string, userID int, accessToken string) (*GitLabUserDetail, error) {
+	var user GitLabUserDetail
+	if _, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/users/%d", userID),
+		token:   accessToken,
+	}, &user); err != nil {
+		return nil, err
 	}
+	return &user, nil
+}
 
-	var deletedCount int
-	var errors []string
-
-	// 删除每个匹配的webhook
-	for _, webhook := range matchingWebhooks {
-		err := s.DeleteProjectWebhook(baseURL, projectID, webhook.ID, accessToken)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("删除webhook ID %d 失败: %v", webhook.ID, err))
-		} else {
-			deletedCount++
-		}
+// FindUserByUsername 按用户名查找 GitLab 用户，不存在时返回 nil
+func (s *gitLabService) FindUserByUsername(ctx context.Context, baseURL, username, accessToken string) (*GitLabUserDetail, error) {
+	var users []GitLabUserDetail
+	if _, err := s.client.do(ctx, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    "/users",
+		query:   url.Values{"username": {username}},
+		token:   accessToken,
+	}, &users); err != nil {
+		return nil, err
 	}
-
-	// 如果有错误，返回部分成功的结果
-	if len(errors) > 0 {
-		return deletedCount, fmt.Errorf("部分删除失败 (%d/%d 成功): %s", deletedCount, len(matchingWebhooks), strings.Join(errors, "; "))
+	if len(users) == 0 {
+		return nil, nil
 	}
+	return &users[0], nil
+}
 
-	return deletedCount, nil
+// ListGroupMembers 获取组成员，包含从上级组继承的成员
+func (s *gitLabService) ListGroupMembers(ctx context.Context, baseURL string, groupID int, accessToken string) ([]*GitLabMember, error) {
+	return gitLabListAll[GitLabMember](ctx, s.client, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/groups/%d/members/all", groupID),
+		token:   accessToken,
+	})
+}
+
+// ListProjectMembers 获取项目成员，包含从所属组继承的成员
+func (s *gitLabService) ListProjectMembers(ctx context.Context, baseURL string, projectID int, accessToken string) ([]*GitLabMember, error) {
+	return gitLabListAll[GitLabMember](ctx, s.client, gitLabRequest{
+		method:  http.MethodGet,
+		baseURL: baseURL,
+		path:    fmt.Sprintf("/projects/%d/members/all", projectID),
+		token:   accessToken,
+	})
 }
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+)
+
+// GitLab API 错误分类，调用方通过 errors.Is 判断
+var (
+	ErrGitLabUnauthorized   = errors.New("gitlab: unauthorized")
+	ErrGitLabForbidden      = errors.New("gitlab: forbidden")
+	ErrGitLabNotFound       = errors.New("gitlab: not found")
+	ErrGitLabInvalidRequest = errors.New("gitlab: invalid request")
+	ErrGitLabRateLimited    = errors.New("gitlab: rate limited")
+	ErrGitLabUnavailable    = errors.New("gitlab: unavailable")
+)
+
+const (
+	gitLabUserAgent      = "GitLab-Merge-Alert/1.0"
+	gitLabPerPage        = 100
+	gitLabMaxPages       = 1000
+	gitLabErrorBodyLimit = 4096
+
+	defaultGitLabAPITimeout      = 30 * time.Second
+	defaultGitLabAPIMaxRetryWait = time.Minute
+	gitLabRetryBaseDelay         = 500 * time.Millisecond
+)
+
+// GitLabAPIError GitLab API 请求失败，StatusCode 为 0 表示未收到响应
+type GitLabAPIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+	Err        error
+}
+
+func (e *GitLabAPIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("GitLab API %s %s 请求失败: %v", e.Method, e.Path, e.Err)
+	}
+	msg := fmt.Sprintf("GitLab API %s %s 返回 %d", e.Method, e.Path, e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+func (e *GitLabAPIError) Unwrap() []error {
+	var errs []error
+	if kind := gitLabErrorKind(e.StatusCode, e.Err); kind != nil {
+		errs = append(errs, kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+func gitLabErrorKind(status int, cause error) error {
+	switch {
+	case status == http.StatusUnauthorized:
+		return ErrGitLabUnauthorized
+	case status == http.StatusForbidden:
+		return ErrGitLabForbidden
+	case status == http.StatusNotFound:
+		return ErrGitLabNotFound
+	case status == http.StatusTooManyRequests:
+		return ErrGitLabRateLimited
+	case status >= 500:
+		return ErrGitLabUnavailable
+	case status >= 400:
+		return ErrGitLabInvalidRequest
+	case status == 0 && !errors.Is(cause, context.Canceled) && !errors.Is(cause, context.DeadlineExceeded):
+		return ErrGitLabUnavailable
+	}
+	return nil
+}
+
+// gitLabClient 所有 GitLab API 请求共用的客户端：统一认证头、分页、429/5xx 重试与请求日志
+type gitLabClient struct {
+	http         *http.Client
+	maxRetries   int
+	maxRetryWait time.Duration
+}
+
+func newGitLabClient(cfg config.GitLabAPIConfig) *gitLabClient {
+	client := &gitLabClient{
+		http:         &http.Client{Timeout: cfg.Timeout},
+		maxRetries:   cfg.MaxRetries,
+		maxRetryWait: cfg.MaxRetryWait,
+	}
+	if client.http.Timeout <= 0 {
+		client.http.Timeout = defaultGitLabAPITimeout
+	}
+	if client.maxRetries < 0 {
+		client.maxRetries = 0
+	}
+	if client.maxRetryWait <= 0 {
+		client.maxRetryWait = defaultGitLabAPIMaxRetryWait
+	}
+	return client
+}
+
+// gitLabRequest 一次 API 调用，path 为 /api/v4 之后的部分
+type gitLabRequest struct {
+	method  string
+	baseURL string
+	path    string
+	query   url.Values
+	token   string
+	body    interface{}
+}
+
+func (r gitLabRequest) url() string {
+	apiURL := strings.TrimSuffix(r.baseURL, "/") + "/api/v4" + r.path
+	if len(r.query) > 0 {
+		apiURL += "?" + r.query.Encode()
+	}
+	return apiURL
+}
+
+// do 发送请求并把响应解析到 out（out 为 nil 时丢弃响应体），返回响应头供分页使用
+func (c *gitLabClient) do(ctx context.Context, req gitLabRequest, out interface{}) (http.Header, error) {
+	return c.doURL(ctx, req, req.url(), out)
+}
+
+func (c *gitLabClient) doURL(ctx context.Context, req gitLabRequest, apiURL string, out interface{}) (http.Header, error) {
+	var payload []byte
+	if req.body != nil {
+		data, err := json.Marshal(req.body)
+		if err != nil {
+			return nil, fmt.Errorf("序列化请求数据失败: %w", err)
+		}
+		payload = data
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req, apiURL, payload, attempt)
+		if err != nil {
+			apiErr := &GitLabAPIError{Method: req.method, Path: req.path, Err: err}
+			if ctx.Err() != nil || attempt >= c.maxRetries || !idempotentMethod(req.method) {
+				return nil, apiErr
+			}
+			delay := gitLabBackoff(attempt)
+			logger.GetLogger().Warnf("GitLab API %s %s 请求失败，%s 后重试（第 %d 次）: %v", req.method, req.path, delay, attempt+1, err)
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, apiErr
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out == nil {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				return resp.Header, nil
+			}
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return nil, fmt.Errorf("解析 GitLab API %s %s 响应失败: %w", req.method, req.path, err)
+			}
+			return resp.Header, nil
+		}
+
+		apiErr := &GitLabAPIError{
+			Method:     req.method,
+			Path:       req.path,
+			StatusCode: resp.StatusCode,
+			Message:    readGitLabErrorMessage(resp.Body),
+		}
+		resp.Body.Close()
+
+		if attempt >= c.maxRetries || !retryableStatus(req.method, resp.StatusCode) {
+			return nil, apiErr
+		}
+		delay := gitLabRetryDelay(resp.Header, attempt, time.Now())
+		if delay > c.maxRetryWait {
+			logger.GetLogger().Warnf("GitLab API %s %s 返回 %d，需要等待 %s 超过上限 %s，不再重试", req.method, req.path, resp.StatusCode, delay, c.maxRetryWait)
+			return nil, apiErr
+		}
+		logger.GetLogger().Warnf("GitLab API %s %s 返回 %d，%s 后重试（第 %d 次）", req.method, req.path, resp.StatusCode, delay, attempt+1)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, apiErr
+		}
+	}
+}
+
+// send 发送单次请求并记录耗时、状态码与剩余配额
+func (c *gitLabClient) send(ctx context.Context, req gitLabRequest, apiURL string, payload []byte, attempt int) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, apiURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	setGitLabAuth(httpReq, req.token)
+	httpReq.Header.Set("User-Agent", gitLabUserAgent)
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	resp, err := c.http.Do(httpReq)
+	elapsed := time.Since(start)
+	if err != nil {
+		logger.GetLogger().Debugf("GitLab API %s %s 失败，耗时 %s，第 %d 次尝试: %v", req.method, req.path, elapsed, attempt+1, err)
+		return nil, err
+	}
+	logger.GetLogger().Debugf("GitLab API %s %s 返回 %d，耗时 %s，第 %d 次尝试，剩余配额 %s",
+		req.method, req.path, resp.StatusCode, elapsed, attempt+1, headerOrDash(resp.Header, "RateLimit-Remaining"))
+	return resp, nil
+}
+
+// gitLabListAll 按 X-Next-Page 或 Link 头依次获取所有分页
+func gitLabListAll[T any](ctx context.Context, c *gitLabClient, req gitLabRequest) ([]*T, error) {
+	query := url.Values{}
+	for key, values := range req.query {
+		query[key] = values
+	}
+	if query.Get("per_page") == "" {
+		query.Set("per_page", strconv.Itoa(gitLabPerPage))
+	}
+	req.query = query
+
+	var result []*T
+	apiURL := req.url()
+	for pages := 0; apiURL != ""; pages++ {
+		if pages >= gitLabMaxPages {
+			return nil, fmt.Errorf("GitLab API %s %s 分页超过 %d 页", req.method, req.path, gitLabMaxPages)
+		}
+		var page []T
+		header, err := c.doURL(ctx, req, apiURL, &page)
+		if err != nil {
+			return nil, err
+		}
+		for i := range page {
+			result = append(result, &page[i])
+		}
+		apiURL = gitLabNextPageURL(apiURL, header)
+	}
+	return result, nil
+}
+
+// gitLabNextPageURL 优先使用 X-Next-Page，keyset 分页只返回 Link 头
+func gitLabNextPageURL(current string, header http.Header) string {
+	if next := strings.TrimSpace(header.Get("X-Next-Page")); next != "" {
+		parsed, err := url.Parse(current)
+		if err != nil {
+			return ""
+		}
+		query := parsed.Query()
+		query.Set("page", next)
+		parsed.RawQuery = query.Encode()
+		return parsed.String()
+	}
+
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if strings.ReplaceAll(strings.TrimSpace(param), " ", "") == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// gitLabRetryDelay 依次参考 Retry-After、RateLimit-Reset，缺失时指数退避
+func gitLabRetryDelay(header http.Header, attempt int, now time.Time) time.Duration {
+	if value := strings.TrimSpace(header.Get("Retry-After")); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return nonNegative(at.Sub(now))
+		}
+	}
+	if value := strings.TrimSpace(header.Get("RateLimit-Reset")); value != "" {
+		if epoch, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return nonNegative(time.Unix(epoch, 0).Sub(now))
+		}
+	}
+	return gitLabBackoff(attempt)
+}
+
+func gitLabBackoff(attempt int) time.Duration {
+	return gitLabRetryBaseDelay << attempt
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// retryableStatus 429 未被 GitLab 处理，任何方法都可重试；5xx 只重试幂等方法，避免重复创建 hook
+func retryableStatus(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotentMethod(method)
+	}
+	return false
+}
+
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// setGitLabAuth 个人、项目与组访问令牌使用 Bearer，其余令牌使用 PRIVATE-TOKEN
+func setGitLabAuth(req *http.Request, token string) {
+	if token == "" {
+		return
+	}
+	if strings.HasPrefix(token, "glpat-") || strings.HasPrefix(token, "glcbt-") {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		req.Header.Set("PRIVATE-TOKEN", token)
+	}
+}
+
+// readGitLabErrorMessage 提取错误响应中的 message/error 字段，message 可能是字符串或按字段分组的对象
+func readGitLabErrorMessage(body io.Reader) string {
+	data, err := io.ReadAll(io.LimitReader(body, gitLabErrorBodyLimit))
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+
+	var payload struct {
+		Message json.RawMessage `json:"message"`
+		Error   string          `json:"error"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return strings.TrimSpace(string(data))
+	}
+	if len(payload.Message) > 0 {
+		var text string
+		if err := json.Unmarshal(payload.Message, &text); err == nil {
+			return text
+		}
+		return string(payload.Message)
+	}
+	return payload.Error
+}
+
+func headerOrDash(header http.Header, key string) string {
+	if value := header.Get(key); value != "" {
+		return value
+	}
+	return "-"
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGitLabNextPageURL(t *testing.T) {
+	const current = "https://gitlab.example.com/api/v4/projects?per_page=100&page=2"
+
+	cases := []struct {
+		name    string
+		current string
+		header  map[string]string
+		want    string
+	}{
+		{
+			name:    "no pagination headers",
+			current: current,
+			want:    "",
+		},
+		{
+			name:    "x-next-page replaces page",
+			current: current,
+			header:  map[string]string{"X-Next-Page": "3"},
+			want:    "https://gitlab.example.com/api/v4/projects?page=3&per_page=100",
+		},
+		{
+			name:    "x-next-page adds page",
+			current: "https://gitlab.example.com/api/v4/groups/7/projects?include_subgroups=true",
+			header:  map[string]string{"X-Next-Page": " 2 "},
+			want:    "https://gitlab.example.com/api/v4/groups/7/projects?include_subgroups=true&page=2",
+		},
+		{
+			name:    "empty x-next-page on the last page",
+			current: current,
+			header:  map[string]string{"X-Next-Page": "", "X-Page": "2", "X-Total-Pages": "2"},
+			want:    "",
+		},
+		{
+			name:    "x-next-page takes precedence over link",
+			current: current,
+			header: map[string]string{
+				"X-Next-Page": "3",
+				"Link":        `<https://gitlab.example.com/api/v4/projects?page=9>; rel="next"`,
+			},
+			want: "https://gitlab.example.com/api/v4/projects?page=3&per_page=100",
+		},
+		{
+			name:    "unparsable current url",
+			current: "http://[::1",
+			header:  map[string]string{"X-Next-Page": "3"},
+			want:    "",
+		},
+		{
+			name:    "keyset link header",
+			current: current,
+			header: map[string]string{
+				"Link": `<https://gitlab.example.com/api/v4/projects?pagination=keyset&id_after=42&per_page=100>; rel="next", ` +
+					`<https://gitlab.example.com/api/v4/projects?pagination=keyset&per_page=100>; rel="first"`,
+			},
+			want: "https://gitlab.example.com/api/v4/projects?pagination=keyset&id_after=42&per_page=100",
+		},
+		{
+			name:    "next is not the first link",
+			current: current,
+			header: map[string]string{
+				"Link": `<https://gitlab.example.com/api/v4/projects?page=1>; rel="prev", ` +
+					`<https://gitlab.example.com/api/v4/projects?page=3>; rel="next", ` +
+					`<https://gitlab.example.com/api/v4/projects?page=5>; rel="last"`,
+			},
+			want: "https://gitlab.example.com/api/v4/projects?page=3",
+		},
+		{
+			name:    "spaces around rel",
+			current: current,
+			header:  map[string]string{"Link": ` <https://gitlab.example.com/api/v4/projects?page=3> ; rel = "next"`},
+			want:    "https://gitlab.example.com/api/v4/projects?page=3",
+		},
+		{
+			name:    "rel after other parameters",
+			current: current,
+			header:  map[string]string{"Link": `<https://gitlab.example.com/api/v4/projects?page=3>; type="application/json"; rel="next"`},
+			want:    "https://gitlab.example.com/api/v4/projects?page=3",
+		},
+		{
+			name:    "link without next",
+			current: current,
+			header: map[string]string{
+				"Link": `<https://gitlab.example.com/api/v4/projects?page=1>; rel="first", ` +
+					`<https://gitlab.example.com/api/v4/projects?page=2>; rel="last"`,
+			},
+			want: "",
+		},
+		{
+			name:    "malformed link",
+			current: current,
+			header:  map[string]string{"Link": `<https://gitlab.example.com/api/v4/projects?page=3>`},
+			want:    "",
+		},
+	}
+
+	for _, tc := range cases {
+		header := http.Header{}
+		for key, value := range tc.header {
+			header.Set(key, value)
+		}
+		if got := gitLabNextPageURL(tc.current, header); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestGitLabRetryDelay(t *testing.T) {
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	epoch := func(d time.Duration) string { return strconv.FormatInt(now.Add(d).Unix(), 10) }
+	httpDate := func(d time.Duration) string { return now.Add(d).Format(http.TimeFormat) }
+
+	cases := []struct {
+		name    string
+		header  map[string]string
+		attempt int
+		want    time.Duration
+	}{
+		{"retry-after seconds", map[string]string{"Retry-After": "30"}, 0, 30 * time.Second},
+		{"retry-after zero", map[string]string{"Retry-After": "0"}, 2, 0},
+		{"retry-after http date", map[string]string{"Retry-After": httpDate(90 * time.Second)}, 0, 90 * time.Second},
+		{"retry-after date in the past", map[string]string{"Retry-After": httpDate(-time.Minute)}, 0, 0},
+		{"retry-after precedes ratelimit-reset", map[string]string{"Retry-After": "5", "RateLimit-Reset": epoch(time.Minute)}, 0, 5 * time.Second},
+		{"negative retry-after falls back", map[string]string{"Retry-After": "-5"}, 0, gitLabRetryBaseDelay},
+		{"invalid retry-after uses ratelimit-reset", map[string]string{"Retry-After": "soon", "RateLimit-Reset": epoch(20 * time.Second)}, 0, 20 * time.Second},
+		{"ratelimit-reset", map[string]string{"RateLimit-Reset": epoch(45 * time.Second)}, 3, 45 * time.Second},
+		{"ratelimit-reset in the past", map[string]string{"RateLimit-Reset": epoch(-10 * time.Second)}, 0, 0},
+		{"invalid ratelimit-reset falls back", map[string]string{"RateLimit-Reset": "later"}, 1, 2 * gitLabRetryBaseDelay},
+		{"backoff first attempt", nil, 0, gitLabRetryBaseDelay},
+		{"backoff doubles per attempt", nil, 3, 8 * gitLabRetryBaseDelay},
+	}
+
+	for _, tc := range cases {
+		header := http.Header{}
+		for key, value := range tc.header {
+			header.Set(key, value)
+		}
+		if got := gitLabRetryDelay(header, tc.attempt, now); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,637 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/security"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrGitLabGroupNotFound    = errors.New("gitlab group not found")
+	ErrGitLabGroupExists      = errors.New("gitlab group already registered")
+	ErrGitLabGroupTokenNeeded = errors.New("gitlab group token is required for custom token source")
+)
+
+// groupSyncRunsKeep 每个组保留的同步记录条数
+const groupSyncRunsKeep = 50
+
+type gitLabGroupService struct {
+	db        *gorm.DB
+	cfg       *config.Config
+	gitlab    GitLabService
+	instances GitLabInstanceService
+	alerts    OpsAlertService
+}
+
+func NewGitLabGroupService(db *gorm.DB, cfg *config.Config, gitlab GitLabService, instances GitLabInstanceService, alerts OpsAlertService) GitLabGroupService {
+	return &gitLabGroupService{db: db, cfg: cfg, gitlab: gitlab, instances: instances, alerts: alerts}
+}
+
+func (s *gitLabGroupService) List() ([]models.GitLabGroup, error) {
+	var groups []models.GitLabGroup
+	if err := s.db.Preload("Webhooks").Order("gitlab_instance_id ASC, full_path ASC").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (s *gitLabGroupService) Get(id uint) (*models.GitLabGroup, error) {
+	var group models.GitLabGroup
+	if err := s.db.Preload("Webhooks").First(&group, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGitLabGroupNotFound
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (s *gitLabGroupService) Create(group *models.GitLabGroup, webhookIDs []uint, accessToken string) error {
+	if group.HookMode == "" {
+		group.HookMode = models.GroupHookModeGroup
+	}
+	if group.TokenSource == "" {
+		group.TokenSource = models.GroupTokenSourceInstance
+	}
+	if err := s.setAccessToken(group, accessToken); err != nil {
+		return err
+	}
+	if group.TokenSource == models.GroupTokenSourceCustom && group.AccessToken == "" {
+		return ErrGitLabGroupTokenNeeded
+	}
+
+	var count int64
+	err := s.db.Model(&models.GitLabGroup{}).
+		Where("gitlab_instance_id = ? AND gitlab_group_id = ?", group.GitLabInstanceID, group.GitLabGroupID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrGitLabGroupExists
+	}
+
+	webhooks, err := s.loadWebhooks(webhookIDs)
+	if err != nil {
+		return err
+	}
+	group.Webhooks = webhooks
+
+	if err := s.db.Create(group).Error; err != nil {
+		return fmt.Errorf("保存 GitLab 组失败: %w", err)
+	}
+	return nil
+}
+
+func (s *gitLabGroupService) Update(group *models.GitLabGroup, webhookIDs *[]uint, accessToken *string) error {
+	if accessToken != nil {
+		if err := s.setAccessToken(group, *accessToken); err != nil {
+			return err
+		}
+	}
+	if group.TokenSource == models.GroupTokenSourceCustom && group.AccessToken == "" {
+		return ErrGitLabGroupTokenNeeded
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(group).Updates(map[string]interface{}{
+			"watched":                      group.Watched,
+			"hook_mode":                    group.HookMode,
+			"token_source":                 group.TokenSource,
+			"access_token":                 group.AccessToken,
+			"default_merge_request_events": group.DefaultMergeRequestEvents,
+			"default_push_events":          group.DefaultPushEvents,
+			"default_tag_push_events":      group.DefaultTagPushEvents,
+			"default_release_events":       group.DefaultReleaseEvents,
+			"default_push_branch_filters":  group.DefaultPushBranchFilters,
+			"default_tag_filters":          group.DefaultTagFilters,
+		}).Error; err != nil {
+			return err
+		}
+		if webhookIDs == nil {
+			return nil
+		}
+
+		webhooks, err := s.loadWebhooks(*webhookIDs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(group).Association("Webhooks").Replace(webhooks); err != nil {
+			return fmt.Errorf("更新组默认 Webhook 失败: %w", err)
+		}
+		group.Webhooks = webhooks
+		return nil
+	})
+}
+
+func (s *gitLabGroupService) Delete(id uint) error {
+	group, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(group).Association("Webhooks").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&models.GitLabGroupSyncRun{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Project{}).Where("source_group_id = ?", id).Update("source_group_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.GitLabGroup{}, id).Error
+	})
+}
+
+// SaveHookStatus 记录组 Hook 的同步结果
+func (s *gitLabGroupService) SaveHookStatus(group *models.GitLabGroup, hookID *int, syncErr error) error {
+	now := time.Now()
+	group.GitLabHookID = hookID
+	group.HookSynced = syncErr == nil && hookID != nil
+	group.LastSyncAt = &now
+	group.LastSyncError = ""
+	if syncErr != nil {
+		group.LastSyncError = syncErr.Error()
+	}
+
+	return s.db.Model(group).Updates(map[string]interface{}{
+		"gitlab_hook_id":  group.GitLabHookID,
+		"hook_synced":     group.HookSynced,
+		"last_sync_at":    group.LastSyncAt,
+		"last_sync_error": group.LastSyncError,
+	}).Error
+}
+
+// CoveringGroup 返回已同步组 Hook 且包含该项目的组，多个组匹配时取层级最深的一个
+func (s *gitLabGroupService) CoveringGroup(instanceID uint, projectPath string) *models.GitLabGroup {
+	groups, err := s.syncedGroups(instanceID)
+	if err != nil {
+		logger.GetLogger().Warnf("查询 GitLab 组失败: %v", err)
+		return nil
+	}
+	return deepestCoveringGroup(groups, projectPath, false)
+}
+
+// CoverageForProjects 批量计算项目是否已由组 Hook 覆盖，返回项目 ID 到组的映射
+func (s *gitLabGroupService) CoverageForProjects(projects []models.Project) map[uint]*models.GitLabGroup {
+	coverage := make(map[uint]*models.GitLabGroup)
+	if len(projects) == 0 {
+		return coverage
+	}
+
+	groups, err := s.syncedGroups(0)
+	if err != nil {
+		logger.GetLogger().Warnf("查询 GitLab 组失败: %v", err)
+		return coverage
+	}
+	if len(groups) == 0 {
+		return coverage
+	}
+
+	byInstance := make(map[uint][]models.GitLabGroup)
+	for _, group := range groups {
+		byInstance[group.GitLabInstanceID] = append(byInstance[group.GitLabInstanceID], group)
+	}
+
+	for idx := range projects {
+		candidates := byInstance[projects[idx].GitLabInstanceID]
+		if len(candidates) == 0 {
+			continue
+		}
+		if group := deepestCoveringGroup(candidates, s.projectPath(projects[idx].URL), false); group != nil {
+			coverage[projects[idx].ID] = group
+		}
+	}
+	return coverage
+}
+
+// CoveredProjects 返回已登记且位于组（含子组）下的项目
+func (s *gitLabGroupService) CoveredProjects(group *models.GitLabGroup) ([]models.Project, error) {
+	var projects []models.Project
+	if err := s.db.Where("gitlab_instance_id = ?", group.GitLabInstanceID).Find(&projects).Error; err != nil {
+		return nil, err
+	}
+
+	covered := make([]models.Project, 0, len(projects))
+	for idx := range projects {
+		if group.Covers(s.projectPath(projects[idx].URL)) {
+			covered = append(covered, projects[idx])
+		}
+	}
+	return covered, nil
+}
+
+// AutoRegisterProject 组 Hook 推送了未登记项目的事件时，若所属组开启了 watched 则自动登记项目
+// 返回的项目为 nil 时：组不为 nil 表示项目由组 Hook 覆盖但未开启自动登记，组也为 nil 表示不属于任何组
+func (s *gitLabGroupService) AutoRegisterProject(instanceID uint, eventProject *models.GitLabProject) (*models.Project, *models.GitLabGroup, error) {
+	projectPath := strings.Trim(eventProject.PathWithNamespace, "/")
+	if projectPath == "" {
+		projectPath = s.projectPath(eventProject.WebURL)
+	}
+
+	groups, err := s.syncedGroups(instanceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	group := deepestCoveringGroup(groups, projectPath, true)
+	if group == nil {
+		return nil, deepestCoveringGroup(groups, projectPath, false), nil
+	}
+
+	if err := s.db.Model(group).Association("Webhooks").Find(&group.Webhooks); err != nil {
+		return nil, group, fmt.Errorf("加载组默认 Webhook 失败: %w", err)
+	}
+
+	name := eventProject.Name
+	if name == "" {
+		name = projectPath
+	}
+	project := group.NewProject(eventProject.ID, name, eventProject.WebURL, eventProject.Description)
+
+	if err := s.db.Create(project).Error; err != nil {
+		// 并发收到同一项目的多个事件时，以先登记的记录为准
+		var existing models.Project
+		if findErr := s.db.Where("gitlab_instance_id = ? AND gitlab_project_id = ?", instanceID, eventProject.ID).First(&existing).Error; findErr == nil {
+			return &existing, group, nil
+		}
+		return nil, group, fmt.Errorf("自动登记项目失败: %w", err)
+	}
+
+	logger.GetLogger().Infof("已从组 %s 自动登记项目 %s (GitLab ID: %d)，关联 %d 个通知渠道",
+		group.FullPath, projectPath, eventProject.ID, len(group.Webhooks))
+	return project, group, nil
+}
+
+// ResolveToken 按组的令牌来源解析访问 GitLab 使用的令牌
+func (s *gitLabGroupService) ResolveToken(group *models.GitLabGroup) (string, error) {
+	switch group.TokenSource {
+	case models.GroupTokenSourceCustom:
+		if group.AccessToken == "" {
+			return "", ErrGitLabGroupTokenNeeded
+		}
+		decrypted, err := security.Decrypt(s.cfg.EncryptionKey, group.AccessToken)
+		if err != nil {
+			return "", fmt.Errorf("解密组 %s 的令牌失败: %w", group.FullPath, err)
+		}
+		return strings.TrimSpace(decrypted), nil
+	case models.GroupTokenSourceAccount:
+		if group.CreatedBy == nil {
+			return "", fmt.Errorf("组 %s 未记录登记人，无法使用账户令牌", group.FullPath)
+		}
+		return accountGitLabToken(s.db, s.cfg.EncryptionKey, s.alerts, *group.CreatedBy)
+	default:
+		instance, err := s.instances.Get(group.GitLabInstanceID)
+		if err != nil {
+			return "", err
+		}
+		if token := s.instances.DefaultToken(instance.ID); token != "" {
+			return token, nil
+		}
+		if token := strings.TrimSpace(s.cfg.GitLabServiceToken); token != "" && instance.IsDefault {
+			return token, nil
+		}
+		return "", fmt.Errorf("GitLab 实例 %s 未配置默认令牌", instance.Name)
+	}
+}
+
+// SyncGroup 扫描组内（含子组）项目：关注的组登记新项目，并标记已归档、已删除或恢复的项目，结果写入同步记录
+func (s *gitLabGroupService) SyncGroup(ctx context.Context, group *models.GitLabGroup, trigger string) (*models.GitLabGroupSyncRun, error) {
+	run := &models.GitLabGroupSyncRun{
+		GroupID:   group.ID,
+		Trigger:   trigger,
+		Status:    models.GroupSyncStatusSuccess,
+		StartedAt: time.Now(),
+	}
+
+	syncErr := s.scanGroup(ctx, group, run)
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	if syncErr != nil {
+		run.Status = models.GroupSyncStatusFailed
+		run.Error = syncErr.Error()
+	}
+
+	if err := s.db.Create(run).Error; err != nil {
+		logger.GetLogger().Warnf("保存组 %s 的同步记录失败: %v", group.FullPath, err)
+	}
+	s.pruneSyncRuns(group.ID)
+
+	group.LastScanAt = &finishedAt
+	group.LastScanError = run.Error
+	if err := s.db.Model(group).Updates(map[string]interface{}{
+		"last_scan_at":    group.LastScanAt,
+		"last_scan_error": group.LastScanError,
+	}).Error; err != nil {
+		logger.GetLogger().Warnf("保存组 %s 的同步状态失败: %v", group.FullPath, err)
+	}
+
+	if syncErr != nil {
+		s.alerts.Notify(OpsAlertEvent{
+			Severity: models.OpsSeverityWarning,
+			Category: models.OpsCategoryGroupSync,
+			Key:      fmt.Sprintf("gitlab_group_sync:group:%d", group.ID),
+			Title:    fmt.Sprintf("GitLab 组 %s 同步失败", group.FullPath),
+			Detail:   fmt.Sprintf("Error: %v", syncErr),
+		})
+		return run, syncErr
+	}
+
+	if len(run.Changes) > 0 {
+		logger.GetLogger().Infof("组 %s 同步完成：扫描 %d 个项目，新增 %d，归档 %d，缺失 %d，恢复 %d",
+			group.FullPath, run.Scanned, run.Added, run.Archived, run.Missing, run.Restored)
+	}
+	return run, nil
+}
+
+// SyncWatchedGroups 定时同步所有关注的组
+func (s *gitLabGroupService) SyncWatchedGroups(ctx context.Context) error {
+	var groups []models.GitLabGroup
+	if err := s.db.Preload("Webhooks").Where("watched = ?", true).Order("id ASC").Find(&groups).Error; err != nil {
+		return err
+	}
+
+	var failed int
+	for idx := range groups {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := s.SyncGroup(ctx, &groups[idx], models.GroupSyncTriggerSchedule); err != nil {
+			logger.GetLogger().Warnf("同步组 %s 失败: %v", groups[idx].FullPath, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个组同步失败", failed)
+	}
+	return nil
+}
+
+func (s *gitLabGroupService) ListSyncRuns(groupID uint, limit int) ([]models.GitLabGroupSyncRun, error) {
+	if limit <= 0 || limit > groupSyncRunsKeep {
+		limit = groupSyncRunsKeep
+	}
+
+	var runs []models.GitLabGroupSyncRun
+	if err := s.db.Where("group_id = ?", groupID).Order("id DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (s *gitLabGroupService) scanGroup(ctx context.Context, group *models.GitLabGroup, run *models.GitLabGroupSyncRun) error {
+	instance, err := s.instances.Get(group.GitLabInstanceID)
+	if err != nil {
+		return fmt.Errorf("查询 GitLab 实例失败: %w", err)
+	}
+	token, err := s.ResolveToken(group)
+	if err != nil {
+		return err
+	}
+
+	// 列表不完整时不能判断项目是否已删除，任一子组获取失败即放弃本次同步
+	remoteProjects, err := s.gitlab.GetGroupProjects(ctx, instance.BaseURL, group.FullPath, token, false)
+	if err != nil {
+		return fmt.Errorf("获取组内项目失败: %w", err)
+	}
+	if group.Watched {
+		group.Webhooks = nil
+		if err := s.db.Model(group).Association("Webhooks").Find(&group.Webhooks); err != nil {
+			return fmt.Errorf("加载组默认 Webhook 失败: %w", err)
+		}
+	}
+
+	var existing []models.Project
+	if err := s.db.Where("gitlab_instance_id = ?", group.GitLabInstanceID).Find(&existing).Error; err != nil {
+		return err
+	}
+	byGitLabID := make(map[int]*models.Project, len(existing))
+	for idx := range existing {
+		byGitLabID[existing[idx].GitLabProjectID] = &existing[idx]
+	}
+
+	seen := make(map[int]bool, len(remoteProjects))
+	for _, info := range remoteProjects {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// 组项目接口会返回共享给组的其他项目，只处理路径位于组内的项目
+		path := strings.Trim(info.PathWithNamespace, "/")
+		if !group.Covers(path) {
+			continue
+		}
+		run.Scanned++
+		seen[info.ID] = true
+
+		state := models.ProjectGitLabStateActive
+		if info.Archived {
+			state = models.ProjectGitLabStateArchived
+		}
+
+		project, ok := byGitLabID[info.ID]
+		if !ok {
+			// 已归档的项目不再接收事件，无需登记
+			if !group.Watched || info.Archived {
+				continue
+			}
+			project = group.NewProject(info.ID, info.Name, info.WebURL, info.Description)
+			if err := s.db.Create(project).Error; err != nil {
+				return fmt.Errorf("登记项目 %s 失败: %w", path, err)
+			}
+			run.Added++
+			run.Changes = append(run.Changes, models.GroupSyncChange{
+				Action: models.GroupSyncActionAdded, ProjectID: project.ID, GitLabProjectID: info.ID, Path: path,
+			})
+			if group.HookMode == models.GroupHookModeProject {
+				s.createProjectHook(ctx, instance, project, token, path, run)
+			}
+			continue
+		}
+
+		if project.GitLabState == state {
+			continue
+		}
+		if err := s.db.Model(project).Update("gitlab_state", state).Error; err != nil {
+			return fmt.Errorf("更新项目 %s 状态失败: %w", path, err)
+		}
+		change := models.GroupSyncChange{ProjectID: project.ID, GitLabProjectID: info.ID, Path: path}
+		if state == models.ProjectGitLabStateArchived {
+			change.Action = models.GroupSyncActionArchived
+			run.Archived++
+		} else {
+			change.Action = models.GroupSyncActionRestored
+			run.Restored++
+		}
+		run.Changes = append(run.Changes, change)
+	}
+
+	// 已登记但 GitLab 中不再出现的项目：已删除或已移出该组
+	for idx := range existing {
+		project := &existing[idx]
+		if seen[project.GitLabProjectID] || project.GitLabState == models.ProjectGitLabStateMissing {
+			continue
+		}
+		path := s.projectPath(project.URL)
+		fromGroup := project.SourceGroupID != nil && *project.SourceGroupID == group.ID
+		if !fromGroup && !group.Covers(path) {
+			continue
+		}
+		if err := s.db.Model(project).Update("gitlab_state", models.ProjectGitLabStateMissing).Error; err != nil {
+			return fmt.Errorf("更新项目 %s 状态失败: %w", project.Name, err)
+		}
+		run.Missing++
+		run.Changes = append(run.Changes, models.GroupSyncChange{
+			Action: models.GroupSyncActionMissing, ProjectID: project.ID, GitLabProjectID: project.GitLabProjectID, Path: path,
+		})
+	}
+	return nil
+}
+
+// createProjectHook 项目级接入方式下为新登记的项目创建 Webhook，失败只记录在变更报告中
+func (s *gitLabGroupService) createProjectHook(ctx context.Context, instance *models.GitLabInstance, project *models.Project, token, path string, run *models.GitLabGroupSyncRun) {
+	change := models.GroupSyncChange{ProjectID: project.ID, GitLabProjectID: project.GitLabProjectID, Path: path}
+
+	events := HookEventsForProject(project)
+	events.SecretToken = s.instances.WebhookSecret(instance.ID)
+	events.SSLVerification = s.cfg.HookReconcile.EnableSSLVerification
+	webhook, _, err := s.gitlab.SyncProjectWebhook(ctx, instance.BaseURL, project.GitLabProjectID, s.instances.InboundWebhookURL(instance), events, token)
+	if err != nil {
+		change.Action = models.GroupSyncActionHookFailed
+		change.Detail = err.Error()
+		run.Changes = append(run.Changes, change)
+		return
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&models.Project{}).Where("id = ?", project.ID).Updates(map[string]interface{}{
+		"gitlab_webhook_id": webhook.ID,
+		"webhook_synced":    true,
+		"last_sync_at":      &now,
+	}).Error; err != nil {
+		logger.GetLogger().Warnf("保存项目 %d webhook状态失败: %v", project.ID, err)
+	}
+	change.Action = models.GroupSyncActionHookCreated
+	run.Changes = append(run.Changes, change)
+}
+
+func (s *gitLabGroupService) setAccessToken(group *models.GitLabGroup, accessToken string) error {
+	accessToken = strings.TrimSpace(accessToken)
+	if accessToken == "" {
+		group.AccessToken = ""
+		return nil
+	}
+
+	encrypted, err := security.Encrypt(s.cfg.EncryptionKey, accessToken)
+	if err != nil {
+		return fmt.Errorf("加密组令牌失败: %w", err)
+	}
+	group.AccessToken = encrypted
+	return nil
+}
+
+// pruneSyncRuns 只保留每个组最近的同步记录
+func (s *gitLabGroupService) pruneSyncRuns(groupID uint) {
+	var cutoff models.GitLabGroupSyncRun
+	err := s.db.Select("id").Where("group_id = ?", groupID).Order("id DESC").Offset(groupSyncRunsKeep).First(&cutoff).Error
+	if err != nil {
+		return
+	}
+	if err := s.db.Where("group_id = ? AND id <= ?", groupID, cutoff.ID).Delete(&models.GitLabGroupSyncRun{}).Error; err != nil {
+		logger.GetLogger().Warnf("清理组 %d 的同步记录失败: %v", groupID, err)
+	}
+}
+
+// syncedGroups 查询使用组 Hook 且已同步的组，instanceID 为 0 时返回所有实例的组
+func (s *gitLabGroupService) syncedGroups(instanceID uint) ([]models.GitLabGroup, error) {
+	query := s.db.Where("hook_mode = ? AND hook_synced = ?", models.GroupHookModeGroup, true)
+	if instanceID != 0 {
+		query = query.Where("gitlab_instance_id = ?", instanceID)
+	}
+
+	var groups []models.GitLabGroup
+	if err := query.Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (s *gitLabGroupService) projectPath(projectURL string) string {
+	if projectURL == "" {
+		return ""
+	}
+	parsed := s.gitlab.ParseGitLabURL(projectURL)
+	if !parsed.IsValid {
+		return ""
+	}
+	return parsed.ProjectPath
+}
+
+func (s *gitLabGroupService) loadWebhooks(ids []uint) ([]models.Webhook, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var webhooks []models.Webhook
+	if err := s.db.Where("id IN ?", ids).Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	if len(webhooks) != len(uniqueIDs(ids)) {
+		return nil, fmt.Errorf("部分 Webhook 不存在")
+	}
+	return webhooks, nil
+}
+
+// deepestCoveringGroup 在组列表中查找包含项目路径且层级最深的组，watchedOnly 为 true 时只考虑开启自动登记的组
+func deepestCoveringGroup(groups []models.GitLabGroup, projectPath string, watchedOnly bool) *models.GitLabGroup {
+	var matched *models.GitLabGroup
+	for idx := range groups {
+		if watchedOnly && !groups[idx].Watched {
+			continue
+		}
+		if !groups[idx].Covers(projectPath) {
+			continue
+		}
+		if matched == nil || len(groups[idx].FullPath) > len(matched.FullPath) {
+			matched = &groups[idx]
+		}
+	}
+	return matched
+}
+
+// accountGitLabToken 读取账户保存的 GitLab 令牌，解密失败时按旧版明文令牌使用并发出告警
+func accountGitLabToken(db *gorm.DB, encryptionKey string, alerts OpsAlertService, accountID uint) (string, error) {
+	var account models.Account
+	if err := db.Select("id", "gitlab_access_token").First(&account, accountID).Error; err != nil {
+		return "", fmt.Errorf("查询账户 %d 失败: %w", accountID, err)
+	}
+	if account.GitLabAccessToken == "" {
+		return "", fmt.Errorf("账户 %d 未配置 GitLab 令牌", accountID)
+	}
+
+	decrypted, err := security.Decrypt(encryptionKey, account.GitLabAccessToken)
+	if err != nil {
+		logger.GetLogger().Warnf("Failed to decrypt GitLab token for account %d, fallback to legacy plaintext: %v", account.ID, err)
+		if alerts != nil {
+			alerts.Notify(TokenDecryptFallbackAlert(account.ID, err))
+		}
+		return strings.TrimSpace(account.GitLabAccessToken), nil
+	}
+	return strings.TrimSpace(decrypted), nil
+}
+
+func uniqueIDs(ids []uint) map[uint]struct{} {
+	seen := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		seen[id] = struct{}{}
+	}
+	return seen
+}
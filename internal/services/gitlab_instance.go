@@ -0,0 +1,375 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/security"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrGitLabInstanceNotFound = errors.New("gitlab instance not found")
+	ErrGitLabInstanceExists   = errors.New("gitlab instance name or base url already exists")
+	ErrGitLabInstanceInUse    = errors.New("gitlab instance still has projects")
+	ErrGitLabInstanceDefault  = errors.New("default gitlab instance is managed by gitlab_url")
+	ErrInvalidGitLabBaseURL   = errors.New("invalid gitlab base url")
+)
+
+const defaultGitLabInstanceName = "default"
+
+type gitLabInstanceService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewGitLabInstanceService(db *gorm.DB, cfg *config.Config) GitLabInstanceService {
+	return &gitLabInstanceService{db: db, cfg: cfg}
+}
+
+// EnsureDefault 按配置的 gitlab_url 维护默认实例，并把未归属实例的项目回填到默认实例
+func (s *gitLabInstanceService) EnsureDefault(baseURL string) (*models.GitLabInstance, error) {
+	normalized := models.NormalizeGitLabBaseURL(baseURL)
+	if normalized == "" {
+		return nil, ErrInvalidGitLabBaseURL
+	}
+
+	var instance models.GitLabInstance
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("is_default = ?", true).First(&instance).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			// 同地址的实例已手动登记时直接升级为默认实例
+			if err := tx.Where("base_url = ?", normalized).First(&instance).Error; err == nil {
+				instance.IsDefault = true
+			} else if errors.Is(err, gorm.ErrRecordNotFound) {
+				instance = models.GitLabInstance{Name: defaultGitLabInstanceName, BaseURL: normalized, IsDefault: true}
+			} else {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if instance.BaseURL != normalized {
+				logger.GetLogger().Infof("默认 GitLab 实例地址变更: %s -> %s", instance.BaseURL, normalized)
+			}
+			instance.BaseURL = normalized
+		}
+
+		if err := tx.Save(&instance).Error; err != nil {
+			return fmt.Errorf("保存默认 GitLab 实例失败: %w", err)
+		}
+
+		result := tx.Model(&models.Project{}).Where("gitlab_instance_id = 0").Update("gitlab_instance_id", instance.ID)
+		if result.Error != nil {
+			return fmt.Errorf("回填项目所属实例失败: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			logger.GetLogger().Infof("已将 %d 个项目归入默认 GitLab 实例 %s", result.RowsAffected, normalized)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+func (s *gitLabInstanceService) List() ([]models.GitLabInstance, error) {
+	var instances []models.GitLabInstance
+	if err := s.db.Order("is_default DESC, id ASC").Find(&instances).Error; err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+func (s *gitLabInstanceService) Get(id uint) (*models.GitLabInstance, error) {
+	var instance models.GitLabInstance
+	if err := s.db.First(&instance, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGitLabInstanceNotFound
+		}
+		return nil, err
+	}
+	return &instance, nil
+}
+
+func (s *gitLabInstanceService) Create(req *models.GitLabInstanceRequest) (*models.GitLabInstance, error) {
+	instance := &models.GitLabInstance{}
+	if err := s.apply(instance, req); err != nil {
+		return nil, err
+	}
+	if err := s.checkUnique(instance); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(instance).Error; err != nil {
+		return nil, fmt.Errorf("保存 GitLab 实例失败: %w", err)
+	}
+	return instance, nil
+}
+
+func (s *gitLabInstanceService) Update(id uint, req *models.GitLabInstanceRequest) (*models.GitLabInstance, error) {
+	instance, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	previousBaseURL := instance.BaseURL
+	if err := s.apply(instance, req); err != nil {
+		return nil, err
+	}
+	if instance.IsDefault && instance.BaseURL != previousBaseURL {
+		return nil, ErrGitLabInstanceDefault
+	}
+	if err := s.checkUnique(instance); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(instance).Error; err != nil {
+		return nil, fmt.Errorf("保存 GitLab 实例失败: %w", err)
+	}
+	return instance, nil
+}
+
+func (s *gitLabInstanceService) Delete(id uint) error {
+	instance, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if instance.IsDefault {
+		return ErrGitLabInstanceDefault
+	}
+
+	count, err := s.ProjectCount(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrGitLabInstanceInUse
+	}
+	return s.db.Delete(&models.GitLabInstance{}, id).Error
+}
+
+func (s *gitLabInstanceService) ProjectCount(id uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.Project{}).Where("gitlab_instance_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// ResolveURL 返回 URL 所属的实例，多个实例匹配时取地址最长的一个（子路径部署优先），未匹配时返回 nil
+func (s *gitLabInstanceService) ResolveURL(rawURL string) *models.GitLabInstance {
+	instances, err := s.List()
+	if err != nil {
+		logger.GetLogger().Warnf("查询 GitLab 实例失败: %v", err)
+		return nil
+	}
+
+	var matched *models.GitLabInstance
+	for i := range instances {
+		if !instances[i].MatchesURL(rawURL) {
+			continue
+		}
+		if matched == nil || len(instances[i].BaseURL) > len(matched.BaseURL) {
+			matched = &instances[i]
+		}
+	}
+	return matched
+}
+
+// ResolveRef 按实例 ID、名称或地址查找实例，GitLab 的 X-Gitlab-Instance 头携带的是实例地址
+func (s *gitLabInstanceService) ResolveRef(ref string) (*models.GitLabInstance, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, ErrGitLabInstanceNotFound
+	}
+	if id, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		return s.Get(uint(id))
+	}
+
+	var instance models.GitLabInstance
+	err := s.db.Where("name = ?", ref).First(&instance).Error
+	if err == nil {
+		return &instance, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	normalized := models.NormalizeGitLabBaseURL(ref)
+	if normalized == "" {
+		return nil, ErrGitLabInstanceNotFound
+	}
+	if matched := s.ResolveURL(normalized); matched != nil {
+		return matched, nil
+	}
+
+	// 头中只有主机地址，实例部署在子路径下时按唯一前缀匹配
+	instances, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var matched *models.GitLabInstance
+	for i := range instances {
+		if strings.HasPrefix(instances[i].BaseURL, normalized+"/") {
+			if matched != nil {
+				return nil, ErrGitLabInstanceNotFound
+			}
+			matched = &instances[i]
+		}
+	}
+	if matched == nil {
+		return nil, ErrGitLabInstanceNotFound
+	}
+	return matched, nil
+}
+
+// ResolveEvent 按事件中的项目地址识别实例
+// 只登记了默认实例时不存在项目 ID 冲突，地址无法匹配（内部地址、端口或协议不同）也归入默认实例；
+// 登记了多个实例时地址必须匹配，否则可能误通知其他实例中同 ID 的项目
+func (s *gitLabInstanceService) ResolveEvent(projectURL string) (*models.GitLabInstance, error) {
+	if projectURL != "" {
+		if matched := s.ResolveURL(projectURL); matched != nil {
+			return matched, nil
+		}
+	}
+
+	var others int64
+	if err := s.db.Model(&models.GitLabInstance{}).Where("is_default = ?", false).Count(&others).Error; err != nil {
+		return nil, err
+	}
+	if others > 0 {
+		return nil, ErrGitLabInstanceNotFound
+	}
+	return s.Default()
+}
+
+// Default 返回默认实例
+func (s *gitLabInstanceService) Default() (*models.GitLabInstance, error) {
+	var instance models.GitLabInstance
+	if err := s.db.Where("is_default = ?", true).First(&instance).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGitLabInstanceNotFound
+		}
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// DefaultToken 返回实例的默认访问令牌，未配置或解密失败时返回空字符串
+func (s *gitLabInstanceService) DefaultToken(instanceID uint) string {
+	if instanceID == 0 {
+		return ""
+	}
+
+	var instance models.GitLabInstance
+	if err := s.db.Select("id", "default_token").First(&instance, instanceID).Error; err != nil {
+		return ""
+	}
+	return s.decrypt(instance.ID, instance.DefaultToken)
+}
+
+// WebhookSecret 返回写入 GitLab Hook 的 Secret Token，未配置时返回空字符串
+func (s *gitLabInstanceService) WebhookSecret(instanceID uint) string {
+	if instanceID == 0 {
+		return ""
+	}
+
+	var instance models.GitLabInstance
+	if err := s.db.Select("id", "webhook_secret").First(&instance, instanceID).Error; err != nil {
+		return ""
+	}
+	return s.decrypt(instance.ID, instance.WebhookSecret)
+}
+
+// InboundWebhookURL 实例的回调地址：默认实例沿用原地址，避免已创建的 hook 失配；其他实例在路径中带上实例 ID
+func (s *gitLabInstanceService) InboundWebhookURL(instance *models.GitLabInstance) string {
+	webhookURL := buildWebhookURL(s.cfg.PublicWebhookURL)
+	if instance == nil || instance.IsDefault {
+		return webhookURL
+	}
+	return fmt.Sprintf("%s/%d", webhookURL, instance.ID)
+}
+
+// VerifyWebhookSecret 校验回调携带的 X-Gitlab-Token，实例未配置密钥时不校验
+func (s *gitLabInstanceService) VerifyWebhookSecret(instance *models.GitLabInstance, token string) bool {
+	if instance.WebhookSecret == "" {
+		return true
+	}
+	secret := s.decrypt(instance.ID, instance.WebhookSecret)
+	return secret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1
+}
+
+func (s *gitLabInstanceService) apply(instance *models.GitLabInstance, req *models.GitLabInstanceRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("实例名称不能为空")
+	}
+	baseURL := models.NormalizeGitLabBaseURL(req.BaseURL)
+	if baseURL == "" {
+		return ErrInvalidGitLabBaseURL
+	}
+
+	instance.Name = name
+	instance.BaseURL = baseURL
+
+	if req.DefaultToken != nil {
+		encrypted, err := s.encrypt(*req.DefaultToken)
+		if err != nil {
+			return err
+		}
+		instance.DefaultToken = encrypted
+	}
+	if req.WebhookSecret != nil {
+		encrypted, err := s.encrypt(*req.WebhookSecret)
+		if err != nil {
+			return err
+		}
+		instance.WebhookSecret = encrypted
+	}
+	return nil
+}
+
+func (s *gitLabInstanceService) checkUnique(instance *models.GitLabInstance) error {
+	var count int64
+	err := s.db.Model(&models.GitLabInstance{}).
+		Where("(name = ? OR base_url = ?) AND id <> ?", instance.Name, instance.BaseURL, instance.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrGitLabInstanceExists
+	}
+	return nil
+}
+
+// encrypt 空字符串表示清除已保存的值
+func (s *gitLabInstanceService) encrypt(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	encrypted, err := security.Encrypt(s.cfg.EncryptionKey, value)
+	if err != nil {
+		return "", fmt.Errorf("加密失败: %w", err)
+	}
+	return encrypted, nil
+}
+
+func (s *gitLabInstanceService) decrypt(instanceID uint, value string) string {
+	if value == "" {
+		return ""
+	}
+	decrypted, err := security.Decrypt(s.cfg.EncryptionKey, value)
+	if err != nil {
+		logger.GetLogger().Warnf("解密 GitLab 实例 %d 的凭证失败: %v", instanceID, err)
+		return ""
+	}
+	return strings.TrimSpace(decrypted)
+}
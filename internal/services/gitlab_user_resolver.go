@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultUserLookupTimeout = 3 * time.Second
+	defaultUserLookupMissTTL = time.Hour
+)
+
+type gitLabUserResolver struct {
+	db        *gorm.DB
+	cfg       *config.Config
+	gitlab    GitLabService
+	instances GitLabInstanceService
+	targets   HookReconcileService
+
+	mu     sync.Mutex
+	misses map[string]time.Time
+}
+
+func NewGitLabUserResolver(db *gorm.DB, cfg *config.Config, gitlab GitLabService, instances GitLabInstanceService, targets HookReconcileService) GitLabUserResolver {
+	return &gitLabUserResolver{
+		db:        db,
+		cfg:       cfg,
+		gitlab:    gitlab,
+		instances: instances,
+		targets:   targets,
+		misses:    make(map[string]time.Time),
+	}
+}
+
+// gitLabEmailCandidate 查询到的邮箱及其来源
+type gitLabEmailCandidate struct {
+	email  string
+	source string
+}
+
+// Resolve 先按用户 ID 查询公开邮箱（管理员令牌还会返回主邮箱），仍未匹配时用管理员令牌按用户名查询；
+// 匹配到本地用户且其未配置 GitLab 用户名时记录映射并标记为待确认，之后的事件直接按用户名匹配
+func (r *gitLabUserResolver) Resolve(ctx context.Context, project *models.Project, assignee models.AssigneeInfo) *models.User {
+	settings := r.cfg.UserLookup
+	if !settings.Enabled || project == nil || (assignee.UserID == 0 && assignee.Username == "") {
+		return nil
+	}
+
+	// 催办等场景只有用户名，优先按用户名缓存，保证同一用户在不同场景共用查询结果
+	key := fmt.Sprintf("%d/%s", project.GitLabInstanceID, assignee.Username)
+	if assignee.Username == "" {
+		key = fmt.Sprintf("%d/#%d", project.GitLabInstanceID, assignee.UserID)
+	}
+	if r.recentlyMissed(key) {
+		return nil
+	}
+
+	target, err := r.targets.ResolveTarget(project)
+	if err != nil {
+		logger.GetLogger().Debugf("查询 GitLab 用户 %s 时获取令牌失败: %v", assignee.Username, err)
+		return nil
+	}
+
+	timeout := settings.Timeout
+	if timeout <= 0 {
+		timeout = defaultUserLookupTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if assignee.UserID != 0 {
+		user, err := r.gitlab.GetUser(ctx, target.BaseURL, assignee.UserID, target.Token)
+		if err != nil {
+			logger.GetLogger().Debugf("查询 GitLab 用户 %d 失败: %v", assignee.UserID, err)
+		} else if found := r.match(user, assignee.Username, emailCandidates(user)); found != nil {
+			return found
+		}
+	}
+
+	if adminToken := r.adminToken(project, target.Token); adminToken != "" && assignee.Username != "" {
+		user, err := r.gitlab.FindUserByUsername(ctx, target.BaseURL, assignee.Username, adminToken)
+		if err != nil {
+			logger.GetLogger().Debugf("按用户名查询 GitLab 用户 %s 失败: %v", assignee.Username, err)
+		} else if user != nil {
+			if found := r.match(user, assignee.Username, emailCandidates(user)); found != nil {
+				return found
+			}
+		}
+	}
+
+	// 因截止时间未查完的不记为未匹配，下次事件重新查询
+	if ctx.Err() == nil {
+		r.remember(key, settings.MissTTL)
+	}
+	return nil
+}
+
+// adminToken 按用户名查询使用的令牌：默认实例使用配置的管理员令牌，其他实例使用实例的默认令牌（仅管理员令牌能返回邮箱）
+func (r *gitLabUserResolver) adminToken(project *models.Project, fallback string) string {
+	instance, err := r.instances.Get(project.GitLabInstanceID)
+	if err != nil {
+		return ""
+	}
+	if instance.IsDefault {
+		return strings.TrimSpace(r.cfg.UserLookup.AdminToken)
+	}
+	return fallback
+}
+
+func emailCandidates(user *GitLabUserDetail) []gitLabEmailCandidate {
+	var candidates []gitLabEmailCandidate
+	if email := strings.TrimSpace(user.PublicEmail); email != "" {
+		candidates = append(candidates, gitLabEmailCandidate{email: email, source: models.GitLabMappingSourcePublicEmail})
+	}
+	if email := strings.TrimSpace(user.Email); email != "" && !strings.EqualFold(email, user.PublicEmail) {
+		candidates = append(candidates, gitLabEmailCandidate{email: email, source: models.GitLabMappingSourceAdminLookup})
+	}
+	return candidates
+}
+
+// match 按邮箱查找本地用户，找到时记录待确认的用户名映射
+func (r *gitLabUserResolver) match(gitlabUser *GitLabUserDetail, username string, candidates []gitLabEmailCandidate) *models.User {
+	if username == "" {
+		username = gitlabUser.Username
+	}
+
+	for _, candidate := range candidates {
+		user, _, err := findUserByEmail(r.db, candidate.email)
+		if err != nil {
+			continue
+		}
+		if user.GitLabUsername == "" && username != "" {
+			r.recordMapping(user, username, candidate.source)
+		}
+		return user
+	}
+	return nil
+}
+
+func (r *gitLabUserResolver) recordMapping(user *models.User, username, source string) {
+	if gitLabUsernameTaken(r.db, username, 0) {
+		return
+	}
+
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND gitlab_username = ''", user.ID).
+		Updates(map[string]interface{}{
+			"gitlab_username":        username,
+			"gitlab_mapping_source":  source,
+			"gitlab_mapping_pending": true,
+		})
+	if result.Error != nil {
+		logger.GetLogger().Warnf("记录用户 %s 的 GitLab 用户名映射失败: %v", user.Email, result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		user.GitLabUsername = username
+		user.GitLabMappingSource = source
+		user.GitLabMappingPending = true
+		logger.GetLogger().Infof("通过 GitLab 用户接口（%s）将用户 %s 映射到 GitLab 用户名 %s，待确认", source, user.Email, username)
+	}
+}
+
+func (r *gitLabUserResolver) recentlyMissed(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expiresAt, ok := r.misses[key]
+	return ok && time.Now().Before(expiresAt)
+}
+
+func (r *gitLabUserResolver) remember(key string, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = defaultUserLookupMissTTL
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for cachedKey, expiresAt := range r.misses {
+		if now.After(expiresAt) {
+			delete(r.misses, cachedKey)
+		}
+	}
+	r.misses[key] = now.Add(ttl)
+}
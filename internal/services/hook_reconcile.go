@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// webhookTokenRejectionThreshold 回调令牌校验失败累计达到该次数才记为令牌偏差
+const webhookTokenRejectionThreshold = 3
+
+// HookTarget 项目 hook 所在实例的地址、回调地址、访问令牌与 Secret Token
+type HookTarget struct {
+	BaseURL     string
+	WebhookURL  string
+	Token       string
+	SecretToken string
+}
+
+// HookInspection 项目 hook 的检查结果，Hook 为 nil 表示 GitLab 中不存在指向本服务的 hook
+type HookInspection struct {
+	Hook  *GitLabWebhook
+	Drift []models.WebhookDriftItem
+}
+
+type hookReconcileService struct {
+	db        *gorm.DB
+	cfg       *config.Config
+	gitlab    GitLabService
+	instances GitLabInstanceService
+	groups    GitLabGroupService
+	alerts    OpsAlertService
+}
+
+func NewHookReconcileService(db *gorm.DB, cfg *config.Config, gitlab GitLabService, instances GitLabInstanceService, groups GitLabGroupService, alerts OpsAlertService) HookReconcileService {
+	return &hookReconcileService{
+		db:        db,
+		cfg:       cfg,
+		gitlab:    gitlab,
+		instances: instances,
+		groups:    groups,
+		alerts:    alerts,
+	}
+}
+
+// DesiredEvents 项目 hook 的期望配置：按项目订阅开启事件，SSL 校验取自配置
+func (s *hookReconcileService) DesiredEvents(project *models.Project, secretToken string) GitLabHookEvents {
+	events := HookEventsForProject(project)
+	events.SSLVerification = s.cfg.HookReconcile.EnableSSLVerification
+	events.SecretToken = secretToken
+	return events
+}
+
+// ResolveTarget 后台任务使用的 hook 配置，令牌依次使用实例默认令牌、服务令牌（仅默认实例）与项目创建者的个人令牌
+func (s *hookReconcileService) ResolveTarget(project *models.Project) (HookTarget, error) {
+	instance, err := s.instances.Get(project.GitLabInstanceID)
+	if err != nil {
+		return HookTarget{}, fmt.Errorf("查询项目所属的 GitLab 实例失败: %w", err)
+	}
+
+	target := HookTarget{
+		BaseURL:     instance.BaseURL,
+		WebhookURL:  s.instances.InboundWebhookURL(instance),
+		Token:       s.instances.DefaultToken(instance.ID),
+		SecretToken: s.instances.WebhookSecret(instance.ID),
+	}
+	if target.Token == "" && instance.IsDefault {
+		target.Token = strings.TrimSpace(s.cfg.GitLabServiceToken)
+	}
+	if target.Token == "" {
+		if project.CreatedBy == nil {
+			return target, fmt.Errorf("项目未配置创建者，无法获取 GitLab 令牌")
+		}
+		if target.Token, err = accountGitLabToken(s.db, s.cfg.EncryptionKey, s.alerts, *project.CreatedBy); err != nil {
+			return target, err
+		}
+	}
+	return target, nil
+}
+
+// Inspect 对比 GitLab 中的 hook 与期望配置，并记录检查结果
+func (s *hookReconcileService) Inspect(ctx context.Context, project *models.Project, target HookTarget) (*HookInspection, error) {
+	hook, err := s.gitlab.FindWebhookByURL(ctx, target.BaseURL, project.GitLabProjectID, target.WebhookURL, target.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := &HookInspection{Hook: hook}
+	if hook == nil {
+		inspection.Drift = append(inspection.Drift, models.WebhookDriftItem{
+			Field: models.WebhookDriftFieldHook, Expected: "present", Actual: "missing",
+		})
+	} else {
+		inspection.Drift = s.DesiredEvents(project, target.SecretToken).Drift(hook)
+	}
+	// GitLab 不回显 Secret Token，只能根据回调校验失败的记录判断令牌被改动；
+	// 回调无需认证，单次失败可能是伪造的请求，累计多次才记为偏差
+	if project.WebhookTokenRejections >= webhookTokenRejectionThreshold && target.SecretToken != "" {
+		inspection.Drift = append(inspection.Drift, models.WebhookDriftItem{
+			Field: models.WebhookDriftFieldToken, Expected: "configured", Actual: "rejected",
+		})
+	}
+
+	now := time.Now()
+	fields := make(models.StringList, 0, len(inspection.Drift))
+	for _, item := range inspection.Drift {
+		fields = append(fields, item.Field)
+	}
+	if err := s.db.Model(&models.Project{}).Where("id = ?", project.ID).Updates(map[string]interface{}{
+		"webhook_drift":    fields,
+		"drift_checked_at": &now,
+	}).Error; err != nil {
+		logger.GetLogger().Warnf("保存项目 %d 的 hook 检查结果失败: %v", project.ID, err)
+	}
+	project.WebhookDrift = fields
+	project.DriftCheckedAt = &now
+	return inspection, nil
+}
+
+// Repair 按期望配置重建或更新 hook，返回修复前的检查结果
+func (s *hookReconcileService) Repair(ctx context.Context, project *models.Project, target HookTarget) (*HookInspection, error) {
+	inspection, err := s.Inspect(ctx, project, target)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, _, err := s.gitlab.SyncProjectWebhook(ctx, target.BaseURL, project.GitLabProjectID, target.WebhookURL, s.DesiredEvents(project, target.SecretToken), target.Token)
+	if err != nil {
+		return inspection, fmt.Errorf("修复 hook 失败: %w", err)
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&models.Project{}).Where("id = ?", project.ID).Updates(map[string]interface{}{
+		"gitlab_webhook_id":         webhook.ID,
+		"webhook_synced":            true,
+		"last_sync_at":              &now,
+		"webhook_drift":             models.StringList{},
+		"drift_checked_at":          &now,
+		"webhook_token_rejected_at": nil,
+		"webhook_token_rejections":  0,
+	}).Error; err != nil {
+		return inspection, fmt.Errorf("保存项目 hook 状态失败: %w", err)
+	}
+	project.GitLabWebhookID = &webhook.ID
+	project.WebhookSynced = true
+	project.LastSyncAt = &now
+	project.ClearWebhookDrift(now)
+
+	if len(inspection.Drift) > 0 {
+		logger.GetLogger().Infof("项目 %s 的 GitLab hook 已修复，偏差: %s", project.Name, driftFields(inspection.Drift))
+	}
+	return inspection, nil
+}
+
+// RecordTokenRejected 记录项目回调的 Secret Token 校验失败，作为令牌被改动的依据
+// 只记录带有 X-Gitlab-Event 头且项目路径与登记地址一致的回调，减少伪造请求的影响
+func (s *hookReconcileService) RecordTokenRejected(instanceID uint, event string, eventProject models.GitLabProject) {
+	if eventProject.ID == 0 || !strings.HasSuffix(event, " Hook") {
+		return
+	}
+
+	var project models.Project
+	if err := s.db.Select("id", "url").Where("gitlab_instance_id = ? AND gitlab_project_id = ?", instanceID, eventProject.ID).
+		First(&project).Error; err != nil {
+		return
+	}
+	if !eventProjectMatches(project.URL, eventProject) {
+		logger.GetLogger().Debugf("回调中的项目路径与项目 %d 的登记地址不一致，不记录令牌校验失败", project.ID)
+		return
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&models.Project{}).Where("id = ?", project.ID).Updates(map[string]interface{}{
+		"webhook_token_rejected_at": &now,
+		"webhook_token_rejections":  gorm.Expr("webhook_token_rejections + 1"),
+	}).Error; err != nil {
+		logger.GetLogger().Warnf("记录项目 %d 的回调令牌校验失败: %v", eventProject.ID, err)
+	}
+}
+
+// eventProjectMatches 比较回调中的项目路径与登记的项目地址，实例部署在子路径下时按后缀匹配
+func eventProjectMatches(projectURL string, eventProject models.GitLabProject) bool {
+	path := strings.Trim(eventProject.PathWithNamespace, "/")
+	if path == "" {
+		parsed, err := url.Parse(eventProject.WebURL)
+		if err != nil {
+			return false
+		}
+		path = strings.Trim(parsed.Path, "/")
+	}
+	parsed, err := url.Parse(projectURL)
+	if err != nil || path == "" {
+		return false
+	}
+	stored := strings.ToLower(strings.Trim(parsed.Path, "/"))
+	path = strings.ToLower(path)
+	return stored == path || strings.HasSuffix(stored, "/"+path)
+}
+
+// ReconcileAll 检查所有由本服务管理项目级 hook 的项目，开启自动修复时修复发现的偏差，否则发送系统告警
+func (s *hookReconcileService) ReconcileAll(ctx context.Context) error {
+	var projects []models.Project
+	if err := s.db.Where("gitlab_state = ? AND (webhook_synced = ? OR gitlab_webhook_id IS NOT NULL)", models.ProjectGitLabStateActive, true).
+		Find(&projects).Error; err != nil {
+		return err
+	}
+	coverage := s.groups.CoverageForProjects(projects)
+
+	var checked, drifted, repaired, failed int
+	for idx := range projects {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		project := &projects[idx]
+		if coverage[project.ID] != nil {
+			continue
+		}
+
+		target, err := s.ResolveTarget(project)
+		if err != nil {
+			logger.GetLogger().Warnf("检查项目 %s 的 hook 失败: %v", project.Name, err)
+			failed++
+			continue
+		}
+		inspection, err := s.Inspect(ctx, project, target)
+		if err != nil {
+			logger.GetLogger().Warnf("检查项目 %s 的 hook 失败: %v", project.Name, err)
+			failed++
+			continue
+		}
+		checked++
+		if len(inspection.Drift) == 0 {
+			continue
+		}
+		drifted++
+
+		// 令牌偏差只来自回调校验失败的记录，不足以证明 hook 被改动，不自动改写 GitLab 中的 hook
+		tokenOnly := onlyTokenDrift(inspection.Drift)
+		if !s.cfg.HookReconcile.AutoRepair || tokenOnly {
+			hint := "可在项目 Webhook 状态中修复，或开启 hook_reconcile.auto_repair"
+			if tokenOnly {
+				hint = "该项目的回调多次未通过 Secret Token 校验，确认 hook 的令牌被改动后可在项目 Webhook 状态中手动修复"
+			}
+			s.alerts.Notify(OpsAlertEvent{
+				Severity: models.OpsSeverityWarning,
+				Category: models.OpsCategoryHookDrift,
+				Key:      fmt.Sprintf("gitlab_hook_drift:project:%d", project.ID),
+				Title:    fmt.Sprintf("项目 %s 的 GitLab Hook 配置与期望不一致", project.Name),
+				Detail:   fmt.Sprintf("偏差: %s\n%s", driftFields(inspection.Drift), hint),
+			})
+			continue
+		}
+		if _, err := s.Repair(ctx, project, target); err != nil {
+			failed++
+			s.alerts.Notify(OpsAlertEvent{
+				Severity: models.OpsSeverityWarning,
+				Category: models.OpsCategoryHookDrift,
+				Key:      fmt.Sprintf("gitlab_hook_drift:project:%d", project.ID),
+				Title:    fmt.Sprintf("项目 %s 的 GitLab Hook 自动修复失败", project.Name),
+				Detail:   fmt.Sprintf("偏差: %s\nError: %v", driftFields(inspection.Drift), err),
+			})
+			continue
+		}
+		repaired++
+	}
+
+	if drifted > 0 || failed > 0 {
+		logger.GetLogger().Infof("Hook 偏差检查完成：检查 %d 个项目，发现偏差 %d 个，已修复 %d 个，失败 %d 个", checked, drifted, repaired, failed)
+	}
+	return nil
+}
+
+func onlyTokenDrift(drift []models.WebhookDriftItem) bool {
+	for _, item := range drift {
+		if item.Field != models.WebhookDriftFieldToken {
+			return false
+		}
+	}
+	return len(drift) > 0
+}
+
+func driftFields(drift []models.WebhookDriftItem) string {
+	fields := make([]string, 0, len(drift))
+	for _, item := range drift {
+		fields = append(fields, fmt.Sprintf("%s(期望 %v，实际 %v)", item.Field, item.Expected, item.Actual))
+	}
+	return strings.Join(fields, ", ")
+}
@@ -1,6 +1,10 @@
 package services
 
 import (
+	"context"
+	"io"
+	"time"
+
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
 )
 
@@ -43,34 +47,218 @@ type WebhookService interface {
 
 // NotificationService 通知服务接口
 type NotificationService interface {
-	ProcessMergeRequest(webhookData *models.GitLabWebhookData) error
+	ProcessMergeRequest(ctx context.Context, instanceID uint, webhookData *models.GitLabWebhookData) error
+	ProcessPushEvent(instanceID uint, event *models.GitLabPushEventData) error
+	ProcessTagPushEvent(instanceID uint, event *models.GitLabPushEventData) error
+	ProcessReleaseEvent(instanceID uint, event *models.GitLabReleaseEventData) error
+	ProcessPipelineEvent(instanceID uint, event *models.GitLabPipelineEventData) error
+	SendProjectMessage(ctx context.Context, project *models.Project, notification *models.Notification, message *TextMessage) error
+	ResolveMentions(ctx context.Context, project *models.Project, usernames []string) []Mention
 	GetAllNotifications() ([]models.NotificationResponse, error)
 	GetNotificationsByProjectID(projectID uint) ([]models.NotificationResponse, error)
 	GetRecentNotifications(limit int) ([]models.NotificationResponse, error)
 	GetNotificationStats() (map[string]interface{}, error)
 }
 
+// MergeRequestTracker 合并请求状态跟踪接口
+type MergeRequestTracker interface {
+	TrackEvent(project *models.Project, webhookData *models.GitLabWebhookData) (*models.MergeRequest, error)
+	TrackPipeline(project *models.Project, event *models.GitLabPipelineEventData) error
+	ListTransitions(mergeRequestID uint) ([]models.MergeRequestTransition, error)
+}
+
 // GitLabService GitLab服务接口
 type GitLabService interface {
 	ParseGitLabURL(projectURL string) *ParsedGitLabURL
-	GetProjectByURL(projectURL, accessToken string) (*GitLabProjectInfo, error)
-	GetProjectByPath(baseURL, projectPath, accessToken string) (*GitLabProjectInfo, error)
-	GetProject(projectID int, accessToken ...string) (*GitLabProjectInfo, error)
-	TestConnection(baseURL, accessToken string) error
-	GetGroupProjects(baseURL, groupPath, accessToken string) ([]*GitLabProjectInfo, error)
-	GetGroupByPath(baseURL, groupPath, accessToken string) (*GitLabGroupInfo, error)
-	ValidateProjectURL(projectURL string) (int, error)
-	CreateProjectWebhook(baseURL string, projectID int, webhookURL, accessToken string) (*GitLabWebhook, error)
-	ListProjectWebhooks(baseURL string, projectID int, accessToken string) ([]*GitLabWebhook, error)
-	DeleteProjectWebhook(baseURL string, projectID, webhookID int, accessToken string) error
-	FindWebhookByURL(baseURL string, projectID int, webhookURL, accessToken string) (*GitLabWebhook, error)
-	FindAllWebhooksByURL(baseURL string, projectID int, webhookURL, accessToken string) ([]*GitLabWebhook, error)
-	DeleteAllWebhooksByURL(baseURL string, projectID int, webhookURL, accessToken string) (int, error)
+	GetProjectByURL(ctx context.Context, projectURL, accessToken string) (*GitLabProjectInfo, error)
+	GetProjectByPath(ctx context.Context, baseURL, projectPath, accessToken string) (*GitLabProjectInfo, error)
+	GetProject(ctx context.Context, projectID int, accessToken ...string) (*GitLabProjectInfo, error)
+	TestConnection(ctx context.Context, baseURL, accessToken string) error
+	GetGroupProjects(ctx context.Context, baseURL, groupPath, accessToken string, allowPartial bool) ([]*GitLabProjectInfo, error)
+	GetGroupByPath(ctx context.Context, baseURL, groupPath, accessToken string) (*GitLabGroupInfo, error)
+	ValidateProjectURL(ctx context.Context, projectURL string) (int, error)
+	CreateProjectWebhook(ctx context.Context, baseURL string, projectID int, webhookURL string, events GitLabHookEvents, accessToken string) (*GitLabWebhook, error)
+	UpdateProjectWebhook(ctx context.Context, baseURL string, projectID, webhookID int, req *CreateWebhookRequest, accessToken string) (*GitLabWebhook, error)
+	SyncProjectWebhook(ctx context.Context, baseURL string, projectID int, webhookURL string, events GitLabHookEvents, accessToken string) (*GitLabWebhook, bool, error)
+	ListProjectWebhooks(ctx context.Context, baseURL string, projectID int, accessToken string) ([]*GitLabWebhook, error)
+	DeleteProjectWebhook(ctx context.Context, baseURL string, projectID, webhookID int, accessToken string) error
+	FindWebhookByURL(ctx context.Context, baseURL string, projectID int, webhookURL, accessToken string) (*GitLabWebhook, error)
+	FindAllWebhooksByURL(ctx context.Context, baseURL string, projectID int, webhookURL, accessToken string) ([]*GitLabWebhook, error)
+	DeleteAllWebhooksByURL(ctx context.Context, baseURL string, projectID int, webhookURL, accessToken string) (int, error)
+	CreateGroupWebhook(ctx context.Context, baseURL string, groupID int, webhookURL string, events GitLabHookEvents, accessToken string) (*GitLabWebhook, error)
+	UpdateGroupWebhook(ctx context.Context, baseURL string, groupID, webhookID int, req *CreateWebhookRequest, accessToken string) (*GitLabWebhook, error)
+	SyncGroupWebhook(ctx context.Context, baseURL string, groupID int, webhookURL string, events GitLabHookEvents, accessToken string) (*GitLabWebhook, bool, error)
+	ListGroupWebhooks(ctx context.Context, baseURL string, groupID int, accessToken string) ([]*GitLabWebhook, error)
+	DeleteGroupWebhook(ctx context.Context, baseURL string, groupID, webhookID int, accessToken string) error
+	DeleteGroupWebhooksByURL(ctx context.Context, baseURL string, groupID int, webhookURL, accessToken string) (int, error)
 	BuildWebhookURL(publicBaseURL string) string
+	ListOpenMergeRequests(ctx context.Context, baseURL string, projectID int, accessToken string) ([]*GitLabMergeRequestInfo, error)
+	GetMergeRequest(ctx context.Context, baseURL string, projectID, iid int, accessToken string) (*GitLabMergeRequestDetail, error)
+	GetMergeRequestApprovals(ctx context.Context, baseURL string, projectID, iid int, accessToken string) (*GitLabMergeRequestApprovals, error)
+	GetLatestMergeRequestPipeline(ctx context.Context, baseURL string, projectID, iid int, accessToken string) (*GitLabPipelineInfo, error)
+	ListMergeRequestDiffs(ctx context.Context, baseURL string, projectID, iid int, accessToken string) ([]*GitLabMergeRequestDiff, error)
+	GetUser(ctx context.Context, baseURL string, userID int, accessToken string) (*GitLabUserDetail, error)
+	GetCurrentUser(ctx context.Context, baseURL, accessToken string) (*GitLabUserDetail, error)
+	FindUserByUsername(ctx context.Context, baseURL, username, accessToken string) (*GitLabUserDetail, error)
+	ListGroupMembers(ctx context.Context, baseURL string, groupID int, accessToken string) ([]*GitLabMember, error)
+	ListProjectMembers(ctx context.Context, baseURL string, projectID int, accessToken string) ([]*GitLabMember, error)
+}
+
+// GitLabInstanceService GitLab 实例管理接口
+type GitLabInstanceService interface {
+	EnsureDefault(baseURL string) (*models.GitLabInstance, error)
+	List() ([]models.GitLabInstance, error)
+	Get(id uint) (*models.GitLabInstance, error)
+	Create(req *models.GitLabInstanceRequest) (*models.GitLabInstance, error)
+	Update(id uint, req *models.GitLabInstanceRequest) (*models.GitLabInstance, error)
+	Delete(id uint) error
+	ProjectCount(id uint) (int64, error)
+	ResolveURL(rawURL string) *models.GitLabInstance
+	ResolveRef(ref string) (*models.GitLabInstance, error)
+	ResolveEvent(projectURL string) (*models.GitLabInstance, error)
+	Default() (*models.GitLabInstance, error)
+	DefaultToken(instanceID uint) string
+	WebhookSecret(instanceID uint) string
+	InboundWebhookURL(instance *models.GitLabInstance) string
+	VerifyWebhookSecret(instance *models.GitLabInstance, token string) bool
+}
+
+// GitLabGroupService 组级 GitLab Webhook 与关注组同步管理接口
+type GitLabGroupService interface {
+	List() ([]models.GitLabGroup, error)
+	Get(id uint) (*models.GitLabGroup, error)
+	Create(group *models.GitLabGroup, webhookIDs []uint, accessToken string) error
+	Update(group *models.GitLabGroup, webhookIDs *[]uint, accessToken *string) error
+	Delete(id uint) error
+	SaveHookStatus(group *models.GitLabGroup, hookID *int, syncErr error) error
+	CoveringGroup(instanceID uint, projectPath string) *models.GitLabGroup
+	CoverageForProjects(projects []models.Project) map[uint]*models.GitLabGroup
+	CoveredProjects(group *models.GitLabGroup) ([]models.Project, error)
+	AutoRegisterProject(instanceID uint, project *models.GitLabProject) (*models.Project, *models.GitLabGroup, error)
+	ResolveToken(group *models.GitLabGroup) (string, error)
+	SyncGroup(ctx context.Context, group *models.GitLabGroup, trigger string) (*models.GitLabGroupSyncRun, error)
+	SyncWatchedGroups(ctx context.Context) error
+	ListSyncRuns(groupID uint, limit int) ([]models.GitLabGroupSyncRun, error)
+}
+
+// HookReconcileService 项目 hook 偏差检查与修复接口
+type HookReconcileService interface {
+	DesiredEvents(project *models.Project, secretToken string) GitLabHookEvents
+	ResolveTarget(project *models.Project) (HookTarget, error)
+	Inspect(ctx context.Context, project *models.Project, target HookTarget) (*HookInspection, error)
+	Repair(ctx context.Context, project *models.Project, target HookTarget) (*HookInspection, error)
+	RecordTokenRejected(instanceID uint, event string, project models.GitLabProject)
+	ReconcileAll(ctx context.Context) error
+}
+
+// MergeRequestEnricher 通过 GitLab API 补充合并请求通知的信息
+type MergeRequestEnricher interface {
+	Enrich(ctx context.Context, project *models.Project, mergeRequest *models.GitLabMergeRequest) *MergeRequestDetails
+}
+
+// GitLabUserResolver 通过 GitLab 用户接口为未匹配的指派人查找本地用户
+type GitLabUserResolver interface {
+	Resolve(ctx context.Context, project *models.Project, assignee models.AssigneeInfo) *models.User
+}
+
+// UserSyncService 从 GitLab 组或项目成员同步用户目录
+type UserSyncService interface {
+	ListSources() ([]models.UserSyncSource, error)
+	GetSource(id uint) (*models.UserSyncSource, error)
+	CreateSource(source *models.UserSyncSource) error
+	DeleteSource(id uint) error
+	// Sync 同步指定来源，sourceIDs 为空时同步全部来源
+	Sync(ctx context.Context, trigger string, sourceIDs ...uint) (*models.UserSyncRun, error)
+	SyncAll(ctx context.Context) error
+	ListRuns(limit int) ([]models.UserSyncRun, error)
+	Report() (*models.UserSyncReport, error)
+}
+
+// UserAliasService 用户别名管理与重复用户合并
+type UserAliasService interface {
+	ListAliases(userID uint) ([]models.UserAlias, error)
+	AddAlias(userID uint, req *models.UserAliasRequest) (*models.UserAlias, error)
+	DeleteAlias(userID, aliasID uint) error
+	// CheckAvailable 检查邮箱或 GitLab 用户名未被其他用户作为主字段或别名使用
+	CheckAvailable(kind, value string, exceptUserID uint) error
+	// MergeUsers 将 sourceID 用户合并到 targetID 用户并删除 sourceID 用户
+	MergeUsers(targetID, sourceID uint) (*models.User, error)
+}
+
+// UserImportService 用户映射的 CSV/XLSX 批量导入与导出
+type UserImportService interface {
+	Parse(format string, r io.Reader) ([]models.UserImportRow, error)
+	// Import 校验并导入用户，冲突的行跳过；opts.DryRun 为 true 时只返回差异
+	Import(rows []models.UserImportRow, opts models.UserImportOptions) (*models.UserImportResult, error)
+	Export(users []models.User, format string, w io.Writer) error
+}
+
+// NotificationPreferenceService 用户通知偏好：免打扰时间、分支过滤与临时静音
+type NotificationPreferenceService interface {
+	Get(userID uint) (*models.UserNotificationPreference, error)
+	Update(userID uint, req *models.UserNotificationPreferenceRequest) (*models.UserNotificationPreference, error)
+	// LinkedUser 按账户令牌所属的 GitLab 用户名找到对应的用户，供用户自助设置
+	LinkedUser(ctx context.Context, accountID uint) (*models.User, error)
+}
+
+// WebhookStatusService 后台刷新项目 hook 状态接口
+type WebhookStatusService interface {
+	RefreshAll(ctx context.Context) error
+	TriggerRefresh() (*models.WebhookStatusRun, error)
+	LatestRun() (*models.WebhookStatusRun, error)
+	CachedResults(projects []models.Project) []models.WebhookStatusResult
 }
 
 // WeChatService 微信服务接口
 type WeChatService interface {
-	SendMessage(webhookURL, content string, mentionedMobiles []string) error
-	FormatMergeRequestMessage(projectName, sourceBranch, targetBranch, mergeFrom, mergeTitle, clickURL string, mergeToList []string, mentionedMobiles []string) string
+	SendMessage(webhookURL, content string, mentionedUserIDs, mentionedMobiles []string) error
+}
+
+// ReminderService 合并请求催办服务接口
+type ReminderService interface {
+	RemindStaleMergeRequests(ctx context.Context) error
+	GetProjectSetting(projectID uint) (*models.ProjectReminderSetting, error)
+	UpdateProjectSetting(projectID uint, req *models.UpdateReminderSettingRequest) (*models.ProjectReminderSetting, error)
+}
+
+// EscalationService 合并请求升级策略服务接口
+type EscalationService interface {
+	EvaluateEscalations(ctx context.Context) error
+	ListPolicies(projectID uint) ([]models.EscalationPolicy, error)
+	CreatePolicy(projectID uint, req *models.EscalationPolicyRequest) (*models.EscalationPolicy, error)
+	UpdatePolicy(projectID, policyID uint, req *models.EscalationPolicyRequest) (*models.EscalationPolicy, error)
+	DeletePolicy(projectID, policyID uint) error
+	ListHistory(projectID uint, limit int) ([]models.EscalationEventResponse, error)
+}
+
+// OpsAlertService 系统告警服务接口
+type OpsAlertService interface {
+	Alert(ctx context.Context, event OpsAlertEvent) error
+	Notify(event OpsAlertEvent)
+	ListRecent(accountID *uint, limit int) ([]models.OpsAlert, error)
+}
+
+// QuotaService 渠道月度配额统计与预警服务接口
+type QuotaService interface {
+	GetUsage(webhooks []models.Webhook, now time.Time) ([]models.QuotaUsageResponse, error)
+	CheckQuotaWarnings(ctx context.Context) error
+}
+
+// CircuitBreakerService 渠道熔断巡检服务接口
+type CircuitBreakerService interface {
+	CheckOpenCircuits(ctx context.Context) error
+}
+
+// DigestService 打开中合并请求汇总服务接口
+type DigestService interface {
+	SendDueDigests(ctx context.Context) error
+	SendDigest(ctx context.Context, webhookID uint) (*DigestResult, error)
+}
+
+// DeliveryQueueService 延迟投递队列接口
+type DeliveryQueueService interface {
+	Hold(webhook *models.Webhook, projectID uint, message *OutboundMessage, releaseAt time.Time, reason string) (*models.DeliveryQueueItem, error)
+	Coalesce(webhook *models.Webhook, projectID uint, message *OutboundMessage, now time.Time) (*models.DeliveryQueueItem, error)
+	AttachNotification(itemIDs []uint, notificationID uint) error
+	FlushDue(ctx context.Context) error
 }
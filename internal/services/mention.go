@@ -0,0 +1,133 @@
+package services
+
+import (
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// Mention 需要 @ 的用户：各渠道的账号标识与手机号，发送时由渠道选择最合适的一种
+type Mention struct {
+	Phone      string            `json:"phone,omitempty"`
+	Identities map[string]string `json:"identities,omitempty"`
+	// Preference 用户的 @ 条件，发送时判断，为空表示总是 @
+	Preference *models.MentionPreference `json:"preference,omitempty"`
+}
+
+// loadMentions 读取用户的渠道身份并生成 @ 对象，既没有手机号也没有渠道身份的用户被忽略
+func loadMentions(db *gorm.DB, users []models.User) ([]Mention, error) {
+	if len(users) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	var identities []models.UserIdentity
+	if err := db.Where("user_id IN ?", ids).Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	byUser := make(map[uint]map[string]string)
+	for _, identity := range identities {
+		if identity.Identifier == "" {
+			continue
+		}
+		if byUser[identity.UserID] == nil {
+			byUser[identity.UserID] = make(map[string]string)
+		}
+		byUser[identity.UserID][identity.Channel] = identity.Identifier
+	}
+
+	var preferences []models.UserNotificationPreference
+	if err := db.Where("user_id IN ?", ids).Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+	preferenceByUser := make(map[uint]*models.MentionPreference, len(preferences))
+	for idx := range preferences {
+		preferenceByUser[preferences[idx].UserID] = &preferences[idx].MentionPreference
+	}
+
+	mentions := make([]Mention, 0, len(users))
+	for _, user := range users {
+		mention := Mention{Phone: user.Phone, Identities: byUser[user.ID], Preference: preferenceByUser[user.ID]}
+		if mention.Phone == "" && len(mention.Identities) == 0 {
+			continue
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions, nil
+}
+
+// activeMentions 过滤掉按通知偏好此时不应 @ 的用户，消息本身照常发送
+func activeMentions(mentions []Mention, branch string, now time.Time) []Mention {
+	active := make([]Mention, 0, len(mentions))
+	for _, mention := range mentions {
+		if mention.Preference != nil && !mention.Preference.AllowsMention(branch, now) {
+			logger.GetLogger().Infof("按通知偏好不 @ 用户: 手机号=%s, 渠道身份=%v", mention.Phone, mention.Identities)
+			continue
+		}
+		active = append(active, mention)
+	}
+	return active
+}
+
+// mentionTargets 按渠道拆分 @ 对象：有该渠道身份的使用身份，其余使用手机号；
+// mobiles 为旧版消息中只记录了手机号的 @ 对象，一并使用
+func mentionTargets(channel string, mentions []Mention, mobiles []string) (userIDs []string, phones []string) {
+	seenIDs := make(map[string]bool)
+	seenPhones := make(map[string]bool)
+	addPhone := func(phone string) {
+		if phone != "" && !seenPhones[phone] {
+			seenPhones[phone] = true
+			phones = append(phones, phone)
+		}
+	}
+
+	for _, mention := range mentions {
+		if id := mention.Identities[channel]; id != "" {
+			if !seenIDs[id] {
+				seenIDs[id] = true
+				userIDs = append(userIDs, id)
+			}
+			continue
+		}
+		addPhone(mention.Phone)
+	}
+	for _, mobile := range mobiles {
+		addPhone(mobile)
+	}
+	return userIDs, phones
+}
+
+// mergeMentionLists 合并多条消息的 @ 对象，手机号或身份完全相同的视为同一人
+func mergeMentionLists(lists ...[]Mention) []Mention {
+	seen := make(map[string]bool)
+	var merged []Mention
+	for _, list := range lists {
+		for _, mention := range list {
+			key := mentionKey(mention)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, mention)
+		}
+	}
+	return merged
+}
+
+func mentionKey(mention Mention) string {
+	if mention.Phone != "" {
+		return "phone:" + mention.Phone
+	}
+	for _, channel := range []string{models.IdentityChannelWeCom, models.IdentityChannelDingTalk, models.IdentityChannelSlack, models.IdentityChannelFeishu, models.IdentityChannelTeams} {
+		if id := mention.Identities[channel]; id != "" {
+			return channel + ":" + id
+		}
+	}
+	return ""
+}
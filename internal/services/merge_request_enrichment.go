@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+)
+
+const (
+	defaultEnrichmentTimeout  = 2 * time.Second
+	defaultEnrichmentCacheTTL = 5 * time.Minute
+)
+
+// MergeRequestDetails 从 GitLab API 补充的合并请求信息，未取到的部分保持零值
+type MergeRequestDetails struct {
+	// ChangesCount 变更文件数，超过 GitLab 的统计上限时为 "1000+"
+	ChangesCount string `json:"changes_count,omitempty"`
+	// HasDiffStats 为 true 时 Additions、Deletions 有效
+	HasDiffStats   bool     `json:"has_diff_stats,omitempty"`
+	Additions      int      `json:"additions,omitempty"`
+	Deletions      int      `json:"deletions,omitempty"`
+	PipelineStatus string   `json:"pipeline_status,omitempty"`
+	HasApprovals   bool     `json:"has_approvals,omitempty"`
+	ApprovalsGiven int      `json:"approvals_given,omitempty"`
+	ApprovalsLeft  int      `json:"approvals_left,omitempty"`
+	ApprovedBy     []string `json:"approved_by,omitempty"`
+	HasConflicts   bool     `json:"has_conflicts,omitempty"`
+	Labels         []string `json:"labels,omitempty"`
+	Milestone      string   `json:"milestone,omitempty"`
+}
+
+type enrichmentCacheEntry struct {
+	details   *MergeRequestDetails
+	expiresAt time.Time
+}
+
+type mergeRequestEnricher struct {
+	cfg     *config.Config
+	gitlab  GitLabService
+	targets HookReconcileService
+
+	mu    sync.Mutex
+	cache map[string]enrichmentCacheEntry
+}
+
+func NewMergeRequestEnricher(cfg *config.Config, gitlab GitLabService, targets HookReconcileService) MergeRequestEnricher {
+	return &mergeRequestEnricher{
+		cfg:     cfg,
+		gitlab:  gitlab,
+		targets: targets,
+		cache:   make(map[string]enrichmentCacheEntry),
+	}
+}
+
+// Enrich 在时间预算内并发获取合并请求详情、审批、流水线与 diff 统计；未启用、超时或全部失败时返回 nil，
+// 部分接口失败时返回已取到的部分，完整结果按合并请求的更新时间缓存
+func (e *mergeRequestEnricher) Enrich(ctx context.Context, project *models.Project, mergeRequest *models.GitLabMergeRequest) *MergeRequestDetails {
+	settings := e.cfg.MergeRequestEnrichment
+	if !settings.Enabled || project == nil || mergeRequest == nil {
+		return nil
+	}
+
+	key := fmt.Sprintf("%d/%d/%s", project.ID, mergeRequest.IID, mergeRequest.UpdatedAt)
+	if details := e.cached(key); details != nil {
+		return details
+	}
+
+	target, err := e.targets.ResolveTarget(project)
+	if err != nil {
+		logger.GetLogger().Debugf("补充合并请求 !%d 信息时获取 GitLab 令牌失败: %v", mergeRequest.IID, err)
+		return nil
+	}
+
+	timeout := settings.Timeout
+	if timeout <= 0 {
+		timeout = defaultEnrichmentTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, complete := e.fetch(ctx, target, project.GitLabProjectID, mergeRequest.IID, settings.DiffStats)
+	if !complete {
+		logger.GetLogger().Infof("补充合并请求 !%d 信息未全部完成（耗时 %s），按已取到的部分发送", mergeRequest.IID, time.Since(start).Round(time.Millisecond))
+	}
+	if details == nil {
+		return nil
+	}
+	if complete {
+		e.store(key, details, settings.CacheTTL)
+	}
+	return details
+}
+
+// fetch 各接口互不依赖，结果写入各自的字段，返回值中的 bool 表示所有接口都成功
+func (e *mergeRequestEnricher) fetch(ctx context.Context, target HookTarget, projectID, iid int, diffStats bool) (*MergeRequestDetails, bool) {
+	var (
+		wg        sync.WaitGroup
+		mr        *GitLabMergeRequestDetail
+		approvals *GitLabMergeRequestApprovals
+		pipeline  *GitLabPipelineInfo
+		diffs     []*GitLabMergeRequestDiff
+		errs      = make([]error, 4)
+	)
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		mr, errs[0] = e.gitlab.GetMergeRequest(ctx, target.BaseURL, projectID, iid, target.Token)
+	}()
+	go func() {
+		defer wg.Done()
+		approvals, errs[1] = e.gitlab.GetMergeRequestApprovals(ctx, target.BaseURL, projectID, iid, target.Token)
+	}()
+	go func() {
+		defer wg.Done()
+		pipeline, errs[2] = e.gitlab.GetLatestMergeRequestPipeline(ctx, target.BaseURL, projectID, iid, target.Token)
+	}()
+	if diffStats {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			diffs, errs[3] = e.gitlab.ListMergeRequestDiffs(ctx, target.BaseURL, projectID, iid, target.Token)
+		}()
+	}
+	wg.Wait()
+
+	complete := true
+	for _, err := range errs {
+		if err != nil {
+			complete = false
+			logger.GetLogger().Debugf("补充合并请求 !%d 信息失败: %v", iid, err)
+		}
+	}
+
+	details := &MergeRequestDetails{}
+	fetched := false
+	if mr != nil {
+		fetched = true
+		details.ChangesCount = mr.ChangesCount
+		details.HasConflicts = mr.HasConflicts
+		details.Labels = mr.Labels
+		if mr.Milestone != nil {
+			details.Milestone = mr.Milestone.Title
+		}
+		if mr.HeadPipeline != nil {
+			details.PipelineStatus = mr.HeadPipeline.Status
+		}
+	}
+	if pipeline != nil {
+		fetched = true
+		details.PipelineStatus = pipeline.Status
+	}
+	if approvals != nil {
+		fetched = true
+		details.HasApprovals = true
+		details.ApprovalsLeft = approvals.ApprovalsLeft
+		details.ApprovalsGiven = len(approvals.ApprovedBy)
+		for _, approval := range approvals.ApprovedBy {
+			details.ApprovedBy = append(details.ApprovedBy, approval.User.Username)
+		}
+	}
+	if errs[3] == nil && diffs != nil {
+		fetched = true
+		details.HasDiffStats = true
+		for _, diff := range diffs {
+			additions, deletions := countDiffLines(diff.Diff)
+			details.Additions += additions
+			details.Deletions += deletions
+		}
+		if details.ChangesCount == "" {
+			details.ChangesCount = fmt.Sprintf("%d", len(diffs))
+		}
+	}
+
+	if !fetched {
+		return nil, false
+	}
+	return details, complete
+}
+
+func (e *mergeRequestEnricher) cached(key string) *MergeRequestDetails {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	entry, ok := e.cache[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil
+	}
+	return entry.details
+}
+
+func (e *mergeRequestEnricher) store(key string, details *MergeRequestDetails, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = defaultEnrichmentCacheTTL
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	for cachedKey, entry := range e.cache {
+		if now.After(entry.expiresAt) {
+			delete(e.cache, cachedKey)
+		}
+	}
+	e.cache[key] = enrichmentCacheEntry{details: details, expiresAt: now.Add(ttl)}
+}
+
+// countDiffLines 统计 unified diff 中新增与删除的行数
+// GitLab 返回的单文件 diff 不带 ---/+++ 文件头，第一个 @@ 之后的 +/- 行都是内容，
+// 删除 "-- xxx" 这类行时会出现 "---" 开头的内容行，不能当作文件头跳过
+func countDiffLines(diff string) (additions, deletions int) {
+	inHunk := false
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case !inHunk:
+			// 第一个 hunk 之前只有文件头
+		case strings.HasPrefix(line, "+"):
+			additions++
+		case strings.HasPrefix(line, "-"):
+			deletions++
+		}
+	}
+	return additions, deletions
+}
@@ -0,0 +1,28 @@
+package services
+
+import "testing"
+
+func TestCountDiffLines(t *testing.T) {
+	cases := []struct {
+		name      string
+		diff      string
+		additions int
+		deletions int
+	}{
+		{"empty", "", 0, 0},
+		{"gitlab per-file diff", "@@ -1,3 +1,3 @@\n context\n-old\n+new\n+more\n", 2, 1},
+		{"with file headers", "--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n-old\n+new\n", 1, 1},
+		{"deleted sql comment", "@@ -1,2 +1,1 @@\n--- drop me\n keep\n", 0, 1},
+		{"added line starting with plus signs", "@@ -0,0 +1,1 @@\n+++counter\n", 1, 0},
+		{"headers after a hunk are content", "@@ -1,1 +1,1 @@\n---\n+++\n@@ -9,1 +9,1 @@\n-a\n+b\n", 2, 2},
+		{"no hunk", "--- a/x\n+++ b/x\n", 0, 0},
+		{"no newline marker", "@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+b\n", 1, 1},
+	}
+
+	for _, tc := range cases {
+		additions, deletions := countDiffLines(tc.diff)
+		if additions != tc.additions || deletions != tc.deletions {
+			t.Errorf("%s: expected +%d -%d, got +%d -%d", tc.name, tc.additions, tc.deletions, additions, deletions)
+		}
+	}
+}
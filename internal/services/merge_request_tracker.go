@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+type mergeRequestTracker struct {
+	db *gorm.DB
+}
+
+func NewMergeRequestTracker(db *gorm.DB) MergeRequestTracker {
+	return &mergeRequestTracker{db: db}
+}
+
+// TrackEvent 根据合并请求事件更新 merge_requests 表，状态变化时追加一条变更记录
+func (t *mergeRequestTracker) TrackEvent(project *models.Project, webhookData *models.GitLabWebhookData) (*models.MergeRequest, error) {
+	attrs := webhookData.ObjectAttributes
+	if attrs.IID == 0 {
+		return nil, fmt.Errorf("合并请求缺少 iid")
+	}
+
+	eventTime, ok := models.ParseGitLabTime(attrs.UpdatedAt)
+	if !ok {
+		eventTime = time.Now().UTC()
+	}
+
+	var mr models.MergeRequest
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("project_id = ? AND iid = ?", project.ID, attrs.IID).First(&mr).Error
+		isNew := errors.Is(err, gorm.ErrRecordNotFound)
+		if err != nil && !isNew {
+			return err
+		}
+
+		// GitLab 重试可能导致事件乱序到达，较旧的事件不再覆盖当前状态
+		if !isNew && eventTime.Before(mr.LastActivityAt) {
+			logger.GetLogger().Debugf("忽略过期的合并请求事件: 项目 %d !%d, 事件时间 %s", project.ID, attrs.IID, eventTime)
+			return nil
+		}
+
+		previousState := mr.State
+		if isNew {
+			mr = models.MergeRequest{
+				ProjectID: project.ID,
+				IID:       attrs.IID,
+			}
+			previousState = ""
+		}
+
+		applyMergeRequestAttributes(&mr, webhookData, eventTime, isNew)
+
+		if err := tx.Save(&mr).Error; err != nil {
+			return err
+		}
+
+		if previousState == mr.State {
+			return nil
+		}
+
+		transition := models.MergeRequestTransition{
+			MergeRequestID: mr.ID,
+			FromState:      previousState,
+			ToState:        mr.State,
+			Action:         attrs.Action,
+			ActorUsername:  webhookData.User.Username,
+			OccurredAt:     eventTime,
+		}
+		return tx.Create(&transition).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("更新合并请求状态失败: %w", err)
+	}
+
+	return &mr, nil
+}
+
+// TrackPipeline 更新合并请求的流水线状态：合并请求流水线按 IID 匹配，分支流水线按源分支匹配打开的合并请求
+func (t *mergeRequestTracker) TrackPipeline(project *models.Project, event *models.GitLabPipelineEventData) error {
+	attrs := event.ObjectAttributes
+	if attrs.ID == 0 || attrs.Tag {
+		return nil
+	}
+
+	query := t.db.Model(&models.MergeRequest{}).Where("project_id = ?", project.ID)
+	if event.MergeRequest != nil && event.MergeRequest.IID != 0 {
+		query = query.Where("iid = ?", event.MergeRequest.IID)
+	} else {
+		query = query.Where("source_branch = ? AND state = ?", attrs.Ref, models.MergeRequestStateOpened)
+	}
+
+	// 只接受更新的流水线，避免旧流水线的事件覆盖最新状态
+	result := query.Where("pipeline_id <= ?", attrs.ID).Updates(map[string]interface{}{
+		"pipeline_id":     attrs.ID,
+		"pipeline_status": attrs.Status,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("更新流水线状态失败: %w", result.Error)
+	}
+
+	logger.GetLogger().Debugf("流水线 %d (%s) 状态 %s 更新了 %d 个合并请求", attrs.ID, attrs.Ref, attrs.Status, result.RowsAffected)
+	return nil
+}
+
+func (t *mergeRequestTracker) ListTransitions(mergeRequestID uint) ([]models.MergeRequestTransition, error) {
+	var transitions []models.MergeRequestTransition
+	err := t.db.Where("merge_request_id = ?", mergeRequestID).
+		Order("occurred_at ASC, id ASC").
+		Find(&transitions).Error
+	return transitions, err
+}
+
+func applyMergeRequestAttributes(mr *models.MergeRequest, webhookData *models.GitLabWebhookData, eventTime time.Time, isNew bool) {
+	attrs := webhookData.ObjectAttributes
+
+	mr.GitLabMRID = attrs.ID
+	mr.Title = attrs.Title
+	mr.URL = attrs.URL
+	mr.SourceBranch = attrs.SourceBranch
+	mr.TargetBranch = attrs.TargetBranch
+	mr.Draft = attrs.Draft || attrs.WorkInProgress
+	mr.Assignees = gitLabUsernames(webhookData.Assignees)
+	mr.Reviewers = gitLabUsernames(webhookData.Reviewers)
+	mr.Labels = gitLabLabelTitles(webhookData.Labels)
+	mr.LastActivityAt = eventTime
+
+	if attrs.State != "" {
+		mr.State = attrs.State
+	} else if mr.State == "" {
+		mr.State = models.MergeRequestStateOpened
+	}
+
+	if attrs.AuthorID != 0 {
+		mr.AuthorID = attrs.AuthorID
+	}
+	// webhook 中的 user 是触发事件的人，仅在作者本人触发时记录作者信息
+	if (webhookData.User.ID != 0 && webhookData.User.ID == mr.AuthorID) || (isNew && attrs.Action == "open") {
+		mr.AuthorName = webhookData.User.Name
+		mr.AuthorUsername = webhookData.User.Username
+	}
+
+	if mr.OpenedAt == nil {
+		if createdAt, ok := models.ParseGitLabTime(attrs.CreatedAt); ok {
+			mr.OpenedAt = &createdAt
+		} else {
+			openedAt := eventTime
+			mr.OpenedAt = &openedAt
+		}
+	}
+
+	// GitLab 15.x 起单人批准的 action 为 approval，全部批准为 approved
+	switch attrs.Action {
+	case "approved", "approval":
+		if mr.ApprovedAt == nil {
+			approvedAt := eventTime
+			mr.ApprovedAt = &approvedAt
+		}
+	case "unapproved", "unapproval":
+		mr.ApprovedAt = nil
+	}
+
+	switch mr.State {
+	case models.MergeRequestStateMerged:
+		if mr.MergedAt == nil {
+			mergedAt := eventTime
+			mr.MergedAt = &mergedAt
+		}
+	case models.MergeRequestStateClosed:
+		if mr.ClosedAt == nil {
+			closedAt := eventTime
+			mr.ClosedAt = &closedAt
+		}
+	case models.MergeRequestStateOpened:
+		// 重新打开后清除关闭时间
+		mr.ClosedAt = nil
+	}
+}
+
+func gitLabUsernames(users []models.GitLabUser) models.StringList {
+	usernames := make(models.StringList, 0, len(users))
+	for _, user := range users {
+		if user.Username != "" {
+			usernames = append(usernames, user.Username)
+		}
+	}
+	return usernames
+}
+
+func gitLabLabelTitles(labels []models.GitLabLabel) models.StringList {
+	titles := make(models.StringList, 0, len(labels))
+	for _, label := range labels {
+		if label.Title != "" {
+			titles = append(titles, label.Title)
+		}
+	}
+	return titles
+}
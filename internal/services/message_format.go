@@ -2,7 +2,11 @@ package services
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
 )
 
 func FormatMergeRequestPayloadText(payload *MergeRequestPayload) string {
@@ -14,7 +18,7 @@ func FormatMergeRequestPayloadText(payload *MergeRequestPayload) string {
 	content := fmt.Sprintf(`%s
 Project: %s
    From: %s -> %s (%s)
-MR Info: %s
+MR Info: %s%s
 Click -> %s`,
 		divider,
 		payload.ProjectName,
@@ -22,6 +26,7 @@ Click -> %s`,
 		payload.TargetBranch,
 		payload.AuthorName,
 		payload.Title,
+		formatMergeRequestDetails(payload.Details),
 		payload.URL,
 	)
 
@@ -47,7 +52,7 @@ func FormatMergeRequestPayloadTextWithPhones(payload *MergeRequestPayload, menti
 	content := fmt.Sprintf(`%s
 Project: %s
    From: %s -> %s (%s)
-MR Info: %s
+MR Info: %s%s
 Click -> %s`,
 		divider,
 		payload.ProjectName,
@@ -55,8 +60,450 @@ Click -> %s`,
 		payload.TargetBranch,
 		payload.AuthorName,
 		payload.Title,
+		formatMergeRequestDetails(payload.Details),
 		payload.URL,
 	)
 
 	return content
 }
+
+// formatMergeRequestDetails 将 GitLab API 补充的信息格式化为若干行，每行以换行开头，没有可展示的信息时返回空字符串
+func formatMergeRequestDetails(details *MergeRequestDetails) string {
+	if details == nil {
+		return ""
+	}
+
+	var lines []string
+	if details.ChangesCount != "" {
+		line := "Changes: " + details.ChangesCount + " files"
+		if details.HasDiffStats {
+			line += fmt.Sprintf(", +%d -%d", details.Additions, details.Deletions)
+		}
+		lines = append(lines, line)
+	}
+	if details.PipelineStatus != "" {
+		lines = append(lines, "     CI: "+details.PipelineStatus)
+	}
+	if details.HasApprovals {
+		line := fmt.Sprintf("Approve: %d/%d", details.ApprovalsGiven, details.ApprovalsGiven+details.ApprovalsLeft)
+		if len(details.ApprovedBy) > 0 {
+			line += " (" + strings.Join(details.ApprovedBy, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	if len(details.Labels) > 0 {
+		lines = append(lines, " Labels: "+strings.Join(details.Labels, ", "))
+	}
+	if details.Milestone != "" {
+		lines = append(lines, "Milestone: "+details.Milestone)
+	}
+	if details.HasConflicts {
+		lines = append(lines, "Warning: 存在合并冲突")
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(lines, "\n")
+}
+
+const (
+	maxPushCommitLines    = 5
+	releaseNotesMaxLength = 200
+	maxDigestEntries      = 30
+)
+
+func eventDivider(title string) string {
+	return strings.Repeat("=", 32) + " " + title + " " + strings.Repeat("=", 32)
+}
+
+// FormatPushEventText 生成分支推送通知内容：提交数、最近提交与对比链接
+func FormatPushEventText(projectName string, event *models.GitLabPushEventData) string {
+	if event == nil {
+		return ""
+	}
+
+	commitCount := event.TotalCommitsCount
+	if commitCount == 0 {
+		commitCount = len(event.Commits)
+	}
+
+	content := fmt.Sprintf(`%s
+Project: %s
+ Branch: %s (%s)
+Commits: %d`,
+		eventDivider("Push"),
+		projectName,
+		event.BranchName(),
+		event.UserName,
+		commitCount,
+	)
+
+	for i, commit := range event.Commits {
+		if i >= maxPushCommitLines {
+			content += fmt.Sprintf("\n  ... %d more", len(event.Commits)-maxPushCommitLines)
+			break
+		}
+		content += fmt.Sprintf("\n  - %s %s", shortSHA(commit.ID), commitTitle(commit))
+	}
+
+	if link := buildCompareURL(event); link != "" {
+		content += "\nCompare -> " + link
+	}
+
+	return content
+}
+
+// FormatTagPushEventText 生成标签推送通知内容
+func FormatTagPushEventText(projectName string, event *models.GitLabPushEventData) string {
+	if event == nil {
+		return ""
+	}
+
+	content := fmt.Sprintf(`%s
+Project: %s
+    Tag: %s (%s)
+ Commit: %s`,
+		eventDivider("Tag Push"),
+		projectName,
+		event.TagName(),
+		event.UserName,
+		shortSHA(event.CheckoutSHA),
+	)
+
+	if event.Project.WebURL != "" {
+		content += fmt.Sprintf("\nClick -> %s/-/tags/%s", strings.TrimRight(event.Project.WebURL, "/"), url.PathEscape(event.TagName()))
+	}
+
+	return content
+}
+
+// FormatReleaseEventText 生成发布通知内容，附带发布说明摘要
+func FormatReleaseEventText(projectName string, event *models.GitLabReleaseEventData) string {
+	if event == nil {
+		return ""
+	}
+
+	name := event.Name
+	if name == "" {
+		name = event.Tag
+	}
+
+	content := fmt.Sprintf(`%s
+Project: %s
+Release: %s (%s)`,
+		eventDivider("Release"),
+		projectName,
+		name,
+		event.Tag,
+	)
+
+	if notes := excerpt(event.Description, releaseNotesMaxLength); notes != "" {
+		content += "\n  Notes: " + notes
+	}
+	if event.URL != "" {
+		content += "\nClick -> " + event.URL
+	}
+
+	return content
+}
+
+// FormatMergeRequestReminderText 生成合并请求催办内容，附带等待时长与提醒次数
+func FormatMergeRequestReminderText(projectName string, mr *models.MergeRequest, waiting time.Duration, maxReminders int, mentions []string) string {
+	if mr == nil {
+		return ""
+	}
+
+	content := fmt.Sprintf(`%s
+Project: %s
+   From: %s -> %s (%s)
+MR Info: %s
+Waiting: %s (reminder %d/%d)
+Click -> %s`,
+		eventDivider("MR Reminder"),
+		projectName,
+		mr.SourceBranch,
+		mr.TargetBranch,
+		mr.AuthorName,
+		mr.Title,
+		formatWaitingDuration(waiting),
+		mr.ReminderCount,
+		maxReminders,
+		mr.URL,
+	)
+
+	if len(mentions) > 0 {
+		content += "\n@" + strings.Join(mentions, " @")
+	}
+
+	return content
+}
+
+// FormatMergeRequestEscalationText 生成升级提醒内容
+func FormatMergeRequestEscalationText(projectName string, mr *models.MergeRequest, waiting time.Duration, policyName string, step int) string {
+	if mr == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(`%s
+Project: %s
+   From: %s -> %s (%s)
+MR Info: %s
+Waiting: %s without approval
+ Policy: %s (step %d)
+Click -> %s`,
+		eventDivider("MR Escalation"),
+		projectName,
+		mr.SourceBranch,
+		mr.TargetBranch,
+		mr.AuthorName,
+		mr.Title,
+		formatWaitingDuration(waiting),
+		policyName,
+		step,
+		mr.URL,
+	)
+}
+
+// FormatOpenMergeRequestDigestText 生成打开中合并请求的汇总内容，按项目分组列出等待时长、作者、指派人与流水线状态
+func FormatOpenMergeRequestDigestText(generatedAt time.Time, groups []DigestProjectGroup, total int) string {
+	content := fmt.Sprintf(`%s
+   Time: %s
+  Total: %d open merge requests`,
+		eventDivider("Open MR Digest"),
+		generatedAt.Format("2006-01-02 15:04 MST"),
+		total,
+	)
+
+	listed := 0
+	for _, group := range groups {
+		if listed >= maxDigestEntries {
+			break
+		}
+
+		content += fmt.Sprintf("\n\n[%s] %d", group.ProjectName, len(group.MergeRequests))
+		for _, mr := range group.MergeRequests {
+			if listed >= maxDigestEntries {
+				break
+			}
+			listed++
+
+			title := mr.Title
+			if mr.Draft && !hasDraftPrefix(title) {
+				title = "[Draft] " + title
+			}
+
+			waiting := "-"
+			if mr.OpenedAt != nil {
+				waiting = formatWaitingDuration(generatedAt.Sub(*mr.OpenedAt))
+			}
+
+			pipeline := mr.PipelineStatus
+			if pipeline == "" {
+				pipeline = "unknown"
+			}
+
+			content += fmt.Sprintf("\n  - !%d %s (%s) | %s | pipeline: %s", mr.IID, title, mr.AuthorName, waiting, pipeline)
+			if len(mr.Assignees) > 0 {
+				content += "\n    assignees: " + strings.Join(mr.Assignees, ", ")
+			}
+			if mr.URL != "" {
+				content += "\n    " + mr.URL
+			}
+		}
+	}
+
+	if total > listed {
+		content += fmt.Sprintf("\n\n  ... %d more", total-listed)
+	}
+
+	return content
+}
+
+// FormatHeldMessagesBatchText 将非工作时间积压的多条消息合并为一条
+func FormatHeldMessagesBatchText(messages []*OutboundMessage) string {
+	content := fmt.Sprintf("%s\n%d notifications received outside working hours", eventDivider("Held Notifications"), len(messages))
+	for _, message := range messages {
+		if text := message.PlainText(); text != "" {
+			content += "\n\n" + text
+		}
+	}
+	return content
+}
+
+// FormatMergeRequestBurstText 将短时间内集中到达的多个合并请求合并为一条消息，提醒对象去重后统一列在末尾
+func FormatMergeRequestBurstText(payloads []*MergeRequestPayload) string {
+	content := fmt.Sprintf("%s\n  Total: %d merge requests", eventDivider("Merge Requests"), len(payloads))
+
+	seen := make(map[string]bool)
+	var mentions []string
+	for i, payload := range payloads {
+		if i >= maxDigestEntries {
+			content += fmt.Sprintf("\n... and %d more", len(payloads)-maxDigestEntries)
+			break
+		}
+		content += fmt.Sprintf("\n%d. [%s] %s\n   %s -> %s (%s)\n   %s",
+			i+1,
+			payload.ProjectName,
+			payload.Title,
+			payload.SourceBranch,
+			payload.TargetBranch,
+			payload.AuthorName,
+			payload.URL,
+		)
+	}
+
+	for _, payload := range payloads {
+		for _, account := range payload.MentionedAccounts {
+			if account != "" && !seen[account] {
+				seen[account] = true
+				mentions = append(mentions, account)
+			}
+		}
+	}
+	if len(mentions) > 0 {
+		content += "\n@" + strings.Join(mentions, " @")
+	}
+
+	return content
+}
+
+// FormatOpsAlertText 生成系统告警内容，去重窗口内重复发生的次数一并提示
+func FormatOpsAlertText(alert *models.OpsAlert) string {
+	content := fmt.Sprintf(`%s
+Severity: %s
+Category: %s
+   Title: %s`,
+		eventDivider("Ops Alert"),
+		strings.ToUpper(alert.Severity),
+		alert.Category,
+		alert.Title,
+	)
+
+	if alert.PendingCount > 1 {
+		since := alert.FirstSeenAt
+		if alert.LastSentAt != nil {
+			since = *alert.LastSentAt
+		}
+		content += fmt.Sprintf("\n Repeats: %d since %s", alert.PendingCount, since.Format("2006-01-02 15:04 MST"))
+	}
+	if alert.Detail != "" {
+		content += "\n\n" + alert.Detail
+	}
+
+	return content
+}
+
+// FormatQuotaUsageDetail 生成月度配额使用情况说明
+func FormatQuotaUsageDetail(usage *models.QuotaUsageResponse) string {
+	content := fmt.Sprintf(`Webhook: %s (%s)
+  Usage: %d/%d (%.2f%%)
+ Period: %s`,
+		usage.WebhookName,
+		usage.Channel,
+		usage.Used,
+		usage.Quota,
+		usage.UsagePercent,
+		usage.PeriodStart.Format("2006-01"),
+	)
+
+	if usage.ProjectedExhaustAt != nil && usage.Remaining > 0 {
+		content += fmt.Sprintf("\nExhaust: ~%s at %.0f/day", usage.ProjectedExhaustAt.Format("2006-01-02 15:04 MST"), usage.DailyRate)
+	}
+
+	return content
+}
+
+// FormatCircuitOpenDetail 生成 Webhook 熔断说明
+func FormatCircuitOpenDetail(webhook *models.Webhook, owner *models.Account, now time.Time) string {
+	ownerName := "-"
+	if owner != nil {
+		ownerName = fmt.Sprintf("%s <%s>", owner.Username, owner.Email)
+	}
+
+	status := "Open"
+	if !webhook.IsActive {
+		status = "Disabled"
+	}
+
+	content := fmt.Sprintf(`Webhook: %s (%s)
+  Owner: %s
+ Status: %s
+  Since: %s (%s)
+  Fails: %d
+Skipped: %d`,
+		webhook.Name,
+		webhook.Type,
+		ownerName,
+		status,
+		webhook.CircuitOpenedAt.In(webhook.Location()).Format("2006-01-02 15:04"),
+		formatWaitingDuration(now.Sub(*webhook.CircuitOpenedAt)),
+		webhook.ConsecutiveFailures,
+		webhook.CircuitSkippedCount,
+	)
+
+	if webhook.LastFailureMessage != "" {
+		content += "\n  Error: " + webhook.LastFailureMessage
+	}
+
+	return content
+}
+
+// hasDraftPrefix 判断标题是否已带有 GitLab 的草稿前缀
+func hasDraftPrefix(title string) bool {
+	lower := strings.ToLower(strings.TrimSpace(title))
+	for _, prefix := range []string{"draft:", "[draft]", "(draft)", "wip:", "[wip]"} {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatWaitingDuration(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+
+	days := int(d / (24 * time.Hour))
+	hours := int((d % (24 * time.Hour)) / time.Hour)
+	if days == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd %dh", days, hours)
+}
+
+func buildCompareURL(event *models.GitLabPushEventData) string {
+	webURL := strings.TrimRight(event.Project.WebURL, "/")
+	if webURL == "" {
+		return ""
+	}
+	if event.IsCreation() {
+		return fmt.Sprintf("%s/-/commit/%s", webURL, event.After)
+	}
+	return fmt.Sprintf("%s/-/compare/%s...%s", webURL, event.Before, event.After)
+}
+
+func commitTitle(commit models.GitLabCommit) string {
+	if commit.Title != "" {
+		return commit.Title
+	}
+	title, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+	return title
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+func excerpt(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "..."
+}
@@ -7,19 +7,88 @@ import (
 )
 
 type MergeRequestPayload struct {
-	ProjectName       string
-	SourceBranch      string
-	TargetBranch      string
-	AuthorName        string
-	Title             string
-	URL               string
+	ProjectName  string
+	SourceBranch string
+	TargetBranch string
+	AuthorName   string
+	Title        string
+	URL          string
+	Mentions     []Mention
+	// MentionedMobiles 旧版消息只记录了手机号，保留以兼容延迟投递队列中的消息
 	MentionedMobiles  []string
 	MentionedAccounts []string
 	Assignees         []models.AssigneeInfo
+	// Details 从 GitLab API 补充的信息，未启用或获取失败时为 nil
+	Details *MergeRequestDetails
+}
+
+// TextMessage 通用文本消息，用于 push、tag、release 等非合并请求事件
+type TextMessage struct {
+	Content          string
+	Mentions         []Mention
+	MentionedMobiles []string
+	AtAll            bool
+	// TargetBranch 与合并请求相关时的目标分支，用于按通知偏好过滤 @ 对象
+	TargetBranch string
+}
+
+// OutboundMessage 待投递的消息，合并请求通知与文本通知二选一，可序列化后进入延迟投递队列
+type OutboundMessage struct {
+	EventType    string               `json:"event_type"`
+	TargetBranch string               `json:"target_branch,omitempty"`
+	MergeRequest *MergeRequestPayload `json:"merge_request,omitempty"`
+	Text         *TextMessage         `json:"text,omitempty"`
+}
+
+// SendVia 通过指定渠道发送消息
+func (m *OutboundMessage) SendVia(ctx context.Context, sender MessageSender, webhook *models.Webhook) error {
+	if m.MergeRequest != nil {
+		return sender.Send(ctx, webhook, m.MergeRequest)
+	}
+	return sender.SendText(ctx, webhook, m.Text)
+}
+
+// PlainText 返回消息的纯文本内容，用于合并多条消息
+func (m *OutboundMessage) PlainText() string {
+	if m.MergeRequest != nil {
+		return FormatMergeRequestPayloadText(m.MergeRequest)
+	}
+	if m.Text != nil {
+		return m.Text.Content
+	}
+	return ""
+}
+
+// MentionsAll 判断消息是否需要 @所有人
+func (m *OutboundMessage) MentionsAll() bool {
+	return m.Text != nil && m.Text.AtAll
+}
+
+// MentionList 返回消息需要 @ 的用户
+func (m *OutboundMessage) MentionList() []Mention {
+	if m.MergeRequest != nil {
+		return m.MergeRequest.Mentions
+	}
+	if m.Text != nil {
+		return m.Text.Mentions
+	}
+	return nil
+}
+
+// Mobiles 返回旧版消息中只记录了手机号的 @ 对象
+func (m *OutboundMessage) Mobiles() []string {
+	if m.MergeRequest != nil {
+		return m.MergeRequest.MentionedMobiles
+	}
+	if m.Text != nil {
+		return m.Text.MentionedMobiles
+	}
+	return nil
 }
 
 type MessageSender interface {
 	Send(ctx context.Context, webhook *models.Webhook, payload *MergeRequestPayload) error
+	SendText(ctx context.Context, webhook *models.Webhook, message *TextMessage) error
 }
 
 type SenderFactory interface {
@@ -3,7 +3,11 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
@@ -11,43 +15,84 @@ import (
 	"gorm.io/gorm"
 )
 
+// rateLimitedRetryDelay 速率受限的消息进入队列后的重试延迟
+const rateLimitedRetryDelay = time.Minute
+
 type notificationService struct {
 	db            *gorm.DB
 	senderFactory SenderFactory
+	tracker       MergeRequestTracker
+	queue         DeliveryQueueService
+	enricher      MergeRequestEnricher
+	users         GitLabUserResolver
+}
+
+// deliveryResult 一次通知的投递结果，held 为因投递时间窗口关闭而进入队列的记录，skipped 为熔断中跳过的渠道
+type deliveryResult struct {
+	sent    int
+	held    []uint
+	skipped []string
+	err     error
 }
 
-func NewNotificationService(db *gorm.DB, factory SenderFactory) NotificationService {
+func NewNotificationService(db *gorm.DB, factory SenderFactory, tracker MergeRequestTracker, queue DeliveryQueueService, enricher MergeRequestEnricher, users GitLabUserResolver) NotificationService {
 	return &notificationService{
 		db:            db,
 		senderFactory: factory,
+		tracker:       tracker,
+		queue:         queue,
+		enricher:      enricher,
+		users:         users,
 	}
 }
 
-func (s *notificationService) ProcessMergeRequest(webhookData *models.GitLabWebhookData) error {
-	if webhookData.ObjectAttributes.State != "opened" {
-		return nil
+// ProcessMergeRequest 处理合并请求事件，ctx 的截止时间限制解析指派人与补充合并请求信息的总耗时，不影响消息发送
+func (s *notificationService) ProcessMergeRequest(ctx context.Context, instanceID uint, webhookData *models.GitLabWebhookData) error {
+	isOpened := webhookData.ObjectAttributes.State == models.MergeRequestStateOpened
+
+	project, err := s.loadProjectWithWebhooks(instanceID, webhookData.Project.ID)
+	if err != nil {
+		if !isOpened {
+			return nil
+		}
+		return err
 	}
 
-	var project models.Project
-	if err := s.db.Where(&models.Project{GitLabProjectID: webhookData.Project.ID}).First(&project).Error; err != nil {
-		return fmt.Errorf("project not found: %w", err)
+	// 所有合并请求事件都更新状态跟踪，跟踪失败不影响通知发送
+	if s.tracker != nil {
+		if _, err := s.tracker.TrackEvent(project, webhookData); err != nil {
+			logger.GetLogger().Warnf("跟踪合并请求 !%d 状态失败: %v", webhookData.ObjectAttributes.IID, err)
+		}
 	}
 
-	if err := s.db.Preload("Webhooks").Preload("Webhooks.Settings").First(&project, project.ID).Error; err != nil {
-		return fmt.Errorf("failed to load project webhooks: %w", err)
+	if !isOpened {
+		return nil
+	}
+
+	if !project.MergeRequestEvents {
+		logger.GetLogger().Infof("项目 %s 未订阅合并请求事件，跳过通知", project.Name)
+		return nil
 	}
 
 	assigneeInfo, assigneeEmails := buildAssigneeInfo(webhookData)
 
-	mentionedMobiles, err := s.lookupMentionedMobiles(assigneeInfo)
+	// 补充合并请求信息与解析指派人同时进行，共用同一截止时间
+	var details *MergeRequestDetails
+	enriched := make(chan struct{})
+	go func() {
+		defer close(enriched)
+		if s.enricher != nil {
+			details = s.enricher.Enrich(ctx, project, &webhookData.ObjectAttributes)
+		}
+	}()
+
+	mentions, err := s.lookupMentions(ctx, project, assigneeInfo)
 	if err != nil {
-		logger.GetLogger().Warnf("查询指派人手机号失败: %v", err)
+		logger.GetLogger().Warnf("查询指派人失败: %v", err)
 	}
+	<-enriched
 
-	authorEmail := webhookData.User.Email
-	if authorEmail == "[REDACTED]" {
-		authorEmail = webhookData.User.Name
-	}
+	authorEmail := displayEmail(webhookData.User.Email, webhookData.User.Name)
 
 	payload := &MergeRequestPayload{
 		ProjectName:       project.Name,
@@ -56,12 +101,14 @@ func (s *notificationService) ProcessMergeRequest(webhookData *models.GitLabWebh
 		AuthorName:        webhookData.User.Name,
 		Title:             webhookData.ObjectAttributes.Title,
 		URL:               webhookData.ObjectAttributes.URL,
-		MentionedMobiles:  mentionedMobiles,
+		Mentions:          mentions,
 		MentionedAccounts: assigneeEmails,
 		Assignees:         assigneeInfo,
+		Details:           details,
 	}
 
 	notification := &models.Notification{
+		EventType:      models.EventTypeMergeRequest,
 		ProjectID:      project.ID,
 		MergeRequestID: webhookData.ObjectAttributes.IID,
 		Title:          webhookData.ObjectAttributes.Title,
@@ -77,21 +124,181 @@ func (s *notificationService) ProcessMergeRequest(webhookData *models.GitLabWebh
 		}
 	}
 
-	if err := s.sendNotifications(context.Background(), &project, payload); err != nil {
-		notification.ErrorMessage = err.Error()
+	message := &OutboundMessage{
+		EventType:    models.EventTypeMergeRequest,
+		TargetBranch: payload.TargetBranch,
+		MergeRequest: payload,
+	}
+	return s.saveNotification(notification, s.sendNotifications(context.Background(), project, message))
+}
+
+// ProcessPushEvent 处理分支推送事件
+func (s *notificationService) ProcessPushEvent(instanceID uint, event *models.GitLabPushEventData) error {
+	project, err := s.loadProjectWithWebhooks(instanceID, pushEventProjectID(event))
+	if err != nil {
+		return ignoreUnregisteredProject(err, pushEventProjectID(event))
+	}
+
+	branch := event.BranchName()
+	if !project.PushEvents || event.IsDeletion() || !project.MatchesPushBranch(branch) {
+		logger.GetLogger().Debugf("项目 %s 的分支 %s 推送未命中订阅，跳过通知", project.Name, branch)
+		return nil
+	}
+
+	commitCount := event.TotalCommitsCount
+	if commitCount == 0 {
+		commitCount = len(event.Commits)
+	}
+
+	notification := &models.Notification{
+		EventType:    models.EventTypePush,
+		ProjectID:    project.ID,
+		Title:        fmt.Sprintf("推送 %d 个提交到 %s", commitCount, branch),
+		SourceBranch: branch,
+		TargetBranch: branch,
+		AuthorEmail:  displayEmail(event.UserEmail, event.UserName),
+		Status:       "pushed",
+	}
+
+	message := &TextMessage{Content: FormatPushEventText(project.Name, event)}
+	return s.saveNotification(notification, s.sendTextNotifications(context.Background(), project, notification, message))
+}
+
+// ProcessTagPushEvent 处理标签推送事件
+func (s *notificationService) ProcessTagPushEvent(instanceID uint, event *models.GitLabPushEventData) error {
+	project, err := s.loadProjectWithWebhooks(instanceID, pushEventProjectID(event))
+	if err != nil {
+		return ignoreUnregisteredProject(err, pushEventProjectID(event))
+	}
+
+	tag := event.TagName()
+	if !project.TagPushEvents || event.IsDeletion() || !project.MatchesTag(tag) {
+		logger.GetLogger().Debugf("项目 %s 的标签 %s 未命中订阅，跳过通知", project.Name, tag)
+		return nil
+	}
+
+	notification := &models.Notification{
+		EventType:    models.EventTypeTagPush,
+		ProjectID:    project.ID,
+		Title:        fmt.Sprintf("新标签 %s", tag),
+		SourceBranch: tag,
+		AuthorEmail:  displayEmail(event.UserEmail, event.UserName),
+		Status:       "created",
+	}
+
+	message := &TextMessage{Content: FormatTagPushEventText(project.Name, event)}
+	return s.saveNotification(notification, s.sendTextNotifications(context.Background(), project, notification, message))
+}
+
+// ProcessReleaseEvent 处理发布事件，仅在新建发布时通知
+func (s *notificationService) ProcessReleaseEvent(instanceID uint, event *models.GitLabReleaseEventData) error {
+	project, err := s.loadProjectWithWebhooks(instanceID, event.Project.ID)
+	if err != nil {
+		return ignoreUnregisteredProject(err, event.Project.ID)
+	}
+
+	if !project.ReleaseEvents || event.Action != "create" || !project.MatchesTag(event.Tag) {
+		logger.GetLogger().Debugf("项目 %s 的发布 %s (%s) 未命中订阅，跳过通知", project.Name, event.Tag, event.Action)
+		return nil
+	}
+
+	notification := &models.Notification{
+		EventType:    models.EventTypeRelease,
+		ProjectID:    project.ID,
+		Title:        fmt.Sprintf("发布 %s", event.Tag),
+		SourceBranch: event.Tag,
+		Status:       event.Action,
+	}
+
+	message := &TextMessage{Content: FormatReleaseEventText(project.Name, event)}
+	return s.saveNotification(notification, s.sendTextNotifications(context.Background(), project, notification, message))
+}
+
+// ProcessPipelineEvent 处理流水线事件，仅用于更新合并请求的流水线状态
+func (s *notificationService) ProcessPipelineEvent(instanceID uint, event *models.GitLabPipelineEventData) error {
+	if s.tracker == nil {
+		return nil
+	}
+
+	var project models.Project
+	if err := s.db.Where("gitlab_instance_id = ? AND gitlab_project_id = ?", instanceID, event.Project.ID).First(&project).Error; err != nil {
+		// 未接入的项目直接忽略，避免 GitLab 反复重试
+		logger.GetLogger().Debugf("流水线事件对应的项目 %d 未接入，忽略", event.Project.ID)
+		return nil
+	}
+
+	return s.tracker.TrackPipeline(&project, event)
+}
+
+// SendProjectMessage 向项目关联的所有启用渠道发送文本消息并记录通知，project 需预加载 Webhooks
+func (s *notificationService) SendProjectMessage(ctx context.Context, project *models.Project, notification *models.Notification, message *TextMessage) error {
+	notification.ProjectID = project.ID
+	return s.saveNotification(notification, s.sendTextNotifications(ctx, project, notification, message))
+}
+
+// ResolveMentions 根据 GitLab 用户名查询需要 @ 的用户
+func (s *notificationService) ResolveMentions(ctx context.Context, project *models.Project, usernames []string) []Mention {
+	assignees := make([]models.AssigneeInfo, 0, len(usernames))
+	for _, username := range usernames {
+		assignees = append(assignees, models.AssigneeInfo{Username: username})
+	}
+
+	mentions, err := s.lookupMentions(ctx, project, assignees)
+	if err != nil {
+		logger.GetLogger().Warnf("查询提醒对象失败: %v", err)
+	}
+	return mentions
+}
+
+func (s *notificationService) loadProjectWithWebhooks(instanceID uint, gitlabProjectID int) (*models.Project, error) {
+	var project models.Project
+	if err := s.db.Where("gitlab_instance_id = ? AND gitlab_project_id = ?", instanceID, gitlabProjectID).First(&project).Error; err != nil {
+		return nil, fmt.Errorf("project not found: %w", err)
+	}
+
+	if err := s.db.Preload("Webhooks").Preload("Webhooks.Settings").First(&project, project.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load project webhooks: %w", err)
+	}
+
+	return &project, nil
+}
+
+// ignoreUnregisteredProject 实例级、组级 Hook 会推送未接入项目的事件，直接忽略以免 GitLab 反复重试并停用 Hook
+func ignoreUnregisteredProject(err error, gitlabProjectID int) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.GetLogger().Debugf("事件对应的项目 %d 未接入，忽略", gitlabProjectID)
+		return nil
+	}
+	return err
+}
+
+func (s *notificationService) saveNotification(notification *models.Notification, result deliveryResult) error {
+	if result.err != nil {
+		notification.ErrorMessage = result.err.Error()
+		notification.NotificationSent = false
+	} else if len(result.skipped) > 0 && result.sent == 0 && len(result.held) == 0 {
+		notification.ErrorMessage = fmt.Sprintf("渠道熔断中，已跳过发送: %s", strings.Join(result.skipped, ", "))
 		notification.NotificationSent = false
 	} else {
-		notification.NotificationSent = true
+		// 全部进入队列时等队列投递成功后再标记为已发送
+		notification.NotificationSent = result.sent > 0 || len(result.held) == 0
 	}
 
 	if err := s.db.Create(notification).Error; err != nil {
 		return fmt.Errorf("failed to save notification: %w", err)
 	}
 
+	if len(result.held) > 0 && s.queue != nil {
+		if err := s.queue.AttachNotification(result.held, notification.ID); err != nil {
+			logger.GetLogger().Warnf("关联队列消息与通知 %d 失败: %v", notification.ID, err)
+		}
+	}
+
 	return nil
 }
 
-func (s *notificationService) sendNotifications(ctx context.Context, project *models.Project, payload *MergeRequestPayload) error {
+func (s *notificationService) sendNotifications(ctx context.Context, project *models.Project, message *OutboundMessage) deliveryResult {
+	payload := message.MergeRequest
 	logger.GetLogger().Infof("开始处理通知发送 - 项目: %s", project.Name)
 
 	if len(payload.Assignees) > 0 {
@@ -103,11 +310,111 @@ func (s *notificationService) sendNotifications(ctx context.Context, project *mo
 		logger.GetLogger().Warnf("没有找到指派人信息")
 	}
 
-	logger.GetLogger().Infof("通过数据库匹配获得 %d 个手机号用于@功能", len(payload.MentionedMobiles))
-	for i, mobile := range payload.MentionedMobiles {
-		logger.GetLogger().Infof("  手机号 %d: %s", i+1, mobile)
+	logger.GetLogger().Infof("通过数据库匹配获得 %d 个用户用于@功能", len(payload.Mentions))
+	for i, mention := range payload.Mentions {
+		logger.GetLogger().Infof("  用户 %d: 手机号=%s, 渠道身份=%v", i+1, mention.Phone, mention.Identities)
+	}
+
+	return s.dispatch(ctx, project, message)
+}
+
+func (s *notificationService) sendTextNotifications(ctx context.Context, project *models.Project, notification *models.Notification, message *TextMessage) deliveryResult {
+	logger.GetLogger().Infof("开始处理事件通知发送 - 项目: %s", project.Name)
+
+	return s.dispatch(ctx, project, &OutboundMessage{
+		EventType:    notification.EventType,
+		TargetBranch: notification.TargetBranch,
+		Text:         message,
+	})
+}
+
+// dispatch 向项目的所有启用渠道投递消息，渠道处于非投递时间且不是紧急分支时放入队列
+func (s *notificationService) dispatch(ctx context.Context, project *models.Project, message *OutboundMessage) deliveryResult {
+	var result deliveryResult
+	now := time.Now()
+
+	result.err = s.forEachActiveWebhook(project, func(sender MessageSender, webhook *models.Webhook) error {
+		item, err := deliverOrHold(ctx, s.queue, sender, webhook, project.ID, message, now)
+		if errors.Is(err, ErrCircuitOpen) {
+			// 熔断中的渠道跳过即可，不影响其他渠道
+			result.skipped = append(result.skipped, webhook.Name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if item != nil {
+			result.held = append(result.held, item.ID)
+			return nil
+		}
+		result.sent++
+		return nil
+	})
+
+	return result
+}
+
+// deliverOrHold 向单个渠道投递消息：非投递时间或合并窗口内的消息放入队列，速率受限时放入队列稍后发送
+// 返回放入队列的消息，直接发送成功时为 nil
+func deliverOrHold(ctx context.Context, queue DeliveryQueueService, sender MessageSender, webhook *models.Webhook, projectID uint, message *OutboundMessage, now time.Time) (*models.DeliveryQueueItem, error) {
+	if releaseAt, ok := holdUntil(queue, webhook, message, now); ok {
+		item, err := queue.Hold(webhook, projectID, message, releaseAt, models.DeliveryReasonWindow)
+		if err != nil {
+			return nil, err
+		}
+		logger.GetLogger().Infof("Webhook %s (%d) 当前不在投递时间内，消息将于 %s 投递",
+			webhook.Name, webhook.ID, releaseAt.In(webhook.Location()).Format("2006-01-02 15:04"))
+		return item, nil
+	}
+
+	if shouldCoalesce(queue, webhook, message) {
+		item, err := queue.Coalesce(webhook, projectID, message, now)
+		if err != nil {
+			return nil, err
+		}
+		logger.GetLogger().Infof("Webhook %s (%d) 合并窗口内的合并请求消息将于 %s 合并发送",
+			webhook.Name, webhook.ID, item.ReleaseAt.In(webhook.Location()).Format("15:04:05"))
+		return item, nil
 	}
 
+	err := message.SendVia(ctx, sender, webhook)
+	if errors.Is(err, ErrRateLimited) && queue != nil {
+		// 速率限制已满时放入队列稍后发送，不丢弃消息
+		item, holdErr := queue.Hold(webhook, projectID, message, now.Add(rateLimitedRetryDelay), models.DeliveryReasonRateLimited)
+		if holdErr != nil {
+			return nil, fmt.Errorf("%w (放入队列失败: %v)", err, holdErr)
+		}
+		logger.GetLogger().Infof("Webhook %s (%d) 速率受限，消息已放入队列", webhook.Name, webhook.ID)
+		return item, nil
+	}
+	return nil, err
+}
+
+// shouldCoalesce 开启合并窗口的渠道，非紧急分支的合并请求消息先进入合并窗口
+func shouldCoalesce(queue DeliveryQueueService, webhook *models.Webhook, message *OutboundMessage) bool {
+	return queue != nil &&
+		webhook.CoalesceWindowSeconds > 0 &&
+		message.MergeRequest != nil &&
+		!webhook.IsUrgentBranch(message.TargetBranch)
+}
+
+// holdUntil 判断消息是否需要延迟投递，返回投递时间
+func holdUntil(queue DeliveryQueueService, webhook *models.Webhook, message *OutboundMessage, now time.Time) (time.Time, bool) {
+	if queue == nil || !webhook.HasDeliveryWindow() || webhook.IsUrgentBranch(message.TargetBranch) {
+		return time.Time{}, false
+	}
+
+	releaseAt, err := webhook.NextDeliveryTime(now)
+	if err != nil {
+		// 配置无效时不拦截消息，避免通知丢失
+		logger.GetLogger().Warnf("Webhook %d 投递时间窗口配置无效，直接发送: %v", webhook.ID, err)
+		return time.Time{}, false
+	}
+
+	return releaseAt, releaseAt.After(now)
+}
+
+func (s *notificationService) forEachActiveWebhook(project *models.Project, send func(sender MessageSender, webhook *models.Webhook) error) error {
 	sentWebhooks := make(map[uint]bool)
 	for _, webhook := range project.Webhooks {
 		if !webhook.IsActive {
@@ -123,7 +430,7 @@ func (s *notificationService) sendNotifications(ctx context.Context, project *mo
 			return fmt.Errorf("failed to find sender for webhook %d: %w", webhook.ID, err)
 		}
 
-		if err := sender.Send(ctx, &webhook, payload); err != nil {
+		if err := send(sender, &webhook); err != nil {
 			return fmt.Errorf("failed to send via webhook %s (%d): %w", webhook.Name, webhook.ID, err)
 		}
 
@@ -133,7 +440,8 @@ func (s *notificationService) sendNotifications(ctx context.Context, project *mo
 	return nil
 }
 
-func (s *notificationService) lookupMentionedMobiles(assignees []models.AssigneeInfo) ([]string, error) {
+// lookupMentions 按 GitLab 用户名、邮箱匹配用户，均未匹配时通过 GitLab 用户接口查找，返回需要 @ 的用户
+func (s *notificationService) lookupMentions(ctx context.Context, project *models.Project, assignees []models.AssigneeInfo) ([]Mention, error) {
 	if len(assignees) == 0 {
 		return nil, nil
 	}
@@ -151,8 +459,16 @@ func (s *notificationService) lookupMentionedMobiles(assignees []models.Assignee
 		}
 	}
 
-	phoneMap := make(map[string]bool)
-	var mentionedMobiles []string
+	var matched []models.User
+	seenUsers := make(map[uint]bool)
+	addUser := func(user models.User) {
+		if !seenUsers[user.ID] {
+			seenUsers[user.ID] = true
+			matched = append(matched, user)
+		}
+	}
+	matchedUsernames := make(map[string]bool)
+	matchedEmails := make(map[string]bool)
 
 	// 优先通过 GitLab 用户名查询，因为它更可靠
 	if len(usernameList) > 0 {
@@ -162,11 +478,9 @@ func (s *notificationService) lookupMentionedMobiles(assignees []models.Assignee
 		} else {
 			logger.GetLogger().Infof("通过 GitLab 用户名查询到 %d 个用户", len(users))
 			for _, user := range users {
-				if user.Phone != "" && !phoneMap[user.Phone] {
-					mentionedMobiles = append(mentionedMobiles, user.Phone)
-					phoneMap[user.Phone] = true
-					logger.GetLogger().Infof("  匹配用户: GitLab用户名=%s, 手机号=%s", user.GitLabUsername, user.Phone)
-				}
+				matchedUsernames[user.GitLabUsername] = true
+				logger.GetLogger().Infof("  匹配用户: GitLab用户名=%s, 手机号=%s", user.GitLabUsername, user.Phone)
+				addUser(user)
 			}
 		}
 	}
@@ -179,16 +493,92 @@ func (s *notificationService) lookupMentionedMobiles(assignees []models.Assignee
 		} else {
 			logger.GetLogger().Infof("通过邮箱查询到 %d 个用户", len(users))
 			for _, user := range users {
-				if user.Phone != "" && !phoneMap[user.Phone] {
-					mentionedMobiles = append(mentionedMobiles, user.Phone)
-					phoneMap[user.Phone] = true
-					logger.GetLogger().Infof("  匹配用户: 邮箱=%s, 手机号=%s", user.Email, user.Phone)
-				}
+				matchedEmails[strings.ToLower(user.Email)] = true
+				logger.GetLogger().Infof("  匹配用户: 邮箱=%s, 手机号=%s", user.Email, user.Phone)
+				addUser(user)
 			}
 		}
 	}
 
-	return mentionedMobiles, nil
+	// 主用户名、主邮箱未匹配的，再按用户别名匹配
+	if aliased, err := usersByAliases(s.db, models.UserAliasKindGitLabUsername, unmatched(usernameList, matchedUsernames, false)); err != nil {
+		logger.GetLogger().Warnf("通过 GitLab 用户名别名查询用户失败: %v", err)
+	} else {
+		for username, user := range aliased {
+			matchedUsernames[username] = true
+			logger.GetLogger().Infof("  匹配用户: GitLab用户名别名=%s, 邮箱=%s", username, user.Email)
+			addUser(user)
+		}
+	}
+	if aliased, err := usersByAliases(s.db, models.UserAliasKindEmail, unmatched(emailList, matchedEmails, true)); err != nil {
+		logger.GetLogger().Warnf("通过邮箱别名查询用户失败: %v", err)
+	} else {
+		for email, user := range aliased {
+			matchedEmails[email] = true
+			logger.GetLogger().Infof("  匹配用户: 邮箱别名=%s, 邮箱=%s", email, user.Email)
+			addUser(user)
+		}
+	}
+
+	// 用户名与邮箱都未匹配（通常是邮箱被隐藏）时，通过 GitLab 用户接口并发查找，总耗时受 ctx 限制
+	if s.users != nil {
+		var pending []models.AssigneeInfo
+		for _, info := range assignees {
+			if !matchedUsernames[info.Username] && !matchedEmails[strings.ToLower(info.Email)] {
+				pending = append(pending, info)
+			}
+		}
+
+		resolved := make([]*models.User, len(pending))
+		var wg sync.WaitGroup
+		for idx, info := range pending {
+			wg.Add(1)
+			go func(idx int, info models.AssigneeInfo) {
+				defer wg.Done()
+				resolved[idx] = s.users.Resolve(ctx, project, info)
+			}(idx, info)
+		}
+		wg.Wait()
+
+		for idx, user := range resolved {
+			if user != nil {
+				logger.GetLogger().Infof("  匹配用户: GitLab用户名=%s, 邮箱=%s（GitLab 用户接口）", pending[idx].Username, user.Email)
+				addUser(*user)
+			}
+		}
+	}
+
+	return loadMentions(s.db, matched)
+}
+
+// unmatched 返回尚未匹配到用户的值，lower 为 true 时按小写比较
+func unmatched(values []string, matched map[string]bool, lower bool) []string {
+	var rest []string
+	for _, value := range values {
+		key := value
+		if lower {
+			key = strings.ToLower(value)
+		}
+		if !matched[key] {
+			rest = append(rest, value)
+		}
+	}
+	return rest
+}
+
+// displayEmail GitLab 隐藏邮箱时使用显示名代替
+func displayEmail(email, name string) string {
+	if email == "" || email == "[REDACTED]" {
+		return name
+	}
+	return email
+}
+
+func pushEventProjectID(event *models.GitLabPushEventData) int {
+	if event.Project.ID != 0 {
+		return event.Project.ID
+	}
+	return event.ProjectID
 }
 
 func buildAssigneeInfo(webhookData *models.GitLabWebhookData) ([]models.AssigneeInfo, []string) {
@@ -202,6 +592,7 @@ func buildAssigneeInfo(webhookData *models.GitLabWebhookData) ([]models.Assignee
 		info[i] = models.AssigneeInfo{
 			Email:    email,
 			Username: assignee.Username,
+			UserID:   assignee.ID,
 		}
 		emails[i] = email
 	}
@@ -278,6 +669,7 @@ func buildNotificationResponses(notifications []models.Notification) ([]models.N
 
 		responses = append(responses, models.NotificationResponse{
 			ID:               notification.ID,
+			EventType:        notification.EventType,
 			ProjectID:        notification.ProjectID,
 			ProjectName:      notification.Project.Name,
 			MergeRequestID:   notification.MergeRequestID,
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidNotificationPreference = errors.New("invalid notification preference")
+	ErrAccountGitLabLinkMissing      = errors.New("account has no gitlab personal access token")
+	ErrAccountUserNotFound           = errors.New("no user matches the gitlab username of the account")
+)
+
+type notificationPreferenceService struct {
+	db        *gorm.DB
+	cfg       *config.Config
+	gitlab    GitLabService
+	instances GitLabInstanceService
+	alerts    OpsAlertService
+}
+
+func NewNotificationPreferenceService(db *gorm.DB, cfg *config.Config, gitlab GitLabService, instances GitLabInstanceService, alerts OpsAlertService) NotificationPreferenceService {
+	return &notificationPreferenceService{db: db, cfg: cfg, gitlab: gitlab, instances: instances, alerts: alerts}
+}
+
+func (s *notificationPreferenceService) Get(userID uint) (*models.UserNotificationPreference, error) {
+	if err := s.ensureUser(userID); err != nil {
+		return nil, err
+	}
+
+	preference := &models.UserNotificationPreference{UserID: userID}
+	if err := s.db.Where("user_id = ?", userID).Limit(1).Find(preference).Error; err != nil {
+		return nil, err
+	}
+	return preference, nil
+}
+
+func (s *notificationPreferenceService) Update(userID uint, req *models.UserNotificationPreferenceRequest) (*models.UserNotificationPreference, error) {
+	if err := s.ensureUser(userID); err != nil {
+		return nil, err
+	}
+
+	preference := &models.UserNotificationPreference{UserID: userID}
+	if err := preference.ApplyRequest(req); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNotificationPreference, err)
+	}
+
+	// 没有任何限制时删除记录，恢复默认的总是 @
+	if preference.IsEmpty() {
+		if err := s.db.Where("user_id = ?", userID).Delete(&models.UserNotificationPreference{}).Error; err != nil {
+			return nil, err
+		}
+		return preference, nil
+	}
+
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"timezone", "mention_hours", "mention_branch_patterns", "muted_until", "updated_at"}),
+	}).Create(preference).Error; err != nil {
+		return nil, err
+	}
+	logger.GetLogger().Infof("更新用户 %d 的通知偏好", userID)
+	return s.Get(userID)
+}
+
+func (s *notificationPreferenceService) LinkedUser(ctx context.Context, accountID uint) (*models.User, error) {
+	var account models.Account
+	if err := s.db.Select("id", "gitlab_username").First(&account, accountID).Error; err != nil {
+		return nil, err
+	}
+
+	username := account.GitLabUsername
+	if username == "" {
+		token, err := accountGitLabToken(s.db, s.cfg.EncryptionKey, s.alerts, accountID)
+		if err != nil || token == "" {
+			return nil, ErrAccountGitLabLinkMissing
+		}
+		gitlabUser, err := s.currentGitLabUser(ctx, accountID, token)
+		if err != nil {
+			return nil, fmt.Errorf("获取令牌所属的 GitLab 用户失败: %w", err)
+		}
+		username = strings.TrimSpace(gitlabUser.Username)
+		if username == "" {
+			return nil, ErrAccountGitLabLinkMissing
+		}
+		if err := s.db.Model(&models.Account{}).Where("id = ?", accountID).Update("gitlab_username", username).Error; err != nil {
+			return nil, err
+		}
+		logger.GetLogger().Infof("账户 %d 关联 GitLab 用户名 %s", accountID, username)
+	}
+
+	var user models.User
+	err := s.db.Where("gitlab_username = ?", username).First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	aliased, err := usersByAliases(s.db, models.UserAliasKindGitLabUsername, []string{username})
+	if err != nil {
+		return nil, err
+	}
+	if aliasUser, ok := aliased[username]; ok {
+		return &aliasUser, nil
+	}
+	return nil, ErrAccountUserNotFound
+}
+
+// currentGitLabUser 查询个人令牌所属的 GitLab 用户，令牌不绑定实例，
+// 依次尝试默认实例和该账户登记的项目所在的实例
+func (s *notificationPreferenceService) currentGitLabUser(ctx context.Context, accountID uint, token string) (*GitLabUserDetail, error) {
+	var instanceIDs []uint
+	if err := s.db.Model(&models.Project{}).Where("created_by = ?", accountID).
+		Distinct("gitlab_instance_id").Pluck("gitlab_instance_id", &instanceIDs).Error; err != nil {
+		return nil, err
+	}
+
+	var baseURLs []string
+	if instance, err := s.instances.Default(); err == nil {
+		baseURLs = append(baseURLs, instance.BaseURL)
+	} else if !errors.Is(err, ErrGitLabInstanceNotFound) {
+		return nil, err
+	}
+	for _, instanceID := range instanceIDs {
+		instance, err := s.instances.Get(instanceID)
+		if err != nil || instance.IsDefault {
+			continue
+		}
+		baseURLs = append(baseURLs, instance.BaseURL)
+	}
+	if len(baseURLs) == 0 {
+		return nil, ErrGitLabInstanceNotFound
+	}
+
+	var lastErr error
+	for _, baseURL := range baseURLs {
+		user, err := s.gitlab.GetCurrentUser(ctx, baseURL, token)
+		if err == nil {
+			return user, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (s *notificationPreferenceService) ensureUser(userID uint) error {
+	var count int64
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
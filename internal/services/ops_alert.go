@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// opsAlertTimeout 后台发送系统告警的超时时间
+const opsAlertTimeout = 10 * time.Second
+
+// OpsAlertEvent 一次系统告警，Key 相同的告警合并去重
+type OpsAlertEvent struct {
+	Severity string
+	Category string
+	Key      string
+	Title    string
+	Detail   string
+	Mentions []Mention
+	// AccountID 告警涉及资源的所属账户，未配置管理员通知渠道时该账户也能在告警列表中看到
+	AccountID *uint
+}
+
+// TokenDecryptFallbackAlert GitLab 令牌解密失败并退回明文时的告警
+func TokenDecryptFallbackAlert(accountID uint, err error) OpsAlertEvent {
+	return OpsAlertEvent{
+		Severity: models.OpsSeverityWarning,
+		Category: models.OpsCategoryTokenDecrypt,
+		Key:      fmt.Sprintf("token_decrypt:account:%d", accountID),
+		Title:    fmt.Sprintf("账户 %d 的 GitLab 令牌解密失败，已按明文令牌使用", accountID),
+		Detail:   fmt.Sprintf("Error: %v\n请确认 encryption_key 未被修改，或让该账户重新保存 GitLab 令牌", err),
+	}
+}
+
+type opsAlertService struct {
+	db            *gorm.DB
+	config        *config.Config
+	senderFactory SenderFactory
+}
+
+func NewOpsAlertService(db *gorm.DB, cfg *config.Config, factory SenderFactory) OpsAlertService {
+	return &opsAlertService{
+		db:            db,
+		config:        cfg,
+		senderFactory: factory,
+	}
+}
+
+// Alert 记录系统告警，达到通知级别且不在去重窗口内时发送到管理员通知渠道
+func (s *opsAlertService) Alert(ctx context.Context, event OpsAlertEvent) error {
+	if event.Severity == "" {
+		event.Severity = models.OpsSeverityWarning
+	}
+	if event.Key == "" {
+		event.Key = event.Category + ":" + event.Title
+	}
+	event.Detail = truncateFailureMessage(event.Detail)
+
+	logger.GetLogger().Warnf("系统告警 [%s] %s: %s", event.Severity, event.Category, event.Title)
+
+	now := time.Now().UTC()
+	alert := models.OpsAlert{
+		DedupKey:     event.Key,
+		Category:     event.Category,
+		Severity:     event.Severity,
+		Title:        event.Title,
+		Detail:       event.Detail,
+		AccountID:    event.AccountID,
+		Occurrences:  1,
+		PendingCount: 1,
+		FirstSeenAt:  now,
+		LastSeenAt:   now,
+	}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "dedup_key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"severity":      event.Severity,
+			"title":         event.Title,
+			"detail":        event.Detail,
+			"occurrences":   gorm.Expr("ops_alerts.occurrences + 1"),
+			"pending_count": gorm.Expr("ops_alerts.pending_count + 1"),
+			"last_seen_at":  now,
+			"updated_at":    now,
+		}),
+	}).Create(&alert).Error; err != nil {
+		return fmt.Errorf("记录系统告警失败: %w", err)
+	}
+
+	adminID := s.config.Notification.AdminWebhookID
+	if adminID == 0 || models.OpsSeverityRank(event.Severity) < models.OpsSeverityRank(s.minSeverity()) {
+		return nil
+	}
+
+	if err := s.db.Where("dedup_key = ?", event.Key).First(&alert).Error; err != nil {
+		return fmt.Errorf("查询系统告警失败: %w", err)
+	}
+
+	// 条件更新占用本次通知，去重窗口内以及其他实例已通知时跳过
+	cutoff := now.Add(-s.dedupWindow())
+	result := s.db.Model(&models.OpsAlert{}).
+		Where("id = ? AND (last_sent_at IS NULL OR last_sent_at <= ?)", alert.ID, cutoff).
+		UpdateColumns(map[string]interface{}{
+			"last_sent_at":  now,
+			"pending_count": gorm.Expr("pending_count - ?", alert.PendingCount),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("更新系统告警通知时间失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	var admin models.Webhook
+	if err := s.db.Preload("Settings").First(&admin, adminID).Error; err != nil {
+		return fmt.Errorf("管理员通知渠道不存在: %w", err)
+	}
+	admin.ApplyDefaults()
+
+	sender, err := s.senderFactory.SenderFor(&admin)
+	if err != nil {
+		return err
+	}
+	return sender.SendText(ctx, &admin, &TextMessage{
+		Content:  FormatOpsAlertText(&alert),
+		Mentions: event.Mentions,
+	})
+}
+
+// Notify 在后台发送告警，用于不希望被告警发送阻塞的请求路径
+func (s *opsAlertService) Notify(event OpsAlertEvent) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), opsAlertTimeout)
+		defer cancel()
+		if err := s.Alert(ctx, event); err != nil {
+			logger.GetLogger().Warnf("发送系统告警失败: %v", err)
+		}
+	}()
+}
+
+// ListRecent 返回最近发生的系统告警，accountID 不为空时只返回该账户的告警
+func (s *opsAlertService) ListRecent(accountID *uint, limit int) ([]models.OpsAlert, error) {
+	query := s.db.Order("last_seen_at DESC").Limit(limit)
+	if accountID != nil {
+		query = query.Where("account_id = ?", *accountID)
+	}
+
+	var alerts []models.OpsAlert
+	if err := query.Find(&alerts).Error; err != nil {
+		return nil, fmt.Errorf("查询系统告警失败: %w", err)
+	}
+	return alerts, nil
+}
+
+func (s *opsAlertService) minSeverity() string {
+	severity := s.config.Notification.OpsAlerts.MinSeverity
+	if models.OpsSeverityRank(severity) == 0 {
+		return models.OpsSeverityWarning
+	}
+	return severity
+}
+
+func (s *opsAlertService) dedupWindow() time.Duration {
+	if window := s.config.Notification.OpsAlerts.DedupWindow; window > 0 {
+		return window
+	}
+	return time.Hour
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// quotaExhaustedThreshold 配额用尽时的预警阈值
+const quotaExhaustedThreshold = 100
+
+type quotaService struct {
+	db     *gorm.DB
+	config *config.Config
+	alerts OpsAlertService
+}
+
+func NewQuotaService(db *gorm.DB, cfg *config.Config, alerts OpsAlertService) QuotaService {
+	return &quotaService{
+		db:     db,
+		config: cfg,
+		alerts: alerts,
+	}
+}
+
+// GetUsage 计算 Webhook 当月的配额使用情况与耗尽预测
+func (s *quotaService) GetUsage(webhooks []models.Webhook, now time.Time) ([]models.QuotaUsageResponse, error) {
+	now = now.UTC()
+	periodStart := startOfMonth(now)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+
+	ids := make([]uint, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		ids = append(ids, webhook.ID)
+	}
+
+	stats := make(map[uint]models.WebhookDeliveryStat)
+	if len(ids) > 0 {
+		var rows []models.WebhookDeliveryStat
+		if err := s.db.Where("webhook_id IN ? AND period_start = ?", ids, periodStart).Find(&rows).Error; err != nil {
+			return nil, fmt.Errorf("查询配额使用情况失败: %w", err)
+		}
+		for _, row := range rows {
+			stats[row.WebhookID] = row
+		}
+	}
+
+	usages := make([]models.QuotaUsageResponse, 0, len(webhooks))
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		webhook.ApplyDefaults()
+		stat := stats[webhook.ID]
+
+		usage := models.QuotaUsageResponse{
+			WebhookID:       webhook.ID,
+			WebhookName:     webhook.Name,
+			Channel:         webhook.Type,
+			PeriodStart:     periodStart,
+			PeriodEnd:       periodEnd,
+			Used:            stat.Count,
+			Quota:           s.monthlyQuota(webhook.Type),
+			WarnedThreshold: stat.WarnedThreshold,
+		}
+
+		// 按本月已过去的时间估算日均用量，至少按 1 小时计算避免月初数据失真
+		elapsed := math.Max(now.Sub(periodStart).Hours(), 1)
+		usage.DailyRate = math.Round(float64(stat.Count)/elapsed*24*100) / 100
+
+		if usage.Quota > 0 {
+			usage.Remaining = usage.Quota - int(stat.Count)
+			if usage.Remaining < 0 {
+				usage.Remaining = 0
+			}
+			usage.UsagePercent = math.Round(float64(stat.Count)/float64(usage.Quota)*10000) / 100
+
+			if stat.Count > 0 {
+				hoursLeft := float64(usage.Remaining) / (float64(stat.Count) / elapsed)
+				exhaustAt := now.Add(time.Duration(hoursLeft * float64(time.Hour)))
+				if exhaustAt.Before(periodEnd) {
+					usage.ProjectedExhaustAt = &exhaustAt
+				}
+			}
+		}
+
+		usages = append(usages, usage)
+	}
+
+	return usages, nil
+}
+
+// CheckQuotaWarnings 检查本月配额使用是否达到预警阈值，每个阈值每月只预警一次
+func (s *quotaService) CheckQuotaWarnings(ctx context.Context) error {
+	quota := s.config.Notification.DingTalk.MonthlyQuota
+	if quota <= 0 {
+		return nil
+	}
+
+	thresholds := s.warningThresholds()
+	now := time.Now().UTC()
+
+	var stats []models.WebhookDeliveryStat
+	if err := s.db.Where("period_start = ? AND channel = ? AND warned_threshold < ? AND count * 100 >= ?",
+		startOfMonth(now), models.WebhookTypeDingTalk, quotaExhaustedThreshold, thresholds[0]*quota).
+		Find(&stats).Error; err != nil {
+		return fmt.Errorf("查询配额使用情况失败: %w", err)
+	}
+
+	var errs []error
+	for _, stat := range stats {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		reached := 0
+		for _, threshold := range thresholds {
+			if int(stat.Count)*100 >= threshold*quota {
+				reached = threshold
+			}
+		}
+		if reached <= stat.WarnedThreshold {
+			continue
+		}
+
+		// 条件更新占用预警，避免多实例重复发送
+		result := s.db.Model(&models.WebhookDeliveryStat{}).
+			Where("id = ? AND warned_threshold < ?", stat.ID, reached).
+			Update("warned_threshold", reached)
+		if result.Error != nil {
+			errs = append(errs, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		if err := s.sendWarning(ctx, stat, reached, now); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %d: %w", stat.WebhookID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *quotaService) sendWarning(ctx context.Context, stat models.WebhookDeliveryStat, threshold int, now time.Time) error {
+	var webhook models.Webhook
+	if err := s.db.First(&webhook, stat.WebhookID).Error; err != nil {
+		return fmt.Errorf("webhook not found: %w", err)
+	}
+
+	usages, err := s.GetUsage([]models.Webhook{webhook}, now)
+	if err != nil {
+		return err
+	}
+
+	severity := models.OpsSeverityWarning
+	title := fmt.Sprintf("Webhook %s 月度配额已使用 %d%%", webhook.Name, threshold)
+	if threshold >= quotaExhaustedThreshold {
+		severity = models.OpsSeverityCritical
+		title = fmt.Sprintf("Webhook %s 月度配额已用尽，后续消息将发送失败", webhook.Name)
+	}
+
+	return s.alerts.Alert(ctx, OpsAlertEvent{
+		Severity: severity,
+		Category: models.OpsCategoryQuota,
+		Key:      fmt.Sprintf("quota:webhook:%d:%s:%d", webhook.ID, stat.PeriodStart.Format("2006-01"), threshold),
+		Title:    title,
+		Detail:   FormatQuotaUsageDetail(&usages[0]),
+	})
+}
+
+func (s *quotaService) warningThresholds() []int {
+	thresholds := make([]int, 0, len(s.config.Notification.DingTalk.QuotaWarningThresholds)+1)
+	for _, threshold := range s.config.Notification.DingTalk.QuotaWarningThresholds {
+		if threshold > 0 && threshold < quotaExhaustedThreshold {
+			thresholds = append(thresholds, threshold)
+		}
+	}
+	thresholds = append(thresholds, quotaExhaustedThreshold)
+	sort.Ints(thresholds)
+	return thresholds
+}
+
+func (s *quotaService) monthlyQuota(channel string) int {
+	if channel == models.WebhookTypeDingTalk {
+		return s.config.Notification.DingTalk.MonthlyQuota
+	}
+	return 0
+}
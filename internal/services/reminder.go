@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+type reminderService struct {
+	db            *gorm.DB
+	config        *config.Config
+	gitlabService GitLabService
+	tracker       MergeRequestTracker
+	notifier      NotificationService
+	alerts        OpsAlertService
+	instances     GitLabInstanceService
+}
+
+func NewReminderService(db *gorm.DB, cfg *config.Config, gitlabService GitLabService, tracker MergeRequestTracker, notifier NotificationService, alerts OpsAlertService, instances GitLabInstanceService) ReminderService {
+	return &reminderService{
+		db:            db,
+		config:        cfg,
+		gitlabService: gitlabService,
+		tracker:       tracker,
+		notifier:      notifier,
+		alerts:        alerts,
+		instances:     instances,
+	}
+}
+
+// RemindStaleMergeRequests 遍历开启催办的项目，对超过阈值仍未处理的合并请求发送提醒
+func (s *reminderService) RemindStaleMergeRequests(ctx context.Context) error {
+	var settings []models.ProjectReminderSetting
+	if err := s.db.Where("enabled = ?", true).Find(&settings).Error; err != nil {
+		return fmt.Errorf("查询催办配置失败: %w", err)
+	}
+
+	var errs []error
+	for i := range settings {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.remindProject(ctx, &settings[i]); err != nil {
+			logger.GetLogger().Warnf("项目 %d 催办失败: %v", settings[i].ProjectID, err)
+			errs = append(errs, fmt.Errorf("project %d: %w", settings[i].ProjectID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *reminderService) GetProjectSetting(projectID uint) (*models.ProjectReminderSetting, error) {
+	var setting models.ProjectReminderSetting
+	err := s.db.Where("project_id = ?", projectID).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		setting = models.DefaultReminderSetting(projectID)
+		return &setting, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+func (s *reminderService) UpdateProjectSetting(projectID uint, req *models.UpdateReminderSettingRequest) (*models.ProjectReminderSetting, error) {
+	setting, err := s.GetProjectSetting(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	setting.Apply(req)
+	if err := s.db.Save(setting).Error; err != nil {
+		return nil, fmt.Errorf("保存催办配置失败: %w", err)
+	}
+	return setting, nil
+}
+
+func (s *reminderService) remindProject(ctx context.Context, setting *models.ProjectReminderSetting) error {
+	var project models.Project
+	if err := s.db.Preload("Webhooks").Preload("Webhooks.Settings").First(&project, setting.ProjectID).Error; err != nil {
+		return fmt.Errorf("project not found: %w", err)
+	}
+
+	// openIIDs 为 nil 表示未从 GitLab 拉取，直接使用本地记录的状态
+	var openIIDs map[int]bool
+	if setting.PollGitLab {
+		polled, err := s.pollOpenMergeRequests(ctx, &project)
+		if err != nil {
+			logger.GetLogger().Warnf("拉取项目 %s 的打开合并请求失败，使用本地状态: %v", project.Name, err)
+		} else {
+			openIIDs = polled
+		}
+	}
+
+	now := time.Now().UTC()
+	query := s.db.Where("project_id = ? AND state = ?", project.ID, models.MergeRequestStateOpened).
+		Where("opened_at <= ?", now.Add(-setting.StaleAfter())).
+		Where("reminder_count < ?", setting.MaxReminders).
+		Where("last_reminded_at IS NULL OR last_reminded_at <= ?", now.Add(-setting.RepeatInterval()))
+	if setting.SkipDrafts {
+		query = query.Where("draft = ?", false)
+	}
+
+	var mergeRequests []models.MergeRequest
+	if err := query.Order("opened_at ASC").Find(&mergeRequests).Error; err != nil {
+		return fmt.Errorf("查询待催办合并请求失败: %w", err)
+	}
+
+	var errs []error
+	for i := range mergeRequests {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		mr := &mergeRequests[i]
+		if openIIDs != nil && !openIIDs[mr.IID] {
+			// GitLab 上已不是打开状态，等待后续 webhook 更新本地记录
+			continue
+		}
+
+		claimed, err := s.claimReminder(mr, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.sendReminder(ctx, &project, setting, mr, now); err != nil {
+			errs = append(errs, fmt.Errorf("!%d: %w", mr.IID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// claimReminder 通过条件更新占用本次提醒，避免并发执行时重复发送
+func (s *reminderService) claimReminder(mr *models.MergeRequest, now time.Time) (bool, error) {
+	result := s.db.Model(&models.MergeRequest{}).
+		Where("id = ? AND reminder_count = ?", mr.ID, mr.ReminderCount).
+		Updates(map[string]interface{}{
+			"reminder_count":   gorm.Expr("reminder_count + 1"),
+			"last_reminded_at": now,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("更新合并请求 !%d 催办次数失败: %w", mr.IID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	mr.ReminderCount++
+	mr.LastRemindedAt = &now
+	return true, nil
+}
+
+func (s *reminderService) sendReminder(ctx context.Context, project *models.Project, setting *models.ProjectReminderSetting, mr *models.MergeRequest, now time.Time) error {
+	mentions := mr.Mentions()
+
+	waiting := time.Duration(0)
+	if mr.OpenedAt != nil {
+		waiting = now.Sub(*mr.OpenedAt)
+	}
+
+	message := &TextMessage{
+		Content:      FormatMergeRequestReminderText(project.Name, mr, waiting, setting.MaxReminders, mentions),
+		Mentions:     s.notifier.ResolveMentions(ctx, project, mentions),
+		TargetBranch: mr.TargetBranch,
+	}
+
+	notification := &models.Notification{
+		EventType:      models.EventTypeMRReminder,
+		MergeRequestID: mr.IID,
+		Title:          mr.Title,
+		SourceBranch:   mr.SourceBranch,
+		TargetBranch:   mr.TargetBranch,
+		AuthorEmail:    mr.AuthorName,
+		Status:         fmt.Sprintf("reminder_%d", mr.ReminderCount),
+	}
+
+	logger.GetLogger().Infof("发送合并请求催办 - 项目: %s, !%d, 第 %d/%d 次", project.Name, mr.IID, mr.ReminderCount, setting.MaxReminders)
+	return s.notifier.SendProjectMessage(ctx, project, notification, message)
+}
+
+// pollOpenMergeRequests 从 GitLab 拉取打开的合并请求并同步到本地，返回打开状态的 IID 集合
+func (s *reminderService) pollOpenMergeRequests(ctx context.Context, project *models.Project) (map[int]bool, error) {
+	token, err := s.resolveProjectToken(project)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeRequests, err := s.gitlabService.ListOpenMergeRequests(ctx, s.projectBaseURL(project), project.GitLabProjectID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	openIIDs := make(map[int]bool, len(mergeRequests))
+	for _, info := range mergeRequests {
+		openIIDs[info.IID] = true
+		if _, err := s.tracker.TrackEvent(project, info.ToWebhookData()); err != nil {
+			logger.GetLogger().Warnf("同步合并请求 !%d 状态失败: %v", info.IID, err)
+		}
+	}
+
+	return openIIDs, nil
+}
+
+func (s *reminderService) projectBaseURL(project *models.Project) string {
+	if parsed := s.gitlabService.ParseGitLabURL(project.URL); parsed != nil && parsed.IsValid {
+		return parsed.BaseURL
+	}
+	if instance := s.projectInstance(project); instance != nil {
+		return instance.BaseURL
+	}
+	return strings.TrimRight(s.config.GitLabURL, "/")
+}
+
+func (s *reminderService) projectInstance(project *models.Project) *models.GitLabInstance {
+	if s.instances == nil || project.GitLabInstanceID == 0 {
+		return nil
+	}
+	instance, err := s.instances.Get(project.GitLabInstanceID)
+	if err != nil {
+		logger.GetLogger().Warnf("查询项目 %s 所属的 GitLab 实例失败: %v", project.Name, err)
+		return nil
+	}
+	return instance
+}
+
+// resolveProjectToken 依次使用实例的默认令牌、配置的服务令牌（仅默认实例）和项目创建者保存的个人令牌
+func (s *reminderService) resolveProjectToken(project *models.Project) (string, error) {
+	instance := s.projectInstance(project)
+	if instance != nil {
+		if token := s.instances.DefaultToken(instance.ID); token != "" {
+			return token, nil
+		}
+	}
+	if instance == nil || instance.IsDefault {
+		if token := strings.TrimSpace(s.config.GitLabServiceToken); token != "" {
+			return token, nil
+		}
+	}
+
+	if project.CreatedBy == nil {
+		return "", fmt.Errorf("项目未配置创建者，无法获取 GitLab 令牌")
+	}
+	return accountGitLabToken(s.db, s.config.EncryptionKey, s.alerts, *project.CreatedBy)
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// ErrCircuitOpen Webhook 熔断中，本次发送被跳过
+var ErrCircuitOpen = errors.New("webhook circuit breaker open")
+
+// 记录的最近一次失败原因最大长度
+const maxFailureMessageLength = 500
+
+type circuitProbeKey struct{}
+
+// WithCircuitProbe 标记本次发送为人工试探（如测试消息），熔断中也会放行，成功后关闭熔断
+func WithCircuitProbe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, circuitProbeKey{}, true)
+}
+
+func isCircuitProbe(ctx context.Context) bool {
+	forced, _ := ctx.Value(circuitProbeKey{}).(bool)
+	return forced
+}
+
+// circuitBreaker 按 Webhook 记录连续失败次数，状态保存在 webhooks 表中，多实例共享
+type circuitBreaker struct {
+	db  *gorm.DB
+	cfg config.CircuitBreakerConfig
+}
+
+func newCircuitBreaker(db *gorm.DB, cfg config.CircuitBreakerConfig) *circuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 5 * time.Minute
+	}
+	return &circuitBreaker{db: db, cfg: cfg}
+}
+
+// circuitBreakerSender 在渠道发送器外层增加熔断判断
+type circuitBreakerSender struct {
+	next    MessageSender
+	breaker *circuitBreaker
+}
+
+func (s *circuitBreakerSender) Send(ctx context.Context, webhook *models.Webhook, payload *MergeRequestPayload) error {
+	return s.breaker.do(ctx, webhook, func() error {
+		return s.next.Send(ctx, webhook, payload)
+	})
+}
+
+func (s *circuitBreakerSender) SendText(ctx context.Context, webhook *models.Webhook, message *TextMessage) error {
+	return s.breaker.do(ctx, webhook, func() error {
+		return s.next.SendText(ctx, webhook, message)
+	})
+}
+
+func (b *circuitBreaker) do(ctx context.Context, webhook *models.Webhook, send func() error) error {
+	if b.db == nil || !b.cfg.Enabled || webhook == nil || webhook.ID == 0 {
+		return send()
+	}
+
+	now := time.Now().UTC()
+	allowed, err := b.allow(ctx, webhook.ID, now)
+	if err != nil {
+		// 熔断状态读取失败时不拦截消息
+		logger.GetLogger().Warnf("读取 Webhook %d 熔断状态失败: %v", webhook.ID, err)
+		return send()
+	}
+	if !allowed {
+		logger.GetLogger().Infof("Webhook %s (%d) 熔断中，跳过发送", webhook.Name, webhook.ID)
+		return fmt.Errorf("%w: %s", ErrCircuitOpen, webhook.Name)
+	}
+
+	sendErr := send()
+	if countsAsCircuitFailure(ctx, sendErr) {
+		b.recordFailure(webhook, sendErr, now)
+	} else if sendErr == nil {
+		b.recordSuccess(webhook)
+	}
+	return sendErr
+}
+
+// allow 熔断关闭时直接放行；熔断打开时冷却结束后只放行一个试探请求，其余请求跳过并计数
+func (b *circuitBreaker) allow(ctx context.Context, webhookID uint, now time.Time) (bool, error) {
+	var state models.Webhook
+	if err := b.db.Select("id", "circuit_opened_at", "circuit_probe_at").First(&state, webhookID).Error; err != nil {
+		return false, err
+	}
+	if state.CircuitOpenedAt == nil || isCircuitProbe(ctx) {
+		return true, nil
+	}
+
+	result := b.db.Model(&models.Webhook{}).
+		Where("id = ? AND (circuit_probe_at IS NULL OR circuit_probe_at <= ?)", webhookID, now).
+		UpdateColumn("circuit_probe_at", now.Add(b.cfg.Cooldown))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		logger.GetLogger().Infof("Webhook %d 熔断冷却结束，发送试探请求", webhookID)
+		return true, nil
+	}
+
+	if err := b.db.Model(&models.Webhook{}).Where("id = ?", webhookID).
+		UpdateColumn("circuit_skipped_count", gorm.Expr("circuit_skipped_count + 1")).Error; err != nil {
+		logger.GetLogger().Warnf("记录 Webhook %d 熔断跳过次数失败: %v", webhookID, err)
+	}
+	return false, nil
+}
+
+func (b *circuitBreaker) recordFailure(webhook *models.Webhook, sendErr error, now time.Time) {
+	if err := b.db.Model(&models.Webhook{}).Where("id = ?", webhook.ID).UpdateColumns(map[string]interface{}{
+		"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+		"last_failure_message": truncateFailureMessage(sendErr.Error()),
+	}).Error; err != nil {
+		logger.GetLogger().Warnf("记录 Webhook %d 发送失败次数失败: %v", webhook.ID, err)
+		return
+	}
+
+	// 条件更新只有一个实例能打开熔断
+	result := b.db.Model(&models.Webhook{}).
+		Where("id = ? AND circuit_opened_at IS NULL AND consecutive_failures >= ?", webhook.ID, b.cfg.FailureThreshold).
+		UpdateColumns(map[string]interface{}{
+			"circuit_opened_at":     now,
+			"circuit_probe_at":      now.Add(b.cfg.Cooldown),
+			"circuit_skipped_count": 0,
+			"circuit_alerted_at":    nil,
+		})
+	if result.Error != nil {
+		logger.GetLogger().Warnf("打开 Webhook %d 熔断失败: %v", webhook.ID, result.Error)
+		return
+	}
+	if result.RowsAffected == 1 {
+		logger.GetLogger().Warnf("Webhook %s (%d) 连续失败 %d 次，熔断已打开，%s 后试探恢复: %v",
+			webhook.Name, webhook.ID, b.cfg.FailureThreshold, b.cfg.Cooldown, sendErr)
+	}
+}
+
+func (b *circuitBreaker) recordSuccess(webhook *models.Webhook) {
+	var state models.Webhook
+	if err := b.db.Select("id", "consecutive_failures", "circuit_opened_at").First(&state, webhook.ID).Error; err != nil {
+		return
+	}
+	if state.ConsecutiveFailures == 0 && state.CircuitOpenedAt == nil {
+		return
+	}
+
+	if err := b.db.Model(&models.Webhook{}).Where("id = ?", webhook.ID).UpdateColumns(map[string]interface{}{
+		"consecutive_failures": 0,
+		"circuit_opened_at":    nil,
+		"circuit_probe_at":     nil,
+		"circuit_alerted_at":   nil,
+	}).Error; err != nil {
+		logger.GetLogger().Warnf("重置 Webhook %d 熔断状态失败: %v", webhook.ID, err)
+		return
+	}
+	if state.CircuitOpenedAt != nil {
+		logger.GetLogger().Infof("Webhook %s (%d) 发送恢复，熔断已关闭", webhook.Name, webhook.ID)
+	}
+}
+
+// countsAsCircuitFailure 速率限制、配额用尽与调用方取消不代表渠道故障，不计入熔断
+func countsAsCircuitFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	return !errors.Is(err, ErrRateLimited) &&
+		!errors.Is(err, ErrDingTalkQuotaExceeded) &&
+		!errors.Is(err, ErrCircuitOpen)
+}
+
+func truncateFailureMessage(message string) string {
+	if len(message) <= maxFailureMessageLength {
+		return message
+	}
+	message = message[:maxFailureMessageLength]
+	for !utf8.ValidString(message) {
+		message = message[:len(message)-1]
+	}
+	return message
+}
+
+type circuitBreakerService struct {
+	db     *gorm.DB
+	config *config.Config
+	alerts OpsAlertService
+}
+
+func NewCircuitBreakerService(db *gorm.DB, cfg *config.Config, alerts OpsAlertService) CircuitBreakerService {
+	return &circuitBreakerService{
+		db:     db,
+		config: cfg,
+		alerts: alerts,
+	}
+}
+
+// CheckOpenCircuits 熔断持续超过告警时长的 Webhook 通知所属账户，按配置停用
+func (s *circuitBreakerService) CheckOpenCircuits(ctx context.Context) error {
+	cfg := s.config.Notification.CircuitBreaker
+	if !cfg.Enabled || cfg.AlertAfter <= 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	var webhooks []models.Webhook
+	if err := s.db.Where("circuit_opened_at IS NOT NULL AND circuit_opened_at <= ? AND circuit_alerted_at IS NULL", now.Add(-cfg.AlertAfter)).
+		Find(&webhooks).Error; err != nil {
+		return fmt.Errorf("查询熔断中的 Webhook 失败: %w", err)
+	}
+
+	var errs []error
+	for i := range webhooks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		webhook := &webhooks[i]
+		// 条件更新占用告警，避免多实例重复通知
+		result := s.db.Model(&models.Webhook{}).
+			Where("id = ? AND circuit_alerted_at IS NULL", webhook.ID).
+			UpdateColumn("circuit_alerted_at", now)
+		if result.Error != nil {
+			errs = append(errs, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		if cfg.AutoDisable && webhook.IsActive {
+			reason := fmt.Sprintf("连续失败 %d 次，熔断超过 %s 自动停用: %s", webhook.ConsecutiveFailures, cfg.AlertAfter, webhook.LastFailureMessage)
+			if err := s.db.Model(&models.Webhook{}).Where("id = ?", webhook.ID).UpdateColumns(map[string]interface{}{
+				"is_active":       false,
+				"disabled_reason": truncateFailureMessage(reason),
+			}).Error; err != nil {
+				errs = append(errs, fmt.Errorf("停用 Webhook %d 失败: %w", webhook.ID, err))
+				continue
+			}
+			webhook.IsActive = false
+			logger.GetLogger().Warnf("Webhook %s (%d) 熔断超过 %s，已自动停用", webhook.Name, webhook.ID, cfg.AlertAfter)
+		}
+
+		if err := s.notifyOwner(ctx, webhook, now); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %d: %w", webhook.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// notifyOwner 通过系统告警提醒 Webhook 所属账户，能匹配到手机号时 @ 所属账户；
+// 告警归属于所属账户，未配置管理员通知渠道时所属账户也能在告警列表中看到
+func (s *circuitBreakerService) notifyOwner(ctx context.Context, webhook *models.Webhook, now time.Time) error {
+	var owner *models.Account
+	var mentions []Mention
+	if webhook.CreatedBy != nil {
+		var account models.Account
+		if err := s.db.First(&account, *webhook.CreatedBy).Error; err == nil {
+			owner = &account
+			if user, _, err := findUserByEmail(s.db, account.Email); err == nil {
+				if mentions, err = loadMentions(s.db, []models.User{*user}); err != nil {
+					logger.GetLogger().Warnf("查询 Webhook %s 所属账户的渠道身份失败: %v", webhook.Name, err)
+				}
+			}
+		}
+	}
+
+	title := fmt.Sprintf("Webhook %s 熔断已持续 %s", webhook.Name, formatWaitingDuration(now.Sub(*webhook.CircuitOpenedAt)))
+	if !webhook.IsActive {
+		title = fmt.Sprintf("Webhook %s 持续发送失败，已自动停用", webhook.Name)
+	}
+
+	return s.alerts.Alert(ctx, OpsAlertEvent{
+		Severity:  models.OpsSeverityCritical,
+		Category:  models.OpsCategoryCircuitBreaker,
+		Key:       fmt.Sprintf("circuit_breaker:webhook:%d:%d", webhook.ID, webhook.CircuitOpenedAt.Unix()),
+		Title:     title,
+		Detail:    FormatCircuitOpenDetail(webhook, owner, now),
+		Mentions:  mentions,
+		AccountID: webhook.CreatedBy,
+	})
+}
@@ -17,3 +17,8 @@ func (s *CustomSender) Send(ctx context.Context, webhook *models.Webhook, payloa
 	logger.GetLogger().Infof("自定义 webhook (%d) 使用 GitLab 原生通知，请确保已在 GitLab 配置该地址: %s", webhook.ID, webhook.URL)
 	return nil
 }
+
+func (s *CustomSender) SendText(ctx context.Context, webhook *models.Webhook, message *TextMessage) error {
+	logger.GetLogger().Infof("自定义 webhook (%d) 使用 GitLab 原生通知，跳过平台内消息发送", webhook.ID)
+	return nil
+}
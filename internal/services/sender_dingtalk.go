@@ -16,19 +16,19 @@ import (
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
-	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/ratelimit"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
-	ErrDingTalkRateLimited   = errors.New("dingtalk rate limit reached")
+	ErrDingTalkRateLimited   = fmt.Errorf("dingtalk %w", ErrRateLimited)
 	ErrDingTalkQuotaExceeded = errors.New("dingtalk monthly quota exceeded")
 )
 
 type DingTalkSender struct {
 	client       *http.Client
-	limiter      *ratelimit.TokenBucket
+	limiter      *webhookLimiter
 	monthlyQuota int
 	db           *gorm.DB
 }
@@ -45,6 +45,7 @@ type dingTalkMessage struct {
 	} `json:"text"`
 	At struct {
 		Mobiles []string `json:"atMobiles,omitempty"`
+		UserIDs []string `json:"atUserIds,omitempty"`
 		IsAtAll bool     `json:"isAtAll"`
 	} `json:"at"`
 }
@@ -54,11 +55,9 @@ func NewDingTalkSender(db *gorm.DB, cfg config.DingTalkConfig) *DingTalkSender {
 	if timeout == 0 {
 		timeout = 5 * time.Second
 	}
-	limiter := ratelimit.NewTokenBucket(cfg.RateLimitPerMinute)
-
 	return &DingTalkSender{
 		client:       &http.Client{Timeout: timeout},
-		limiter:      limiter,
+		limiter:      newWebhookLimiter(db, models.WebhookTypeDingTalk, cfg.RateLimitPerMinute, cfg.RateLimitMaxWait),
 		monthlyQuota: cfg.MonthlyQuota,
 		db:           db,
 	}
@@ -69,20 +68,44 @@ func (s *DingTalkSender) Send(ctx context.Context, webhook *models.Webhook, payl
 		return errors.New("nil payload")
 	}
 
+	userIDs, mobiles := mentionTargets(models.IdentityChannelDingTalk, activeMentions(payload.Mentions, payload.TargetBranch, time.Now()), payload.MentionedMobiles)
+	return s.deliver(ctx, webhook, FormatMergeRequestPayloadText(payload), userIDs, mobiles, false)
+}
+
+func (s *DingTalkSender) SendText(ctx context.Context, webhook *models.Webhook, message *TextMessage) error {
+	if message == nil {
+		return errors.New("nil message")
+	}
+
+	userIDs, mobiles := mentionTargets(models.IdentityChannelDingTalk, activeMentions(message.Mentions, message.TargetBranch, time.Now()), message.MentionedMobiles)
+	return s.deliver(ctx, webhook, message.Content, userIDs, mobiles, message.AtAll)
+}
+
+func (s *DingTalkSender) deliver(ctx context.Context, webhook *models.Webhook, content string, mentionedUserIDs, mentionedMobiles []string, atAll bool) (err error) {
 	if s.monthlyQuota > 0 {
-		exceeded, current, err := s.isQuotaExceeded(webhook.ID)
-		if err != nil {
-			return err
+		reserved, reserveErr := s.reserveQuota(webhook.ID)
+		if reserveErr != nil {
+			return reserveErr
 		}
-		if exceeded {
-			logger.GetLogger().Warnf("钉钉 webhook %d 已达到月度配额: %d", webhook.ID, current)
+		if !reserved {
+			logger.GetLogger().Warnf("钉钉 webhook %d 已达到月度配额: %d", webhook.ID, s.monthlyQuota)
 			return ErrDingTalkQuotaExceeded
 		}
-	}
-
-	if !s.limiter.Allow() {
-		logger.GetLogger().Warnf("钉钉 webhook %d 触发速率限制", webhook.ID)
-		return ErrDingTalkRateLimited
+		// 发送失败时归还预占的配额
+		defer func() {
+			if err != nil {
+				if releaseErr := s.releaseQuota(webhook.ID); releaseErr != nil {
+					logger.GetLogger().Warnf("归还钉钉 webhook %d 配额失败: %v", webhook.ID, releaseErr)
+				}
+			}
+		}()
+	}
+
+	if err := s.limiter.Wait(ctx, webhook); err != nil {
+		if errors.Is(err, ErrRateLimited) {
+			return ErrDingTalkRateLimited
+		}
+		return err
 	}
 
 	webhook.ApplyDefaults()
@@ -92,13 +115,13 @@ func (s *DingTalkSender) Send(ctx context.Context, webhook *models.Webhook, payl
 		secret = webhook.Settings.Secret
 	}
 
-	content := FormatMergeRequestPayloadText(payload)
 	signedURL, timestamp := buildSignedDingTalkURL(webhook.URL, secret)
 
 	message := dingTalkMessage{MsgType: "text"}
 	message.Text.Content = content
-	message.At.Mobiles = payload.MentionedMobiles
-	message.At.IsAtAll = false
+	message.At.Mobiles = mentionedMobiles
+	message.At.UserIDs = mentionedUserIDs
+	message.At.IsAtAll = atAll
 
 	body, err := json.Marshal(message)
 	if err != nil {
@@ -132,62 +155,39 @@ func (s *DingTalkSender) Send(ctx context.Context, webhook *models.Webhook, payl
 		return fmt.Errorf("dingtalk error %d: %s", response.ErrCode, response.ErrMsg)
 	}
 
-	if err := s.incrementQuota(webhook.ID); err != nil {
-		return err
-	}
-
 	return nil
 }
 
-func (s *DingTalkSender) isQuotaExceeded(webhookID uint) (bool, uint, error) {
-	if s.monthlyQuota <= 0 {
-		return false, 0, nil
-	}
-
-	stat, err := s.ensureStatRecord(webhookID)
-	if err != nil {
-		return false, 0, err
-	}
-
-	return stat.Count >= uint(s.monthlyQuota), stat.Count, nil
+// reserveQuota 原子地预占一次当月配额：不存在记录时插入，已存在且未超额时计数加一
+func (s *DingTalkSender) reserveQuota(webhookID uint) (bool, error) {
+	now := time.Now().UTC()
+	stat := models.WebhookDeliveryStat{
+		WebhookID:   webhookID,
+		Channel:     models.WebhookTypeDingTalk,
+		PeriodStart: startOfMonth(now),
+		Count:       1,
+	}
+
+	result := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "webhook_id"}, {Name: "period_start"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":      gorm.Expr("webhook_delivery_stats.count + 1"),
+			"updated_at": now,
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("webhook_delivery_stats.count < ?", s.monthlyQuota),
+		}},
+	}).Create(&stat)
+	if result.Error != nil {
+		return false, fmt.Errorf("预占钉钉配额失败: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
 }
 
-func (s *DingTalkSender) incrementQuota(webhookID uint) error {
-	if s.monthlyQuota <= 0 {
-		return nil
-	}
-
-	stat, err := s.ensureStatRecord(webhookID)
-	if err != nil {
-		return err
-	}
-
+func (s *DingTalkSender) releaseQuota(webhookID uint) error {
 	return s.db.Model(&models.WebhookDeliveryStat{}).
-		Where("id = ?", stat.ID).
-		UpdateColumn("count", gorm.Expr("count + ?", 1)).Error
-}
-
-func (s *DingTalkSender) ensureStatRecord(webhookID uint) (*models.WebhookDeliveryStat, error) {
-	period := startOfMonth(time.Now().UTC())
-	stat := models.WebhookDeliveryStat{}
-	err := s.db.Where("webhook_id = ? AND period_start = ?", webhookID, period).
-		First(&stat).Error
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		stat = models.WebhookDeliveryStat{
-			WebhookID:   webhookID,
-			Channel:     models.WebhookTypeDingTalk,
-			PeriodStart: period,
-			Count:       0,
-		}
-		if err := s.db.Create(&stat).Error; err != nil {
-			return nil, err
-		}
-		return &stat, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	return &stat, nil
+		Where("webhook_id = ? AND period_start = ? AND count > 0", webhookID, startOfMonth(time.Now().UTC())).
+		UpdateColumn("count", gorm.Expr("count - ?", 1)).Error
 }
 
 func buildSignedDingTalkURL(rawURL, secret string) (string, int64) {
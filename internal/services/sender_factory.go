@@ -17,9 +17,10 @@ type messageSenderFactory struct {
 
 func NewMessageSenderFactory(db *gorm.DB, cfg *config.Config, wechatService WeChatService) SenderFactory {
 	dingTalkSender := NewDingTalkSender(db, cfg.Notification.DingTalk)
+	breaker := newCircuitBreaker(db, cfg.Notification.CircuitBreaker)
 	return &messageSenderFactory{
-		wecom:    NewWeComSender(wechatService),
-		dingtalk: dingTalkSender,
+		wecom:    &circuitBreakerSender{next: NewWeComSender(db, wechatService, cfg.Notification.WeCom), breaker: breaker},
+		dingtalk: &circuitBreakerSender{next: dingTalkSender, breaker: breaker},
 		custom:   NewCustomSender(),
 	}
 }
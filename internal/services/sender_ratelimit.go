@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/ratelimit"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrRateLimited 渠道速率限制已满且等待超时，调用方可将消息放入队列稍后重试
+var ErrRateLimited = errors.New("rate limit reached")
+
+// webhookLimiter 每个 Webhook 独立的速率限制，Webhook 未单独配置时使用渠道默认速率。
+//
+// 配置了数据库时令牌桶保存在 rate_limit_buckets 表中并通过条件更新扣减，多个实例共享同一限额；
+// 否则退化为进程内令牌桶。
+type webhookLimiter struct {
+	db               *gorm.DB
+	channel          string
+	defaultPerMinute int
+	maxWait          time.Duration
+	buckets          *ratelimit.Keyed
+}
+
+func newWebhookLimiter(db *gorm.DB, channel string, perMinute int, maxWait time.Duration) *webhookLimiter {
+	return &webhookLimiter{
+		db:               db,
+		channel:          channel,
+		defaultPerMinute: perMinute,
+		maxWait:          maxWait,
+		buckets:          ratelimit.NewKeyed(),
+	}
+}
+
+// Wait 等待 Webhook 的令牌，最长等待 maxWait
+func (l *webhookLimiter) Wait(ctx context.Context, webhook *models.Webhook) error {
+	perMinute := l.defaultPerMinute
+	if webhook.RateLimitPerMinute > 0 {
+		perMinute = webhook.RateLimitPerMinute
+	}
+	if perMinute <= 0 {
+		return nil
+	}
+
+	start := time.Now()
+	var err error
+	if l.db != nil {
+		err = l.waitShared(ctx, l.channel+":"+strconv.FormatUint(uint64(webhook.ID), 10), perMinute, start.Add(l.maxWait))
+	} else {
+		err = l.waitLocal(ctx, strconv.FormatUint(uint64(webhook.ID), 10), perMinute)
+	}
+
+	if errors.Is(err, ErrRateLimited) {
+		logger.GetLogger().Warnf("%s webhook %d 触发速率限制 (%d/分钟)", l.channel, webhook.ID, perMinute)
+		return fmt.Errorf("%s webhook %d: %w", l.channel, webhook.ID, ErrRateLimited)
+	}
+	if err != nil {
+		return err
+	}
+
+	if waited := time.Since(start); waited > 100*time.Millisecond {
+		logger.GetLogger().Infof("%s webhook %d 等待速率限制 %s", l.channel, webhook.ID, waited.Round(time.Millisecond))
+	}
+	return nil
+}
+
+func (l *webhookLimiter) waitLocal(ctx context.Context, key string, perMinute int) error {
+	bucket := l.buckets.Get(key, perMinute)
+	if bucket.Allow() {
+		return nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, l.maxWait)
+	defer cancel()
+
+	if err := bucket.Wait(waitCtx); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return ErrRateLimited
+	}
+	return nil
+}
+
+func (l *webhookLimiter) waitShared(ctx context.Context, key string, perMinute int, deadline time.Time) error {
+	for {
+		ok, retryAfter, err := l.takeShared(key, perMinute, time.Now())
+		if err != nil {
+			// 数据库异常时不阻塞发送，由渠道自身的限流兜底
+			logger.GetLogger().Warnf("读取共享速率限制失败，跳过限流: %v", err)
+			return nil
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().Add(retryAfter).After(deadline) {
+			return ErrRateLimited
+		}
+
+		timer := time.NewTimer(retryAfter)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// takeShared 原子地从数据库令牌桶中取一个令牌，失败时返回下一个令牌的等待时间
+func (l *webhookLimiter) takeShared(key string, perMinute int, now time.Time) (bool, time.Duration, error) {
+	capacity := float64(perMinute)
+	ratePerMs := capacity / float64(time.Minute/time.Millisecond)
+	nowMs := now.UnixMilli()
+
+	if err := l.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RateLimitBucket{Key: key, Tokens: capacity, RefilledAt: nowMs}).Error; err != nil {
+		return false, 0, err
+	}
+
+	// 各实例时钟可能略有偏差，refilled_at 只前进不后退
+	available := "MIN(?, tokens + MAX(0, ? - refilled_at) * ?)"
+	result := l.db.Exec(
+		"UPDATE rate_limit_buckets SET tokens = "+available+" - 1, refilled_at = MAX(refilled_at, ?) "+
+			"WHERE bucket_key = ? AND "+available+" >= 1",
+		capacity, nowMs, ratePerMs, nowMs, key, capacity, nowMs, ratePerMs,
+	)
+	if result.Error != nil {
+		return false, 0, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, 0, nil
+	}
+
+	var bucket models.RateLimitBucket
+	if err := l.db.Where("bucket_key = ?", key).First(&bucket).Error; err != nil {
+		return false, 0, err
+	}
+	tokens := math.Min(capacity, bucket.Tokens+math.Max(0, float64(nowMs-bucket.RefilledAt))*ratePerMs)
+	retryAfter := time.Duration((1-tokens)/ratePerMs) * time.Millisecond
+	if retryAfter < 10*time.Millisecond {
+		retryAfter = 10 * time.Millisecond
+	}
+	return false, retryAfter, nil
+}
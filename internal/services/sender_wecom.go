@@ -2,32 +2,54 @@ package services
 
 import (
 	"context"
+	"time"
 
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
 	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"gorm.io/gorm"
 )
 
 type WeComSender struct {
 	service WeChatService
+	limiter *webhookLimiter
 }
 
-func NewWeComSender(service WeChatService) *WeComSender {
-	return &WeComSender{service: service}
+func NewWeComSender(db *gorm.DB, service WeChatService, cfg config.WeComConfig) *WeComSender {
+	return &WeComSender{
+		service: service,
+		limiter: newWebhookLimiter(db, models.WebhookTypeWeCom, cfg.RateLimitPerMinute, cfg.RateLimitMaxWait),
+	}
 }
 
 func (s *WeComSender) Send(ctx context.Context, webhook *models.Webhook, payload *MergeRequestPayload) error {
 	if payload == nil {
 		return nil
 	}
-	content := s.service.FormatMergeRequestMessage(
-		payload.ProjectName,
-		payload.SourceBranch,
-		payload.TargetBranch,
-		payload.AuthorName,
-		payload.Title,
-		payload.URL,
-		payload.MentionedAccounts,
-		payload.MentionedMobiles,
-	)
-
-	return s.service.SendMessage(webhook.URL, content, payload.MentionedMobiles)
+	userIDs, mobiles := mentionTargets(models.IdentityChannelWeCom, activeMentions(payload.Mentions, payload.TargetBranch, time.Now()), payload.MentionedMobiles)
+	content := FormatMergeRequestPayloadTextWithPhones(payload, mobiles)
+
+	if err := s.limiter.Wait(ctx, webhook); err != nil {
+		return err
+	}
+
+	return s.service.SendMessage(webhook.URL, content, userIDs, mobiles)
+}
+
+func (s *WeComSender) SendText(ctx context.Context, webhook *models.Webhook, message *TextMessage) error {
+	if message == nil {
+		return nil
+	}
+
+	userIDs, mobiles := mentionTargets(models.IdentityChannelWeCom, activeMentions(message.Mentions, message.TargetBranch, time.Now()), message.MentionedMobiles)
+	if message.AtAll {
+		// 企业微信通过在手机号列表中加入 @all 提醒所有人
+		mobiles = append(append([]string{}, mobiles...), "@all")
+	}
+
+	if err := s.limiter.Wait(ctx, webhook); err != nil {
+		return err
+	}
+
+	return s.service.SendMessage(webhook.URL, message.Content, userIDs, mobiles)
 }
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrUserAliasNotFound = errors.New("user alias not found")
+	ErrUserAliasTaken    = errors.New("email or gitlab username is already used by a user")
+	ErrUserMergeSelf     = errors.New("cannot merge a user into itself")
+)
+
+type userAliasService struct {
+	db *gorm.DB
+}
+
+func NewUserAliasService(db *gorm.DB) UserAliasService {
+	return &userAliasService{db: db}
+}
+
+func (s *userAliasService) ListAliases(userID uint) ([]models.UserAlias, error) {
+	if _, err := s.findUser(s.db, userID); err != nil {
+		return nil, err
+	}
+
+	var aliases []models.UserAlias
+	if err := s.db.Where("user_id = ?", userID).Order("kind, value").Find(&aliases).Error; err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+func (s *userAliasService) AddAlias(userID uint, req *models.UserAliasRequest) (*models.UserAlias, error) {
+	user, err := s.findUser(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	alias := &models.UserAlias{UserID: user.ID, Kind: req.Kind, Value: normalizeAliasValue(req.Kind, req.Value)}
+	if alias.Value == "" {
+		return nil, ErrUserAliasTaken
+	}
+	// 与自己的主邮箱、主用户名相同的别名没有意义
+	if aliasMatchesPrimary(user, alias.Kind, alias.Value) {
+		return nil, ErrUserAliasTaken
+	}
+	if err := s.CheckAvailable(alias.Kind, alias.Value, user.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(alias).Error; err != nil {
+		return nil, err
+	}
+	logger.GetLogger().Infof("为用户 %s 添加别名 %s=%s", user.Email, alias.Kind, alias.Value)
+	return alias, nil
+}
+
+func (s *userAliasService) DeleteAlias(userID, aliasID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", aliasID, userID).Delete(&models.UserAlias{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserAliasNotFound
+	}
+	return nil
+}
+
+func (s *userAliasService) CheckAvailable(kind, value string, exceptUserID uint) error {
+	value = normalizeAliasValue(kind, value)
+	if value == "" {
+		return nil
+	}
+
+	query := s.db.Model(&models.User{}).Where("id != ?", exceptUserID)
+	switch kind {
+	case models.UserAliasKindEmail:
+		query = query.Where("LOWER(email) = ?", value)
+	case models.UserAliasKindGitLabUsername:
+		query = query.Where("gitlab_username = ?", value)
+	default:
+		return ErrUserAliasTaken
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrUserAliasTaken
+	}
+
+	if err := s.db.Model(&models.UserAlias{}).Where("kind = ? AND value = ?", kind, value).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrUserAliasTaken
+	}
+	return nil
+}
+
+// MergeUsers 合并重复用户：源用户的邮箱、GitLab 用户名转为目标用户的别名，别名、渠道身份与通知偏好归入目标用户，
+// 目标用户缺少的手机号、姓名与 GitLab 关联从源用户补全，同步记录中的源用户改为目标用户，最后删除源用户
+func (s *userAliasService) MergeUsers(targetID, sourceID uint) (*models.User, error) {
+	if targetID == sourceID {
+		return nil, ErrUserMergeSelf
+	}
+
+	var merged *models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		target, err := s.findUser(tx, targetID)
+		if err != nil {
+			return err
+		}
+		source, err := s.findUser(tx, sourceID)
+		if err != nil {
+			return err
+		}
+
+		if err := mergeUserIdentities(tx, target.ID, source.ID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.UserAlias{}).Where("user_id = ?", source.ID).Update("user_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := mergeUserPreferences(tx, target.ID, source.ID); err != nil {
+			return err
+		}
+		if err := mergeUserManagers(tx, target.ID, source.ID); err != nil {
+			return err
+		}
+		if err := rewriteUserSyncHistory(tx, source.ID, target.ID); err != nil {
+			return err
+		}
+		// 先删除源用户释放邮箱与用户名的唯一约束，再写入目标用户
+		if err := tx.Delete(&models.User{}, source.ID).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		var aliases []models.UserAlias
+		if source.Email != "" && !strings.EqualFold(source.Email, target.Email) {
+			aliases = append(aliases, models.UserAlias{UserID: target.ID, Kind: models.UserAliasKindEmail, Value: strings.ToLower(source.Email)})
+		}
+		switch {
+		case source.GitLabUsername == "" || source.GitLabUsername == target.GitLabUsername:
+		case target.GitLabUsername == "":
+			updates["gitlab_username"] = source.GitLabUsername
+			updates["gitlab_mapping_source"] = source.GitLabMappingSource
+			updates["gitlab_mapping_pending"] = source.GitLabMappingPending
+			if err := tx.Where("kind = ? AND value = ?", models.UserAliasKindGitLabUsername, source.GitLabUsername).Delete(&models.UserAlias{}).Error; err != nil {
+				return err
+			}
+		default:
+			aliases = append(aliases, models.UserAlias{UserID: target.ID, Kind: models.UserAliasKindGitLabUsername, Value: source.GitLabUsername})
+		}
+		if target.Phone == "" && source.Phone != "" {
+			updates["phone"] = source.Phone
+		}
+		if target.Name == "" && source.Name != "" {
+			updates["name"] = source.Name
+		}
+		if target.GitLabUserID == 0 && source.GitLabUserID != 0 {
+			updates["gitlab_instance_id"] = source.GitLabInstanceID
+			updates["gitlab_user_id"] = source.GitLabUserID
+		}
+
+		if len(updates) > 0 {
+			if err := tx.Model(&models.User{}).Where("id = ?", target.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		for idx := range aliases {
+			var exists int64
+			if err := tx.Model(&models.UserAlias{}).Where("kind = ? AND value = ?", aliases[idx].Kind, aliases[idx].Value).Count(&exists).Error; err != nil {
+				return err
+			}
+			if exists > 0 {
+				continue
+			}
+			if err := tx.Create(&aliases[idx]).Error; err != nil {
+				return err
+			}
+		}
+
+		if merged, err = s.findUser(tx, target.ID); err != nil {
+			return err
+		}
+		// 源用户的别名可能与目标用户的主字段相同
+		var redundant []uint
+		for _, alias := range merged.Aliases {
+			if aliasMatchesPrimary(merged, alias.Kind, alias.Value) {
+				redundant = append(redundant, alias.ID)
+			}
+		}
+		if len(redundant) > 0 {
+			if err := tx.Delete(&models.UserAlias{}, redundant).Error; err != nil {
+				return err
+			}
+			merged, err = s.findUser(tx, target.ID)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.GetLogger().Infof("已将用户 %d 合并到用户 %d (%s)", sourceID, targetID, merged.Email)
+	return merged, nil
+}
+
+func (s *userAliasService) findUser(db *gorm.DB, id uint) (*models.User, error) {
+	var user models.User
+	if err := db.Preload("Identities").Preload("Aliases").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// mergeUserIdentities 将源用户的渠道身份移到目标用户，目标用户已有的渠道以目标用户为准
+func mergeUserIdentities(tx *gorm.DB, targetID, sourceID uint) error {
+	var channels []string
+	if err := tx.Model(&models.UserIdentity{}).Where("user_id = ?", targetID).Pluck("channel", &channels).Error; err != nil {
+		return err
+	}
+	if len(channels) > 0 {
+		if err := tx.Where("user_id = ? AND channel IN ?", sourceID, channels).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.UserIdentity{}).Where("user_id = ?", sourceID).Update("user_id", targetID).Error
+}
+
+// mergeUserPreferences 目标用户没有通知偏好时沿用源用户的，否则以目标用户为准
+func mergeUserPreferences(tx *gorm.DB, targetID, sourceID uint) error {
+	var count int64
+	if err := tx.Model(&models.UserNotificationPreference{}).Where("user_id = ?", targetID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return tx.Where("user_id = ?", sourceID).Delete(&models.UserNotificationPreference{}).Error
+	}
+	return tx.Model(&models.UserNotificationPreference{}).Where("user_id = ?", sourceID).Update("user_id", targetID).Error
+}
+
+// mergeUserManagers 将源用户的负责人移到目标用户，已是目标用户负责人的以目标用户为准
+func mergeUserManagers(tx *gorm.DB, targetID, sourceID uint) error {
+	var managerIDs []uint
+	if err := tx.Model(&models.ResourceManager{}).
+		Where("resource_type = ? AND resource_id = ?", models.ResourceTypeUser, targetID).
+		Pluck("manager_id", &managerIDs).Error; err != nil {
+		return err
+	}
+	if len(managerIDs) > 0 {
+		if err := tx.Where("resource_type = ? AND resource_id = ? AND manager_id IN ?", models.ResourceTypeUser, sourceID, managerIDs).
+			Delete(&models.ResourceManager{}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.ResourceManager{}).
+		Where("resource_type = ? AND resource_id = ?", models.ResourceTypeUser, sourceID).
+		Update("resource_id", targetID).Error
+}
+
+// rewriteUserSyncHistory 将同步记录中的源用户改为目标用户
+func rewriteUserSyncHistory(tx *gorm.DB, sourceID, targetID uint) error {
+	var runs []models.UserSyncRun
+	if err := tx.Select("id", "changes").Find(&runs).Error; err != nil {
+		return err
+	}
+	for _, run := range runs {
+		changed := false
+		for idx := range run.Changes {
+			if run.Changes[idx].UserID == sourceID {
+				run.Changes[idx].UserID = targetID
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := tx.Model(&models.UserSyncRun{}).Where("id = ?", run.ID).Update("changes", run.Changes).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func normalizeAliasValue(kind, value string) string {
+	value = strings.TrimSpace(value)
+	if kind == models.UserAliasKindEmail {
+		return strings.ToLower(value)
+	}
+	return value
+}
+
+func aliasMatchesPrimary(user *models.User, kind, value string) bool {
+	if kind == models.UserAliasKindEmail {
+		return strings.EqualFold(user.Email, value)
+	}
+	return user.GitLabUsername == value
+}
+
+// findUserByEmail 按主邮箱或邮箱别名查找用户，忽略大小写
+func findUserByEmail(db *gorm.DB, email string) (*models.User, bool, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	var user models.User
+	err := db.Where("LOWER(email) = ?", email).First(&user).Error
+	if err == nil {
+		return &user, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	users, err := usersByAliases(db, models.UserAliasKindEmail, []string{email})
+	if err != nil {
+		return nil, false, err
+	}
+	if aliased, ok := users[email]; ok {
+		return &aliased, true, nil
+	}
+	return nil, false, gorm.ErrRecordNotFound
+}
+
+// usersByAliases 按别名查找用户，返回别名到用户的映射；邮箱别名的键为小写
+func usersByAliases(db *gorm.DB, kind string, values []string) (map[string]models.User, error) {
+	normalized := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = normalizeAliasValue(kind, value); value != "" {
+			normalized = append(normalized, value)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+
+	var aliases []models.UserAlias
+	if err := db.Where("kind = ? AND value IN ?", kind, normalized).Find(&aliases).Error; err != nil {
+		return nil, err
+	}
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(aliases))
+	for _, alias := range aliases {
+		ids = append(ids, alias.UserID)
+	}
+	var users []models.User
+	if err := db.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	result := make(map[string]models.User, len(aliases))
+	for _, alias := range aliases {
+		if user, ok := byID[alias.UserID]; ok {
+			result[alias.Value] = user
+		}
+	}
+	return result, nil
+}
+
+// gitLabUsernameTaken 判断 GitLab 用户名是否已是其他用户的主用户名或别名
+func gitLabUsernameTaken(db *gorm.DB, username string, exceptUserID uint) bool {
+	var count int64
+	if err := db.Model(&models.User{}).Where("gitlab_username = ? AND id != ?", username, exceptUserID).Count(&count).Error; err != nil || count > 0 {
+		return true
+	}
+	if err := db.Model(&models.UserAlias{}).Where("kind = ? AND value = ? AND user_id != ?", models.UserAliasKindGitLabUsername, username, exceptUserID).Count(&count).Error; err != nil {
+		return true
+	}
+	return count > 0
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+)
+
+func TestMergeUsersMovesManagers(t *testing.T) {
+	db := newUserImportTestDB(t)
+
+	var managers []models.Account
+	for i := 0; i < 3; i++ {
+		account := models.Account{Username: fmt.Sprintf("manager%d", i), Email: fmt.Sprintf("manager%d@example.com", i), PasswordHash: "x"}
+		if err := db.Create(&account).Error; err != nil {
+			t.Fatalf("create account: %v", err)
+		}
+		managers = append(managers, account)
+	}
+	target := models.User{Email: "target@example.com"}
+	source := models.User{Email: "source@example.com"}
+	other := models.User{Email: "other@example.com"}
+	for _, user := range []*models.User{&target, &source, &other} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	assign := func(userID uint, manager models.Account) {
+		row := models.ResourceManager{ResourceID: userID, ResourceType: models.ResourceTypeUser, ManagerID: manager.ID, CreatedBy: manager.ID}
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatalf("assign manager: %v", err)
+		}
+	}
+	assign(target.ID, managers[0])
+	assign(source.ID, managers[0])
+	assign(source.ID, managers[1])
+	assign(other.ID, managers[2])
+	// 与用户 ID 相同的项目负责人不能被改动
+	if err := db.Create(&models.ResourceManager{ResourceID: source.ID, ResourceType: models.ResourceTypeProject, ManagerID: managers[2].ID, CreatedBy: managers[2].ID}).Error; err != nil {
+		t.Fatalf("assign project manager: %v", err)
+	}
+
+	if _, err := NewUserAliasService(db).MergeUsers(target.ID, source.ID); err != nil {
+		t.Fatalf("merge users: %v", err)
+	}
+
+	cases := []struct {
+		resourceType models.ResourceType
+		resourceID   uint
+		want         []uint
+	}{
+		{models.ResourceTypeUser, target.ID, []uint{managers[0].ID, managers[1].ID}},
+		{models.ResourceTypeUser, source.ID, nil},
+		{models.ResourceTypeUser, other.ID, []uint{managers[2].ID}},
+		{models.ResourceTypeProject, source.ID, []uint{managers[2].ID}},
+	}
+
+	for _, tc := range cases {
+		var got []uint
+		if err := db.Model(&models.ResourceManager{}).
+			Where("resource_type = ? AND resource_id = ?", tc.resourceType, tc.resourceID).
+			Order("manager_id").Pluck("manager_id", &got).Error; err != nil {
+			t.Fatalf("query managers: %v", err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s %d: expected managers %v, got %v", tc.resourceType, tc.resourceID, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// userImportMaxRows 单次导入的最大行数
+const userImportMaxRows = 5000
+
+// userTableFormulaPrefixes 表格软件视为公式的起始字符
+const userTableFormulaPrefixes = "=+-@\t\r"
+
+var (
+	ErrUserTableFormat    = errors.New("unsupported user table format")
+	ErrUserTableHeader    = errors.New("user table header must contain an email column")
+	ErrUserImportTooLarge = errors.New("too many rows in user table")
+)
+
+var (
+	phonePattern = regexp.MustCompile(`^\+?[0-9]{6,20}$`)
+	// userTableHeaders 表头名称到列的映射，同时接受中文表头
+	userTableHeaders = map[string]string{
+		"email":           "email",
+		"邮箱":              "email",
+		"phone":           "phone",
+		"手机号":             "phone",
+		"name":            "name",
+		"姓名":              "name",
+		"gitlab_username": "gitlab_username",
+		"gitlab用户名":       "gitlab_username",
+		"gitlab 用户名":      "gitlab_username",
+	}
+	// utf8BOM Excel 保存的 CSV 带有 BOM，导出时同样写入以便 Excel 正确识别中文
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+)
+
+type userImportService struct {
+	db *gorm.DB
+}
+
+func NewUserImportService(db *gorm.DB) UserImportService {
+	return &userImportService{db: db}
+}
+
+func (s *userImportService) Parse(format string, r io.Reader) ([]models.UserImportRow, error) {
+	var records [][]string
+	switch format {
+	case models.UserTableFormatCSV:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		if records, err = reader.ReadAll(); err != nil {
+			return nil, fmt.Errorf("解析 CSV 失败: %w", err)
+		}
+	case models.UserTableFormatXLSX:
+		file, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("解析 XLSX 失败: %w", err)
+		}
+		defer file.Close()
+		sheets := file.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, ErrUserTableHeader
+		}
+		// 读取原始值，避免手机号被显示为科学计数法
+		if records, err = file.GetRows(sheets[0], excelize.Options{RawCellValue: true}); err != nil {
+			return nil, fmt.Errorf("解析 XLSX 失败: %w", err)
+		}
+	default:
+		return nil, ErrUserTableFormat
+	}
+
+	if len(records) == 0 {
+		return nil, ErrUserTableHeader
+	}
+	columns := make(map[string]int)
+	for idx, header := range records[0] {
+		if column, ok := userTableHeaders[strings.ToLower(strings.TrimSpace(header))]; ok {
+			if _, exists := columns[column]; !exists {
+				columns[column] = idx
+			}
+		}
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, ErrUserTableHeader
+	}
+	if len(records)-1 > userImportMaxRows {
+		return nil, ErrUserImportTooLarge
+	}
+
+	cell := func(record []string, column string) string {
+		idx, ok := columns[column]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(unescapeUserTableCell(strings.TrimSpace(record[idx])))
+	}
+
+	rows := make([]models.UserImportRow, 0, len(records)-1)
+	for idx, record := range records[1:] {
+		row := models.UserImportRow{
+			Line:           idx + 2,
+			Email:          cell(record, "email"),
+			Phone:          cell(record, "phone"),
+			Name:           cell(record, "name"),
+			GitLabUsername: cell(record, "gitlab_username"),
+		}
+		if row.Email == "" && row.Phone == "" && row.Name == "" && row.GitLabUsername == "" {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (s *userImportService) Import(rows []models.UserImportRow, opts models.UserImportOptions) (*models.UserImportResult, error) {
+	result := &models.UserImportResult{DryRun: opts.DryRun, Upsert: opts.Upsert, Rows: make([]models.UserImportRowResult, 0, len(rows))}
+	creates := make(map[int]*models.User)
+	updates := make(map[int]map[string]interface{})
+	emailLines := make(map[string]int)
+	usernameLines := make(map[string]int)
+
+	for _, row := range rows {
+		normalizeUserImportRow(&row)
+		rowResult := models.UserImportRowResult{Line: row.Line, Email: row.Email, GitLabUsername: row.GitLabUsername}
+		conflict := func(format string, args ...interface{}) {
+			rowResult.Action, rowResult.Error = models.UserImportActionConflict, fmt.Sprintf(format, args...)
+		}
+
+		switch {
+		case row.Email == "":
+			conflict("邮箱不能为空")
+		case !validImportEmail(row.Email):
+			conflict("邮箱格式不正确: %s", row.Email)
+		case row.Phone != "" && !phonePattern.MatchString(row.Phone):
+			conflict("手机号格式不正确: %s", row.Phone)
+		case emailLines[strings.ToLower(row.Email)] != 0:
+			conflict("与第 %d 行的邮箱重复", emailLines[strings.ToLower(row.Email)])
+		case row.GitLabUsername != "" && usernameLines[row.GitLabUsername] != 0:
+			conflict("与第 %d 行的 GitLab 用户名重复", usernameLines[row.GitLabUsername])
+		default:
+			emailLines[strings.ToLower(row.Email)] = row.Line
+			if row.GitLabUsername != "" {
+				usernameLines[row.GitLabUsername] = row.Line
+			}
+			if err := s.planRow(&row, opts, &rowResult, creates, updates); err != nil {
+				return nil, err
+			}
+		}
+
+		switch rowResult.Action {
+		case models.UserImportActionCreate:
+			result.Created++
+		case models.UserImportActionUpdate:
+			result.Updated++
+		case models.UserImportActionUnchanged:
+			result.Unchanged++
+		case models.UserImportActionConflict:
+			result.Conflicts++
+		}
+		result.Rows = append(result.Rows, rowResult)
+	}
+
+	if opts.DryRun || len(creates)+len(updates) == 0 {
+		return result, nil
+	}
+
+	// 冲突的行不写入，其余行在同一事务中写入
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for idx := range result.Rows {
+			rowResult := &result.Rows[idx]
+			if user, ok := creates[rowResult.Line]; ok {
+				if err := tx.Create(user).Error; err != nil {
+					return fmt.Errorf("第 %d 行创建用户失败: %w", rowResult.Line, err)
+				}
+				rowResult.UserID = user.ID
+			}
+			if changes, ok := updates[rowResult.Line]; ok {
+				if err := tx.Model(&models.User{}).Where("id = ?", rowResult.UserID).Updates(changes).Error; err != nil {
+					return fmt.Errorf("第 %d 行更新用户失败: %w", rowResult.Line, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.GetLogger().Infof("导入用户完成: 新建 %d, 更新 %d, 无变化 %d, 冲突 %d", result.Created, result.Updated, result.Unchanged, result.Conflicts)
+	return result, nil
+}
+
+// planRow 对比已有用户，确定该行新建、更新、无变化还是冲突
+func (s *userImportService) planRow(row *models.UserImportRow, opts models.UserImportOptions, rowResult *models.UserImportRowResult, creates map[int]*models.User, updates map[int]map[string]interface{}) error {
+	existing, _, err := findUserByEmail(s.db, row.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if existing == nil {
+		if row.GitLabUsername != "" && gitLabUsernameTaken(s.db, row.GitLabUsername, 0) {
+			rowResult.Action, rowResult.Error = models.UserImportActionConflict, "GitLab 用户名已被其他用户使用"
+			return nil
+		}
+		user := &models.User{
+			Email:          row.Email,
+			Phone:          row.Phone,
+			Name:           row.Name,
+			GitLabUsername: row.GitLabUsername,
+			CreatedBy:      &opts.AccountID,
+		}
+		if user.GitLabUsername != "" {
+			user.GitLabMappingSource = models.GitLabMappingSourceManual
+		}
+		rowResult.Action = models.UserImportActionCreate
+		creates[row.Line] = user
+		return nil
+	}
+
+	rowResult.UserID = existing.ID
+	switch {
+	case !opts.Upsert:
+		rowResult.Action, rowResult.Error = models.UserImportActionConflict, "用户已存在"
+		return nil
+	case opts.EditableUserIDs != nil && !opts.EditableUserIDs[existing.ID]:
+		rowResult.Action, rowResult.Error = models.UserImportActionConflict, "无权修改该用户"
+		return nil
+	}
+
+	// 空单元格表示保持原值
+	changes := make(map[string]interface{})
+	if row.Phone != "" && row.Phone != existing.Phone {
+		changes["phone"] = row.Phone
+		rowResult.Changes = append(rowResult.Changes, fmt.Sprintf("手机号 %q -> %q", existing.Phone, row.Phone))
+	}
+	if row.Name != "" && row.Name != existing.Name {
+		changes["name"] = row.Name
+		rowResult.Changes = append(rowResult.Changes, fmt.Sprintf("姓名 %q -> %q", existing.Name, row.Name))
+	}
+	if row.GitLabUsername != "" && row.GitLabUsername != existing.GitLabUsername {
+		if gitLabUsernameTaken(s.db, row.GitLabUsername, existing.ID) {
+			rowResult.Action, rowResult.Error, rowResult.Changes = models.UserImportActionConflict, "GitLab 用户名已被其他用户使用", nil
+			return nil
+		}
+		changes["gitlab_username"] = row.GitLabUsername
+		changes["gitlab_mapping_source"] = models.GitLabMappingSourceManual
+		changes["gitlab_mapping_pending"] = false
+		rowResult.Changes = append(rowResult.Changes, fmt.Sprintf("GitLab 用户名 %q -> %q", existing.GitLabUsername, row.GitLabUsername))
+	}
+
+	if len(changes) == 0 {
+		rowResult.Action = models.UserImportActionUnchanged
+		return nil
+	}
+	rowResult.Action = models.UserImportActionUpdate
+	updates[row.Line] = changes
+	return nil
+}
+
+func (s *userImportService) Export(users []models.User, format string, w io.Writer) error {
+	records := make([][]string, 0, len(users)+1)
+	records = append(records, models.UserTableColumns)
+	for _, user := range users {
+		records = append(records, []string{user.Email, user.Phone, user.Name, user.GitLabUsername})
+	}
+
+	switch format {
+	case models.UserTableFormatCSV:
+		if _, err := w.Write(utf8BOM); err != nil {
+			return err
+		}
+		// 表格软件打开 CSV 时会把 =、+、-、@ 开头的内容当作公式执行，加 ' 前缀按文本处理，导入时去掉
+		for _, record := range records[1:] {
+			for col, value := range record {
+				record[col] = escapeUserTableCell(value)
+			}
+		}
+		writer := csv.NewWriter(w)
+		if err := writer.WriteAll(records); err != nil {
+			return err
+		}
+		return writer.Error()
+	case models.UserTableFormatXLSX:
+		file := excelize.NewFile()
+		defer file.Close()
+		sheet := file.GetSheetName(0)
+		for idx, record := range records {
+			for col, value := range record {
+				axis, err := excelize.CoordinatesToCellName(col+1, idx+1)
+				if err != nil {
+					return err
+				}
+				// 统一按文本单元格写入，避免手机号被当作数字、= 开头的内容被当作公式
+				if err := file.SetCellStr(sheet, axis, value); err != nil {
+					return err
+				}
+			}
+		}
+		return file.Write(w)
+	default:
+		return ErrUserTableFormat
+	}
+}
+
+func normalizeUserImportRow(row *models.UserImportRow) {
+	row.Email = strings.TrimSpace(row.Email)
+	row.Name = strings.TrimSpace(row.Name)
+	row.GitLabUsername = strings.TrimPrefix(strings.TrimSpace(row.GitLabUsername), "@")
+	row.Phone = strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(row.Phone))
+}
+
+// escapeUserTableCell 为可能被表格软件当作公式的内容加上 ' 前缀
+func escapeUserTableCell(value string) string {
+	if value != "" && strings.ContainsRune(userTableFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// unescapeUserTableCell 去掉导出时加上的 ' 前缀
+func unescapeUserTableCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(userTableFormulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+func validImportEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/database"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+func newUserImportTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.Init(filepath.Join(t.TempDir(), "import.db"))
+	if err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestUserImportParse(t *testing.T) {
+	bom := string(utf8BOM)
+
+	cases := []struct {
+		name string
+		data string
+		want []models.UserImportRow
+	}{
+		{
+			name: "english headers",
+			data: "email,phone,name,gitlab_username\nalice@example.com,13800000000,Alice,alice\n",
+			want: []models.UserImportRow{
+				{Line: 2, Email: "alice@example.com", Phone: "13800000000", Name: "Alice", GitLabUsername: "alice"},
+			},
+		},
+		{
+			name: "chinese headers with bom",
+			data: bom + "邮箱,手机号,姓名,GitLab 用户名\nalice@example.com,13800000000,张三,alice\n",
+			want: []models.UserImportRow{
+				{Line: 2, Email: "alice@example.com", Phone: "13800000000", Name: "张三", GitLabUsername: "alice"},
+			},
+		},
+		{
+			name: "header aliases ignore case and spaces",
+			data: " EMAIL , Phone,NAME,GitLab用户名\nalice@example.com,13800000000,Alice,alice\n",
+			want: []models.UserImportRow{
+				{Line: 2, Email: "alice@example.com", Phone: "13800000000", Name: "Alice", GitLabUsername: "alice"},
+			},
+		},
+		{
+			name: "columns in any order with unknown columns",
+			data: "department,gitlab_username,email\nqa,bob,bob@example.com\n",
+			want: []models.UserImportRow{
+				{Line: 2, Email: "bob@example.com", GitLabUsername: "bob"},
+			},
+		},
+		{
+			name: "first duplicate header wins",
+			data: "email,邮箱\nfirst@example.com,second@example.com\n",
+			want: []models.UserImportRow{
+				{Line: 2, Email: "first@example.com"},
+			},
+		},
+		{
+			name: "blank rows are skipped but keep line numbers",
+			data: "email,phone\n,\nalice@example.com, 13800000000 \n  ,  \nbob@example.com\n",
+			want: []models.UserImportRow{
+				{Line: 3, Email: "alice@example.com", Phone: "13800000000"},
+				{Line: 5, Email: "bob@example.com"},
+			},
+		},
+		{
+			name: "quoted cells",
+			data: "email,name\n\"carol@example.com\",\"Li, Carol\"\n",
+			want: []models.UserImportRow{
+				{Line: 2, Email: "carol@example.com", Name: "Li, Carol"},
+			},
+		},
+		{
+			name: "formula escape prefix is removed",
+			data: "email,phone,name\nalice@example.com,'+8613800000000,'=Alice\nbob@example.com,,'Bob\n",
+			want: []models.UserImportRow{
+				{Line: 2, Email: "alice@example.com", Phone: "+8613800000000", Name: "=Alice"},
+				{Line: 3, Email: "bob@example.com", Name: "'Bob"},
+			},
+		},
+		{
+			name: "header only",
+			data: bom + "email,phone\n",
+			want: []models.UserImportRow{},
+		},
+	}
+
+	service := &userImportService{}
+	for _, tc := range cases {
+		rows, err := service.Parse(models.UserTableFormatCSV, strings.NewReader(tc.data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(rows, tc.want) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.want, rows)
+		}
+	}
+}
+
+func TestUserImportParseErrors(t *testing.T) {
+	tooLarge := "email\n" + strings.Repeat("user@example.com\n", userImportMaxRows+1)
+
+	cases := []struct {
+		name   string
+		format string
+		data   string
+		want   error
+	}{
+		{"unknown format", "json", "email\n", ErrUserTableFormat},
+		{"empty file", models.UserTableFormatCSV, "", ErrUserTableHeader},
+		{"bom only", models.UserTableFormatCSV, string(utf8BOM), ErrUserTableHeader},
+		{"missing email column", models.UserTableFormatCSV, "phone,name\n13800000000,Alice\n", ErrUserTableHeader},
+		{"too many rows", models.UserTableFormatCSV, tooLarge, ErrUserImportTooLarge},
+		{"malformed csv", models.UserTableFormatCSV, "email\n\"alice@example.com\n", nil},
+		{"malformed xlsx", models.UserTableFormatXLSX, "email\n", nil},
+	}
+
+	service := &userImportService{}
+	for _, tc := range cases {
+		_, err := service.Parse(tc.format, strings.NewReader(tc.data))
+		if err == nil {
+			t.Errorf("%s: expected error", tc.name)
+			continue
+		}
+		if tc.want != nil && !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestUserImportExportRoundTrip(t *testing.T) {
+	users := []models.User{
+		{Email: "alice@example.com", Phone: "13800000000", Name: "张三", GitLabUsername: "alice"},
+		{Email: "bob@example.com", Phone: "+8613900000000"},
+		{Email: "mallory@example.com", Name: `=HYPERLINK("http://evil","x")`, GitLabUsername: "-mallory"},
+		{Email: "@eve@example.com", Name: "O'Brien"},
+	}
+	want := []models.UserImportRow{
+		{Line: 2, Email: "alice@example.com", Phone: "13800000000", Name: "张三", GitLabUsername: "alice"},
+		{Line: 3, Email: "bob@example.com", Phone: "+8613900000000"},
+		{Line: 4, Email: "mallory@example.com", Name: `=HYPERLINK("http://evil","x")`, GitLabUsername: "-mallory"},
+		{Line: 5, Email: "@eve@example.com", Name: "O'Brien"},
+	}
+
+	service := &userImportService{}
+	for _, format := range []string{models.UserTableFormatCSV, models.UserTableFormatXLSX} {
+		var buf bytes.Buffer
+		if err := service.Export(users, format, &buf); err != nil {
+			t.Fatalf("%s: export: %v", format, err)
+		}
+		if format == models.UserTableFormatCSV {
+			for _, cell := range []string{"'+8613900000000", `"'=HYPERLINK(""http://evil"",""x"")"`, "'-mallory", "'@eve@example.com"} {
+				if !strings.Contains(buf.String(), cell) {
+					t.Errorf("%s: expected escaped cell %s in %q", format, cell, buf.String())
+				}
+			}
+		}
+		if format == models.UserTableFormatXLSX {
+			file, err := excelize.OpenReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("%s: open: %v", format, err)
+			}
+			sheet := file.GetSheetName(0)
+			if formula, _ := file.GetCellFormula(sheet, "C4"); formula != "" {
+				t.Errorf("%s: expected no formula in C4, got %q", format, formula)
+			}
+			if cellType, _ := file.GetCellType(sheet, "B3"); cellType != excelize.CellTypeSharedString {
+				t.Errorf("%s: expected B3 to be a string cell, got %v", format, cellType)
+			}
+			file.Close()
+		}
+		rows, err := service.Parse(format, &buf)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", format, err)
+		}
+		if !reflect.DeepEqual(rows, want) {
+			t.Errorf("%s: expected %+v, got %+v", format, want, rows)
+		}
+	}
+}
+
+func TestUserImportImport(t *testing.T) {
+	db := newUserImportTestDB(t)
+	existing := []models.User{
+		{Email: "alice@example.com", Phone: "13800000000", Name: "Alice", GitLabUsername: "alice"},
+		{Email: "bob@example.com", Phone: "13900000000", Name: "Bob", GitLabUsername: "bob"},
+		{Email: "grace@example.com", Name: "Grace"},
+	}
+	for idx := range existing {
+		if err := db.Create(&existing[idx]).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	if err := db.Create(&models.UserAlias{UserID: existing[0].ID, Kind: models.UserAliasKindGitLabUsername, Value: "alice.legacy"}).Error; err != nil {
+		t.Fatalf("create alias: %v", err)
+	}
+
+	rows := []models.UserImportRow{
+		{Line: 2, Email: "carol@example.com", Phone: "138-0000 0001", Name: " Carol ", GitLabUsername: "@carol"},
+		{Line: 3, Email: "ALICE@example.com", Name: "Alice Li"},
+		{Line: 4, Email: "bob@example.com", Phone: "13900000000", Name: "Bob", GitLabUsername: "bob"},
+		{Line: 5, Email: "Carol@example.com"},
+		{Line: 6, Email: "dave@example.com", GitLabUsername: "carol"},
+		{Line: 7, Email: "not-an-email"},
+		{Line: 8, Phone: "13800000002"},
+		{Line: 9, Email: "erin@example.com", Phone: "call me"},
+		{Line: 10, Email: "frank@example.com", GitLabUsername: "alice"},
+		{Line: 11, Email: "grace@example.com", GitLabUsername: "alice.legacy"},
+		{Line: 12, Email: "Heidi <heidi@example.com>"},
+		{Line: 13, Email: "ivan@example.com"},
+	}
+	wantActions := map[int]string{
+		2:  models.UserImportActionCreate,
+		3:  models.UserImportActionUpdate,
+		4:  models.UserImportActionUnchanged,
+		5:  models.UserImportActionConflict,
+		6:  models.UserImportActionConflict,
+		7:  models.UserImportActionConflict,
+		8:  models.UserImportActionConflict,
+		9:  models.UserImportActionConflict,
+		10: models.UserImportActionConflict,
+		11: models.UserImportActionConflict,
+		12: models.UserImportActionConflict,
+		13: models.UserImportActionCreate,
+	}
+	wantErrors := map[int]string{
+		5:  "与第 2 行的邮箱重复",
+		6:  "与第 2 行的 GitLab 用户名重复",
+		7:  "邮箱格式不正确: not-an-email",
+		8:  "邮箱不能为空",
+		9:  "手机号格式不正确: callme",
+		10: "GitLab 用户名已被其他用户使用",
+		11: "GitLab 用户名已被其他用户使用",
+	}
+
+	service := NewUserImportService(db)
+	checkResult := func(label string, result *models.UserImportResult) {
+		t.Helper()
+		if result.Created != 2 || result.Updated != 1 || result.Unchanged != 1 || result.Conflicts != 8 {
+			t.Errorf("%s: unexpected summary %+v", label, result)
+		}
+		if len(result.Rows) != len(rows) {
+			t.Fatalf("%s: expected %d rows, got %d", label, len(rows), len(result.Rows))
+		}
+		for _, row := range result.Rows {
+			if row.Action != wantActions[row.Line] {
+				t.Errorf("%s: line %d expected %s, got %s (%s)", label, row.Line, wantActions[row.Line], row.Action, row.Error)
+			}
+			if want, ok := wantErrors[row.Line]; ok && row.Error != want {
+				t.Errorf("%s: line %d expected error %q, got %q", label, row.Line, want, row.Error)
+			}
+		}
+	}
+
+	preview, err := service.Import(rows, models.UserImportOptions{DryRun: true, Upsert: true, AccountID: 1})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	checkResult("dry run", preview)
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	if count != int64(len(existing)) {
+		t.Fatalf("dry run wrote users: expected %d, got %d", len(existing), count)
+	}
+
+	result, err := service.Import(rows, models.UserImportOptions{Upsert: true, AccountID: 1})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	checkResult("import", result)
+
+	var carol models.User
+	if err := db.Where("email = ?", "carol@example.com").First(&carol).Error; err != nil {
+		t.Fatalf("carol not created: %v", err)
+	}
+	if carol.Phone != "13800000001" || carol.Name != "Carol" || carol.GitLabUsername != "carol" ||
+		carol.GitLabMappingSource != models.GitLabMappingSourceManual || carol.CreatedBy == nil || *carol.CreatedBy != 1 {
+		t.Errorf("unexpected created user %+v", carol)
+	}
+	if result.Rows[0].UserID != carol.ID {
+		t.Errorf("expected created row to report user %d, got %d", carol.ID, result.Rows[0].UserID)
+	}
+
+	var alice models.User
+	db.First(&alice, existing[0].ID)
+	if alice.Name != "Alice Li" || alice.Phone != "13800000000" || alice.GitLabUsername != "alice" {
+		t.Errorf("unexpected updated user %+v", alice)
+	}
+	var grace models.User
+	db.First(&grace, existing[2].ID)
+	if grace.GitLabUsername != "" {
+		t.Errorf("conflicting row must not be written, got gitlab username %q", grace.GitLabUsername)
+	}
+	db.Model(&models.User{}).Count(&count)
+	if count != int64(len(existing)+2) {
+		t.Errorf("expected %d users, got %d", len(existing)+2, count)
+	}
+}
+
+func TestUserImportImportExistingUsers(t *testing.T) {
+	db := newUserImportTestDB(t)
+	alice := models.User{Email: "alice@example.com", Phone: "13800000000", Name: "Alice", GitLabUsername: "alice"}
+	if err := db.Create(&alice).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := db.Create(&models.UserAlias{UserID: alice.ID, Kind: models.UserAliasKindEmail, Value: "alice.old@example.com"}).Error; err != nil {
+		t.Fatalf("create alias: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		row     models.UserImportRow
+		opts    models.UserImportOptions
+		action  string
+		message string
+		changes int
+	}{
+		{
+			name:    "existing user without upsert",
+			row:     models.UserImportRow{Line: 2, Email: "alice@example.com", Name: "Alice Li"},
+			opts:    models.UserImportOptions{DryRun: true},
+			action:  models.UserImportActionConflict,
+			message: "用户已存在",
+		},
+		{
+			name:    "existing user not editable",
+			row:     models.UserImportRow{Line: 2, Email: "alice@example.com", Name: "Alice Li"},
+			opts:    models.UserImportOptions{DryRun: true, Upsert: true, EditableUserIDs: map[uint]bool{alice.ID + 1: true}},
+			action:  models.UserImportActionConflict,
+			message: "无权修改该用户",
+		},
+		{
+			name:    "existing user editable",
+			row:     models.UserImportRow{Line: 2, Email: "alice@example.com", Phone: "13800000009", Name: "Alice Li", GitLabUsername: "alice.li"},
+			opts:    models.UserImportOptions{DryRun: true, Upsert: true, EditableUserIDs: map[uint]bool{alice.ID: true}},
+			action:  models.UserImportActionUpdate,
+			changes: 3,
+		},
+		{
+			name:   "empty cells keep existing values",
+			row:    models.UserImportRow{Line: 2, Email: "alice@example.com"},
+			opts:   models.UserImportOptions{DryRun: true, Upsert: true},
+			action: models.UserImportActionUnchanged,
+		},
+		{
+			name:    "email alias matches the existing user",
+			row:     models.UserImportRow{Line: 2, Email: "alice.old@example.com", Name: "Alice Li"},
+			opts:    models.UserImportOptions{DryRun: true, Upsert: true},
+			action:  models.UserImportActionUpdate,
+			changes: 1,
+		},
+	}
+
+	service := NewUserImportService(db)
+	for _, tc := range cases {
+		result, err := service.Import([]models.UserImportRow{tc.row}, tc.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		row := result.Rows[0]
+		if row.Action != tc.action || row.Error != tc.message || len(row.Changes) != tc.changes {
+			t.Errorf("%s: expected %s %q with %d changes, got %s %q %v", tc.name, tc.action, tc.message, tc.changes, row.Action, row.Error, row.Changes)
+		}
+		if row.UserID != alice.ID {
+			t.Errorf("%s: expected user %d, got %d", tc.name, alice.ID, row.UserID)
+		}
+	}
+}
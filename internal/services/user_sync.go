@@ -0,0 +1,478 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserSyncSourceNotFound = errors.New("user sync source not found")
+	ErrUserSyncSourceExists   = errors.New("user sync source already registered")
+	ErrUserSyncRunning        = errors.New("user sync is already running")
+)
+
+// userSyncRunsKeep 保留的同步记录条数
+const userSyncRunsKeep = 50
+
+type userSyncService struct {
+	db      *gorm.DB
+	cfg     *config.Config
+	gitlab  GitLabService
+	targets HookReconcileService
+	alerts  OpsAlertService
+
+	running sync.Mutex
+}
+
+func NewUserSyncService(db *gorm.DB, cfg *config.Config, gitlab GitLabService, targets HookReconcileService, alerts OpsAlertService) UserSyncService {
+	return &userSyncService{db: db, cfg: cfg, gitlab: gitlab, targets: targets, alerts: alerts}
+}
+
+func (s *userSyncService) ListSources() ([]models.UserSyncSource, error) {
+	var sources []models.UserSyncSource
+	if err := s.db.Order("gitlab_instance_id ASC, full_path ASC").Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+func (s *userSyncService) GetSource(id uint) (*models.UserSyncSource, error) {
+	var source models.UserSyncSource
+	if err := s.db.First(&source, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserSyncSourceNotFound
+		}
+		return nil, err
+	}
+	return &source, nil
+}
+
+func (s *userSyncService) CreateSource(source *models.UserSyncSource) error {
+	var count int64
+	err := s.db.Model(&models.UserSyncSource{}).
+		Where("gitlab_instance_id = ? AND source_type = ? AND gitlab_id = ?", source.GitLabInstanceID, source.SourceType, source.GitLabID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrUserSyncSourceExists
+	}
+
+	if err := s.db.Create(source).Error; err != nil {
+		return fmt.Errorf("保存成员来源失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteSource 取消登记成员来源，已同步的用户保留
+func (s *userSyncService) DeleteSource(id uint) error {
+	result := s.db.Delete(&models.UserSyncSource{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserSyncSourceNotFound
+	}
+	return nil
+}
+
+// SyncAll 定时同步全部来源
+func (s *userSyncService) SyncAll(ctx context.Context) error {
+	run, err := s.Sync(ctx, models.UserSyncTriggerSchedule)
+	if errors.Is(err, ErrUserSyncRunning) {
+		logger.GetLogger().Infof("用户目录同步正在进行，跳过本次定时同步")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if run.Status != models.UserSyncStatusSuccess {
+		return fmt.Errorf("用户目录同步未全部完成: %s", run.Error)
+	}
+	return nil
+}
+
+// Sync 读取来源的成员，按 GitLab 用户 ID 创建或更新用户，结果写入同步记录；同一时间只允许一次同步
+func (s *userSyncService) Sync(ctx context.Context, trigger string, sourceIDs ...uint) (*models.UserSyncRun, error) {
+	if !s.running.TryLock() {
+		return nil, ErrUserSyncRunning
+	}
+	defer s.running.Unlock()
+
+	query := s.db.Order("id ASC")
+	if len(sourceIDs) > 0 {
+		query = query.Where("id IN ?", sourceIDs)
+	}
+	var sources []models.UserSyncSource
+	if err := query.Find(&sources).Error; err != nil {
+		return nil, err
+	}
+
+	run := &models.UserSyncRun{
+		Trigger:   trigger,
+		Status:    models.UserSyncStatusSuccess,
+		Sources:   len(sources),
+		StartedAt: time.Now(),
+	}
+	syncer := &userDirectorySync{service: s, run: run, seen: make(map[string]uint)}
+
+	var failed []string
+	for idx := range sources {
+		if err := ctx.Err(); err != nil {
+			failed = append(failed, err.Error())
+			break
+		}
+		if err := syncer.syncSource(ctx, &sources[idx]); err != nil {
+			logger.GetLogger().Warnf("同步成员来源 %s 失败: %v", sources[idx].FullPath, err)
+			failed = append(failed, fmt.Sprintf("%s: %v", sources[idx].FullPath, err))
+		}
+	}
+	run.MissingPhone = syncer.countMissingPhone()
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	if len(failed) > 0 {
+		run.Status = models.UserSyncStatusPartial
+		if len(failed) == len(sources) {
+			run.Status = models.UserSyncStatusFailed
+		}
+		run.Error = strings.Join(failed, "; ")
+		s.alerts.Notify(OpsAlertEvent{
+			Severity: models.OpsSeverityWarning,
+			Category: models.OpsCategoryUserSync,
+			Key:      "user_sync",
+			Title:    "用户目录同步失败",
+			Detail:   fmt.Sprintf("Error: %s", run.Error),
+		})
+	}
+
+	if err := s.db.Create(run).Error; err != nil {
+		logger.GetLogger().Warnf("保存用户目录同步记录失败: %v", err)
+	}
+	s.pruneRuns()
+
+	logger.GetLogger().Infof("用户目录同步完成：%d 个来源，%d 名成员，新增 %d，更新 %d，关联 %d，跳过 %d，缺少手机号 %d",
+		run.Sources, run.Members, run.Created, run.Updated, run.Linked, run.Skipped, run.MissingPhone)
+	return run, nil
+}
+
+func (s *userSyncService) ListRuns(limit int) ([]models.UserSyncRun, error) {
+	if limit <= 0 || limit > userSyncRunsKeep {
+		limit = userSyncRunsKeep
+	}
+
+	var runs []models.UserSyncRun
+	if err := s.db.Order("id DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// Report 最近一次同步的跳过成员，以及已关联 GitLab 用户但缺少手机号、无法被 @ 的用户
+func (s *userSyncService) Report() (*models.UserSyncReport, error) {
+	report := &models.UserSyncReport{
+		MissingPhone: []models.UserResponse{},
+		Skipped:      []models.UserSyncChange{},
+	}
+
+	var latest models.UserSyncRun
+	err := s.db.Order("id DESC").First(&latest).Error
+	switch {
+	case err == nil:
+		report.LatestRun = &latest
+		for _, change := range latest.Changes {
+			if change.Action == models.UserSyncActionSkipped {
+				report.Skipped = append(report.Skipped, change)
+			}
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	var users []models.User
+	if err := s.db.Where("gitlab_user_id != 0 AND phone = ''").Where(noIdentityCondition).Order("name ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for idx := range users {
+		report.MissingPhone = append(report.MissingPhone, users[idx].ToResponse())
+	}
+	return report, nil
+}
+
+func (s *userSyncService) pruneRuns() {
+	var cutoff models.UserSyncRun
+	if err := s.db.Select("id").Order("id DESC").Offset(userSyncRunsKeep).First(&cutoff).Error; err != nil {
+		return
+	}
+	if err := s.db.Where("id <= ?", cutoff.ID).Delete(&models.UserSyncRun{}).Error; err != nil {
+		logger.GetLogger().Warnf("清理用户目录同步记录失败: %v", err)
+	}
+}
+
+// userDirectorySync 一次同步的状态，seen 记录本次已处理的成员，避免多个来源重复处理
+type userDirectorySync struct {
+	service *userSyncService
+	run     *models.UserSyncRun
+	seen    map[string]uint
+}
+
+func (y *userDirectorySync) syncSource(ctx context.Context, source *models.UserSyncSource) error {
+	s := y.service
+	members, target, err := y.listMembers(ctx, source)
+
+	now := time.Now()
+	updates := map[string]interface{}{"last_sync_at": now, "last_sync_error": ""}
+	if err != nil {
+		updates["last_sync_error"] = err.Error()
+		y.run.Changes = append(y.run.Changes, models.UserSyncChange{
+			Action: models.UserSyncActionSourceFailed, SourceID: source.ID, Detail: err.Error(),
+		})
+	}
+	if updateErr := s.db.Model(&models.UserSyncSource{}).Where("id = ?", source.ID).Updates(updates).Error; updateErr != nil {
+		logger.GetLogger().Warnf("保存成员来源 %s 的同步状态失败: %v", source.FullPath, updateErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if !syncableMember(member) {
+			continue
+		}
+		key := fmt.Sprintf("%d/%d", source.GitLabInstanceID, member.ID)
+		if _, ok := y.seen[key]; ok {
+			continue
+		}
+		y.run.Members++
+		y.seen[key] = y.syncMember(ctx, source, target, member)
+	}
+	return nil
+}
+
+func (y *userDirectorySync) listMembers(ctx context.Context, source *models.UserSyncSource) ([]*GitLabMember, HookTarget, error) {
+	s := y.service
+	target, err := s.targets.ResolveTarget(&models.Project{GitLabInstanceID: source.GitLabInstanceID, CreatedBy: source.CreatedBy})
+	if err != nil {
+		return nil, target, err
+	}
+
+	var members []*GitLabMember
+	if source.SourceType == models.UserSyncSourceProject {
+		members, err = s.gitlab.ListProjectMembers(ctx, target.BaseURL, source.GitLabID, target.Token)
+	} else {
+		members, err = s.gitlab.ListGroupMembers(ctx, target.BaseURL, source.GitLabID, target.Token)
+	}
+	return members, target, err
+}
+
+// syncableMember 跳过已封禁、停用的账号与项目/组访问令牌生成的机器人账号
+func syncableMember(member *GitLabMember) bool {
+	if member.State != "" && member.State != "active" {
+		return false
+	}
+	username := strings.ToLower(member.Username)
+	return !(strings.HasSuffix(username, "_bot") || strings.Contains(username, "_bot_"))
+}
+
+// syncMember 依次按 GitLab 用户 ID、GitLab 用户名、邮箱查找本地用户，找到时同步 GitLab 用户名（GitLab 中改名后随之更新），否则新建；
+// 手机号由人工维护，从不覆盖。返回处理后的用户 ID，未能登记时为 0
+func (y *userDirectorySync) syncMember(ctx context.Context, source *models.UserSyncSource, target HookTarget, member *GitLabMember) uint {
+	s := y.service
+	change := models.UserSyncChange{SourceID: source.ID, GitLabUserID: member.ID, Username: member.Username}
+
+	var user models.User
+	err := s.db.Where("gitlab_instance_id = ? AND gitlab_user_id = ?", source.GitLabInstanceID, member.ID).First(&user).Error
+	if err == nil {
+		if detail := y.refreshUser(&user, member, false); detail != "" {
+			change.Action, change.UserID, change.Detail = models.UserSyncActionUpdated, user.ID, detail
+			y.record(change)
+		}
+		return user.ID
+	}
+
+	// 用户名是别名的成员是已登记用户的另一个 GitLab 账号，不关联也不修改该用户
+	aliased, err := usersByAliases(s.db, models.UserAliasKindGitLabUsername, []string{member.Username})
+	if err != nil {
+		change.Action, change.Detail = models.UserSyncActionSkipped, err.Error()
+		y.record(change)
+		return 0
+	}
+	if aliasUser, ok := aliased[member.Username]; ok {
+		return aliasUser.ID
+	}
+
+	err = s.db.Where("gitlab_username = ? AND gitlab_user_id = 0", member.Username).First(&user).Error
+	if err != nil {
+		email := y.memberEmail(ctx, target, member)
+		if email == "" {
+			change.Action, change.Detail = models.UserSyncActionSkipped, "无法获取邮箱，请为该成员设置公开邮箱或使用管理员令牌"
+			y.record(change)
+			return 0
+		}
+		found, byAlias, findErr := findUserByEmail(s.db, email)
+		if errors.Is(findErr, gorm.ErrRecordNotFound) {
+			return y.createUser(source, member, email, change)
+		}
+		if findErr != nil {
+			change.Action, change.Detail = models.UserSyncActionSkipped, findErr.Error()
+			y.record(change)
+			return 0
+		}
+		if byAlias {
+			return found.ID
+		}
+		user = *found
+	}
+
+	if user.GitLabUserID != 0 && (user.GitLabInstanceID != source.GitLabInstanceID || user.GitLabUserID != member.ID) {
+		change.Action, change.UserID = models.UserSyncActionSkipped, user.ID
+		change.Detail = fmt.Sprintf("本地用户 %s 已关联其他 GitLab 用户", user.Email)
+		y.record(change)
+		return 0
+	}
+
+	user.GitLabInstanceID = source.GitLabInstanceID
+	user.GitLabUserID = member.ID
+	change.Action, change.UserID, change.Detail = models.UserSyncActionLinked, user.ID, y.refreshUser(&user, member, true)
+	y.record(change)
+	return user.ID
+}
+
+// refreshUser 同步 GitLab 用户名并补全姓名，link 为 true 时同时写入关联的 GitLab 用户；返回变更说明，无变更时为空
+func (y *userDirectorySync) refreshUser(user *models.User, member *GitLabMember, link bool) string {
+	s := y.service
+	updates := map[string]interface{}{}
+	var details []string
+
+	if link {
+		updates["gitlab_instance_id"] = user.GitLabInstanceID
+		updates["gitlab_user_id"] = user.GitLabUserID
+	}
+	// 姓名只在为空时补全，与手机号一样以人工维护的为准
+	if member.Name != "" && user.Name == "" {
+		details = append(details, fmt.Sprintf("姓名 -> %q", member.Name))
+		updates["name"] = member.Name
+		user.Name = member.Name
+	}
+	if member.Username != "" && user.GitLabUsername != member.Username {
+		if y.usernameTaken(member.Username, user.ID) {
+			details = append(details, fmt.Sprintf("GitLab 用户名 %s 已被其他用户占用", member.Username))
+		} else {
+			details = append(details, fmt.Sprintf("GitLab 用户名 %q -> %q", user.GitLabUsername, member.Username))
+			updates["gitlab_username"] = member.Username
+			user.GitLabUsername = member.Username
+		}
+	}
+	// 按 GitLab 用户 ID 关联后，用户名映射不再需要人工确认
+	if user.GitLabUsername == member.Username && (user.GitLabMappingPending || user.GitLabMappingSource == "") {
+		updates["gitlab_mapping_source"] = models.GitLabMappingSourceMemberSync
+		updates["gitlab_mapping_pending"] = false
+		user.GitLabMappingSource = models.GitLabMappingSourceMemberSync
+		user.GitLabMappingPending = false
+	}
+
+	if len(updates) == 0 {
+		return ""
+	}
+	if err := s.db.Model(&models.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+		logger.GetLogger().Warnf("更新用户 %s 失败: %v", user.Email, err)
+		return fmt.Sprintf("更新失败: %v", err)
+	}
+	return strings.Join(details, "；")
+}
+
+func (y *userDirectorySync) createUser(source *models.UserSyncSource, member *GitLabMember, email string, change models.UserSyncChange) uint {
+	user := &models.User{
+		Email:               email,
+		Name:                member.Name,
+		GitLabInstanceID:    source.GitLabInstanceID,
+		GitLabUserID:        member.ID,
+		GitLabMappingSource: models.GitLabMappingSourceMemberSync,
+		CreatedBy:           source.CreatedBy,
+	}
+	if !y.usernameTaken(member.Username, 0) {
+		user.GitLabUsername = member.Username
+	}
+
+	if err := y.service.db.Create(user).Error; err != nil {
+		change.Action, change.Detail = models.UserSyncActionSkipped, fmt.Sprintf("创建用户失败: %v", err)
+		y.record(change)
+		return 0
+	}
+
+	change.Action, change.UserID, change.Detail = models.UserSyncActionCreated, user.ID, email
+	y.record(change)
+	return user.ID
+}
+
+// memberEmail 成员接口未返回邮箱时，通过用户接口读取公开邮箱（管理员令牌可读取主邮箱）
+func (y *userDirectorySync) memberEmail(ctx context.Context, target HookTarget, member *GitLabMember) string {
+	if email := strings.TrimSpace(member.Email); email != "" {
+		return email
+	}
+
+	user, err := y.service.gitlab.GetUser(ctx, target.BaseURL, member.ID, target.Token)
+	if err != nil {
+		logger.GetLogger().Debugf("查询 GitLab 用户 %s 失败: %v", member.Username, err)
+		return ""
+	}
+	if candidates := emailCandidates(user); len(candidates) > 0 {
+		return candidates[0].email
+	}
+	return ""
+}
+
+func (y *userDirectorySync) usernameTaken(username string, exceptUserID uint) bool {
+	if username == "" {
+		return true
+	}
+	return gitLabUsernameTaken(y.service.db, username, exceptUserID)
+}
+
+func (y *userDirectorySync) record(change models.UserSyncChange) {
+	switch change.Action {
+	case models.UserSyncActionCreated:
+		y.run.Created++
+	case models.UserSyncActionUpdated:
+		y.run.Updated++
+	case models.UserSyncActionLinked:
+		y.run.Linked++
+	case models.UserSyncActionSkipped:
+		y.run.Skipped++
+	}
+	y.run.Changes = append(y.run.Changes, change)
+}
+
+// noIdentityCondition 用户没有任何渠道身份；既没有手机号也没有渠道身份的用户无法被 @
+const noIdentityCondition = "NOT EXISTS (SELECT 1 FROM user_identities WHERE user_identities.user_id = users.id)"
+
+// countMissingPhone 本次同步到的用户中缺少手机号且没有渠道身份的人数
+func (y *userDirectorySync) countMissingPhone() int {
+	ids := make([]uint, 0, len(y.seen))
+	for _, id := range y.seen {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return 0
+	}
+
+	var count int64
+	if err := y.service.db.Model(&models.User{}).Where("id IN ? AND phone = ''", ids).Where(noIdentityCondition).Count(&count).Error; err != nil {
+		logger.GetLogger().Warnf("统计缺少手机号的用户失败: %v", err)
+		return 0
+	}
+	return int(count)
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/config"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/internal/models"
+	"github.com/Alfonsxh/gitlab-merge-alert-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+var ErrWebhookStatusRunning = errors.New("webhook status refresh already running")
+
+const (
+	defaultWebhookStatusConcurrency = 10
+	// webhookStatusStaleAfter 超过该时长仍未结束的执行视为已中断（例如进程重启），允许重新开始
+	webhookStatusStaleAfter = 30 * time.Minute
+	// webhookStatusRunsKeep 保留的执行记录条数
+	webhookStatusRunsKeep = 20
+)
+
+type webhookStatusService struct {
+	db         *gorm.DB
+	cfg        *config.Config
+	gitlab     GitLabService
+	groups     GitLabGroupService
+	reconciler HookReconcileService
+}
+
+func NewWebhookStatusService(db *gorm.DB, cfg *config.Config, gitlab GitLabService, groups GitLabGroupService, reconciler HookReconcileService) WebhookStatusService {
+	return &webhookStatusService{
+		db:         db,
+		cfg:        cfg,
+		gitlab:     gitlab,
+		groups:     groups,
+		reconciler: reconciler,
+	}
+}
+
+// webhookCheck 单个项目的检查结果
+type webhookCheck struct {
+	project *models.Project
+	hookID  *int
+	synced  bool
+	err     error
+}
+
+// RefreshAll 定时任务入口：已有执行进行中时跳过
+func (s *webhookStatusService) RefreshAll(ctx context.Context) error {
+	run, err := s.startRun(models.WebhookStatusTriggerSchedule)
+	if errors.Is(err, ErrWebhookStatusRunning) {
+		logger.GetLogger().Debugf("项目 hook 状态刷新正在进行，跳过本次定时执行")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.execute(ctx, run)
+}
+
+// TriggerRefresh 立即在后台刷新，返回新建的执行记录；已有执行进行中时返回该执行与 ErrWebhookStatusRunning
+func (s *webhookStatusService) TriggerRefresh() (*models.WebhookStatusRun, error) {
+	run, err := s.startRun(models.WebhookStatusTriggerManual)
+	if err != nil {
+		return run, err
+	}
+
+	go func() {
+		if err := s.execute(context.Background(), run); err != nil {
+			logger.GetLogger().Warnf("刷新项目 hook 状态失败: %v", err)
+		}
+	}()
+	return run, nil
+}
+
+// LatestRun 返回最近一次执行，从未执行过时返回 nil
+func (s *webhookStatusService) LatestRun() (*models.WebhookStatusRun, error) {
+	var run models.WebhookStatusRun
+	if err := s.db.Order("id DESC").First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// CachedResults 返回项目最近一次检查的缓存结果，已由组 Hook 覆盖的项目视为已同步
+func (s *webhookStatusService) CachedResults(projects []models.Project) []models.WebhookStatusResult {
+	coverage := s.groups.CoverageForProjects(projects)
+
+	results := make([]models.WebhookStatusResult, 0, len(projects))
+	for idx := range projects {
+		project := &projects[idx]
+		result := models.WebhookStatusResult{
+			ProjectID:       project.ID,
+			ProjectName:     project.Name,
+			WebhookSynced:   project.WebhookSynced,
+			GitLabWebhookID: project.GitLabWebhookID,
+			CheckedAt:       project.WebhookCheckedAt,
+			Error:           project.WebhookCheckError,
+		}
+		if group := coverage[project.ID]; group != nil {
+			result.WebhookSynced = true
+			result.CoveredByGroupID = &group.ID
+			result.Error = ""
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// startRun 创建执行记录；同一时间只允许一个未过期的执行
+func (s *webhookStatusService) startRun(trigger string) (*models.WebhookStatusRun, error) {
+	var run *models.WebhookStatusRun
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var running models.WebhookStatusRun
+		err := tx.Where("status = ?", models.WebhookStatusRunRunning).Order("id DESC").First(&running).Error
+		switch {
+		case err == nil && time.Since(running.StartedAt) < webhookStatusStaleAfter:
+			run = &running
+			return ErrWebhookStatusRunning
+		case err == nil:
+			now := time.Now()
+			if err := tx.Model(&running).Updates(map[string]interface{}{
+				"status":      models.WebhookStatusRunFailed,
+				"error":       "执行中断",
+				"finished_at": &now,
+			}).Error; err != nil {
+				return err
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		run = &models.WebhookStatusRun{
+			Trigger:   trigger,
+			Status:    models.WebhookStatusRunRunning,
+			StartedAt: time.Now(),
+		}
+		return tx.Create(run).Error
+	})
+	return run, err
+}
+
+func (s *webhookStatusService) execute(ctx context.Context, run *models.WebhookStatusRun) error {
+	runErr := s.checkProjects(ctx, run)
+
+	now := time.Now()
+	run.FinishedAt = &now
+	run.Status = models.WebhookStatusRunCompleted
+	if runErr != nil {
+		run.Status = models.WebhookStatusRunFailed
+		run.Error = runErr.Error()
+	}
+	if err := s.db.Model(run).Updates(map[string]interface{}{
+		"status":      run.Status,
+		"error":       run.Error,
+		"finished_at": run.FinishedAt,
+	}).Error; err != nil {
+		logger.GetLogger().Warnf("保存项目 hook 状态刷新结果失败: %v", err)
+	}
+	s.pruneRuns()
+
+	logger.GetLogger().Infof("项目 hook 状态刷新完成：共 %d 个项目，已同步 %d，缺失 %d，失败 %d，状态变化 %d",
+		run.Total, run.Synced, run.Missing, run.Errors, run.Changed)
+	return runErr
+}
+
+// checkProjects 以有限并发检查项目 hook，检查由工作协程完成，数据库写入集中在当前协程，避免 SQLite 写锁竞争
+func (s *webhookStatusService) checkProjects(ctx context.Context, run *models.WebhookStatusRun) error {
+	var projects []models.Project
+	if err := s.db.Where("gitlab_state = ?", models.ProjectGitLabStateActive).Find(&projects).Error; err != nil {
+		return err
+	}
+	coverage := s.groups.CoverageForProjects(projects)
+
+	pending := make([]*models.Project, 0, len(projects))
+	for idx := range projects {
+		if coverage[projects[idx].ID] == nil {
+			pending = append(pending, &projects[idx])
+		}
+	}
+	run.Total = len(pending)
+	if err := s.db.Model(run).Update("total", run.Total).Error; err != nil {
+		return err
+	}
+
+	concurrency := s.cfg.WebhookStatus.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultWebhookStatusConcurrency
+	}
+
+	jobs := make(chan *models.Project)
+	results := make(chan webhookCheck)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for project := range jobs {
+				results <- s.checkProject(ctx, project)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, project := range pending {
+			select {
+			case jobs <- project:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		s.saveCheck(run, result)
+	}
+	return ctx.Err()
+}
+
+func (s *webhookStatusService) checkProject(ctx context.Context, project *models.Project) webhookCheck {
+	result := webhookCheck{project: project}
+
+	target, err := s.reconciler.ResolveTarget(project)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	hook, err := s.gitlab.FindWebhookByURL(ctx, target.BaseURL, project.GitLabProjectID, target.WebhookURL, target.Token)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	if hook != nil {
+		result.synced = true
+		result.hookID = &hook.ID
+	}
+	return result
+}
+
+// saveCheck 写入项目的检查结果并累计执行进度
+func (s *webhookStatusService) saveCheck(run *models.WebhookStatusRun, result webhookCheck) {
+	project := result.project
+	now := time.Now()
+	updates := map[string]interface{}{
+		"webhook_checked_at":  &now,
+		"webhook_check_error": "",
+	}
+
+	run.Checked++
+	switch {
+	case result.err != nil:
+		run.Errors++
+		updates["webhook_check_error"] = fmt.Sprintf("检查失败: %v", result.err)
+	case result.synced != project.WebhookSynced:
+		run.Changed++
+		updates["webhook_synced"] = result.synced
+		updates["gitlab_webhook_id"] = result.hookID
+		updates["last_sync_at"] = &now
+		logger.GetLogger().Infof("项目 %d 的 webhook 状态由 %v 更新为 %v", project.ID, project.WebhookSynced, result.synced)
+	case !sameWebhookID(result.hookID, project.GitLabWebhookID):
+		// hook 在 GitLab 中被删除后重建时状态不变但 ID 已变化
+		run.Changed++
+		updates["gitlab_webhook_id"] = result.hookID
+		updates["last_sync_at"] = &now
+		logger.GetLogger().Infof("项目 %d 的 webhook ID 已变化，更新记录", project.ID)
+	}
+	if result.err == nil {
+		if result.synced {
+			run.Synced++
+		} else {
+			run.Missing++
+		}
+	}
+
+	if err := s.db.Model(&models.Project{}).Where("id = ?", project.ID).Updates(updates).Error; err != nil {
+		logger.GetLogger().Warnf("保存项目 %d 的 webhook 状态失败: %v", project.ID, err)
+	}
+	if err := s.db.Model(run).Updates(map[string]interface{}{
+		"checked": run.Checked,
+		"synced":  run.Synced,
+		"missing": run.Missing,
+		"errors":  run.Errors,
+		"changed": run.Changed,
+	}).Error; err != nil {
+		logger.GetLogger().Warnf("保存项目 hook 状态刷新进度失败: %v", err)
+	}
+}
+
+func sameWebhookID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (s *webhookStatusService) pruneRuns() {
+	var cutoff models.WebhookStatusRun
+	if err := s.db.Select("id").Order("id DESC").Offset(webhookStatusRunsKeep).First(&cutoff).Error; err != nil {
+		return
+	}
+	if err := s.db.Where("id <= ?", cutoff.ID).Delete(&models.WebhookStatusRun{}).Error; err != nil {
+		logger.GetLogger().Warnf("清理项目 hook 状态刷新记录失败: %v", err)
+	}
+}
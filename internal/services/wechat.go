@@ -22,19 +22,21 @@ type WeChatMessage struct {
 	MsgType string `json:"msgtype"`
 	Text    struct {
 		Content             string   `json:"content"`
+		MentionedList       []string `json:"mentioned_list,omitempty"`
 		MentionedMobileList []string `json:"mentioned_mobile_list,omitempty"`
 	} `json:"text"`
 }
 
-func (s *weChatService) SendMessage(webhookURL, content string, mentionedMobiles []string) error {
+func (s *weChatService) SendMessage(webhookURL, content string, mentionedUserIDs, mentionedMobiles []string) error {
 	logger.GetLogger().Infof("准备发送企业微信消息到: %s", webhookURL)
 	logger.GetLogger().Infof("消息内容: %s", content)
-	logger.GetLogger().Infof("需要@的手机号列表: %v", mentionedMobiles)
+	logger.GetLogger().Infof("需要@的成员: userid=%v, 手机号=%v", mentionedUserIDs, mentionedMobiles)
 
 	message := WeChatMessage{
 		MsgType: "text",
 	}
 	message.Text.Content = content
+	message.Text.MentionedList = mentionedUserIDs
 	message.Text.MentionedMobileList = mentionedMobiles
 
 	// 记录完整的发送数据
@@ -63,18 +65,3 @@ func (s *weChatService) SendMessage(webhookURL, content string, mentionedMobiles
 	logger.GetLogger().Infof("企业微信消息发送成功")
 	return nil
 }
-
-func (s *weChatService) FormatMergeRequestMessage(projectName, sourceBranch, targetBranch, mergeFrom, mergeTitle, clickURL string, mergeToList []string, mentionedMobiles []string) string {
-
-	payload := &MergeRequestPayload{
-		ProjectName:       projectName,
-		SourceBranch:      sourceBranch,
-		TargetBranch:      targetBranch,
-		AuthorName:        mergeFrom,
-		Title:             mergeTitle,
-		URL:               clickURL,
-		MentionedAccounts: mergeToList,
-	}
-
-	return FormatMergeRequestPayloadTextWithPhones(payload, mentionedMobiles)
-}
@@ -0,0 +1,73 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInvalid(t *testing.T) {
+	cases := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"abc * * * *",
+	}
+
+	for _, expr := range cases {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	cases := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"0 10 * * *", time.Date(2026, 10, 19, 9, 30, 0, 0, shanghai), time.Date(2026, 10, 19, 10, 0, 0, 0, shanghai)},
+		{"0 10 * * *", time.Date(2026, 10, 19, 10, 0, 0, 0, shanghai), time.Date(2026, 10, 20, 10, 0, 0, 0, shanghai)},
+		{"0 10 * * 1-5", time.Date(2026, 10, 23, 11, 0, 0, 0, shanghai), time.Date(2026, 10, 26, 10, 0, 0, 0, shanghai)},
+		{"0 10 * * MON", time.Date(2026, 10, 19, 10, 0, 30, 0, shanghai), time.Date(2026, 10, 26, 10, 0, 0, 0, shanghai)},
+		{"*/15 * * * *", time.Date(2026, 10, 19, 9, 46, 0, 0, time.UTC), time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)},
+		{"30 9 1 * *", time.Date(2026, 12, 15, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 10, 19, 23, 59, 0, 0, time.UTC), time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)},
+		// 日与周同时限制时任一匹配即触发：1 号或周一
+		{"0 0 1 * 1", time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range cases {
+		schedule, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.expr, err)
+		}
+		got := schedule.Next(tc.from)
+		if !got.Equal(tc.want) {
+			t.Errorf("%q from %s: expected %s, got %s", tc.expr, tc.from, tc.want, got)
+		}
+	}
+}
+
+func TestNextNeverFires(t *testing.T) {
+	schedule, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := schedule.Next(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Fatalf("expected zero time, got %s", got)
+	}
+}
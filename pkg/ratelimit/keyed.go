@@ -0,0 +1,33 @@
+package ratelimit
+
+import "sync"
+
+// Keyed 按 key 维护独立的令牌桶，例如每个 Webhook 一个桶
+type Keyed struct {
+	mu      sync.Mutex
+	buckets map[string]*TokenBucket
+}
+
+func NewKeyed() *Keyed {
+	return &Keyed{buckets: make(map[string]*TokenBucket)}
+}
+
+// Get 返回 key 对应的令牌桶，速率变化时重建
+func (k *Keyed) Get(key string, perMinute int) *TokenBucket {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	bucket, ok := k.buckets[key]
+	if !ok || bucket.PerMinute() != perMinute {
+		bucket = NewTokenBucket(perMinute)
+		k.buckets[key] = bucket
+	}
+	return bucket
+}
+
+// Remove 删除 key 对应的令牌桶
+func (k *Keyed) Remove(key string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.buckets, key)
+}
@@ -1,13 +1,19 @@
 package ratelimit
 
 import (
+	"context"
+	"errors"
 	"math"
 	"sync"
 	"time"
 )
 
+// ErrWaitExceedsDeadline 等待令牌所需时间超过 context 的截止时间
+var ErrWaitExceedsDeadline = errors.New("ratelimit: wait would exceed context deadline")
+
 type TokenBucket struct {
 	mu         sync.Mutex
+	perMinute  int
 	capacity   float64
 	tokens     float64
 	refillRate float64
@@ -21,6 +27,7 @@ func NewTokenBucket(perMinute int) *TokenBucket {
 	rate := float64(perMinute) / 60.0
 	now := time.Now()
 	return &TokenBucket{
+		perMinute:  perMinute,
 		capacity:   float64(perMinute),
 		tokens:     float64(perMinute),
 		refillRate: rate,
@@ -49,10 +56,62 @@ func (tb *TokenBucket) Allow() bool {
 	return false
 }
 
+// Wait 阻塞直到获得令牌或 ctx 结束。
+//
+// 令牌先被预占（余量可以为负），排队的调用按先后顺序依次获得令牌；
+// 如果 ctx 的截止时间早于可获得令牌的时间，立即返回 ErrWaitExceedsDeadline 而不等待。
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tb.mu.Lock()
+	now := time.Now()
+	tb.refill(now)
+	tb.tokens -= 1.0
+
+	var delay time.Duration
+	if tb.tokens < 0 {
+		delay = time.Duration(-tb.tokens / tb.refillRate * float64(time.Second))
+	}
+	if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+		tb.tokens += 1.0
+		tb.mu.Unlock()
+		return ErrWaitExceedsDeadline
+	}
+	tb.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// 归还预占的令牌
+		tb.mu.Lock()
+		tb.tokens += 1.0
+		tb.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// PerMinute 返回每分钟的令牌数
+func (tb *TokenBucket) PerMinute() int {
+	return tb.perMinute
+}
+
 func (tb *TokenBucket) Remaining() int {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 
 	tb.refill(time.Now())
+	if tb.tokens <= 0 {
+		return 0
+	}
 	return int(math.Floor(tb.tokens + 1e-9))
 }
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketAllow(t *testing.T) {
+	tb := NewTokenBucket(2)
+	if !tb.Allow() || !tb.Allow() {
+		t.Fatal("expected initial burst of 2 tokens")
+	}
+	if tb.Allow() {
+		t.Fatal("expected bucket to be empty")
+	}
+	if got := tb.Remaining(); got != 0 {
+		t.Fatalf("Remaining() = %d, want 0", got)
+	}
+}
+
+func TestTokenBucketWaitForToken(t *testing.T) {
+	tb := NewTokenBucket(600) // 每 100ms 一个令牌
+	for tb.Allow() {
+	}
+
+	start := time.Now()
+	if err := tb.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("Wait() returned after %s, expected to block for a refill", elapsed)
+	}
+}
+
+func TestTokenBucketWaitExceedsDeadline(t *testing.T) {
+	tb := NewTokenBucket(1)
+	if !tb.Allow() {
+		t.Fatal("expected initial token")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := tb.Wait(ctx)
+	if !errors.Is(err, ErrWaitExceedsDeadline) {
+		t.Fatalf("Wait() error = %v, want ErrWaitExceedsDeadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Fatalf("Wait() blocked for %s, expected to fail fast", elapsed)
+	}
+}
+
+func TestTokenBucketWaitCancelReturnsToken(t *testing.T) {
+	tb := NewTokenBucket(60) // 每秒一个令牌
+	for tb.Allow() {
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tb.Wait(ctx) }()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait() error = %v, want context.Canceled", err)
+	}
+
+	tb.mu.Lock()
+	tokens := tb.tokens
+	tb.mu.Unlock()
+	if tokens < -0.1 {
+		t.Fatalf("tokens = %f, expected reservation to be returned", tokens)
+	}
+}
+
+func TestKeyedSeparatesBuckets(t *testing.T) {
+	k := NewKeyed()
+	a := k.Get("a", 1)
+	if !a.Allow() {
+		t.Fatal("expected token for a")
+	}
+	if !k.Get("b", 1).Allow() {
+		t.Fatal("bucket b should not be affected by a")
+	}
+	if k.Get("a", 1) != a {
+		t.Fatal("expected same bucket for unchanged rate")
+	}
+	if k.Get("a", 2) == a {
+		t.Fatal("expected new bucket after rate change")
+	}
+}